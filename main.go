@@ -13,6 +13,7 @@
 	"github.com/ehazlett/simplelog"
 	_ "github.com/rancher/norman/controller"
 	"github.com/rancher/norman/pkg/kwrapper/k8s"
+	"github.com/rancher/rancher/pkg/auth/audit"
 	"github.com/rancher/rancher/pkg/data/management"
 	"github.com/rancher/rancher/pkg/logserver"
 	"github.com/rancher/rancher/pkg/rancher"
@@ -30,6 +31,7 @@
 func main() {
 	management.RegisterPasswordResetCommand()
 	management.RegisterEnsureDefaultAdminCommand()
+	audit.RegisterVerifyCommand()
 	if reexec.Init() {
 		return
 	}
@@ -148,6 +150,24 @@ func main() {
 			Usage:       "Audit log level: 0 - disable audit log, 1 - log event metadata, 2 - log event metadata and request body, 3 - log event metadata, request body and response body",
 			Destination: &config.AuditLevel,
 		},
+		cli.StringFlag{
+			Name:        "audit-policy-file",
+			EnvVar:      "AUDIT_POLICY_FILE",
+			Usage:       "Path to an audit policy file with ordered rules selecting the audit level per request. Requests matching no rule use audit-level",
+			Destination: &config.AuditPolicyFile,
+		},
+		cli.BoolFlag{
+			Name:        "audit-log-hash-chain",
+			EnvVar:      "AUDIT_LOG_HASH_CHAIN",
+			Usage:       "Chain audit log entries with sequence numbers and hashes so modifications can be detected with verify-audit-log",
+			Destination: &config.AuditLogHashChain,
+		},
+		cli.StringFlag{
+			Name:        "audit-log-hash-chain-key-file",
+			EnvVar:      "AUDIT_LOG_HASH_CHAIN_KEY_FILE",
+			Usage:       "File containing the HMAC key used to chain audit log entries. If unset entries are chained with plain SHA-256",
+			Destination: &config.AuditLogHashChainKeyFile,
+		},
 		cli.StringFlag{
 			Name:        "profile-listen-address",
 			Value:       "127.0.0.1:6060",
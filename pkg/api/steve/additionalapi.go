@@ -14,6 +14,7 @@
 	"github.com/rancher/rancher/pkg/provisioningv2/rke2/configserver"
 	"github.com/rancher/rancher/pkg/provisioningv2/rke2/server"
 	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/tunnelserver"
 	"github.com/rancher/rancher/pkg/wrangler"
 	steve "github.com/rancher/steve/pkg/server"
 )
@@ -64,5 +65,5 @@ func AdditionalAPIs(ctx context.Context, config *wrangler.Context, steve *steve.
 func Tunnel(config *wrangler.Context) http.Handler {
 	config.TunnelAuthorizer.Add(proxy.NewAuthorizer(config))
 	config.TunnelAuthorizer.Add(aggregation.New(config))
-	return config.TunnelServer
+	return tunnelserver.SessionMetrics(config.TunnelServer)
 }
@@ -0,0 +1,44 @@
+package catalog
+
+import (
+	"github.com/rancher/apiserver/pkg/types"
+	catalogcontrollers "github.com/rancher/rancher/pkg/generated/controllers/catalog.cattle.io/v1"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/util/retry"
+)
+
+// appInstallStore records the user who created an AppInstall in its status, which the helm controller runs the
+// operations of the AppInstall as. The status can't be set by the request that creates the AppInstall, so AppInstalls
+// created without this store have no creator and are not reconciled.
+type appInstallStore struct {
+	types.Store
+	appInstalls catalogcontrollers.AppInstallClient
+}
+
+func (s *appInstallStore) Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error) {
+	user, ok := request.UserFrom(apiOp.Context())
+	if !ok || user.GetName() == "" {
+		return types.APIObject{}, validation.Unauthorized
+	}
+
+	result, err := s.Store.Create(apiOp, schema, data)
+	if err != nil {
+		return result, err
+	}
+
+	return result, retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		appInstall, err := s.appInstalls.Get(result.Name(), metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if appInstall.Status.Creator == user.GetName() {
+			return nil
+		}
+		appInstall = appInstall.DeepCopy()
+		appInstall.Status.Creator = user.GetName()
+		_, err = s.appInstalls.UpdateStatus(appInstall)
+		return err
+	})
+}
@@ -0,0 +1,83 @@
+package catalog
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	catalogcontrollers "github.com/rancher/rancher/pkg/generated/controllers/catalog.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+type fakeStore struct {
+	types.Store
+	created []types.APIObject
+}
+
+func (f *fakeStore) Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error) {
+	f.created = append(f.created, data)
+	return data, nil
+}
+
+type fakeAppInstallClient struct {
+	catalogcontrollers.AppInstallClient
+	appInstalls map[string]*catalog.AppInstall
+}
+
+func (f *fakeAppInstallClient) Get(name string, opts metav1.GetOptions) (*catalog.AppInstall, error) {
+	return f.appInstalls[name], nil
+}
+
+func (f *fakeAppInstallClient) UpdateStatus(appInstall *catalog.AppInstall) (*catalog.AppInstall, error) {
+	f.appInstalls[appInstall.Name] = appInstall
+	return appInstall, nil
+}
+
+func apiRequest(t *testing.T, u user.Info) *types.APIRequest {
+	req, err := http.NewRequest(http.MethodPost, "/v1/catalog.cattle.io.appinstalls", nil)
+	require.NoError(t, err)
+	ctx := context.Background()
+	if u != nil {
+		ctx = request.WithUser(ctx, u)
+	}
+	return &types.APIRequest{Request: req.WithContext(ctx)}
+}
+
+func TestAppInstallStoreCreate(t *testing.T) {
+	inner := &fakeStore{}
+	client := &fakeAppInstallClient{appInstalls: map[string]*catalog.AppInstall{
+		"app": {
+			ObjectMeta: metav1.ObjectMeta{Name: "app"},
+			Status:     catalog.AppInstallStatus{Creator: "user-admin"},
+		},
+	}}
+	store := &appInstallStore{
+		Store:       inner,
+		appInstalls: client,
+	}
+	data := types.APIObject{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name": "app",
+			"annotations": map[string]interface{}{
+				"field.cattle.io/creatorId": "user-admin",
+			},
+		},
+		"status": map[string]interface{}{
+			"creator": "user-admin",
+		},
+	}}
+
+	_, err := store.Create(apiRequest(t, &user.DefaultInfo{Name: "u-dev"}), nil, data)
+	require.NoError(t, err)
+	assert.Equal(t, "u-dev", client.appInstalls["app"].Status.Creator)
+
+	_, err = store.Create(apiRequest(t, nil), nil, data)
+	assert.Error(t, err)
+	assert.Len(t, inner.created, 1)
+}
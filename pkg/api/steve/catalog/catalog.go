@@ -11,6 +11,7 @@
 	"github.com/rancher/rancher/pkg/apis/catalog.cattle.io"
 	"github.com/rancher/rancher/pkg/catalogv2/content"
 	"github.com/rancher/rancher/pkg/catalogv2/helmop"
+	catalogcontrollers "github.com/rancher/rancher/pkg/generated/controllers/catalog.cattle.io/v1"
 	schema2 "github.com/rancher/steve/pkg/schema"
 	steve "github.com/rancher/steve/pkg/server"
 	schemas3 "github.com/rancher/wrangler/pkg/schemas"
@@ -19,7 +20,8 @@
 
 func Register(ctx context.Context, server *steve.Server,
 	helmop *helmop.Operations,
-	contentManager *content.Manager) error {
+	contentManager *content.Manager,
+	appInstalls catalogcontrollers.AppInstallClient) error {
 	ops := newOperation(helmop)
 	server.ClusterCache.OnAdd(ctx, ops.OnAdd)
 	server.ClusterCache.OnChange(ctx, ops.OnChange)
@@ -28,17 +30,23 @@ func Register(ctx context.Context, server *steve.Server,
 		contentManager: contentManager,
 	}
 
-	addSchemas(server, ops, index)
+	addSchemas(server, ops, index, appInstalls)
 	return nil
 }
 
-func addSchemas(server *steve.Server, ops *operation, index http.Handler) {
+func addSchemas(server *steve.Server, ops *operation, index http.Handler, appInstalls catalogcontrollers.AppInstallClient) {
 	server.BaseSchemas.MustImportAndCustomize(types2.ChartUninstallAction{}, nil)
+	server.BaseSchemas.MustImportAndCustomize(types2.ChartRollbackAction{}, nil)
 	server.BaseSchemas.MustImportAndCustomize(types2.ChartUpgradeAction{}, nil)
 	server.BaseSchemas.MustImportAndCustomize(types2.ChartUpgrade{}, nil)
 	server.BaseSchemas.MustImportAndCustomize(types2.ChartInstallAction{}, nil)
 	server.BaseSchemas.MustImportAndCustomize(types2.ChartInstall{}, nil)
 	server.BaseSchemas.MustImportAndCustomize(types2.ChartActionOutput{}, nil)
+	server.BaseSchemas.MustImportAndCustomize(types2.ChartDryRunOutput{}, nil)
+	server.BaseSchemas.MustImportAndCustomize(types2.ChartDryRun{}, nil)
+	server.BaseSchemas.MustImportAndCustomize(types2.ResourceDiff{}, nil)
+	server.BaseSchemas.MustImportAndCustomize(types2.ReleaseHistory{}, nil)
+	server.BaseSchemas.MustImportAndCustomize(types2.ReleaseRevision{}, nil)
 
 	operationTemplate := schema2.Template{
 		Group: catalog.GroupName,
@@ -60,12 +68,20 @@ func addSchemas(server *steve.Server, ops *operation, index http.Handler) {
 		Customize: func(apiSchema *types.APISchema) {
 			apiSchema.ActionHandlers = map[string]http.Handler{
 				"uninstall": ops,
+				"rollback":  ops,
 			}
 			apiSchema.ResourceActions = map[string]schemas3.Action{
 				"uninstall": {
 					Input:  "chartUninstallAction",
 					Output: "chartActionOutput",
 				},
+				"rollback": {
+					Input:  "chartRollbackAction",
+					Output: "chartActionOutput",
+				},
+			}
+			apiSchema.LinkHandlers = map[string]http.Handler{
+				"history": ops,
 			}
 		},
 	}
@@ -107,12 +123,23 @@ func addSchemas(server *steve.Server, ops *operation, index http.Handler) {
 	}
 	chartRepoTemplate := repoTemplate
 	chartRepoTemplate.Kind = "ClusterRepo"
+	appInstallTemplate := schema2.Template{
+		Group: catalog.GroupName,
+		Kind:  "AppInstall",
+		StoreFactory: func(innerStore types.Store) types.Store {
+			return &appInstallStore{
+				Store:       innerStore,
+				appInstalls: appInstalls,
+			}
+		},
+	}
 
 	server.SchemaFactory.AddTemplate(
 		operationTemplate,
 		appTemplate,
 		repoTemplate,
-		chartRepoTemplate)
+		chartRepoTemplate,
+		appInstallTemplate)
 }
 
 func isClusterRepo(typeName string) bool {
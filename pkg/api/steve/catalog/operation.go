@@ -1,8 +1,12 @@
 package catalog
 
 import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
 	"net/http"
 
+	"github.com/rancher/apiserver/pkg/apierror"
 	"github.com/rancher/apiserver/pkg/types"
 	catalogtypes "github.com/rancher/rancher/pkg/api/steve/catalog/types"
 	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
@@ -39,6 +43,17 @@ func (o *operation) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	)
 
 	ns, name := nsAndName(apiRequest)
+	switch apiRequest.Action {
+	case "install", "upgrade":
+		if dryRun, err := isDryRun(req); err != nil {
+			apiRequest.WriteError(err)
+			return
+		} else if dryRun {
+			o.dryRun(apiRequest, ns, name)
+			return
+		}
+	}
+
 	switch apiRequest.Action {
 	case "install":
 		op, err = o.ops.Install(apiRequest.Context(), user, ns, name, req.Body)
@@ -46,12 +61,23 @@ func (o *operation) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 		op, err = o.ops.Upgrade(apiRequest.Context(), user, ns, name, req.Body)
 	case "uninstall":
 		op, err = o.ops.Uninstall(apiRequest.Context(), user, ns, name, req.Body)
+	case "rollback":
+		op, err = o.ops.Rollback(apiRequest.Context(), user, ns, name, req.Body)
 	}
 
 	switch apiRequest.Link {
 	case "logs":
 		err = o.ops.Log(apiRequest.Response, apiRequest.Request,
 			apiRequest.Namespace, apiRequest.Name)
+	case "history":
+		var history *catalogtypes.ReleaseHistory
+		history, err = o.ops.History(apiRequest.Context(), apiRequest.Namespace, apiRequest.Name)
+		if err == nil {
+			apiRequest.WriteResponse(http.StatusOK, types.APIObject{
+				Type:   "releaseHistory",
+				Object: history,
+			})
+		}
 	}
 
 	if err != nil {
@@ -72,6 +98,36 @@ func (o *operation) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	})
 }
 
+func (o *operation) dryRun(apiRequest *types.APIRequest, ns, name string) {
+	output, err := o.ops.DryRun(apiRequest.Context(), apiRequest.Action, ns, name, apiRequest.Request.Body)
+	if err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+
+	apiRequest.WriteResponse(http.StatusOK, types.APIObject{
+		Type:   "chartDryRunOutput",
+		Object: output,
+	})
+}
+
+// isDryRun peeks at the dryRun field of an install or upgrade request, leaving the body to be read again
+func isDryRun(req *http.Request) (bool, error) {
+	data, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return false, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+	var options struct {
+		DryRun bool `json:"dryRun"`
+	}
+	if err := json.Unmarshal(data, &options); err != nil {
+		return false, apierror.NewAPIError(validation.InvalidBodyContent, err.Error())
+	}
+	return options.DryRun, nil
+}
+
 func (o *operation) OnAdd(gvk schema2.GroupVersionKind, key string, obj runtime.Object) error {
 	return o.ops.Impersonator.PurgeOldRoles(gvk, key, obj)
 }
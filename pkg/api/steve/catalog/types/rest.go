@@ -1,6 +1,7 @@
 package types
 
 import (
+	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
 	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -21,6 +22,7 @@ type ChartInstallAction struct {
 	DisableOpenAPIValidation bool             `json:"disableOpenAPIValidation,omitempty"`
 	Namespace                string           `json:"namespace,omitempty"`
 	ProjectID                string           `json:"projectId,omitempty"`
+	DryRun                   bool             `json:"dryRun,omitempty"`
 
 	Charts []ChartInstall `json:"charts,omitempty"`
 }
@@ -41,6 +43,15 @@ type ChartUninstallAction struct {
 	Description  string           `json:"description,omitempty"`
 }
 
+type ChartRollbackAction struct {
+	// Revision is the release revision to roll back to, the previous one if unset
+	Revision      int              `json:"revision,omitempty"`
+	Timeout       *metav1.Duration `json:"timeout,omitempty"`
+	Wait          bool             `json:"wait,omitempty"`
+	CleanupOnFail bool             `json:"cleanupOnFail,omitempty"`
+	Force         bool             `json:"force,omitempty"`
+}
+
 type ChartUpgradeAction struct {
 	Timeout                  *metav1.Duration `json:"timeout,omitempty"`
 	Wait                     bool             `json:"wait,omitempty"`
@@ -52,6 +63,7 @@ type ChartUpgradeAction struct {
 	Install                  bool             `json:"install,omitempty"`
 	Namespace                string           `json:"namespace,omitempty"`
 	CleanupOnFail            bool             `json:"cleanupOnFail,omitempty"`
+	DryRun                   bool             `json:"dryRun,omitempty"`
 	Charts                   []ChartUpgrade   `json:"charts,omitempty"`
 }
 
@@ -70,3 +82,45 @@ type ChartActionOutput struct {
 	OperationName      string `json:"operationName,omitempty"`
 	OperationNamespace string `json:"operationNamespace,omitempty"`
 }
+
+type ChartDryRunOutput struct {
+	Charts []ChartDryRun `json:"charts,omitempty"`
+}
+
+type ChartDryRun struct {
+	ChartName   string `json:"chartName,omitempty"`
+	Version     string `json:"version,omitempty"`
+	ReleaseName string `json:"releaseName,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+	// Manifest is the rendered chart, without hooks
+	Manifest string `json:"manifest,omitempty"`
+	// Diff lists the resources that are added, removed or changed compared to the deployed release
+	Diff []ResourceDiff `json:"diff,omitempty"`
+	// Verification is the result of checking the signature of the chart, if its repo verifies charts
+	Verification *catalog.ChartVerification `json:"verification,omitempty"`
+}
+
+type ResourceDiff struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name,omitempty"`
+	// Change is one of added, removed or changed
+	Change string `json:"change,omitempty"`
+	// Patch is the JSON merge patch from the deployed resource to the rendered one, for changed resources
+	Patch string `json:"patch,omitempty"`
+}
+
+type ReleaseHistory struct {
+	Revisions []ReleaseRevision `json:"revisions,omitempty"`
+}
+
+type ReleaseRevision struct {
+	Revision     int          `json:"revision,omitempty"`
+	ChartName    string       `json:"chartName,omitempty"`
+	ChartVersion string       `json:"chartVersion,omitempty"`
+	AppVersion   string       `json:"appVersion,omitempty"`
+	Status       string       `json:"status,omitempty"`
+	Description  string       `json:"description,omitempty"`
+	Updated      *metav1.Time `json:"updated,omitempty"`
+}
@@ -0,0 +1,72 @@
+package provisioningcluster
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/controllers/provisioningv2/rke2/provisioningcluster"
+	provisioningcontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
+	rkecontrollers "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/provisioningv2/rke2/planner"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+)
+
+type planPreview struct {
+	clusters         provisioningcontrollers.ClusterCache
+	rkeControlPlanes rkecontrollers.RKEControlPlaneCache
+	planner          *planner.Planner
+}
+
+func (p *planPreview) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	apiRequest := types.GetAPIContext(req.Context())
+	if err := apiRequest.AccessControl.CanUpdate(apiRequest, types.APIObject{}, apiRequest.Schema); err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+
+	preview, err := p.preview(apiRequest)
+	if err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+
+	apiRequest.WriteResponse(http.StatusOK, types.APIObject{
+		Type:   "planPreview",
+		Object: preview,
+	})
+}
+
+// preview runs the planner against the cluster spec in the request body, or the saved spec if the body has no
+// rkeConfig. The latter shows what the planner has yet to roll out.
+func (p *planPreview) preview(apiRequest *types.APIRequest) (*planner.PlanPreview, error) {
+	cluster, err := p.clusters.Get(apiRequest.Namespace, apiRequest.Name)
+	if err != nil {
+		return nil, err
+	}
+	if cluster.Spec.RKEConfig == nil {
+		return nil, apierror.NewAPIError(validation.ActionNotAvailable, "cluster is not provisioned with RKE2 or K3s")
+	}
+
+	input := &rancherv1.Cluster{}
+	if err := json.NewDecoder(apiRequest.Request.Body).Decode(input); err != nil && err != io.EOF {
+		return nil, apierror.NewAPIError(validation.InvalidBodyContent, err.Error())
+	}
+
+	proposed := cluster.DeepCopy()
+	if input.Spec.RKEConfig != nil {
+		proposed.Spec = input.Spec
+	}
+
+	controlPlane, err := p.rkeControlPlanes.Get(cluster.Namespace, cluster.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	controlPlane = controlPlane.DeepCopy()
+	controlPlane.Spec = provisioningcluster.RKEControlPlane(proposed).Spec
+	return p.planner.Preview(controlPlane)
+}
@@ -0,0 +1,49 @@
+package provisioningcluster
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/rancher/pkg/provisioningv2/rke2/planner"
+	"github.com/rancher/rancher/pkg/wrangler"
+	schema2 "github.com/rancher/steve/pkg/schema"
+	steve "github.com/rancher/steve/pkg/server"
+	schemas3 "github.com/rancher/wrangler/pkg/schemas"
+)
+
+func Register(ctx context.Context, server *steve.Server, clients *wrangler.Context) {
+	preview := &planPreview{
+		clusters:         clients.Provisioning.Cluster().Cache(),
+		rkeControlPlanes: clients.RKE.RKEControlPlane().Cache(),
+		planner:          planner.New(ctx, clients),
+	}
+
+	registries := &registryValidator{
+		clusters:     clients.Provisioning.Cluster().Cache(),
+		clusterStore: clients.Provisioning.Cluster(),
+		secrets:      clients.Core.Secret().Cache(),
+	}
+
+	server.BaseSchemas.MustImportAndCustomize(planner.PlanPreview{}, nil)
+	server.BaseSchemas.MustImportAndCustomize(planner.RegistryValidation{}, nil)
+	server.SchemaFactory.AddTemplate(schema2.Template{
+		Group: "provisioning.cattle.io",
+		Kind:  "Cluster",
+		Customize: func(apiSchema *types.APISchema) {
+			apiSchema.ActionHandlers = map[string]http.Handler{
+				"previewPlan":        preview,
+				"validateRegistries": registries,
+			}
+			apiSchema.ResourceActions = map[string]schemas3.Action{
+				"previewPlan": {
+					Input:  "provisioning.cattle.io.cluster",
+					Output: "planPreview",
+				},
+				"validateRegistries": {
+					Output: "registryValidation",
+				},
+			}
+		},
+	})
+}
@@ -0,0 +1,84 @@
+package provisioningcluster
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	provisioningcontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/provisioningv2/rke2/planner"
+	"github.com/rancher/wrangler/pkg/condition"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+)
+
+// RegistriesValid reports the result of the last validation of the registries config saved on a cluster
+var RegistriesValid = condition.Cond("RegistriesValid")
+
+type registryValidator struct {
+	clusters     provisioningcontrollers.ClusterCache
+	clusterStore provisioningcontrollers.ClusterClient
+	secrets      corecontrollers.SecretCache
+}
+
+func (r *registryValidator) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	apiRequest := types.GetAPIContext(req.Context())
+	result, err := r.validate(apiRequest)
+	if err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+
+	apiRequest.WriteResponse(http.StatusOK, types.APIObject{
+		Type:   "registryValidation",
+		Object: result,
+	})
+}
+
+// validate checks the registries config saved on the cluster and reports the result on its RegistriesValid condition.
+// Only saved configs are validated, the rancher server must not be made to dial endpoints taken from a request.
+func (r *registryValidator) validate(apiRequest *types.APIRequest) (*planner.RegistryValidation, error) {
+	cluster, err := r.clusters.Get(apiRequest.Namespace, apiRequest.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := apiRequest.AccessControl.CanDo(apiRequest, "provisioning.cattle.io/clusters", "update", cluster.Namespace, cluster.Name); err != nil {
+		return nil, err
+	}
+	if cluster.Spec.RKEConfig == nil {
+		return nil, apierror.NewAPIError(validation.ActionNotAvailable, "cluster is not provisioned with RKE2 or K3s")
+	}
+
+	result := planner.ValidateRegistries(apiRequest.Context(), r.secrets, cluster.Namespace, cluster.Spec.RKEConfig.Registries)
+	return result, r.setCondition(cluster, result)
+}
+
+func (r *registryValidator) setCondition(cluster *rancherv1.Cluster, result *planner.RegistryValidation) error {
+	var failures []string
+	for _, check := range result.Results {
+		if check.Passed {
+			continue
+		}
+		target := check.Registry
+		if check.Endpoint != "" {
+			target = check.Endpoint
+		}
+		failures = append(failures, fmt.Sprintf("%s: %s", target, check.Message))
+	}
+
+	cluster = cluster.DeepCopy()
+	if result.Valid {
+		RegistriesValid.True(cluster)
+		RegistriesValid.Message(cluster, "")
+	} else {
+		RegistriesValid.False(cluster)
+		RegistriesValid.Message(cluster, strings.Join(failures, "; "))
+	}
+	RegistriesValid.LastUpdated(cluster, time.Now().UTC().Format(time.RFC3339))
+	_, err := r.clusterStore.UpdateStatus(cluster)
+	return err
+}
@@ -7,8 +7,10 @@
 	"github.com/rancher/rancher/pkg/api/steve/clusters"
 	"github.com/rancher/rancher/pkg/api/steve/machine"
 	"github.com/rancher/rancher/pkg/api/steve/navlinks"
+	"github.com/rancher/rancher/pkg/api/steve/provisioningcluster"
 	"github.com/rancher/rancher/pkg/api/steve/settings"
 	"github.com/rancher/rancher/pkg/api/steve/userpreferences"
+	"github.com/rancher/rancher/pkg/features"
 	"github.com/rancher/rancher/pkg/wrangler"
 	steve "github.com/rancher/steve/pkg/server"
 )
@@ -19,10 +21,14 @@ func Setup(ctx context.Context, server *steve.Server, config *wrangler.Context)
 		return err
 	}
 	machine.Register(server, config)
+	if features.RKE2.Enabled() {
+		provisioningcluster.Register(ctx, server, config)
+	}
 	navlinks.Register(ctx, server)
 	settings.Register(server)
 	return catalog.Register(ctx,
 		server,
 		config.HelmOperations,
-		config.CatalogContentManager)
+		config.CatalogContentManager,
+		config.Catalog.AppInstall())
 }
@@ -0,0 +1,105 @@
+package v1
+
+import (
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/wrangler/pkg/genericcondition"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// AppInstall is a chart of a ClusterRepo that is kept installed in the local cluster. The release is installed,
+// upgraded and uninstalled as the user who created the AppInstall through the rancher API.
+type AppInstall struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              AppInstallSpec   `json:"spec"`
+	Status            AppInstallStatus `json:"status"`
+}
+
+type AppInstallSpec struct {
+	// RepoName is the name of the ClusterRepo the chart is installed from
+	RepoName string `json:"repoName,omitempty"`
+
+	// Chart is the name of the chart in the repo index
+	Chart string `json:"chart,omitempty"`
+
+	// Version is a semver constraint, like "~1.2.0" or ">=2.0.0 <3.0.0". The newest version of the repo index
+	// matching it is installed, and the release is upgraded when a newer matching version is indexed.
+	// If unspecified, the newest version that is not a prerelease is installed.
+	Version string `json:"version,omitempty"`
+
+	// ReleaseName is the name of the helm release, defaults to the name of the AppInstall
+	ReleaseName string `json:"releaseName,omitempty"`
+
+	// Namespace the release is installed to, defaults to "default"
+	Namespace string `json:"namespace,omitempty"`
+
+	// ProjectID is the project a missing namespace is created in, as <cluster>:<project>
+	ProjectID string `json:"projectId,omitempty"`
+
+	// ValuesFrom are values.yaml documents read from secrets and config maps of the release namespace, merged in order
+	ValuesFrom []ValuesReference `json:"valuesFrom,omitempty"`
+
+	// Values are merged over the values of ValuesFrom
+	Values v3.MapStringInterface `json:"values,omitempty"`
+}
+
+// ValuesReference is a key of a secret or a config map holding a values.yaml document. One of SecretKeyRef
+// and ConfigMapKeyRef is set.
+type ValuesReference struct {
+	SecretKeyRef    *KeyReference `json:"secretKeyRef,omitempty"`
+	ConfigMapKeyRef *KeyReference `json:"configMapKeyRef,omitempty"`
+}
+
+type KeyReference struct {
+	Name string `json:"name,omitempty"`
+	// Namespace must be empty or the namespace of the release
+	Namespace string `json:"namespace,omitempty"`
+	// Key defaults to "values.yaml"
+	Key string `json:"key,omitempty"`
+}
+
+type AppInstallCondition string
+
+const (
+	AppInstallReconciled AppInstallCondition = "Reconciled"
+)
+
+type AppInstallStatus struct {
+	ObservedGeneration int64 `json:"observedGeneration"`
+
+	// Creator is the user the operations of the AppInstall run as. It is set by the rancher API to the user who
+	// created the AppInstall, AppInstalls without a creator are not reconciled.
+	Creator string `json:"creator,omitempty"`
+
+	// Version is the chart version the release was last deployed with
+	Version string `json:"version,omitempty"`
+
+	// OperationName and OperationNamespace identify the operation last run for the AppInstall
+	OperationName      string `json:"operationName,omitempty"`
+	OperationNamespace string `json:"operationNamespace,omitempty"`
+
+	// AppliedHash is the hash of the chart version and values the last operation was run with
+	AppliedHash string `json:"appliedHash,omitempty"`
+
+	// Drift lists the resources of the release that no longer match the manifest helm deployed
+	Drift []ResourceDrift `json:"drift,omitempty"`
+
+	Conditions []genericcondition.GenericCondition `json:"conditions,omitempty"`
+}
+
+// ResourceDrift is a resource of a release that was changed or deleted after it was deployed
+type ResourceDrift struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name,omitempty"`
+	// Change is missing if the resource was deleted, or modified
+	Change string `json:"change,omitempty"`
+	// Patch is the JSON merge patch restoring the fields set by the chart of a modified resource. The values of
+	// secrets are replaced with "[redacted]".
+	Patch string `json:"patch,omitempty"`
+}
@@ -23,7 +23,8 @@ type SecretReference struct {
 }
 
 type RepoSpec struct {
-	// URL A http URL of the repo to connect to
+	// URL A http URL of the repo to connect to, or an oci:// URL of a registry repository whose tags are
+	// indexed as chart versions
 	URL string `json:"url,omitempty"`
 
 	// GitRepo a git repo to clone and index as the helm repo
@@ -61,6 +62,31 @@ type RepoSpec struct {
 
 	// If disabled the repo clone will not be updated or allowed to be installed from
 	Enabled *bool `json:"enabled,omitempty"`
+
+	// Verification is how the signatures of charts are checked before they are installed or upgraded
+	Verification *RepoVerification `json:"verification,omitempty"`
+}
+
+type VerificationPolicy string
+
+const (
+	// VerificationPolicyNone installs charts without checking their signature
+	VerificationPolicyNone VerificationPolicy = "none"
+	// VerificationPolicyWarn checks the signature of charts and reports the result, but installs unverified charts
+	VerificationPolicyWarn VerificationPolicy = "warn"
+	// VerificationPolicyRequire refuses to install charts whose signature can not be verified
+	VerificationPolicyRequire VerificationPolicy = "require"
+)
+
+type RepoVerification struct {
+	// Policy is one of none, warn or require. Defaults to none.
+	Policy VerificationPolicy `json:"policy,omitempty"`
+
+	// KeyringSecret is the secret holding the public keys charts are verified against. Helm provenance files
+	// are checked against the PGP keyring in the "keyring" key, binary or ASCII armored, and charts of oci://
+	// repos against the PEM encoded cosign public keys in the "cosign.pub" key.
+	// For a repo the Namespace field will be ignored
+	KeyringSecret *SecretReference `json:"keyringSecret,omitempty"`
 }
 
 type RepoCondition string
@@ -115,5 +141,18 @@ type OperationStatus struct {
 	PodName            string                              `json:"podName,omitempty"`
 	PodNamespace       string                              `json:"podNamespace,omitempty"`
 	PodCreated         bool                                `json:"podCreated,omitempty"`
+	Verifications      []ChartVerification                 `json:"verifications,omitempty"`
 	Conditions         []genericcondition.GenericCondition `json:"conditions,omitempty"`
 }
+
+// ChartVerification is the result of checking the signature of a chart of an operation
+type ChartVerification struct {
+	Chart   string `json:"chart,omitempty"`
+	Version string `json:"version,omitempty"`
+	// Method is pgp for Helm provenance files and cosign for charts of oci:// repos
+	Method   string `json:"method,omitempty"`
+	Verified bool   `json:"verified"`
+	// Signer is the identity of the PGP key or the cosign public key the chart was signed with
+	Signer  string `json:"signer,omitempty"`
+	Message string `json:"message,omitempty"`
+}
@@ -29,6 +29,7 @@
 
 var (
 	AppResourceName         = "apps"
+	AppInstallResourceName  = "appinstalls"
 	ClusterRepoResourceName = "clusterrepos"
 	OperationResourceName   = "operations"
 )
@@ -56,6 +57,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&App{},
 		&AppList{},
+		&AppInstall{},
+		&AppInstallList{},
 		&ClusterRepo{},
 		&ClusterRepoList{},
 		&Operation{},
@@ -44,6 +44,16 @@ type GithubConfigList struct {
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
+// GitlabConfigList is a list of GitlabConfig resources
+type GitlabConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []GitlabConfig `json:"items"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
 // GoogleOauthConfigList is a list of GoogleOauthConfig resources
 type GoogleOauthConfigList struct {
 	metav1.TypeMeta `json:",inline"`
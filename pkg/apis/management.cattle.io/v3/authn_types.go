@@ -7,7 +7,10 @@
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-const UserConditionInitialRolesPopulated condition.Cond = "InitialRolesPopulated"
+const (
+	UserConditionInitialRolesPopulated condition.Cond = "InitialRolesPopulated"
+	UserConditionLoginLocked           condition.Cond = "LoginLocked"
+)
 
 // +genclient
 // +genclient:nonNamespaced
@@ -32,12 +35,26 @@ type Token struct {
 	Current         bool              `json:"current"`
 	ClusterName     string            `json:"clusterName,omitempty" norman:"noupdate,type=reference[cluster]"`
 	Enabled         *bool             `json:"enabled,omitempty" norman:"default=true"`
+	Scope           *TokenScope       `json:"scope,omitempty" norman:"noupdate"`
 }
 
 func (t *Token) ObjClusterName() string {
 	return t.ClusterName
 }
 
+// TokenScope restricts a token to a subset of what its user is allowed to do. Empty fields do not restrict.
+type TokenScope struct {
+	// Clusters the token can be used against.
+	Clusters []string `json:"clusters,omitempty"`
+	// Projects the token can be used against, in the form <cluster>:<project>. Only requests for the projects
+	// and namespaces belonging to them are allowed.
+	Projects []string `json:"projects,omitempty"`
+	// ReadOnly limits the token to get, list and watch requests.
+	ReadOnly bool `json:"readOnly,omitempty"`
+	// Resources is an allow-list of resource types, e.g. deployments or apps.deployments.
+	Resources []string `json:"resources,omitempty"`
+}
+
 // +genclient
 // +genclient:nonNamespaced
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -214,6 +231,30 @@ type GithubConfigApplyInput struct {
 	Enabled      bool         `json:"enabled,omitempty"`
 }
 
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type GitlabConfig struct {
+	AuthConfig `json:",inline" mapstructure:",squash"`
+
+	Hostname     string `json:"hostname,omitempty" norman:"default=gitlab.com,required"`
+	TLS          bool   `json:"tls,omitempty" norman:"notnullable,default=true,required"`
+	ClientID     string `json:"clientId,omitempty" norman:"required"`
+	ClientSecret string `json:"clientSecret,omitempty" norman:"required,type=password"`
+	RancherURL   string `json:"rancherUrl,omitempty" norman:"required,notnullable"`
+}
+
+type GitlabConfigTestOutput struct {
+	RedirectURL string `json:"redirectUrl"`
+}
+
+type GitlabConfigApplyInput struct {
+	GitlabConfig GitlabConfig `json:"gitlabConfig,omitempty"`
+	Code         string       `json:"code,omitempty"`
+	Enabled      bool         `json:"enabled,omitempty"`
+}
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 type GoogleOauthConfig struct {
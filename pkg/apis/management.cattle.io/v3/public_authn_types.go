@@ -39,6 +39,20 @@ type BasicLogin struct {
 	Password     string `json:"password" norman:"type=string,required"`
 }
 
+type LocalLogin struct {
+	BasicLogin   `json:",inline"`
+	TOTPCode     string `json:"totpCode,omitempty"`
+	RecoveryCode string `json:"recoveryCode,omitempty"`
+}
+
+// MFAEnrollOutput is returned when a user starts enrolling a TOTP authenticator. Enrollment completes with the first
+// login that includes a valid code.
+type MFAEnrollOutput struct {
+	Secret        string   `json:"secret"`
+	URL           string   `json:"url"`
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
 // +genclient
 // +genclient:nonNamespaced
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -70,6 +84,23 @@ type GithubLogin struct {
 // +genclient:nonNamespaced
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
+type GitlabProvider struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	AuthProvider      `json:",inline"`
+
+	RedirectURL string `json:"redirectUrl"`
+}
+
+type GitlabLogin struct {
+	GenericLogin `json:",inline"`
+	Code         string `json:"code" norman:"type=string,required"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
 type GoogleOAuthProvider struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -4032,6 +4032,174 @@ func (in *GithubProviderList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitlabConfig) DeepCopyInto(out *GitlabConfig) {
+	*out = *in
+	in.AuthConfig.DeepCopyInto(&out.AuthConfig)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitlabConfig.
+func (in *GitlabConfig) DeepCopy() *GitlabConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(GitlabConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GitlabConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitlabConfigApplyInput) DeepCopyInto(out *GitlabConfigApplyInput) {
+	*out = *in
+	in.GitlabConfig.DeepCopyInto(&out.GitlabConfig)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitlabConfigApplyInput.
+func (in *GitlabConfigApplyInput) DeepCopy() *GitlabConfigApplyInput {
+	if in == nil {
+		return nil
+	}
+	out := new(GitlabConfigApplyInput)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitlabConfigList) DeepCopyInto(out *GitlabConfigList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GitlabConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitlabConfigList.
+func (in *GitlabConfigList) DeepCopy() *GitlabConfigList {
+	if in == nil {
+		return nil
+	}
+	out := new(GitlabConfigList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GitlabConfigList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitlabConfigTestOutput) DeepCopyInto(out *GitlabConfigTestOutput) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitlabConfigTestOutput.
+func (in *GitlabConfigTestOutput) DeepCopy() *GitlabConfigTestOutput {
+	if in == nil {
+		return nil
+	}
+	out := new(GitlabConfigTestOutput)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitlabLogin) DeepCopyInto(out *GitlabLogin) {
+	*out = *in
+	out.GenericLogin = in.GenericLogin
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitlabLogin.
+func (in *GitlabLogin) DeepCopy() *GitlabLogin {
+	if in == nil {
+		return nil
+	}
+	out := new(GitlabLogin)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitlabProvider) DeepCopyInto(out *GitlabProvider) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.AuthProvider.DeepCopyInto(&out.AuthProvider)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitlabProvider.
+func (in *GitlabProvider) DeepCopy() *GitlabProvider {
+	if in == nil {
+		return nil
+	}
+	out := new(GitlabProvider)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GitlabProvider) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitlabProviderList) DeepCopyInto(out *GitlabProviderList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GitlabProvider, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitlabProviderList.
+func (in *GitlabProviderList) DeepCopy() *GitlabProviderList {
+	if in == nil {
+		return nil
+	}
+	out := new(GitlabProviderList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GitlabProviderList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GlobalDNSProviderSpec) DeepCopyInto(out *GlobalDNSProviderSpec) {
 	*out = *in
@@ -5211,6 +5379,23 @@ func (in *LocalConfigList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LocalLogin) DeepCopyInto(out *LocalLogin) {
+	*out = *in
+	out.BasicLogin = in.BasicLogin
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LocalLogin.
+func (in *LocalLogin) DeepCopy() *LocalLogin {
+	if in == nil {
+		return nil
+	}
+	out := new(LocalLogin)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LocalProvider) DeepCopyInto(out *LocalProvider) {
 	*out = *in
@@ -5356,6 +5541,27 @@ func (in *LoggingTargets) DeepCopy() *LoggingTargets {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MFAEnrollOutput) DeepCopyInto(out *MFAEnrollOutput) {
+	*out = *in
+	if in.RecoveryCodes != nil {
+		in, out := &in.RecoveryCodes, &out.RecoveryCodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MFAEnrollOutput.
+func (in *MFAEnrollOutput) DeepCopy() *MFAEnrollOutput {
+	if in == nil {
+		return nil
+	}
+	out := new(MFAEnrollOutput)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MSTeamsConfig) DeepCopyInto(out *MSTeamsConfig) {
 	*out = *in
@@ -10005,6 +10211,11 @@ func (in *Token) DeepCopyInto(out *Token) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.Scope != nil {
+		in, out := &in.Scope, &out.Scope
+		*out = new(TokenScope)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -10059,6 +10270,37 @@ func (in *TokenList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TokenScope) DeepCopyInto(out *TokenScope) {
+	*out = *in
+	if in.Clusters != nil {
+		in, out := &in.Clusters, &out.Clusters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Projects != nil {
+		in, out := &in.Projects, &out.Projects
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TokenScope.
+func (in *TokenScope) DeepCopy() *TokenScope {
+	if in == nil {
+		return nil
+	}
+	out := new(TokenScope)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UpdateGlobalDNSTargetsInput) DeepCopyInto(out *UpdateGlobalDNSTargetsInput) {
 	*out = *in
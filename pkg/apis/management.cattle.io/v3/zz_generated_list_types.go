@@ -536,6 +536,23 @@ func NewGithubProvider(namespace, name string, obj GithubProvider) *GithubProvid
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
+// GitlabProviderList is a list of GitlabProvider resources
+type GitlabProviderList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []GitlabProvider `json:"items"`
+}
+
+func NewGitlabProvider(namespace, name string, obj GitlabProvider) *GitlabProvider {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("GitlabProvider").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
 // GlobalDnsList is a list of GlobalDns resources
 type GlobalDnsList struct {
 	metav1.TypeMeta `json:",inline"`
@@ -59,6 +59,7 @@
 	FleetWorkspaceResourceName                          = "fleetworkspaces"
 	FreeIpaProviderResourceName                         = "freeipaproviders"
 	GithubProviderResourceName                          = "githubproviders"
+	GitlabProviderResourceName                          = "gitlabproviders"
 	GlobalDnsResourceName                               = "globaldnses"
 	GlobalDnsProviderResourceName                       = "globaldnsproviders"
 	GlobalRoleResourceName                              = "globalroles"
@@ -190,6 +191,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&FreeIpaProviderList{},
 		&GithubProvider{},
 		&GithubProviderList{},
+		&GitlabProvider{},
+		&GitlabProviderList{},
 		&GlobalDns{},
 		&GlobalDnsList{},
 		&GlobalDnsProvider{},
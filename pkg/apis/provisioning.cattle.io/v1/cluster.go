@@ -38,6 +38,22 @@ type ClusterStatus struct {
 	ObservedGeneration int64                               `json:"observedGeneration"`
 	Conditions         []genericcondition.GenericCondition `json:"conditions,omitempty"`
 	ETCDSnapshots      []rkev1.ETCDSnapshot                `json:"etcdSnapshots,omitempty"`
+
+	CertificateExpiration []rkev1.CertificateExpiration `json:"certificateExpiration,omitempty"`
+	MachinePools          []MachinePoolStatus           `json:"machinePools,omitempty"`
+}
+
+// MachinePoolStatus reports the size of a machine pool and when it last changed, either because the quantity of the
+// pool was changed or because the cluster autoscaler resized it.
+type MachinePoolStatus struct {
+	Name              string `json:"name,omitempty"`
+	Replicas          int32  `json:"replicas"`
+	ReadyReplicas     int32  `json:"readyReplicas"`
+	AvailableReplicas int32  `json:"availableReplicas"`
+	MinSize           int32  `json:"minSize,omitempty"`
+	MaxSize           int32  `json:"maxSize,omitempty"`
+	LastScaleTime     string `json:"lastScaleTime,omitempty"`
+	LastScaleFrom     int32  `json:"lastScaleFrom,omitempty"`
 }
 
 type ImportedConfig struct {
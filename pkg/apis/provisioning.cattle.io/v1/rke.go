@@ -3,6 +3,7 @@
 import (
 	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
@@ -18,6 +19,8 @@ type RKEMachinePool struct {
 	DisplayName                  string                       `json:"displayName,omitempty"`
 	Quantity                     *int32                       `json:"quantity,omitempty"`
 	RollingUpdate                *RKEMachinePoolRollingUpdate `json:"rollingUpdate,omitempty"`
+	HealthCheck                  *RKEMachinePoolHealthCheck   `json:"healthCheck,omitempty"`
+	Autoscaling                  *RKEMachinePoolAutoscaling   `json:"autoscaling,omitempty"`
 	MachineDeploymentLabels      map[string]string            `json:"machineDeploymentLabels,omitempty"`
 	MachineDeploymentAnnotations map[string]string            `json:"machineDeploymentAnnotations,omitempty"`
 }
@@ -55,11 +58,55 @@ type RKEMachinePoolRollingUpdate struct {
 	MaxSurge *intstr.IntOrString `json:"maxSurge,omitempty"`
 }
 
+// RKEMachinePoolHealthCheck enables the replacement of unhealthy machines of a pool. It can't be set on etcd or
+// control plane pools.
+type RKEMachinePoolHealthCheck struct {
+	// How long the node of a machine can be NotReady or Unknown before the
+	// machine is replaced.
+	// Defaults to 5m.
+	// +optional
+	UnhealthyNodeTimeout *metav1.Duration `json:"unhealthyNodeTimeout,omitempty"`
+
+	// How long a machine can take to register its node before it is
+	// replaced.
+	// Defaults to 10m.
+	// +optional
+	NodeStartupTimeout *metav1.Duration `json:"nodeStartupTimeout,omitempty"`
+
+	// How long the probes of the plan of a machine can fail before the
+	// machine is replaced. Failing probes never cause a replacement if unset.
+	// +optional
+	UnhealthyProbesTimeout *metav1.Duration `json:"unhealthyProbesTimeout,omitempty"`
+
+	// No machine of the pool is replaced while more machines than this are
+	// unhealthy.
+	// Value can be an absolute number (ex: 1) or a percentage of the
+	// machines of the pool (ex: 40%).
+	// Absolute number is calculated from percentage by rounding down.
+	// Defaults to 1.
+	// +optional
+	MaxUnhealthy *intstr.IntOrString `json:"maxUnhealthy,omitempty"`
+}
+
+// RKEMachinePoolAutoscaling hands the quantity of a pool over to the cluster autoscaler, which Rancher deploys for
+// the cluster as long as one of its pools has autoscaling set. Quantity is only used as the initial size. Only worker
+// pools can be autoscaled.
+type RKEMachinePoolAutoscaling struct {
+	// The fewest machines the autoscaler can scale the pool down to, at least 1 as the autoscaler can't scale a pool
+	// up from zero machines.
+	MinSize int32 `json:"minSize,omitempty"`
+
+	// The most machines the autoscaler can scale the pool up to.
+	MaxSize int32 `json:"maxSize,omitempty"`
+}
+
 type RKEConfig struct {
 	rkev1.RKEClusterSpecCommon
 
-	ETCDSnapshotCreate  *rkev1.ETCDSnapshotCreate `json:"etcdSnapshotCreate,omitempty"`
-	ETCDSnapshotRestore *rkev1.ETCDSnapshot       `json:"etcdSnapshotRestore,omitempty"`
-	MachinePools        []RKEMachinePool          `json:"machinePools,omitempty"`
-	InfrastructureRef   *corev1.ObjectReference   `json:"infrastructureRef,omitempty"`
+	ETCDSnapshotCreate   *rkev1.ETCDSnapshotCreate   `json:"etcdSnapshotCreate,omitempty"`
+	ETCDSnapshotRestore  *rkev1.ETCDSnapshot         `json:"etcdSnapshotRestore,omitempty"`
+	RotateCertificates   *rkev1.RotateCertificates   `json:"rotateCertificates,omitempty"`
+	RotateEncryptionKeys *rkev1.RotateEncryptionKeys `json:"rotateEncryptionKeys,omitempty"`
+	MachinePools         []RKEMachinePool            `json:"machinePools,omitempty"`
+	InfrastructureRef    *corev1.ObjectReference     `json:"infrastructureRef,omitempty"`
 }
@@ -24,6 +24,7 @@
 	rkecattleiov1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
 	genericcondition "github.com/rancher/wrangler/pkg/genericcondition"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	intstr "k8s.io/apimachinery/pkg/util/intstr"
 )
@@ -158,6 +159,16 @@ func (in *ClusterStatus) DeepCopyInto(out *ClusterStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.CertificateExpiration != nil {
+		in, out := &in.CertificateExpiration, &out.CertificateExpiration
+		*out = make([]rkecattleiov1.CertificateExpiration, len(*in))
+		copy(*out, *in)
+	}
+	if in.MachinePools != nil {
+		in, out := &in.MachinePools, &out.MachinePools
+		*out = make([]MachinePoolStatus, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -187,6 +198,22 @@ func (in *ImportedConfig) DeepCopy() *ImportedConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MachinePoolStatus) DeepCopyInto(out *MachinePoolStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MachinePoolStatus.
+func (in *MachinePoolStatus) DeepCopy() *MachinePoolStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MachinePoolStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RKEConfig) DeepCopyInto(out *RKEConfig) {
 	*out = *in
@@ -201,6 +228,16 @@ func (in *RKEConfig) DeepCopyInto(out *RKEConfig) {
 		*out = new(rkecattleiov1.ETCDSnapshot)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.RotateCertificates != nil {
+		in, out := &in.RotateCertificates, &out.RotateCertificates
+		*out = new(rkecattleiov1.RotateCertificates)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RotateEncryptionKeys != nil {
+		in, out := &in.RotateEncryptionKeys, &out.RotateEncryptionKeys
+		*out = new(rkecattleiov1.RotateEncryptionKeys)
+		**out = **in
+	}
 	if in.MachinePools != nil {
 		in, out := &in.MachinePools, &out.MachinePools
 		*out = make([]RKEMachinePool, len(*in))
@@ -245,6 +282,16 @@ func (in *RKEMachinePool) DeepCopyInto(out *RKEMachinePool) {
 		*out = new(RKEMachinePoolRollingUpdate)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.HealthCheck != nil {
+		in, out := &in.HealthCheck, &out.HealthCheck
+		*out = new(RKEMachinePoolHealthCheck)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Autoscaling != nil {
+		in, out := &in.Autoscaling, &out.Autoscaling
+		*out = new(RKEMachinePoolAutoscaling)
+		**out = **in
+	}
 	if in.MachineDeploymentLabels != nil {
 		in, out := &in.MachineDeploymentLabels, &out.MachineDeploymentLabels
 		*out = make(map[string]string, len(*in))
@@ -272,6 +319,58 @@ func (in *RKEMachinePool) DeepCopy() *RKEMachinePool {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RKEMachinePoolAutoscaling) DeepCopyInto(out *RKEMachinePoolAutoscaling) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RKEMachinePoolAutoscaling.
+func (in *RKEMachinePoolAutoscaling) DeepCopy() *RKEMachinePoolAutoscaling {
+	if in == nil {
+		return nil
+	}
+	out := new(RKEMachinePoolAutoscaling)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RKEMachinePoolHealthCheck) DeepCopyInto(out *RKEMachinePoolHealthCheck) {
+	*out = *in
+	if in.UnhealthyNodeTimeout != nil {
+		in, out := &in.UnhealthyNodeTimeout, &out.UnhealthyNodeTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.NodeStartupTimeout != nil {
+		in, out := &in.NodeStartupTimeout, &out.NodeStartupTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.UnhealthyProbesTimeout != nil {
+		in, out := &in.UnhealthyProbesTimeout, &out.UnhealthyProbesTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MaxUnhealthy != nil {
+		in, out := &in.MaxUnhealthy, &out.MaxUnhealthy
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RKEMachinePoolHealthCheck.
+func (in *RKEMachinePoolHealthCheck) DeepCopy() *RKEMachinePoolHealthCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(RKEMachinePoolHealthCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RKEMachinePoolRollingUpdate) DeepCopyInto(out *RKEMachinePoolRollingUpdate) {
 	*out = *in
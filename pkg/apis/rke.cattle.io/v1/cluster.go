@@ -30,6 +30,7 @@ type RKEClusterSpecCommon struct {
 	AdditionalManifest       string                   `json:"additionalManifest,omitempty"`
 	Registries               *Registry                `json:"registries,omitempty"`
 	ETCD                     *ETCD                    `json:"etcd,omitempty"`
+	LifecycleHooks           []LifecycleHook          `json:"lifecycleHooks,omitempty"`
 }
 
 type LocalClusterAuthEndpoint struct {
@@ -43,6 +44,30 @@ type RKESystemConfig struct {
 	Config               GenericMap            `json:"config,omitempty" wrangler:"nullable"`
 }
 
+type LifecycleHookPoint string
+
+const (
+	// LifecycleHookPreDrain hooks run on a node that is about to receive a new plan, before it is drained.
+	LifecycleHookPreDrain LifecycleHookPoint = "pre-drain"
+	// LifecycleHookPostInstall hooks run right after the install instruction of every plan.
+	LifecycleHookPostInstall LifecycleHookPoint = "post-install"
+	// LifecycleHookPostRejoin hooks run once a node applied its plan and was uncordoned.
+	LifecycleHookPostRejoin LifecycleHookPoint = "post-rejoin"
+)
+
+// LifecycleHook is a user defined instruction added to the plan of the machines matching MachineLabelSelector,
+// all machines if it is nil. The output of a hook is saved and a failing hook blocks the rollout.
+type LifecycleHook struct {
+	Name                 string                `json:"name,omitempty"`
+	Point                LifecycleHookPoint    `json:"point,omitempty"`
+	MachineLabelSelector *metav1.LabelSelector `json:"machineLabelSelector,omitempty"`
+	// Image defaults to the system agent installer image
+	Image   string   `json:"image,omitempty"`
+	Command string   `json:"command,omitempty"`
+	Args    []string `json:"args,omitempty"`
+	Env     []string `json:"env,omitempty"`
+}
+
 type RKEClusterSpec struct {
 	// Not used in anyway, just here to make cluster-api happy
 	ControlPlaneEndpoint *Endpoint `json:"controlPlaneEndpoint,omitempty"`
@@ -57,6 +82,36 @@ type ClusterUpgradeStrategy struct {
 	// How many workers should be upgraded at a time
 	WorkerConcurrency  string       `json:"workerConcurrency,omitempty"`
 	WorkerDrainOptions DrainOptions `json:"workerDrainOptions,omitempty"`
+
+	// Paused stops machines from receiving a new plan, machines that already received one finish applying it.
+	// Pausing and resuming also resumes an upgrade that was halted.
+	Paused bool `json:"paused,omitempty"`
+	// MaintenanceWindows restricts when existing machines receive a new plan, new machines are provisioned at any
+	// time. No windows means upgrades roll out as soon as the spec changes.
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
+	// Canary upgrades a few workers first and holds the other workers until the canaries are proven healthy
+	Canary *CanaryStrategy `json:"canary,omitempty"`
+	// MaxUnhealthyMachines halts an upgrade when more machines than this have failing probes, 0 disables the check
+	MaxUnhealthyMachines int `json:"maxUnhealthyMachines,omitempty"`
+}
+
+type MaintenanceWindow struct {
+	// Schedule is a standard cron expression for when the window opens
+	Schedule string `json:"schedule,omitempty"`
+	// Duration is how long the window stays open, for example "4h"
+	Duration string `json:"duration,omitempty"`
+	// TimeZone the schedule is evaluated in, defaults to UTC
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+type CanaryStrategy struct {
+	// Workers is the number of workers upgraded before the others
+	Workers int `json:"workers,omitempty"`
+	// SoakSeconds is how long the canaries must be healthy before the other workers are upgraded
+	SoakSeconds int `json:"soakSeconds,omitempty"`
+	// RequireApproval holds the other workers until ApprovedRollout is set to the rollout in the upgrade status
+	RequireApproval bool  `json:"requireApproval,omitempty"`
+	ApprovedRollout int64 `json:"approvedRollout,omitempty"`
 }
 
 type DrainOptions struct {
@@ -19,13 +19,15 @@ type RKEControlPlane struct {
 type RKEControlPlaneSpec struct {
 	RKEClusterSpecCommon
 
-	AgentEnvVars          []corev1.EnvVar     `json:"agentEnvVars,omitempty"`
-	ETCDSnapshotCreate    *ETCDSnapshotCreate `json:"etcdSnapshotCreate,omitempty"`
-	ETCDSnapshotRestore   *ETCDSnapshot       `json:"etcdSnapshotRestore,omitempty"`
-	KubernetesVersion     string              `json:"kubernetesVersion,omitempty"`
-	ClusterName           string              `json:"clusterName,omitempty" wrangler:"required"`
-	ManagementClusterName string              `json:"managementClusterName,omitempty" wrangler:"required"`
-	UnmanagedConfig       bool                `json:"unmanagedConfig,omitempty"`
+	AgentEnvVars          []corev1.EnvVar       `json:"agentEnvVars,omitempty"`
+	ETCDSnapshotCreate    *ETCDSnapshotCreate   `json:"etcdSnapshotCreate,omitempty"`
+	ETCDSnapshotRestore   *ETCDSnapshot         `json:"etcdSnapshotRestore,omitempty"`
+	RotateCertificates    *RotateCertificates   `json:"rotateCertificates,omitempty"`
+	RotateEncryptionKeys  *RotateEncryptionKeys `json:"rotateEncryptionKeys,omitempty"`
+	KubernetesVersion     string                `json:"kubernetesVersion,omitempty"`
+	ClusterName           string                `json:"clusterName,omitempty" wrangler:"required"`
+	ManagementClusterName string                `json:"managementClusterName,omitempty" wrangler:"required"`
+	UnmanagedConfig       bool                  `json:"unmanagedConfig,omitempty"`
 }
 
 type ETCDSnapshotPhase string
@@ -46,4 +48,82 @@ type RKEControlPlaneStatus struct {
 	ETCDSnapshotCreate       *ETCDSnapshotCreate                 `json:"etcdSnapshotCreate,omitempty"`
 	ETCDSnapshotCreatePhase  ETCDSnapshotPhase                   `json:"etcdSnapshotCreatePhase,omitempty"`
 	ConfigGeneration         int64                               `json:"configGeneration,omitempty"`
+	Upgrade                  *UpgradeStatus                      `json:"upgrade,omitempty"`
+
+	CertificateRotationGeneration int64                   `json:"certificateRotationGeneration,omitempty"`
+	CertificateExpiration         []CertificateExpiration `json:"certificateExpiration,omitempty"`
+
+	ETCDSnapshotChecksums []ETCDSnapshotChecksum `json:"etcdSnapshotChecksums,omitempty"`
+	AppliedSpec           *AppliedSpec           `json:"appliedSpec,omitempty"`
+	// AppliedSpecHistory are the last specs rolled out to every machine, oldest first. The spec in effect when a
+	// snapshot was taken is found from the creation time of the snapshot.
+	AppliedSpecHistory []AppliedSpec `json:"appliedSpecHistory,omitempty"`
+
+	RotateEncryptionKeys       *RotateEncryptionKeys     `json:"rotateEncryptionKeys,omitempty"`
+	RotateEncryptionKeysPhase  RotateEncryptionKeysPhase `json:"rotateEncryptionKeysPhase,omitempty"`
+	RotateEncryptionKeysLeader string                    `json:"rotateEncryptionKeysLeader,omitempty"`
+}
+
+// AppliedSpec is the part of the spec of a control plane that was last rolled out to every machine
+type AppliedSpec struct {
+	KubernetesVersion string                `json:"kubernetesVersion,omitempty"`
+	RKEConfig         *RKEClusterSpecCommon `json:"rkeConfig,omitempty"`
+	// AppliedAt is when the spec was rolled out to every machine
+	AppliedAt *metav1.Time `json:"appliedAt,omitempty"`
+}
+
+// RotateEncryptionKeys rotates the key used to encrypt secrets at rest whenever Generation changes
+type RotateEncryptionKeys struct {
+	Generation int64 `json:"generation,omitempty"`
+}
+
+type RotateEncryptionKeysPhase string
+
+var (
+	RotateEncryptionKeysPhasePrepare              RotateEncryptionKeysPhase = "Prepare"
+	RotateEncryptionKeysPhasePostPrepareRestart   RotateEncryptionKeysPhase = "PostPrepareRestart"
+	RotateEncryptionKeysPhaseRotate               RotateEncryptionKeysPhase = "Rotate"
+	RotateEncryptionKeysPhasePostRotateRestart    RotateEncryptionKeysPhase = "PostRotateRestart"
+	RotateEncryptionKeysPhaseReencrypt            RotateEncryptionKeysPhase = "Reencrypt"
+	RotateEncryptionKeysPhasePostReencryptRestart RotateEncryptionKeysPhase = "PostReencryptRestart"
+	RotateEncryptionKeysPhaseDone                 RotateEncryptionKeysPhase = "Done"
+)
+
+// RotateCertificates rotates the certificates of every node whenever Generation changes
+type RotateCertificates struct {
+	Generation int64 `json:"generation,omitempty"`
+	// Services to rotate the certificates of, for example "api-server" or "etcd". Empty means all of them.
+	Services []string `json:"services,omitempty"`
+}
+
+// CertificateExpiration is the certificate of a machine that expires first
+type CertificateExpiration struct {
+	MachineName    string `json:"machineName,omitempty"`
+	NodeName       string `json:"nodeName,omitempty"`
+	Certificate    string `json:"certificate,omitempty"`
+	ExpirationDate string `json:"expirationDate,omitempty"`
+}
+
+type UpgradePhase string
+
+var (
+	UpgradePhaseRolling            UpgradePhase = "Rolling"
+	UpgradePhasePaused             UpgradePhase = "Paused"
+	UpgradePhaseWaitingForWindow   UpgradePhase = "WaitingForWindow"
+	UpgradePhaseCanary             UpgradePhase = "Canary"
+	UpgradePhaseWaitingForApproval UpgradePhase = "WaitingForApproval"
+	UpgradePhaseHalted             UpgradePhase = "Halted"
+	UpgradePhaseComplete           UpgradePhase = "Complete"
+)
+
+type UpgradeStatus struct {
+	// Rollout is incremented every time machines start to receive new plans after all of them were in sync
+	Rollout            int64        `json:"rollout,omitempty"`
+	Phase              UpgradePhase `json:"phase,omitempty"`
+	Message            string       `json:"message,omitempty"`
+	CanaryMachines     []string     `json:"canaryMachines,omitempty"`
+	CanaryHealthySince string       `json:"canaryHealthySince,omitempty"`
+	CanaryComplete     bool         `json:"canaryComplete,omitempty"`
+	NextWindow         string       `json:"nextWindow,omitempty"`
+	PendingMachines    int          `json:"pendingMachines,omitempty"`
 }
@@ -24,11 +24,76 @@ type ETCDSnapshot struct {
 	CreatedAt *metav1.Time    `json:"createdAt,omitempty"`
 	Size      int64           `json:"size,omitempty"`
 	S3        *ETCDSnapshotS3 `json:"s3,omitempty"`
+	// Record is the name of an ETCDSnapshotRecord of the cluster to restore, the other fields are ignored when
+	// it is set. Restoring a snapshot that failed its last verification is refused.
+	Record string `json:"record,omitempty"`
+	// RestoreRKEConfig rolls the cluster back to the configuration recorded with the snapshot before etcd is
+	// restored, one of none, kubernetesVersion or all. It defaults to none.
+	RestoreRKEConfig ETCDSnapshotRestoreRKEConfig `json:"restoreRKEConfig,omitempty"`
 }
 
+type ETCDSnapshotRestoreRKEConfig string
+
+const (
+	// ETCDSnapshotRestoreRKEConfigNone only restores etcd
+	ETCDSnapshotRestoreRKEConfigNone ETCDSnapshotRestoreRKEConfig = "none"
+	// ETCDSnapshotRestoreRKEConfigKubernetesVersion also restores the Kubernetes version
+	ETCDSnapshotRestoreRKEConfigKubernetesVersion ETCDSnapshotRestoreRKEConfig = "kubernetesVersion"
+	// ETCDSnapshotRestoreRKEConfigAll also restores the Kubernetes version and the rke config, machine pools excluded
+	ETCDSnapshotRestoreRKEConfigAll ETCDSnapshotRestoreRKEConfig = "all"
+)
+
 type ETCD struct {
 	DisableSnapshots     bool            `json:"disableSnapshots,omitempty"`
 	SnapshotScheduleCron string          `json:"snapshotScheduleCron,omitempty"`
 	SnapshotRetention    int             `json:"snapshotRetention,omitempty"`
 	S3                   *ETCDSnapshotS3 `json:"s3,omitempty"`
+	// S3SnapshotRetention is how many snapshots Rancher keeps in S3, the oldest ones are deleted from the bucket.
+	// SnapshotRetention applies to both locations if unset.
+	S3SnapshotRetention int `json:"s3SnapshotRetention,omitempty"`
+}
+
+// ETCDSnapshotChecksum is the checksum of a local snapshot file read on its node.
+type ETCDSnapshotChecksum struct {
+	Name     string `json:"name,omitempty"`
+	NodeName string `json:"nodeName,omitempty"`
+	Checksum string `json:"checksum,omitempty"`
+}
+
+type ETCDSnapshotRecordState string
+
+const (
+	ETCDSnapshotRecordStateUnverified ETCDSnapshotRecordState = "Unverified"
+	ETCDSnapshotRecordStateVerified   ETCDSnapshotRecordState = "Verified"
+	ETCDSnapshotRecordStateMissing    ETCDSnapshotRecordState = "Missing"
+	ETCDSnapshotRecordStateCorrupt    ETCDSnapshotRecordState = "Corrupt"
+	ETCDSnapshotRecordStatePruned     ETCDSnapshotRecordState = "Pruned"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ETCDSnapshotRecord catalogues one snapshot of a cluster in one location, local or S3.
+type ETCDSnapshotRecord struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ETCDSnapshotRecordSpec   `json:"spec"`
+	Status ETCDSnapshotRecordStatus `json:"status,omitempty"`
+}
+
+type ETCDSnapshotRecordSpec struct {
+	ClusterName string       `json:"clusterName,omitempty"`
+	Snapshot    ETCDSnapshot `json:"snapshot,omitempty"`
+}
+
+type ETCDSnapshotRecordStatus struct {
+	// Checksum is the sha256 of a local snapshot, or the ETag of an S3 snapshot, the first time it was read
+	Checksum     string                  `json:"checksum,omitempty"`
+	State        ETCDSnapshotRecordState `json:"state,omitempty"`
+	Message      string                  `json:"message,omitempty"`
+	LastVerified string                  `json:"lastVerified,omitempty"`
+	// AppliedSpec is the configuration of the cluster that was in effect when the snapshot was taken. It is unset
+	// if the snapshot is older than the specs the control plane keeps, the configuration can't be restored then.
+	AppliedSpec *AppliedSpec `json:"appliedSpec,omitempty"`
 }
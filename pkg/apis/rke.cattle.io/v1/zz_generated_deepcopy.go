@@ -28,6 +28,31 @@
 	v1alpha4 "sigs.k8s.io/cluster-api/api/v1alpha4"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AppliedSpec) DeepCopyInto(out *AppliedSpec) {
+	*out = *in
+	if in.RKEConfig != nil {
+		in, out := &in.RKEConfig, &out.RKEConfig
+		*out = new(RKEClusterSpecCommon)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AppliedAt != nil {
+		in, out := &in.AppliedAt, &out.AppliedAt
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppliedSpec.
+func (in *AppliedSpec) DeepCopy() *AppliedSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AppliedSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AuthConfig) DeepCopyInto(out *AuthConfig) {
 	*out = *in
@@ -44,11 +69,53 @@ func (in *AuthConfig) DeepCopy() *AuthConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CanaryStrategy) DeepCopyInto(out *CanaryStrategy) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryStrategy.
+func (in *CanaryStrategy) DeepCopy() *CanaryStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(CanaryStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificateExpiration) DeepCopyInto(out *CertificateExpiration) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificateExpiration.
+func (in *CertificateExpiration) DeepCopy() *CertificateExpiration {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificateExpiration)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterUpgradeStrategy) DeepCopyInto(out *ClusterUpgradeStrategy) {
 	*out = *in
 	in.ControlPlaneDrainOptions.DeepCopyInto(&out.ControlPlaneDrainOptions)
 	in.WorkerDrainOptions.DeepCopyInto(&out.WorkerDrainOptions)
+	if in.MaintenanceWindows != nil {
+		in, out := &in.MaintenanceWindows, &out.MaintenanceWindows
+		*out = make([]MaintenanceWindow, len(*in))
+		copy(*out, *in)
+	}
+	if in.Canary != nil {
+		in, out := &in.Canary, &out.Canary
+		*out = new(CanaryStrategy)
+		**out = **in
+	}
 	return
 }
 
@@ -227,6 +294,22 @@ func (in *ETCDSnapshot) DeepCopy() *ETCDSnapshot {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDSnapshotChecksum) DeepCopyInto(out *ETCDSnapshotChecksum) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotChecksum.
+func (in *ETCDSnapshotChecksum) DeepCopy() *ETCDSnapshotChecksum {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDSnapshotChecksum)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ETCDSnapshotCreate) DeepCopyInto(out *ETCDSnapshotCreate) {
 	*out = *in
@@ -248,6 +331,105 @@ func (in *ETCDSnapshotCreate) DeepCopy() *ETCDSnapshotCreate {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDSnapshotRecord) DeepCopyInto(out *ETCDSnapshotRecord) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotRecord.
+func (in *ETCDSnapshotRecord) DeepCopy() *ETCDSnapshotRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDSnapshotRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ETCDSnapshotRecord) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDSnapshotRecordList) DeepCopyInto(out *ETCDSnapshotRecordList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ETCDSnapshotRecord, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotRecordList.
+func (in *ETCDSnapshotRecordList) DeepCopy() *ETCDSnapshotRecordList {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDSnapshotRecordList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ETCDSnapshotRecordList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDSnapshotRecordSpec) DeepCopyInto(out *ETCDSnapshotRecordSpec) {
+	*out = *in
+	in.Snapshot.DeepCopyInto(&out.Snapshot)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotRecordSpec.
+func (in *ETCDSnapshotRecordSpec) DeepCopy() *ETCDSnapshotRecordSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDSnapshotRecordSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDSnapshotRecordStatus) DeepCopyInto(out *ETCDSnapshotRecordStatus) {
+	*out = *in
+	if in.AppliedSpec != nil {
+		in, out := &in.AppliedSpec, &out.AppliedSpec
+		*out = new(AppliedSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotRecordStatus.
+func (in *ETCDSnapshotRecordStatus) DeepCopy() *ETCDSnapshotRecordStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDSnapshotRecordStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ETCDSnapshotS3) DeepCopyInto(out *ETCDSnapshotS3) {
 	*out = *in
@@ -290,6 +472,37 @@ func (in *GenericMap) DeepCopy() *GenericMap {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LifecycleHook) DeepCopyInto(out *LifecycleHook) {
+	*out = *in
+	if in.MachineLabelSelector != nil {
+		in, out := &in.MachineLabelSelector, &out.MachineLabelSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LifecycleHook.
+func (in *LifecycleHook) DeepCopy() *LifecycleHook {
+	if in == nil {
+		return nil
+	}
+	out := new(LifecycleHook)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LocalClusterAuthEndpoint) DeepCopyInto(out *LocalClusterAuthEndpoint) {
 	*out = *in
@@ -306,6 +519,22 @@ func (in *LocalClusterAuthEndpoint) DeepCopy() *LocalClusterAuthEndpoint {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Mirror) DeepCopyInto(out *Mirror) {
 	*out = *in
@@ -615,6 +844,13 @@ func (in *RKEClusterSpecCommon) DeepCopyInto(out *RKEClusterSpecCommon) {
 		*out = new(ETCD)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.LifecycleHooks != nil {
+		in, out := &in.LifecycleHooks, &out.LifecycleHooks
+		*out = make([]LifecycleHook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -761,6 +997,16 @@ func (in *RKEControlPlaneSpec) DeepCopyInto(out *RKEControlPlaneSpec) {
 		*out = new(ETCDSnapshot)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.RotateCertificates != nil {
+		in, out := &in.RotateCertificates, &out.RotateCertificates
+		*out = new(RotateCertificates)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RotateEncryptionKeys != nil {
+		in, out := &in.RotateEncryptionKeys, &out.RotateEncryptionKeys
+		*out = new(RotateEncryptionKeys)
+		**out = **in
+	}
 	return
 }
 
@@ -792,6 +1038,38 @@ func (in *RKEControlPlaneStatus) DeepCopyInto(out *RKEControlPlaneStatus) {
 		*out = new(ETCDSnapshotCreate)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Upgrade != nil {
+		in, out := &in.Upgrade, &out.Upgrade
+		*out = new(UpgradeStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CertificateExpiration != nil {
+		in, out := &in.CertificateExpiration, &out.CertificateExpiration
+		*out = make([]CertificateExpiration, len(*in))
+		copy(*out, *in)
+	}
+	if in.ETCDSnapshotChecksums != nil {
+		in, out := &in.ETCDSnapshotChecksums, &out.ETCDSnapshotChecksums
+		*out = make([]ETCDSnapshotChecksum, len(*in))
+		copy(*out, *in)
+	}
+	if in.AppliedSpec != nil {
+		in, out := &in.AppliedSpec, &out.AppliedSpec
+		*out = new(AppliedSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AppliedSpecHistory != nil {
+		in, out := &in.AppliedSpecHistory, &out.AppliedSpecHistory
+		*out = make([]AppliedSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RotateEncryptionKeys != nil {
+		in, out := &in.RotateEncryptionKeys, &out.RotateEncryptionKeys
+		*out = new(RotateEncryptionKeys)
+		**out = **in
+	}
 	return
 }
 
@@ -898,3 +1176,61 @@ func (in *RegistryConfig) DeepCopy() *RegistryConfig {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RotateCertificates) DeepCopyInto(out *RotateCertificates) {
+	*out = *in
+	if in.Services != nil {
+		in, out := &in.Services, &out.Services
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RotateCertificates.
+func (in *RotateCertificates) DeepCopy() *RotateCertificates {
+	if in == nil {
+		return nil
+	}
+	out := new(RotateCertificates)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RotateEncryptionKeys) DeepCopyInto(out *RotateEncryptionKeys) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RotateEncryptionKeys.
+func (in *RotateEncryptionKeys) DeepCopy() *RotateEncryptionKeys {
+	if in == nil {
+		return nil
+	}
+	out := new(RotateEncryptionKeys)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeStatus) DeepCopyInto(out *UpgradeStatus) {
+	*out = *in
+	if in.CanaryMachines != nil {
+		in, out := &in.CanaryMachines, &out.CanaryMachines
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeStatus.
+func (in *UpgradeStatus) DeepCopy() *UpgradeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
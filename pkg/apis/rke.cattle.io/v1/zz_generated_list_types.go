@@ -43,6 +43,23 @@ func NewCustomMachine(namespace, name string, obj CustomMachine) *CustomMachine
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
+// ETCDSnapshotRecordList is a list of ETCDSnapshotRecord resources
+type ETCDSnapshotRecordList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []ETCDSnapshotRecord `json:"items"`
+}
+
+func NewETCDSnapshotRecord(namespace, name string, obj ETCDSnapshotRecord) *ETCDSnapshotRecord {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("ETCDSnapshotRecord").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
 // RKEBootstrapList is a list of RKEBootstrap resources
 type RKEBootstrapList struct {
 	metav1.TypeMeta `json:",inline"`
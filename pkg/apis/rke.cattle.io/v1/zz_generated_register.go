@@ -29,6 +29,7 @@
 
 var (
 	CustomMachineResourceName        = "custommachines"
+	ETCDSnapshotRecordResourceName   = "etcdsnapshotrecords"
 	RKEBootstrapResourceName         = "rkebootstraps"
 	RKEBootstrapTemplateResourceName = "rkebootstraptemplates"
 	RKEClusterResourceName           = "rkeclusters"
@@ -58,6 +59,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&CustomMachine{},
 		&CustomMachineList{},
+		&ETCDSnapshotRecord{},
+		&ETCDSnapshotRecordList{},
 		&RKEBootstrap{},
 		&RKEBootstrapList{},
 		&RKEBootstrapTemplate{},
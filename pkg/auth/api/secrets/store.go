@@ -16,6 +16,7 @@
 var (
 	TypeToFields = map[string][]string{
 		client.GithubConfigType:          {client.GithubConfigFieldClientSecret},
+		client.GitlabConfigType:          {client.GitlabConfigFieldClientSecret},
 		client.ActiveDirectoryConfigType: {client.ActiveDirectoryConfigFieldServiceAccountPassword},
 		client.AzureADConfigType:         {client.AzureADConfigFieldApplicationSecret},
 		client.OpenLdapConfigType:        {client.LdapConfigFieldServiceAccountPassword},
@@ -9,6 +9,8 @@
 	"github.com/rancher/norman/store/crd"
 	"github.com/rancher/norman/types"
 	"github.com/rancher/rancher/pkg/auth/api/user"
+	"github.com/rancher/rancher/pkg/auth/lockout"
+	"github.com/rancher/rancher/pkg/auth/passwordpolicy"
 	"github.com/rancher/rancher/pkg/auth/principals"
 	"github.com/rancher/rancher/pkg/auth/providerrefresh"
 	"github.com/rancher/rancher/pkg/auth/providers"
@@ -31,6 +33,8 @@ func User(ctx context.Context, schemas *types.Schemas, management *config.Scaled
 		UserClient:               management.Management.Users(""),
 		GlobalRoleBindingsClient: management.Management.GlobalRoleBindings(""),
 		UserAuthRefresher:        providerrefresh.NewUserAuthRefresher(ctx, management),
+		Lockout:                  lockout.NewManager(management.Core, management.Management.Users("")),
+		PasswordPolicy:           passwordpolicy.NewManager(management.Core.Secrets("")),
 	}
 
 	schema.Formatter = handler.UserFormatter
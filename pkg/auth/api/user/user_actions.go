@@ -5,9 +5,15 @@
 	"strings"
 
 	"github.com/pkg/errors"
+	"github.com/rancher/norman/api/access"
 	"github.com/rancher/norman/httperror"
 	"github.com/rancher/norman/parse"
 	"github.com/rancher/norman/types"
+	"github.com/rancher/norman/types/convert"
+	"github.com/rancher/norman/types/values"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/auth/lockout"
+	"github.com/rancher/rancher/pkg/auth/passwordpolicy"
 	"github.com/rancher/rancher/pkg/auth/providerrefresh"
 	"github.com/rancher/rancher/pkg/auth/settings"
 	client "github.com/rancher/rancher/pkg/client/generated/management/v3"
@@ -22,6 +28,21 @@ func (h *Handler) UserFormatter(apiContext *types.APIContext, resource *types.Ra
 	if canRefresh := h.userCanRefresh(apiContext); canRefresh {
 		resource.AddAction(apiContext, "refreshauthprovideraccess")
 	}
+
+	if isLoginLocked(resource.Values) && h.userCanUnlock(apiContext) {
+		resource.AddAction(apiContext, "unlock")
+	}
+}
+
+// isLoginLocked reports whether the LoginLocked condition of the user is true.
+func isLoginLocked(data map[string]interface{}) bool {
+	conditions, _ := values.GetSlice(data, "conditions")
+	for _, cond := range conditions {
+		if convert.ToString(cond["type"]) == string(v32.UserConditionLoginLocked) {
+			return convert.ToString(cond["status"]) == "True"
+		}
+	}
+	return false
 }
 
 func (h *Handler) CollectionFormatter(apiContext *types.APIContext, collection *types.GenericCollection) {
@@ -35,6 +56,8 @@ type Handler struct {
 	UserClient               v3.UserInterface
 	GlobalRoleBindingsClient v3.GlobalRoleBindingInterface
 	UserAuthRefresher        providerrefresh.UserAuthRefresher
+	Lockout                  *lockout.Manager
+	PasswordPolicy           *passwordpolicy.Manager
 }
 
 func (h *Handler) Actions(actionName string, action *types.Action, apiContext *types.APIContext) error {
@@ -51,6 +74,8 @@ func (h *Handler) Actions(actionName string, action *types.Action, apiContext *t
 		if err := h.refreshAttributes(actionName, action, apiContext); err != nil {
 			return err
 		}
+	case "unlock":
+		return h.unlock(apiContext)
 	default:
 		return errors.Errorf("bad action %v", actionName)
 	}
@@ -98,19 +123,8 @@ func (h *Handler) changePassword(actionName string, action *types.Action, reques
 		return httperror.NewAPIError(httperror.InvalidBodyContent, "invalid current password")
 	}
 
-	newPassHash, err := HashPasswordString(newPass)
-	if err != nil {
-		return err
-	}
-
-	user.Password = newPassHash
-	user.MustChangePassword = false
-	user, err = h.UserClient.Update(user)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	_, err = h.PasswordPolicy.SetPassword(user, newPass, h.UserClient.Update)
+	return err
 }
 
 func (h *Handler) setPassword(actionName string, action *types.Action, request *types.APIContext) error {
@@ -124,8 +138,8 @@ func (h *Handler) setPassword(actionName string, action *types.Action, request *
 		return errors.New("no user store available")
 	}
 
-	userData, err := store.ByID(request, request.Schema, request.ID)
-	if err != nil {
+	// the store hides system users
+	if _, err := store.ByID(request, request.Schema, request.ID); err != nil {
 		return err
 	}
 
@@ -134,14 +148,15 @@ func (h *Handler) setPassword(actionName string, action *types.Action, request *
 		return errors.New("Invalid password")
 	}
 
-	userData[client.UserFieldPassword] = newPass
-	if err := hashPassword(userData); err != nil {
+	user, err := h.UserClient.Get(request.ID, v1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if _, err := h.PasswordPolicy.SetPassword(user, newPass, h.UserClient.Update); err != nil {
 		return err
 	}
-	userData[client.UserFieldMustChangePassword] = false
-	delete(userData, "me")
 
-	userData, err = store.Update(request, request.Schema, userData, request.ID)
+	userData, err := store.ByID(request, request.Schema, request.ID)
 	if err != nil {
 		return err
 	}
@@ -167,6 +182,31 @@ func (h *Handler) refreshAttributes(actionName string, action *types.Action, req
 	return nil
 }
 
+func (h *Handler) unlock(request *types.APIContext) error {
+	if !h.userCanUnlock(request) {
+		return httperror.NewAPIError(httperror.PermissionDenied, "not allowed to unlock users")
+	}
+
+	user, err := h.UserClient.Get(request.ID, v1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if err := h.Lockout.Unlock(user); err != nil {
+		return err
+	}
+
+	userData := map[string]interface{}{}
+	if err := access.ByID(request, request.Version, client.UserType, request.ID, &userData); err != nil {
+		return err
+	}
+	request.WriteResponse(http.StatusOK, userData)
+	return nil
+}
+
+func (h *Handler) userCanUnlock(request *types.APIContext) bool {
+	return request.AccessControl.CanDo(v3.UserGroupVersionKind.Group, v3.UserResource.Name, "update", request, nil, request.Schema) == nil
+}
+
 func (h *Handler) userCanRefresh(request *types.APIContext) bool {
 	return request.AccessControl.CanDo(v3.UserGroupVersionKind.Group, v3.UserResource.Name, "create", request, nil, request.Schema) == nil
 }
@@ -42,12 +42,14 @@
 type auditLog struct {
 	log                *log
 	writer             *LogWriter
+	level              int
 	reqBody            []byte
 	keysToConcealRegex *regexp.Regexp
 }
 
 type log struct {
 	AuditID           k8stypes.UID `json:"auditID,omitempty"`
+	Stage             Stage        `json:"stage,omitempty"`
 	RequestURI        string       `json:"requestURI,omitempty"`
 	User              *User        `json:"user,omitempty"`
 	Method            string       `json:"method,omitempty"`
@@ -98,9 +100,10 @@ func FromContext(ctx context.Context) (*User, bool) {
 	return u, ok
 }
 
-func newAuditLog(writer *LogWriter, req *http.Request, keysToConcealRegex *regexp.Regexp) (*auditLog, error) {
+func newAuditLog(writer *LogWriter, req *http.Request, keysToConcealRegex *regexp.Regexp, level int) (*auditLog, error) {
 	auditLog := &auditLog{
 		writer: writer,
+		level:  level,
 		log: &log{
 			AuditID:          k8stypes.UID(uuid.NewRandom().String()),
 			RequestURI:       req.RequestURI,
@@ -113,7 +116,7 @@ func newAuditLog(writer *LogWriter, req *http.Request, keysToConcealRegex *regex
 
 	contentType := req.Header.Get("Content-Type")
 	loginReq := isLoginRequest(req.RequestURI)
-	if writer.Level >= levelRequest || loginReq {
+	if level >= levelRequest || loginReq {
 		if bodyMethods[req.Method] && strings.HasPrefix(contentType, contentTypeJSON) {
 			reqBody, err := readBodyWithoutLosingContent(req)
 			if err != nil {
@@ -125,7 +128,7 @@ func newAuditLog(writer *LogWriter, req *http.Request, keysToConcealRegex *regex
 					auditLog.log.UserLoginName = loginName
 				}
 			}
-			if writer.Level >= levelRequest {
+			if level >= levelRequest {
 				auditLog.reqBody = reqBody
 			}
 		}
@@ -133,20 +136,40 @@ func newAuditLog(writer *LogWriter, req *http.Request, keysToConcealRegex *regex
 	return auditLog, nil
 }
 
+// writeRequestReceived writes the entry for the RequestReceived stage, before the request is handled.
+func (a *auditLog) writeRequestReceived(userInfo *User, reqHeaders http.Header) error {
+	a.log.Stage = StageRequestReceived
+	a.log.User = a.userWithLoginName(userInfo)
+	a.log.RequestHeader = filterOutHeaders(reqHeaders, sensitiveRequestHeader)
+	return a.writeLog(nil)
+}
+
 func (a *auditLog) write(userInfo *User, reqHeaders, resHeaders http.Header, resCode int, resBody []byte) error {
-	a.log.User = userInfo
+	a.log.Stage = StageResponseComplete
+	a.log.User = a.userWithLoginName(userInfo)
 	a.log.ResponseTimestamp = time.Now().Format(time.RFC3339)
 	a.log.RequestHeader = filterOutHeaders(reqHeaders, sensitiveRequestHeader)
 	a.log.ResponseHeader = filterOutHeaders(resHeaders, sensitiveResponseHeader)
 	a.log.ResponseCode = resCode
+
+	if a.level >= levelRequestResponse && resHeaders.Get("Content-Type") == contentTypeJSON {
+		return a.writeLog(resBody)
+	}
+	return a.writeLog(nil)
+}
+
+func (a *auditLog) userWithLoginName(userInfo *User) *User {
 	if a.log.UserLoginName != "" {
-		if a.log.User.Extra == nil {
-			a.log.User.Extra = make(map[string][]string)
+		if userInfo.Extra == nil {
+			userInfo.Extra = make(map[string][]string)
 		}
-		a.log.User.Extra["username"] = []string{a.log.UserLoginName}
+		userInfo.Extra["username"] = []string{a.log.UserLoginName}
 		logrus.Debugf("Added username for login request to audit log %v", a.log.UserLoginName)
 	}
+	return userInfo
+}
 
+func (a *auditLog) writeLog(resBody []byte) error {
 	var buffer bytes.Buffer
 	alByte, err := json.Marshal(a.log)
 	if err != nil {
@@ -154,11 +177,11 @@ func (a *auditLog) write(userInfo *User, reqHeaders, resHeaders http.Header, res
 	}
 
 	buffer.Write(bytes.TrimSuffix(alByte, []byte("}")))
-	if a.writer.Level >= levelRequest && len(a.reqBody) > 0 {
+	if a.level >= levelRequest && len(a.reqBody) > 0 {
 		buffer.WriteString(`,"requestBody":`)
 		buffer.Write(bytes.TrimSuffix(a.concealSensitiveData(a.log.RequestURI, a.reqBody), []byte("\n")))
 	}
-	if a.writer.Level >= levelRequestResponse && resHeaders.Get("Content-Type") == contentTypeJSON && len(resBody) > 0 {
+	if len(resBody) > 0 {
 		buffer.WriteString(`,"responseBody":`)
 		buffer.Write(bytes.TrimSuffix(a.concealSensitiveData(a.log.RequestURI, resBody), []byte("\n")))
 	}
@@ -171,7 +194,7 @@ func (a *auditLog) write(userInfo *User, reqHeaders, resHeaders http.Header, res
 	}
 
 	compactBuffer.WriteString("\n")
-	_, err = a.writer.Output.Write(compactBuffer.Bytes())
+	_, err = a.writer.Write(compactBuffer.Bytes())
 	return err
 }
 
@@ -238,6 +261,16 @@ func (a *auditLog) concealSensitiveData(requestURI string, body []byte) []byte {
 		}
 	}
 
+	// Conceal the TOTP secret and recovery codes returned when enrolling MFA.
+	if strings.Contains(requestURI, "action=enrollMfa") {
+		for _, key := range []string{"secret", "url", "recoveryCodes"} {
+			if _, ok := m[key]; ok {
+				changed = true
+				m[key] = redacted
+			}
+		}
+	}
+
 	// Conceal values for data considered sensitive: passwords, tokens, etc.
 	if !a.concealMap(m) && !changed {
 		return body
@@ -120,6 +120,17 @@ func Test_concealSensitiveData(t *testing.T) {
 			input: []byte(`{"sensitiveData": {"accessToken": "fake_access_token", "user": "fake_user"}}`),
 			want:  []byte(fmt.Sprintf(`{"sensitiveData": {"accessToken": "%s", "user": "fake_user"}}`, redacted)),
 		},
+		{
+			name:  "With MFA codes",
+			input: []byte(`{"username": "admin", "totpCode": "123456", "recoveryCode": "abcd-efgh"}`),
+			want:  []byte(fmt.Sprintf(`{"username": "admin", "totpCode": "%s", "recoveryCode": "%[1]s"}`, redacted)),
+		},
+		{
+			name:  "With MFA enrollment",
+			input: []byte(`{"secret": "JBSWY3DPEHPK3PXP", "url": "otpauth://totp/Rancher:admin?secret=JBSWY3DPEHPK3PXP", "recoveryCodes": ["abcd-efgh"]}`),
+			want:  []byte(fmt.Sprintf(`{"secret": "%s", "url": "%[1]s", "recoveryCodes": "%[1]s"}`, redacted)),
+			uri:   "/v3-public/localProviders/local?action=enrollMfa",
+		},
 		{
 			name:  "With all machine driver fields",
 			input: machineDataInput,
@@ -0,0 +1,261 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// hashField is always the last field of a chained entry so the hashed content can be recovered by stripping it.
+const hashField = `,"hash":"`
+
+// maxEntrySize bounds the size of a single audit entry read back from disk; response bodies can be large.
+const maxEntrySize = 64 * 1024 * 1024
+
+// chain links audit entries together. Every entry gets a sequence number and the hash of the previous entry, and is
+// then hashed (HMAC-SHA256 when a key is configured, SHA-256 otherwise). Editing, removing or reordering entries
+// breaks the chain, which Verify detects.
+type chain struct {
+	key      []byte
+	seq      uint64
+	prevHash string
+}
+
+type chainFields struct {
+	Seq      uint64 `json:"seq"`
+	PrevHash string `json:"prevHash"`
+	Hash     string `json:"hash"`
+}
+
+func newChain(key []byte) *chain {
+	return &chain{key: key}
+}
+
+func newHash(key []byte) hash.Hash {
+	if len(key) > 0 {
+		return hmac.New(sha256.New, key)
+	}
+	return sha256.New()
+}
+
+// resume continues the chain from the last entry of the log at path, or of its newest backup if the log was just
+// rotated, so sequence numbers and hashes carry over across restarts. Without any entries a new chain is started.
+func (c *chain) resume(path string) error {
+	files, err := logFiles(path)
+	if err != nil {
+		return nil
+	}
+
+	var last []byte
+	for i := len(files) - 1; i >= 0 && last == nil; i-- {
+		err := readEntries(files[i], func(_ int, entry []byte) {
+			last = append(last[:0], entry...)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if last == nil {
+		return nil
+	}
+
+	var fields chainFields
+	if err := json.Unmarshal(last, &fields); err != nil {
+		return fmt.Errorf("parsing last audit log entry: %w", err)
+	}
+	if fields.Hash == "" {
+		// The log was written without chaining, start a new chain.
+		return nil
+	}
+	c.seq = fields.Seq
+	c.prevHash = fields.Hash
+	return nil
+}
+
+// link adds seq, prevHash and hash to a compact JSON entry terminated by a newline. Callers must serialize calls and
+// deliver entries in the order they were linked.
+func (c *chain) link(entry []byte) []byte {
+	c.seq++
+	body := bytes.TrimSuffix(bytes.TrimSuffix(entry, []byte("\n")), []byte("}"))
+
+	var buf bytes.Buffer
+	buf.Write(body)
+	if len(body) > 1 {
+		buf.WriteString(",")
+	}
+	fmt.Fprintf(&buf, `"seq":%d,"prevHash":"%s"}`, c.seq, c.prevHash)
+
+	sum := c.sum(buf.Bytes())
+	c.prevHash = sum
+
+	out := buf.Bytes()
+	out = append(out[:len(out)-1], hashField...)
+	out = append(out, sum...)
+	out = append(out, "\"}\n"...)
+	return out
+}
+
+func (c *chain) sum(content []byte) string {
+	h := newHash(c.key)
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// VerifyIssue describes a problem found while verifying a chained audit log.
+type VerifyIssue struct {
+	File    string
+	Line    int
+	Seq     uint64
+	Message string
+}
+
+func (v VerifyIssue) String() string {
+	return fmt.Sprintf("%s:%d: seq %d: %s", v.File, v.Line, v.Seq, v.Message)
+}
+
+// VerifyResult summarizes the verification of a rotated audit log set.
+type VerifyResult struct {
+	Files    []string
+	Entries  int
+	FirstSeq uint64
+	LastSeq  uint64
+	Issues   []VerifyIssue
+}
+
+// Verify walks the audit log at path and its lumberjack backups, oldest first, and checks that every entry's hash
+// matches its content, that prevHash links to the preceding entry and that sequence numbers have no gaps.
+// The first retained entry is allowed to link to an entry that was removed by rotation.
+func Verify(path string, key []byte) (*VerifyResult, error) {
+	files, err := logFiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &VerifyResult{Files: files}
+	c := newChain(key)
+	var (
+		prevSeq  uint64
+		prevHash string
+	)
+	for _, file := range files {
+		err := readEntries(file, func(line int, entry []byte) {
+			issue := func(seq uint64, format string, args ...interface{}) {
+				result.Issues = append(result.Issues, VerifyIssue{File: file, Line: line, Seq: seq, Message: fmt.Sprintf(format, args...)})
+			}
+
+			var fields chainFields
+			if err := json.Unmarshal(entry, &fields); err != nil {
+				issue(0, "invalid entry: %v", err)
+				return
+			}
+			if fields.Hash == "" {
+				issue(0, "entry is not chained")
+				return
+			}
+
+			i := bytes.LastIndex(entry, []byte(hashField))
+			if i < 0 || c.sum(append(entry[:i:i], '}')) != fields.Hash {
+				issue(fields.Seq, "hash mismatch, entry was modified")
+			}
+
+			if result.Entries > 0 {
+				switch {
+				case fields.Seq == 1 && fields.PrevHash == "":
+					issue(fields.Seq, "chain restarted after seq %d", prevSeq)
+				case fields.Seq <= prevSeq:
+					issue(fields.Seq, "sequence out of order, previous seq %d", prevSeq)
+				case fields.Seq != prevSeq+1:
+					issue(fields.Seq, "gap, %d entries missing after seq %d", fields.Seq-prevSeq-1, prevSeq)
+				case fields.PrevHash != prevHash:
+					issue(fields.Seq, "prevHash does not match the hash of seq %d", prevSeq)
+				}
+			} else {
+				result.FirstSeq = fields.Seq
+			}
+
+			result.Entries++
+			result.LastSeq = fields.Seq
+			prevSeq = fields.Seq
+			prevHash = fields.Hash
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// logFiles returns the backups of the log at path in the order they were written, followed by path itself.
+func logFiles(path string) ([]string, error) {
+	ext := filepath.Ext(path)
+	prefix := strings.TrimSuffix(path, ext) + "-"
+
+	matches, err := filepath.Glob(prefix + "*")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, match := range matches {
+		name := strings.TrimSuffix(match, ".gz")
+		if strings.HasSuffix(name, ext) {
+			files = append(files, match)
+		}
+	}
+	// lumberjack backup names embed a sortable timestamp
+	sort.Strings(files)
+
+	if _, err := os.Stat(path); err == nil {
+		files = append(files, path)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no audit log files found for %s", path)
+	}
+	return files, nil
+}
+
+func readEntries(file string, f func(line int, entry []byte)) error {
+	fh, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	var r io.Reader = fh
+	if strings.HasSuffix(file, ".gz") {
+		gz, err := gzip.NewReader(fh)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	scanner := newEntryScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		f(line, scanner.Bytes())
+	}
+	return scanner.Err()
+}
+
+func newEntryScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEntrySize)
+	return scanner
+}
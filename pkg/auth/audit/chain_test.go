@@ -0,0 +1,136 @@
+package audit
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeChained(t *testing.T, c *chain, path string, from, to int) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	defer f.Close()
+	for i := from; i <= to; i++ {
+		_, err := f.Write(c.link([]byte(fmt.Sprintf(`{"auditID":"%d","method":"GET"}`+"\n", i))))
+		require.NoError(t, err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	key := []byte("secret")
+
+	tests := []struct {
+		name   string
+		tamper func(lines [][]byte) [][]byte
+		issues []string
+	}{
+		{
+			name: "untouched",
+		},
+		{
+			name: "modified entry",
+			tamper: func(lines [][]byte) [][]byte {
+				lines[2] = bytes.Replace(lines[2], []byte(`"GET"`), []byte(`"PUT"`), 1)
+				return lines
+			},
+			issues: []string{"hash mismatch"},
+		},
+		{
+			name: "deleted entry",
+			tamper: func(lines [][]byte) [][]byte {
+				return append(lines[:2], lines[3:]...)
+			},
+			issues: []string{"gap, 1 entries missing after seq 2"},
+		},
+		{
+			name: "swapped entries",
+			tamper: func(lines [][]byte) [][]byte {
+				lines[1], lines[2] = lines[2], lines[1]
+				return lines
+			},
+			issues: []string{"gap", "sequence out of order", "gap"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "audit")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			path := filepath.Join(dir, "rancher-api-audit.log")
+			backup := filepath.Join(dir, "rancher-api-audit-2021-07-20T10-00-00.000.log")
+
+			c := newChain(key)
+			writeChained(t, c, backup, 1, 3)
+			writeChained(t, c, path, 4, 5)
+
+			if tt.tamper != nil {
+				data, err := ioutil.ReadFile(backup)
+				require.NoError(t, err)
+				data = append(data, mustRead(t, path)...)
+				lines := tt.tamper(bytes.Split(bytes.TrimSpace(data), []byte("\n")))
+				require.NoError(t, os.Remove(backup))
+				require.NoError(t, ioutil.WriteFile(path, append(bytes.Join(lines, []byte("\n")), '\n'), 0600))
+			}
+
+			result, err := Verify(path, key)
+			require.NoError(t, err)
+
+			var messages []string
+			for _, issue := range result.Issues {
+				messages = append(messages, issue.Message)
+			}
+			require.Len(t, messages, len(tt.issues), "issues: %v", messages)
+			for i := range tt.issues {
+				assert.True(t, strings.HasPrefix(messages[i], tt.issues[i]), "got %q, want %q", messages[i], tt.issues[i])
+			}
+		})
+	}
+}
+
+func TestVerifyWrongKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+	writeChained(t, newChain([]byte("secret")), path, 1, 2)
+
+	result, err := Verify(path, []byte("other"))
+	require.NoError(t, err)
+	assert.Len(t, result.Issues, 2)
+}
+
+func TestChainResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+	writeChained(t, newChain(nil), path, 1, 2)
+
+	c := newChain(nil)
+	require.NoError(t, c.resume(path))
+	assert.Equal(t, uint64(2), c.seq)
+	writeChained(t, c, path, 3, 4)
+
+	result, err := Verify(path, nil)
+	require.NoError(t, err)
+	assert.Empty(t, result.Issues)
+	assert.Equal(t, 4, result.Entries)
+	assert.Equal(t, uint64(1), result.FirstSeq)
+	assert.Equal(t, uint64(4), result.LastSeq)
+}
+
+func mustRead(t *testing.T, path string) []byte {
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	return data
+}
@@ -27,7 +27,8 @@ func NewAuditLogMiddleware(auditWriter *LogWriter) (func(http.Handler) http.Hand
 	}, err
 }
 
-// constructKeyConcealRegex builds a regex for matching non-public fields from management.DriverData as well as fields that end with [pP]assword or [tT]oken.
+// constructKeyConcealRegex builds a regex for matching non-public fields from management.DriverData as well as fields that end with [pP]assword or [tT]oken
+// and MFA codes.
 func constructKeyConcealRegex() (*regexp.Regexp, error) {
 	s := strings.Builder{}
 	s.WriteRune('(')
@@ -41,7 +42,7 @@ func constructKeyConcealRegex() (*regexp.Regexp, error) {
 			}
 		}
 	}
-	s.WriteString(`[pP]assword|[tT]oken)`)
+	s.WriteString(`[pP]assword|[tT]oken|totpCode|recoveryCode)`)
 
 	return regexp.Compile(s.String())
 }
@@ -63,16 +64,28 @@ func (h auditHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	context := context.WithValue(req.Context(), userKey, user)
 	req = req.WithContext(context)
 
-	auditLog, err := newAuditLog(h.auditWriter, req, h.sanitizingRegex)
+	level, omitStages := h.auditWriter.Policy.evaluate(getRequestAttributes(req, user), h.auditWriter.Level)
+	if level == levelNull {
+		h.next.ServeHTTP(rw, req)
+		return
+	}
+
+	auditLog, err := newAuditLog(h.auditWriter, req, h.sanitizingRegex, level)
 	if err != nil {
 		util.ReturnHTTPError(rw, req, 500, err.Error())
 		return
 	}
 
+	if !omitStages[StageRequestReceived] {
+		auditLog.writeRequestReceived(user, req.Header)
+	}
+
 	wr := &wrapWriter{ResponseWriter: rw, auditWriter: h.auditWriter, statusCode: http.StatusOK}
 	h.next.ServeHTTP(wr, req)
 
-	auditLog.write(user, req.Header, wr.Header(), wr.statusCode, wr.buf.Bytes())
+	if !omitStages[StageResponseComplete] {
+		auditLog.write(user, req.Header, wr.Header(), wr.statusCode, wr.buf.Bytes())
+	}
 }
 
 type wrapWriter struct {
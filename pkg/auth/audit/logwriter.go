@@ -2,37 +2,180 @@
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"sync"
 
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/sirupsen/logrus"
 	lumberjack "gopkg.in/natefinch/lumberjack.v2"
 )
 
+// maxPendingEntries bounds the entries held until Start when there is no log file to write them to.
+const maxPendingEntries = 10000
+
 type LogWriter struct {
 	Level  int
+	Policy *Policy
+	// Output is the log file, nil if audit entries are only sent to the stdout, webhook or syslog sinks.
 	Output *lumberjack.Logger
+
+	lock    sync.RWMutex
+	sinks   []Sink
+	pending [][]byte
+	dropped int
+
+	chainLock sync.Mutex
+	chain     *chain
+}
+
+// EnableHashChain turns on tamper-evident chaining of entries, see Verify. The chain resumes from the last entry of
+// the log file. The HMAC key is read from keyFile; without a key entries are hashed with plain SHA-256.
+func (l *LogWriter) EnableHashChain(keyFile string) error {
+	if l == nil {
+		return nil
+	}
+	key, err := readHashChainKey(keyFile)
+	if err != nil {
+		return err
+	}
+	c := newChain(key)
+	if l.Output != nil {
+		if err := c.resume(l.Output.Filename); err != nil {
+			return fmt.Errorf("resuming audit log hash chain: %w", err)
+		}
+	}
+	l.chain = c
+	return nil
 }
 
+// Start builds the configured sinks from settings and closes them when ctx is done. Settings must be available, so
+// Start has to be called after the settings provider is registered. Until Start is called entries go to the file, or
+// are held in memory and handed to the sinks by Start if there is no file.
 func (l *LogWriter) Start(ctx context.Context) {
 	if l == nil {
 		return
 	}
+
+	sinks, err := newSinks(sinkConfigFromSettings(), l.file())
+	if err == nil && len(sinks) == 0 {
+		err = fmt.Errorf("none of the sinks %q can be used", settings.AuditLogSinks.Get())
+	}
+	if err != nil {
+		fallback := sinkFile
+		if l.Output == nil {
+			fallback = sinkStdout
+		}
+		logrus.Errorf("[audit] failed to configure audit log sinks, falling back to %s: %v", fallback, err)
+		sinks, _ = newSinks(SinkConfig{Sinks: fallback, BufferSize: settings.AuditLogSinkBufferSize.GetInt()}, l.file())
+	}
+
+	l.lock.Lock()
+	l.sinks = sinks
+	pending, dropped := l.pending, l.dropped
+	l.pending, l.dropped = nil, 0
+	for _, entry := range pending {
+		l.writeSinks(entry)
+	}
+	l.lock.Unlock()
+
+	if dropped > 0 {
+		logrus.Warnf("[audit] dropped %d entries written before the audit log sinks were started", dropped)
+	}
+
 	go func() {
 		<-ctx.Done()
-		l.Output.Close()
+		l.lock.Lock()
+		sinks := l.sinks
+		l.sinks = nil
+		l.lock.Unlock()
+		closeSinks(sinks)
+		if l.Output != nil {
+			l.Output.Close()
+		}
 	}()
 }
 
-func NewLogWriter(path string, level, maxAge, maxBackup, maxSize int) *LogWriter {
-	if path == "" || level == levelNull {
+// file returns the log file as a sink writer, or nil if there is none.
+func (l *LogWriter) file() io.WriteCloser {
+	if l.Output == nil {
+		return nil
+	}
+	return l.Output
+}
+
+// Write hands the entry to every sink. The file and stdout sinks are written synchronously, the webhook and syslog
+// sinks queue entries so Write never blocks on a slow remote endpoint.
+func (l *LogWriter) Write(entry []byte) (int, error) {
+	if l.chain != nil {
+		// entries must reach the sinks in chain order
+		l.chainLock.Lock()
+		defer l.chainLock.Unlock()
+		entry = l.chain.link(entry)
+	}
+
+	l.lock.RLock()
+	if l.sinks != nil {
+		defer l.lock.RUnlock()
+		l.writeSinks(entry)
+		return len(entry), nil
+	}
+	l.lock.RUnlock()
+
+	if l.Output != nil {
+		return l.Output.Write(entry)
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.sinks != nil {
+		l.writeSinks(entry)
+	} else if len(l.pending) < maxPendingEntries {
+		l.pending = append(l.pending, append([]byte(nil), entry...))
+	} else {
+		l.dropped++
+	}
+	return len(entry), nil
+}
+
+func (l *LogWriter) writeSinks(entry []byte) {
+	for _, sink := range l.sinks {
+		// full queues are reported by the sink itself
+		if err := sink.Write(entry); err != nil && err != errQueueFull {
+			logrus.Warnf("[audit] %v", err)
+		}
+	}
+}
+
+func sinkConfigFromSettings() SinkConfig {
+	return SinkConfig{
+		Sinks:                  settings.AuditLogSinks.Get(),
+		BufferSize:             settings.AuditLogSinkBufferSize.GetInt(),
+		WebhookURL:             settings.AuditLogWebhookURL.Get(),
+		WebhookCACerts:         settings.AuditLogWebhookCACerts.Get(),
+		WebhookBatchSize:       settings.AuditLogWebhookBatchSize.GetInt(),
+		WebhookFlushIntervalMS: settings.AuditLogWebhookFlushIntervalMS.GetInt(),
+		SyslogAddress:          settings.AuditLogSyslogAddress.Get(),
+		SyslogCACerts:          settings.AuditLogSyslogCACerts.Get(),
+	}
+}
+
+func NewLogWriter(path string, level, maxAge, maxBackup, maxSize int, policy *Policy) *LogWriter {
+	if level == levelNull && policy == nil {
 		return nil
 	}
 
-	return &LogWriter{
-		Level: level,
-		Output: &lumberjack.Logger{
+	writer := &LogWriter{
+		Level:  level,
+		Policy: policy,
+	}
+	if path != "" {
+		writer.Output = &lumberjack.Logger{
 			Filename:   path,
 			MaxAge:     maxAge,
 			MaxBackups: maxBackup,
 			MaxSize:    maxSize,
-		},
+		}
 	}
+	return writer
 }
@@ -0,0 +1,323 @@
+package audit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Level is the amount of information recorded for a request, as in the Kubernetes audit policy.
+type Level string
+
+const (
+	LevelNone            Level = "None"
+	LevelMetadata        Level = "Metadata"
+	LevelRequest         Level = "Request"
+	LevelRequestResponse Level = "RequestResponse"
+)
+
+// Stage is a point in the handling of a request at which an audit entry can be written.
+type Stage string
+
+const (
+	StageRequestReceived  Stage = "RequestReceived"
+	StageResponseComplete Stage = "ResponseComplete"
+)
+
+const (
+	verbGet    = "get"
+	verbList   = "list"
+	verbWatch  = "watch"
+	verbCreate = "create"
+	verbUpdate = "update"
+	verbPatch  = "patch"
+	verbDelete = "delete"
+	verbAction = "action"
+)
+
+var levels = map[Level]int{
+	LevelNone:            levelNull,
+	LevelMetadata:        levelMetadata,
+	LevelRequest:         levelRequest,
+	LevelRequestResponse: levelRequestResponse,
+}
+
+// Policy is an ordered list of rules selecting the audit level of a request. It is modelled on the audit.k8s.io/v1
+// Policy, with rules matching on Rancher concepts such as URI prefixes and cluster IDs rather than API groups.
+// The first rule matching a request wins; requests matching no rule are logged at the audit-level flag's level.
+type Policy struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+	// OmitStages applies to all rules in addition to the rule's own OmitStages.
+	OmitStages []Stage      `json:"omitStages,omitempty"`
+	Rules      []PolicyRule `json:"rules,omitempty"`
+}
+
+// PolicyRule matches a request when every non-empty field matches. Fields with several values match if any value does.
+type PolicyRule struct {
+	Level      Level    `json:"level"`
+	Users      []string `json:"users,omitempty"`
+	UserGroups []string `json:"userGroups,omitempty"`
+	// Verbs are get, list, watch, create, update, patch, delete and action for Rancher API actions (?action=).
+	Verbs       []string `json:"verbs,omitempty"`
+	URIPrefixes []string `json:"uriPrefixes,omitempty"`
+	// Resources are matched against the plural resource type of the request, e.g. "secrets" or
+	// "clusterroletemplatebindings". Steve types such as "management.cattle.io.users" match both the full type and "users".
+	Resources  []string `json:"resources,omitempty"`
+	ClusterIDs []string `json:"clusterIDs,omitempty"`
+	OmitStages []Stage  `json:"omitStages,omitempty"`
+}
+
+// LoadPolicy reads a policy from a YAML or JSON file. An empty path returns a nil policy.
+func LoadPolicy(path string) (*Policy, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading audit policy file: %w", err)
+	}
+	return parsePolicy(data)
+}
+
+func parsePolicy(data []byte) (*Policy, error) {
+	policy := &Policy{}
+	if err := yaml.UnmarshalStrict(data, policy); err != nil {
+		return nil, fmt.Errorf("parsing audit policy: %w", err)
+	}
+	for i, rule := range policy.Rules {
+		if _, ok := levels[rule.Level]; !ok {
+			return nil, fmt.Errorf("audit policy rule %d: invalid level %q", i, rule.Level)
+		}
+		if err := validateStages(rule.OmitStages); err != nil {
+			return nil, fmt.Errorf("audit policy rule %d: %w", i, err)
+		}
+	}
+	if err := validateStages(policy.OmitStages); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+func validateStages(stages []Stage) error {
+	for _, stage := range stages {
+		if stage != StageRequestReceived && stage != StageResponseComplete {
+			return fmt.Errorf("invalid stage %q", stage)
+		}
+	}
+	return nil
+}
+
+// requestAttributes are the properties of a request that policy rules match on.
+type requestAttributes struct {
+	user      *User
+	verb      string
+	uri       string
+	resource  string
+	clusterID string
+}
+
+// evaluate returns the level and omitted stages for a request. Without a policy, or when no rule matches,
+// defaultLevel is used and only the ResponseComplete stage is logged.
+func (p *Policy) evaluate(attrs *requestAttributes, defaultLevel int) (int, map[Stage]bool) {
+	omit := map[Stage]bool{}
+	if p == nil {
+		omit[StageRequestReceived] = true
+		return defaultLevel, omit
+	}
+
+	for _, stage := range p.OmitStages {
+		omit[stage] = true
+	}
+	for _, rule := range p.Rules {
+		if !rule.matches(attrs) {
+			continue
+		}
+		for _, stage := range rule.OmitStages {
+			omit[stage] = true
+		}
+		return levels[rule.Level], omit
+	}
+	omit[StageRequestReceived] = true
+	return defaultLevel, omit
+}
+
+func (r *PolicyRule) matches(attrs *requestAttributes) bool {
+	if len(r.Users) > 0 && (attrs.user == nil || !isExist(r.Users, attrs.user.Name)) {
+		return false
+	}
+	if len(r.UserGroups) > 0 && (attrs.user == nil || !anyExist(r.UserGroups, attrs.user.Group)) {
+		return false
+	}
+	if len(r.Verbs) > 0 && !isExist(r.Verbs, attrs.verb) {
+		return false
+	}
+	if len(r.URIPrefixes) > 0 && !hasAnyPrefix(attrs.uri, r.URIPrefixes) {
+		return false
+	}
+	if len(r.Resources) > 0 && !matchesResource(r.Resources, attrs.resource) {
+		return false
+	}
+	if len(r.ClusterIDs) > 0 && !isExist(r.ClusterIDs, attrs.clusterID) {
+		return false
+	}
+	return true
+}
+
+func anyExist(array []string, keys []string) bool {
+	for _, key := range keys {
+		if isExist(array, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesResource(resources []string, resource string) bool {
+	if resource == "" {
+		return false
+	}
+	resource = strings.ToLower(resource)
+	for _, r := range resources {
+		r = strings.ToLower(r)
+		if r == "*" || r == resource || strings.HasSuffix(resource, "."+r) {
+			return true
+		}
+	}
+	return false
+}
+
+func getRequestAttributes(req *http.Request, user *User) *requestAttributes {
+	attrs := &requestAttributes{
+		user: user,
+		uri:  req.URL.Path,
+	}
+	named := parseResource(req.URL.Path, attrs)
+	attrs.verb = getVerb(req, named)
+	return attrs
+}
+
+func getVerb(req *http.Request, named bool) string {
+	query := req.URL.Query()
+	if query.Get("action") != "" {
+		return verbAction
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		if query.Get("watch") == "true" || strings.EqualFold(req.Header.Get("Upgrade"), "websocket") {
+			return verbWatch
+		}
+		if named {
+			return verbGet
+		}
+		return verbList
+	case http.MethodPost:
+		return verbCreate
+	case http.MethodPut:
+		return verbUpdate
+	case http.MethodPatch:
+		return verbPatch
+	case http.MethodDelete:
+		return verbDelete
+	}
+	return strings.ToLower(req.Method)
+}
+
+// parseResource fills in the resource type and cluster ID of the request from the norman (/v3), steve (/v1) and
+// cluster proxy (/k8s/clusters) URL layouts. It returns whether the URL refers to a single named object.
+func parseResource(path string, attrs *requestAttributes) bool {
+	parts := splitPath(path)
+	if len(parts) == 0 {
+		return false
+	}
+
+	switch parts[0] {
+	case "k8s":
+		if len(parts) < 3 || parts[1] != "clusters" {
+			return false
+		}
+		attrs.clusterID = parts[2]
+		return parseKubernetesResource(parts[3:], attrs)
+	case "v3", "v3-public":
+		parts = parts[1:]
+		if len(parts) >= 2 && (parts[0] == "cluster" || parts[0] == "project") {
+			// /v3/cluster/<cluster>/<type>/<name> and /v3/project/<project>/<type>/<name>
+			attrs.clusterID = clusterIDFromScope(parts[1])
+			parts = parts[2:]
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		attrs.resource = strings.ToLower(parts[0])
+		if len(parts) >= 2 && attrs.clusterID == "" && (attrs.resource == "clusters" || attrs.resource == "projects") {
+			attrs.clusterID = clusterIDFromScope(parts[1])
+		}
+		return len(parts) >= 2
+	case "v1":
+		if len(parts) < 2 {
+			return false
+		}
+		attrs.resource = strings.ToLower(parts[1])
+		if attrs.resource == "management.cattle.io.clusters" && len(parts) >= 3 {
+			attrs.clusterID = parts[2]
+		}
+		return len(parts) >= 3
+	}
+	return false
+}
+
+func parseKubernetesResource(parts []string, attrs *requestAttributes) bool {
+	switch {
+	case len(parts) >= 2 && parts[0] == "api":
+		parts = parts[2:]
+	case len(parts) >= 3 && parts[0] == "apis":
+		parts = parts[3:]
+	default:
+		return false
+	}
+	if len(parts) >= 2 && parts[0] == "namespaces" {
+		if len(parts) == 2 {
+			attrs.resource = "namespaces"
+			return true
+		}
+		parts = parts[2:]
+	}
+	if len(parts) == 0 {
+		return false
+	}
+	attrs.resource = parts[0]
+	return len(parts) >= 2
+}
+
+func clusterIDFromScope(id string) string {
+	if i := strings.Index(id, ":"); i >= 0 {
+		return id[:i]
+	}
+	return id
+}
+
+func splitPath(path string) []string {
+	if unescaped, err := url.PathUnescape(path); err == nil {
+		path = unescaped
+	}
+	var parts []string
+	for _, part := range strings.Split(path, "/") {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
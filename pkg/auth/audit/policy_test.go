@@ -0,0 +1,188 @@
+package audit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPolicy = `
+apiVersion: audit.cattle.io/v1
+kind: Policy
+omitStages:
+- RequestReceived
+rules:
+- level: None
+  verbs: ["watch"]
+- level: Metadata
+  verbs: ["get", "list"]
+- level: RequestResponse
+  resources: ["clusterroletemplatebindings", "projectroletemplatebindings", "authconfigs"]
+- level: Request
+  clusterIDs: ["c-prod"]
+  userGroups: ["local://admins"]
+  omitStages:
+  - ResponseComplete
+- level: Metadata
+  uriPrefixes: ["/v3-public/"]
+`
+
+func TestParsePolicy(t *testing.T) {
+	_, err := parsePolicy([]byte(`rules: [{level: Everything}]`))
+	assert.Error(t, err)
+
+	_, err = parsePolicy([]byte(`rules: [{level: None, omitStages: [Panic]}]`))
+	assert.Error(t, err)
+
+	_, err = parsePolicy([]byte(`rules: [{level: None, namespaces: [default]}]`))
+	assert.Error(t, err, "unknown fields should be rejected")
+
+	policy, err := parsePolicy([]byte(testPolicy))
+	require.NoError(t, err)
+	assert.Len(t, policy.Rules, 5)
+}
+
+func TestPolicyEvaluate(t *testing.T) {
+	policy, err := parsePolicy([]byte(testPolicy))
+	require.NoError(t, err)
+
+	admin := &User{Name: "u-admin", Group: []string{"system:authenticated", "local://admins"}}
+	tests := []struct {
+		name      string
+		method    string
+		uri       string
+		user      *User
+		level     int
+		omitStage Stage
+	}{
+		{
+			name:   "steve watch",
+			method: http.MethodGet,
+			uri:    "/v1/management.cattle.io.clusters?watch=true",
+			level:  levelNull,
+		},
+		{
+			name:   "norman list",
+			method: http.MethodGet,
+			uri:    "/v3/clusterroletemplatebindings",
+			level:  levelMetadata,
+		},
+		{
+			name:   "norman crtb create",
+			method: http.MethodPost,
+			uri:    "/v3/clusterroletemplatebindings",
+			level:  levelRequestResponse,
+		},
+		{
+			name:   "steve authconfig update",
+			method: http.MethodPut,
+			uri:    "/v1/management.cattle.io.authconfigs/github",
+			level:  levelRequestResponse,
+		},
+		{
+			name:   "authconfig action",
+			method: http.MethodPost,
+			uri:    "/v3/authConfigs/github?action=configureTest",
+			level:  levelRequestResponse,
+		},
+		{
+			name:      "cluster proxy in prod cluster",
+			method:    http.MethodDelete,
+			uri:       "/k8s/clusters/c-prod/api/v1/namespaces/default/pods/web",
+			user:      admin,
+			level:     levelRequest,
+			omitStage: StageResponseComplete,
+		},
+		{
+			name:   "project scoped request in prod cluster",
+			method: http.MethodPost,
+			uri:    "/v3/project/c-prod:p-abc/workloads",
+			user:   admin,
+			level:  levelRequest,
+		},
+		{
+			name:   "prod cluster not in group",
+			method: http.MethodDelete,
+			uri:    "/k8s/clusters/c-prod/api/v1/namespaces/default/pods/web",
+			user:   &User{Name: "u-dev"},
+			level:  levelRequestResponse,
+		},
+		{
+			name:   "login",
+			method: http.MethodPost,
+			uri:    "/v3-public/localProviders/local?action=login",
+			level:  levelMetadata,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.uri, nil)
+			user := tt.user
+			if user == nil {
+				user = &User{Name: "u-default"}
+			}
+			level, omit := policy.evaluate(getRequestAttributes(req, user), levelRequestResponse)
+			assert.Equal(t, tt.level, level)
+			assert.True(t, omit[StageRequestReceived])
+			if tt.omitStage != "" {
+				assert.True(t, omit[tt.omitStage])
+			}
+		})
+	}
+}
+
+func TestPolicyEvaluateWithoutPolicy(t *testing.T) {
+	var policy *Policy
+	req := httptest.NewRequest(http.MethodGet, "/v3/users", nil)
+	level, omit := policy.evaluate(getRequestAttributes(req, &User{}), levelRequest)
+	assert.Equal(t, levelRequest, level)
+	assert.True(t, omit[StageRequestReceived])
+	assert.False(t, omit[StageResponseComplete])
+}
+
+func TestPolicyEvaluateNoMatchingRule(t *testing.T) {
+	policy, err := parsePolicy([]byte(`rules: [{level: Metadata, verbs: ["get"]}]`))
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/v3/users/u-abc", nil)
+	_, omit := policy.evaluate(getRequestAttributes(req, &User{}), levelRequest)
+	assert.False(t, omit[StageRequestReceived])
+
+	req = httptest.NewRequest(http.MethodDelete, "/v3/users/u-abc", nil)
+	level, omit := policy.evaluate(getRequestAttributes(req, &User{}), levelRequest)
+	assert.Equal(t, levelRequest, level)
+	assert.True(t, omit[StageRequestReceived])
+	assert.False(t, omit[StageResponseComplete])
+}
+
+func TestParseResource(t *testing.T) {
+	tests := []struct {
+		path      string
+		resource  string
+		clusterID string
+		named     bool
+	}{
+		{"/v3/clusters/c-abc", "clusters", "c-abc", true},
+		{"/v3/projects/c-abc:p-xyz", "projects", "c-abc", true},
+		{"/v3/cluster/c-abc/namespaces/default", "namespaces", "c-abc", true},
+		{"/v3/tokens", "tokens", "", false},
+		{"/v1/secrets/cattle-system/tls-ca", "secrets", "", true},
+		{"/v1/management.cattle.io.clusters/c-abc", "management.cattle.io.clusters", "c-abc", true},
+		{"/k8s/clusters/c-abc/apis/apps/v1/namespaces/default/deployments", "deployments", "c-abc", false},
+		{"/k8s/clusters/c-abc/api/v1/namespaces/default", "namespaces", "c-abc", true},
+		{"/k8s/clusters/c-abc/api/v1/nodes/node-1", "nodes", "c-abc", true},
+		{"/dashboard/", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			attrs := &requestAttributes{}
+			named := parseResource(tt.path, attrs)
+			assert.Equal(t, tt.resource, attrs.resource)
+			assert.Equal(t, tt.clusterID, attrs.clusterID)
+			assert.Equal(t, tt.named, named)
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package audit
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+var errQueueFull = fmt.Errorf("audit log sink queue is full")
+
+const (
+	sinkFile    = "file"
+	sinkStdout  = "stdout"
+	sinkWebhook = "webhook"
+	sinkSyslog  = "syslog"
+)
+
+// Sink is a destination for audit log entries. Each entry passed to Write is a single compact JSON document
+// terminated by a newline.
+type Sink interface {
+	Write(entry []byte) error
+	Close() error
+}
+
+// writerSink adapts an io.WriteCloser, such as the lumberjack file logger, to a Sink.
+type writerSink struct {
+	w io.WriteCloser
+}
+
+func (s *writerSink) Write(entry []byte) error {
+	_, err := s.w.Write(entry)
+	return err
+}
+
+func (s *writerSink) Close() error {
+	return s.w.Close()
+}
+
+// stdoutSink writes entries to the process stdout so they can be collected with the container logs.
+type stdoutSink struct {
+	sync.Mutex
+}
+
+func (s *stdoutSink) Write(entry []byte) error {
+	s.Lock()
+	defer s.Unlock()
+	_, err := os.Stdout.Write(entry)
+	return err
+}
+
+func (s *stdoutSink) Close() error {
+	return nil
+}
+
+// asyncSink decouples a remote Sink from the request path. Entries are queued on a bounded channel and written by a
+// single goroutine; when the queue is full entries are dropped so that a slow or failing sink never blocks API
+// requests. Drops are logged as warnings when they start and when the queue accepts entries again.
+type asyncSink struct {
+	name    string
+	sink    Sink
+	queue   chan []byte
+	done    chan struct{}
+	dropped uint64
+	mu      sync.Mutex
+	closed  bool
+}
+
+func newAsyncSink(name string, sink Sink, bufferSize int) *asyncSink {
+	if bufferSize <= 0 {
+		bufferSize = 1
+	}
+	a := &asyncSink{
+		name:  name,
+		sink:  sink,
+		queue: make(chan []byte, bufferSize),
+		done:  make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+func (a *asyncSink) run() {
+	defer close(a.done)
+	for entry := range a.queue {
+		if err := a.sink.Write(entry); err != nil {
+			logrus.Errorf("[audit] failed to write entry to %s sink: %v", a.name, err)
+		}
+	}
+}
+
+func (a *asyncSink) Write(entry []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return fmt.Errorf("%s sink is closed", a.name)
+	}
+
+	select {
+	case a.queue <- entry:
+		if a.dropped > 0 {
+			logrus.Warnf("[audit] %s sink dropped %d entries because its queue was full", a.name, a.dropped)
+			a.dropped = 0
+		}
+		return nil
+	default:
+		a.dropped++
+		if a.dropped == 1 {
+			logrus.Warnf("[audit] %s sink queue is full, dropping entries", a.name)
+		}
+		return errQueueFull
+	}
+}
+
+// Close stops accepting entries, waits for the queue to drain and closes the underlying sink.
+func (a *asyncSink) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	close(a.queue)
+	a.mu.Unlock()
+
+	<-a.done
+	return a.sink.Close()
+}
+
+// SinkConfig holds the options used to build the audit log sinks.
+type SinkConfig struct {
+	// Sinks is a comma separated list of sink names: file, stdout, webhook and syslog.
+	Sinks                  string
+	BufferSize             int
+	WebhookURL             string
+	WebhookCACerts         string
+	WebhookBatchSize       int
+	WebhookFlushIntervalMS int
+	SyslogAddress          string
+	SyslogCACerts          string
+}
+
+func newSinks(config SinkConfig, file io.WriteCloser) ([]Sink, error) {
+	var (
+		sinks []Sink
+		seen  = map[string]bool{}
+	)
+	for _, name := range strings.Split(config.Sinks, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		var (
+			sink Sink
+			err  error
+		)
+		switch name {
+		case sinkFile:
+			if file == nil {
+				continue
+			}
+			sink = &writerSink{w: file}
+		case sinkStdout:
+			sink = &stdoutSink{}
+		case sinkWebhook:
+			sink, err = newWebhookSink(config.WebhookURL, config.WebhookCACerts, config.WebhookBatchSize, config.WebhookFlushIntervalMS)
+		case sinkSyslog:
+			sink, err = newSyslogSink(config.SyslogAddress, config.SyslogCACerts)
+		default:
+			err = fmt.Errorf("unknown sink type")
+		}
+		if err != nil {
+			closeSinks(sinks)
+			return nil, fmt.Errorf("invalid audit log sink %s: %w", name, err)
+		}
+		if name == sinkWebhook || name == sinkSyslog {
+			sink = newAsyncSink(name, sink, config.BufferSize)
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks, nil
+}
+
+func closeSinks(sinks []Sink) {
+	for _, sink := range sinks {
+		if err := sink.Close(); err != nil {
+			logrus.Errorf("[audit] failed to close sink: %v", err)
+		}
+	}
+}
+
+func tlsConfig(caCerts string) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if caCerts == "" {
+		return config, nil
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM([]byte(caCerts)) {
+		return nil, fmt.Errorf("failed to parse CA certificates")
+	}
+	config.RootCAs = pool
+	return config, nil
+}
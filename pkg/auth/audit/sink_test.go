@@ -0,0 +1,112 @@
+package audit
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type blockingSink struct {
+	release chan struct{}
+}
+
+func (b *blockingSink) Write(entry []byte) error {
+	<-b.release
+	return nil
+}
+
+func (b *blockingSink) Close() error {
+	return nil
+}
+
+func TestAsyncSinkDropsWhenFull(t *testing.T) {
+	b := &blockingSink{release: make(chan struct{})}
+	a := newAsyncSink("test", b, 1)
+
+	// The first entry is picked up by the writer goroutine and blocks, the second fills the queue.
+	assert.NoError(t, a.Write([]byte("1\n")))
+	assert.Eventually(t, func() bool { return len(a.queue) == 0 }, time.Second, 10*time.Millisecond)
+	assert.NoError(t, a.Write([]byte("2\n")))
+	assert.Error(t, a.Write([]byte("3\n")))
+
+	close(b.release)
+	assert.NoError(t, a.Close())
+	assert.Error(t, a.Write([]byte("4\n")))
+}
+
+func TestWebhookSinkBatches(t *testing.T) {
+	var (
+		lock    sync.Mutex
+		batches [][]map[string]interface{}
+		fail    = true
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if fail {
+			fail = false
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := ioutil.ReadAll(req.Body)
+		var batch []map[string]interface{}
+		if err := json.Unmarshal(body, &batch); err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		batches = append(batches, batch)
+	}))
+	defer server.Close()
+
+	s, err := newWebhookSink(server.URL, "", 2, 60000)
+	require.NoError(t, err)
+	s.backoff.Duration = time.Millisecond
+
+	assert.NoError(t, s.Write([]byte(`{"auditID":"1"}`+"\n")))
+	assert.NoError(t, s.Write([]byte(`{"auditID":"2"}`+"\n")))
+	assert.NoError(t, s.Write([]byte(`{"auditID":"3"}`+"\n")))
+	assert.NoError(t, s.Close())
+
+	lock.Lock()
+	defer lock.Unlock()
+	require.Len(t, batches, 2)
+	assert.Len(t, batches[0], 2)
+	assert.Equal(t, "3", batches[1][0]["auditID"])
+}
+
+func TestSyslogFormat(t *testing.T) {
+	s, err := newSyslogSink("tcp://localhost:514", "")
+	require.NoError(t, err)
+	s.hostname = "rancher-0"
+
+	msg := string(s.format(time.Date(2021, 7, 1, 10, 0, 0, 0, time.UTC), []byte(`{"auditID":"1"}`+"\n")))
+	parts := strings.SplitN(msg, " ", 2)
+	require.Len(t, parts, 2)
+	length, err := strconv.Atoi(parts[0])
+	require.NoError(t, err)
+	assert.Equal(t, len(parts[1]), length)
+	assert.True(t, strings.HasPrefix(parts[1], "<134>1 2021-07-01T10:00:00Z rancher-0 rancher "))
+	assert.True(t, strings.HasSuffix(parts[1], ` audit - {"auditID":"1"}`))
+}
+
+func TestNewSinks(t *testing.T) {
+	_, err := newSinks(SinkConfig{Sinks: "stdout,webhook"}, nil)
+	assert.Error(t, err, "webhook without URL should be rejected")
+
+	_, err = newSinks(SinkConfig{Sinks: "kafka"}, nil)
+	assert.Error(t, err)
+
+	sinks, err := newSinks(SinkConfig{Sinks: "stdout, stdout,file"}, nil)
+	assert.NoError(t, err)
+	assert.Len(t, sinks, 1)
+	closeSinks(sinks)
+}
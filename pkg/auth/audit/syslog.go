@@ -0,0 +1,118 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	syslogAppName = "rancher"
+	syslogMsgID   = "audit"
+	// facility local0 (16), severity informational (6)
+	syslogPriority = 16*8 + 6
+	syslogTimeout  = 10 * time.Second
+)
+
+// syslogSink sends entries as RFC5424 messages over TCP or TLS using octet-counting framing (RFC6587/RFC5425).
+// The connection is established lazily and re-established after a write error.
+type syslogSink struct {
+	network   string
+	address   string
+	tlsConfig *tls.Config
+	hostname  string
+	conn      net.Conn
+}
+
+func newSyslogSink(address, caCerts string) (*syslogSink, error) {
+	if address == "" {
+		return nil, fmt.Errorf("syslog address is not set")
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("syslog address must be of the form tcp://host:port or tls://host:port")
+	}
+
+	s := &syslogSink{
+		network: u.Scheme,
+		address: u.Host,
+	}
+	switch u.Scheme {
+	case "tcp":
+	case "tls":
+		s.tlsConfig, err = tlsConfig(caCerts)
+		if err != nil {
+			return nil, err
+		}
+		s.tlsConfig.ServerName = u.Hostname()
+	default:
+		return nil, fmt.Errorf("unsupported syslog scheme %q", u.Scheme)
+	}
+
+	s.hostname, _ = os.Hostname()
+	if s.hostname == "" {
+		s.hostname = "-"
+	}
+	return s, nil
+}
+
+func (s *syslogSink) connect() error {
+	dialer := &net.Dialer{Timeout: syslogTimeout}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if s.tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", s.address, s.tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", s.address)
+	}
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	return nil
+}
+
+func (s *syslogSink) Write(entry []byte) error {
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			return err
+		}
+	}
+
+	msg := s.format(time.Now(), entry)
+	s.conn.SetWriteDeadline(time.Now().Add(syslogTimeout))
+	if _, err := s.conn.Write(msg); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+// format renders an RFC5424 message with the entry as MSG, prefixed with its length as required by octet-counting.
+func (s *syslogSink) format(t time.Time, entry []byte) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<%d>1 %s %s %s %d %s - ", syslogPriority, t.UTC().Format(time.RFC3339Nano), s.hostname,
+		syslogAppName, os.Getpid(), syslogMsgID)
+	buf.Write(bytes.TrimSuffix(entry, []byte("\n")))
+	return append([]byte(strconv.Itoa(buf.Len())+" "), buf.Bytes()...)
+}
+
+func (s *syslogSink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
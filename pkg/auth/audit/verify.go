@@ -0,0 +1,75 @@
+package audit
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/docker/docker/pkg/reexec"
+	"github.com/urfave/cli"
+)
+
+const defaultLogPath = "/var/log/auditlog/rancher-api-audit.log"
+
+func RegisterVerifyCommand() {
+	reexec.Register("/usr/bin/verify-audit-log", verifyAuditLog)
+	reexec.Register("verify-audit-log", verifyAuditLog)
+}
+
+func verifyAuditLog() {
+	app := cli.NewApp()
+	app.Description = "Verify the hash chain of the Rancher API audit log and its rotated backups"
+	app.ArgsUsage = "[audit log path]"
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:   "key-file",
+			EnvVar: "AUDIT_LOG_HASH_CHAIN_KEY_FILE",
+			Usage:  "File containing the HMAC key the log was written with. Leave empty for logs chained with plain SHA-256",
+		},
+	}
+
+	app.Action = func(c *cli.Context) error {
+		path := defaultLogPath
+		if c.NArg() > 0 {
+			path = c.Args().First()
+		}
+
+		key, err := readHashChainKey(c.String("key-file"))
+		if err != nil {
+			return err
+		}
+
+		result, err := Verify(path, key)
+		if err != nil {
+			return err
+		}
+
+		for _, issue := range result.Issues {
+			fmt.Fprintln(os.Stdout, issue)
+		}
+		fmt.Fprintf(os.Stdout, "Verified %d entries (seq %d to %d) in %d files, %d issues found\n",
+			result.Entries, result.FirstSeq, result.LastSeq, len(result.Files), len(result.Issues))
+		if len(result.Issues) > 0 {
+			return fmt.Errorf("audit log verification failed")
+		}
+		return nil
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// readHashChainKey reads the HMAC key used to chain entries. An empty path returns no key.
+func readHashChainKey(path string) ([]byte, error) {
+	if path == "" {
+		return nil, nil
+	}
+	key, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading audit log hash chain key: %w", err)
+	}
+	return bytes.TrimSpace(key), nil
+}
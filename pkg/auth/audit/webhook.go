@@ -0,0 +1,165 @@
+package audit
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	defaultWebhookBatchSize     = 100
+	defaultWebhookFlushInterval = 5 * time.Second
+	webhookTimeout              = 30 * time.Second
+)
+
+// webhookSink POSTs batches of entries as a JSON array to an HTTP(S) endpoint. Batches are sent when they reach the
+// configured size or when the flush interval elapses, whichever comes first. Failed requests are retried with an
+// exponential backoff before the batch is given up on.
+type webhookSink struct {
+	url       string
+	client    *http.Client
+	batchSize int
+	backoff   wait.Backoff
+
+	mu     sync.Mutex
+	batch  [][]byte
+	sendMu sync.Mutex
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+func newWebhookSink(webhookURL, caCerts string, batchSize, flushIntervalMS int) (*webhookSink, error) {
+	if webhookURL == "" {
+		return nil, fmt.Errorf("webhook URL is not set")
+	}
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return nil, fmt.Errorf("unsupported webhook URL scheme %q", u.Scheme)
+	}
+
+	tlsConfig, err := tlsConfig(caCerts)
+	if err != nil {
+		return nil, err
+	}
+
+	if batchSize <= 0 {
+		batchSize = defaultWebhookBatchSize
+	}
+	flushInterval := defaultWebhookFlushInterval
+	if flushIntervalMS > 0 {
+		flushInterval = time.Duration(flushIntervalMS) * time.Millisecond
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	s := &webhookSink{
+		url: webhookURL,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   webhookTimeout,
+		},
+		batchSize: batchSize,
+		backoff: wait.Backoff{
+			Duration: 500 * time.Millisecond,
+			Factor:   2,
+			Jitter:   0.1,
+			Steps:    5,
+		},
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go s.flushLoop(flushInterval)
+	return s, nil
+}
+
+func (s *webhookSink) flushLoop(interval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.flush(); err != nil {
+				logrus.Errorf("[audit] failed to write entries to webhook sink: %v", err)
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *webhookSink) Write(entry []byte) error {
+	s.mu.Lock()
+	s.batch = append(s.batch, bytes.TrimSuffix(entry, []byte("\n")))
+	full := len(s.batch) >= s.batchSize
+	s.mu.Unlock()
+
+	if full {
+		return s.flush()
+	}
+	return nil
+}
+
+func (s *webhookSink) Close() error {
+	close(s.stop)
+	<-s.done
+	return s.flush()
+}
+
+func (s *webhookSink) flush() error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	s.mu.Lock()
+	batch := s.batch
+	s.batch = nil
+	s.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	body := bytes.Join([][]byte{[]byte("["), bytes.Join(batch, []byte(",")), []byte("]")}, nil)
+
+	var lastErr error
+	err := wait.ExponentialBackoff(s.backoff, func() (bool, error) {
+		lastErr = s.post(body)
+		return lastErr == nil, nil
+	})
+	if err != nil {
+		return fmt.Errorf("dropping %d entries after retries: %v", len(batch), lastErr)
+	}
+	return nil
+}
+
+func (s *webhookSink) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentTypeJSON)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,353 @@
+// Package lockout counts failed logins per user and per source IP and locks further attempts once a threshold is
+// reached. Counters are kept in config maps so all Rancher replicas share them.
+package lockout
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rancher/norman/httperror"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	corev1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/namespace"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	attemptsLabel  = "auth.cattle.io/login-attempts"
+	failuresKey    = "failures"
+	windowStartKey = "windowStart"
+	lockedUntilKey = "lockedUntil"
+	kindUser       = "user"
+	kindIP         = "ip"
+	purgeInterval  = 10 * time.Minute
+)
+
+var ErrLocked = httperror.ErrorCode{Code: "TooManyFailedLogins", Status: http.StatusTooManyRequests}
+
+type Manager struct {
+	configMaps corev1.ConfigMapInterface
+	users      v3.UserInterface
+	now        func() time.Time
+}
+
+func NewManager(core corev1.Interface, users v3.UserInterface) *Manager {
+	return &Manager{
+		configMaps: core.ConfigMaps(namespace.GlobalNamespace),
+		users:      users,
+		now:        time.Now,
+	}
+}
+
+type attempts struct {
+	failures    int
+	windowStart time.Time
+	lockedUntil time.Time
+}
+
+// Check returns ErrLocked if username or the source IP of req is locked out.
+func (m *Manager) Check(username string, req *http.Request) error {
+	now := m.now()
+	for _, key := range m.keys(username, req) {
+		a, _, err := m.get(key)
+		if err != nil {
+			return err
+		}
+		if now.Before(a.lockedUntil) {
+			return httperror.NewAPIError(ErrLocked, "too many failed login attempts, try again later")
+		}
+	}
+	return nil
+}
+
+// RecordFailure counts a failed login of username from the source IP of req. The counter of the user is kept even if
+// no such user exists, so lockouts do not reveal which usernames exist. user is nil for unknown usernames.
+func (m *Manager) RecordFailure(username string, user *v3.User, req *http.Request) {
+	for _, key := range m.keys(username, req) {
+		locked, err := m.recordFailure(key)
+		if err != nil {
+			logrus.Warnf("[lockout] failed to record failed login: %v", err)
+			continue
+		}
+		if locked && key.kind == kindUser && user != nil {
+			if err := m.setLockedCondition(user.Name, true); err != nil {
+				logrus.Warnf("[lockout] failed to set locked condition on user %s: %v", user.Name, err)
+			}
+		}
+	}
+}
+
+// RecordSuccess resets the failure counter of user and clears an expired lockout. The counter of the source IP is
+// kept, otherwise a single valid account would allow unlimited attempts against others.
+func (m *Manager) RecordSuccess(user *v3.User) {
+	if threshold(settings.AuthLocalLockoutUserThreshold) > 0 {
+		if err := m.delete(key{kind: kindUser, value: user.Username}); err != nil {
+			logrus.Warnf("[lockout] failed to reset failed logins: %v", err)
+		}
+	}
+	if v32.UserConditionLoginLocked.IsTrue(user) {
+		if err := m.setLockedCondition(user.Name, false); err != nil {
+			logrus.Warnf("[lockout] failed to clear locked condition on user %s: %v", user.Name, err)
+		}
+	}
+}
+
+// Unlock removes the lockout of user before it expires.
+func (m *Manager) Unlock(user *v3.User) error {
+	if err := m.delete(key{kind: kindUser, value: user.Username}); err != nil {
+		return err
+	}
+	return m.setLockedCondition(user.Name, false)
+}
+
+// Purge periodically deletes counters that expired.
+func (m *Manager) Purge(ctx context.Context) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.purge(); err != nil {
+				logrus.Warnf("[lockout] failed to purge failed login counters: %v", err)
+			}
+		}
+	}
+}
+
+func (m *Manager) purge() error {
+	list, err := m.configMaps.List(metav1.ListOptions{LabelSelector: attemptsLabel})
+	if err != nil {
+		return err
+	}
+	now := m.now()
+	for i := range list.Items {
+		a := parse(&list.Items[i])
+		if now.Before(a.lockedUntil) || now.Before(a.windowStart.Add(duration())) {
+			continue
+		}
+		if err := m.configMaps.Delete(list.Items[i].Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+type key struct {
+	kind  string
+	value string
+}
+
+func (k key) name() string {
+	sum := sha256.Sum256([]byte(k.value))
+	return "login-attempts-" + k.kind + "-" + hex.EncodeToString(sum[:])[:20]
+}
+
+func (m *Manager) keys(username string, req *http.Request) []key {
+	var keys []key
+	if threshold(settings.AuthLocalLockoutUserThreshold) > 0 && username != "" {
+		keys = append(keys, key{kind: kindUser, value: username})
+	}
+	if ip := sourceIP(req); threshold(settings.AuthLocalLockoutIPThreshold) > 0 && ip != "" {
+		keys = append(keys, key{kind: kindIP, value: ip})
+	}
+	return keys
+}
+
+func (m *Manager) get(k key) (attempts, *v1.ConfigMap, error) {
+	cm, err := m.configMaps.GetNamespaced(namespace.GlobalNamespace, k.name(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return attempts{}, nil, nil
+	} else if err != nil {
+		return attempts{}, nil, err
+	}
+	return parse(cm), cm, nil
+}
+
+func (m *Manager) recordFailure(k key) (bool, error) {
+	var locked bool
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		a, cm, err := m.get(k)
+		if err != nil {
+			return err
+		}
+
+		now := m.now()
+		if now.After(a.windowStart.Add(duration())) {
+			a = attempts{windowStart: now}
+		}
+		a.failures++
+
+		limit := threshold(settings.AuthLocalLockoutUserThreshold)
+		if k.kind == kindIP {
+			limit = threshold(settings.AuthLocalLockoutIPThreshold)
+		}
+		locked = a.failures >= limit && !now.Before(a.lockedUntil)
+		if locked {
+			a.lockedUntil = now.Add(duration())
+			logrus.Infof("[lockout] locking out %s %s until %s after %d failed logins", k.kind, k.value, a.lockedUntil.Format(time.RFC3339), a.failures)
+		}
+
+		if cm == nil {
+			cm = &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      k.name(),
+					Namespace: namespace.GlobalNamespace,
+					Labels:    map[string]string{attemptsLabel: k.kind},
+				},
+			}
+			cm.Data = a.data()
+			_, err = m.configMaps.Create(cm)
+			if apierrors.IsAlreadyExists(err) {
+				// another replica created it first, retry as an update
+				return apierrors.NewConflict(v1.Resource("configmaps"), cm.Name, err)
+			}
+			return err
+		}
+		cm = cm.DeepCopy()
+		cm.Data = a.data()
+		_, err = m.configMaps.Update(cm)
+		return err
+	})
+	return locked, err
+}
+
+func (m *Manager) delete(k key) error {
+	err := m.configMaps.DeleteNamespaced(namespace.GlobalNamespace, k.name(), &metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (m *Manager) setLockedCondition(userName string, locked bool) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		user, err := m.users.Get(userName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if !locked && !v32.UserConditionLoginLocked.IsTrue(user) {
+			return nil
+		}
+
+		user = user.DeepCopy()
+		if locked {
+			v32.UserConditionLoginLocked.True(user)
+			v32.UserConditionLoginLocked.Reason(user, "TooManyFailedLogins")
+			v32.UserConditionLoginLocked.Message(user, "locked until "+m.now().Add(duration()).UTC().Format(time.RFC3339))
+		} else {
+			v32.UserConditionLoginLocked.False(user)
+			v32.UserConditionLoginLocked.Reason(user, "Unlocked")
+			v32.UserConditionLoginLocked.Message(user, "")
+		}
+		_, err = m.users.Update(user)
+		return err
+	})
+}
+
+func parse(cm *v1.ConfigMap) attempts {
+	var a attempts
+	a.failures, _ = strconv.Atoi(cm.Data[failuresKey])
+	a.windowStart, _ = time.Parse(time.RFC3339, cm.Data[windowStartKey])
+	a.lockedUntil, _ = time.Parse(time.RFC3339, cm.Data[lockedUntilKey])
+	return a
+}
+
+func (a attempts) data() map[string]string {
+	data := map[string]string{
+		failuresKey:    strconv.Itoa(a.failures),
+		windowStartKey: a.windowStart.UTC().Format(time.RFC3339),
+	}
+	if !a.lockedUntil.IsZero() {
+		data[lockedUntilKey] = a.lockedUntil.UTC().Format(time.RFC3339)
+	}
+	return data
+}
+
+// sourceIP returns the address of the client. X-Forwarded-For is only used when Rancher is configured to be behind a
+// proxy. Each proxy appends the address it received the request from, so the client is the right-most entry that is
+// not one of the trusted proxies; entries left of it are set by the client and can't be trusted.
+func sourceIP(req *http.Request) string {
+	if req == nil {
+		return ""
+	}
+	if settings.AuthLocalLockoutTrustForwardedFor.Get() == "true" {
+		if forwarded := req.Header.Get("X-Forwarded-For"); forwarded != "" {
+			trusted := trustedProxies()
+			parts := strings.Split(forwarded, ",")
+			for i := len(parts) - 1; i >= 0; i-- {
+				ip := strings.TrimSpace(parts[i])
+				if i == 0 || !isTrustedProxy(trusted, ip) {
+					return ip
+				}
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+func trustedProxies() []*net.IPNet {
+	var result []*net.IPNet
+	for _, value := range strings.Split(settings.AuthLocalLockoutTrustedProxies.Get(), ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if !strings.Contains(value, "/") {
+			if strings.Contains(value, ":") {
+				value += "/128"
+			} else {
+				value += "/32"
+			}
+		}
+		if _, cidr, err := net.ParseCIDR(value); err == nil {
+			result = append(result, cidr)
+		}
+	}
+	return result
+}
+
+func isTrustedProxy(trusted []*net.IPNet, value string) bool {
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return false
+	}
+	for _, cidr := range trusted {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func threshold(setting settings.Setting) int {
+	if v := setting.GetInt(); v > 0 {
+		return v
+	}
+	return 0
+}
+
+func duration() time.Duration {
+	minutes := settings.AuthLocalLockoutDurationMinutes.GetInt()
+	if minutes <= 0 {
+		minutes = 15
+	}
+	return time.Duration(minutes) * time.Minute
+}
@@ -0,0 +1,98 @@
+package lockout
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rancher/rancher/pkg/generated/norman/core/v1/fakes"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestManager(now *time.Time) *Manager {
+	configMaps := map[string]*v1.ConfigMap{}
+	return &Manager{
+		configMaps: &fakes.ConfigMapInterfaceMock{
+			GetNamespacedFunc: func(namespace string, name string, opts metav1.GetOptions) (*v1.ConfigMap, error) {
+				if cm, ok := configMaps[name]; ok {
+					return cm, nil
+				}
+				return nil, apierrors.NewNotFound(v1.Resource("configmaps"), name)
+			},
+			CreateFunc: func(cm *v1.ConfigMap) (*v1.ConfigMap, error) {
+				configMaps[cm.Name] = cm
+				return cm, nil
+			},
+			UpdateFunc: func(cm *v1.ConfigMap) (*v1.ConfigMap, error) {
+				configMaps[cm.Name] = cm
+				return cm, nil
+			},
+			DeleteNamespacedFunc: func(namespace string, name string, options *metav1.DeleteOptions) error {
+				delete(configMaps, name)
+				return nil
+			},
+		},
+		now: func() time.Time { return *now },
+	}
+}
+
+func TestLockout(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	m := newTestManager(&now)
+
+	req := httptest.NewRequest("POST", "/v3-public/localProviders/local?action=login", nil)
+	req.RemoteAddr = "10.0.0.1:51234"
+	other := httptest.NewRequest("POST", "/v3-public/localProviders/local?action=login", nil)
+	other.RemoteAddr = "10.0.0.2:51234"
+
+	for i := 0; i < 9; i++ {
+		m.RecordFailure("admin", nil, req)
+	}
+	assert.NoError(t, m.Check("admin", req))
+
+	m.RecordFailure("admin", nil, req)
+	assert.Error(t, m.Check("admin", req))
+	assert.Error(t, m.Check("admin", other), "user is locked from any address")
+	assert.NoError(t, m.Check("someone", req), "address is below its threshold")
+
+	now = now.Add(16 * time.Minute)
+	assert.NoError(t, m.Check("admin", req))
+}
+
+func TestSourceIP(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.1:51234"
+	assert.Equal(t, "10.0.0.1", sourceIP(req))
+
+	req.Header.Set("X-Forwarded-For", "1.2.3.4, 192.168.0.7")
+	assert.Equal(t, "10.0.0.1", sourceIP(req), "X-Forwarded-For is ignored unless trusted")
+
+	require.NoError(t, settings.AuthLocalLockoutTrustForwardedFor.Set("true"))
+	defer settings.AuthLocalLockoutTrustForwardedFor.Set("false")
+	assert.Equal(t, "192.168.0.7", sourceIP(req), "only the entry appended by the proxy is trusted")
+
+	require.NoError(t, settings.AuthLocalLockoutTrustedProxies.Set("192.168.0.0/24, 172.16.0.1"))
+	defer settings.AuthLocalLockoutTrustedProxies.Set("")
+	req.Header.Set("X-Forwarded-For", "6.6.6.6, 1.2.3.4, 172.16.0.1, 192.168.0.7")
+	assert.Equal(t, "1.2.3.4", sourceIP(req), "the right-most entry that is not a trusted proxy is the client")
+
+	req.Header.Set("X-Forwarded-For", "172.16.0.1, 192.168.0.7")
+	assert.Equal(t, "172.16.0.1", sourceIP(req))
+
+	assert.Equal(t, "", sourceIP(nil))
+}
+
+func TestAttemptsData(t *testing.T) {
+	a := attempts{
+		failures:    3,
+		windowStart: time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC),
+		lockedUntil: time.Date(2021, 6, 1, 12, 15, 0, 0, time.UTC),
+	}
+	assert.Equal(t, a, parse(&v1.ConfigMap{Data: a.data()}))
+	assert.NotContains(t, attempts{failures: 1}.data(), lockedUntilKey)
+}
@@ -0,0 +1,71 @@
+package passwordpolicy
+
+// commonPasswords are the most frequent passwords found in breaches, compared case-insensitively. Deployments can deny
+// more with the password-deny-list setting.
+var commonPasswords = map[string]struct{}{
+	"000000":                    {},
+	"111111":                    {},
+	"11111111":                  {},
+	"112233":                    {},
+	"121212":                    {},
+	"123123":                    {},
+	"123123123":                 {},
+	"1234":                      {},
+	"12345":                     {},
+	"123456":                    {},
+	"1234567":                   {},
+	"12345678":                  {},
+	"123456789":                 {},
+	"1234567890":                {},
+	"123456789012":              {},
+	"123qwe":                    {},
+	"1q2w3e4r":                  {},
+	"1q2w3e4r5t":                {},
+	"1qaz2wsx":                  {},
+	"654321":                    {},
+	"666666":                    {},
+	"696969":                    {},
+	"7777777":                   {},
+	"888888":                    {},
+	"987654321":                 {},
+	"aa123456":                  {},
+	"abc123":                    {},
+	"abcd1234":                  {},
+	"admin":                     {},
+	"admin123":                  {},
+	"adminadmin":                {},
+	"administrator":             {},
+	"asdfghjkl":                 {},
+	"baseball":                  {},
+	"changeme":                  {},
+	"charlie":                   {},
+	"dragon":                    {},
+	"football":                  {},
+	"iloveyou":                  {},
+	"letmein":                   {},
+	"master":                    {},
+	"monkey":                    {},
+	"password":                  {},
+	"password1":                 {},
+	"password123":               {},
+	"password1234":              {},
+	"passw0rd":                  {},
+	"princess":                  {},
+	"qwerty":                    {},
+	"qwerty123":                 {},
+	"qwertyuiop":                {},
+	"qwertyuiop123":             {},
+	"rancher":                   {},
+	"rancher123":                {},
+	"rancheradmin":              {},
+	"shadow":                    {},
+	"sunshine":                  {},
+	"superman":                  {},
+	"trustno1":                  {},
+	"welcome":                   {},
+	"welcome1":                  {},
+	"welcome123":                {},
+	"zaq12wsx":                  {},
+	"zxcvbnm":                   {},
+	"correcthorsebatterystaple": {},
+}
@@ -0,0 +1,211 @@
+// Package passwordpolicy enforces the password settings on local users: length, character classes, common passwords,
+// reuse of previous passwords and maximum age.
+package passwordpolicy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/rancher/pkg/auth/providers/common"
+	corev1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ChangedAtAnnotation records when the password of a user was last changed, users without it use their creation time.
+	ChangedAtAnnotation = "auth.cattle.io/password-changed-at"
+
+	historySecretPrefix = "password-history-"
+	historyKey          = "hashes"
+)
+
+// Validate checks password against the policy and returns an error listing every rule it violates.
+func Validate(username, password string) error {
+	var violations []string
+
+	if minLength := settings.PasswordMinLength.GetInt(); len([]rune(password)) < minLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters long", minLength))
+	}
+	if minClasses := settings.PasswordMinCharacterClasses.GetInt(); characterClasses(password) < minClasses {
+		violations = append(violations, fmt.Sprintf("must contain at least %d of lowercase letters, uppercase letters, digits and symbols", minClasses))
+	}
+	if username != "" && strings.EqualFold(password, username) {
+		violations = append(violations, "must not be the username")
+	}
+	if denied(password) {
+		violations = append(violations, "must not be a commonly used password")
+	}
+
+	if len(violations) > 0 {
+		return httperror.NewAPIError(httperror.InvalidBodyContent, "password "+strings.Join(violations, ", "))
+	}
+	return nil
+}
+
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	return lower + upper + digit + symbol
+}
+
+func denied(password string) bool {
+	password = strings.ToLower(password)
+	if _, ok := commonPasswords[password]; ok {
+		return true
+	}
+	for _, entry := range strings.Split(settings.PasswordDenyList.Get(), ",") {
+		if entry = strings.TrimSpace(entry); entry != "" && strings.ToLower(entry) == password {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired reports whether the password of user is older than the password-max-age-days setting.
+func Expired(user *v3.User, now time.Time) bool {
+	maxAge := settings.PasswordMaxAgeDays.GetInt()
+	if maxAge <= 0 || user.Password == "" {
+		return false
+	}
+	changedAt := user.CreationTimestamp.Time
+	if value, ok := user.Annotations[ChangedAtAnnotation]; ok {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			changedAt = t
+		}
+	}
+	return now.After(changedAt.Add(time.Duration(maxAge) * 24 * time.Hour))
+}
+
+// Manager sets passwords of local users. The hashes of previous passwords are kept in a secret per user so they can't
+// be read through the user resource.
+type Manager struct {
+	secrets corev1.SecretInterface
+}
+
+func NewManager(secrets corev1.SecretInterface) *Manager {
+	return &Manager{
+		secrets: secrets,
+	}
+}
+
+// SetPassword checks password against the policy and the previous passwords of user, changes a copy of user to use
+// it and saves it with update. The previous password is only added to the history once update succeeded.
+func (m *Manager) SetPassword(user *v3.User, password string, update func(*v3.User) (*v3.User, error)) (*v3.User, error) {
+	if err := Validate(user.Username, password); err != nil {
+		return nil, err
+	}
+
+	var (
+		secret *v1.Secret
+		hashes []string
+	)
+	historyCount := settings.PasswordHistoryCount.GetInt()
+	if historyCount > 0 {
+		var err error
+		secret, hashes, err = m.history(user)
+		if err != nil {
+			return nil, err
+		}
+		if user.Password != "" {
+			hashes = append([]string{user.Password}, hashes...)
+		}
+		if len(hashes) > historyCount {
+			hashes = hashes[:historyCount]
+		}
+		for _, hash := range hashes {
+			if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+				return nil, httperror.NewAPIError(httperror.InvalidBodyContent, fmt.Sprintf("password must not be one of the last %d passwords", historyCount))
+			}
+		}
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, errors.Wrap(err, "problem encrypting password")
+	}
+	user = user.DeepCopy()
+	user.Password = string(hash)
+	user.MustChangePassword = false
+	if user.Annotations == nil {
+		user.Annotations = map[string]string{}
+	}
+	user.Annotations[ChangedAtAnnotation] = time.Now().UTC().Format(time.RFC3339)
+
+	user, err = update(user)
+	if err != nil {
+		return nil, err
+	}
+
+	if historyCount > 0 {
+		// the password was changed, failing the request would only make the client retry with the same password
+		if err := m.saveHistory(user, secret, hashes); err != nil {
+			logrus.Errorf("failed to record the previous password of user %s: %v", user.Name, err)
+		}
+	}
+	return user, nil
+}
+
+func (m *Manager) history(user *v3.User) (*v1.Secret, []string, error) {
+	secret, err := m.secrets.GetNamespaced(common.SecretsNamespace, historySecretPrefix+user.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+	var hashes []string
+	for _, hash := range strings.Split(string(secret.Data[historyKey]), "\n") {
+		if hash != "" {
+			hashes = append(hashes, hash)
+		}
+	}
+	return secret, hashes, nil
+}
+
+func (m *Manager) saveHistory(user *v3.User, secret *v1.Secret, hashes []string) error {
+	data := map[string][]byte{
+		historyKey: []byte(strings.Join(hashes, "\n")),
+	}
+	if secret != nil {
+		secret = secret.DeepCopy()
+		secret.Data = data
+		_, err := m.secrets.Update(secret)
+		return err
+	}
+	_, err := m.secrets.Create(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      historySecretPrefix + user.Name,
+			Namespace: common.SecretsNamespace,
+			// removed with the user
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "management.cattle.io/v3",
+				Kind:       "User",
+				Name:       user.Name,
+				UID:        user.UID,
+			}},
+		},
+		Type: v1.SecretTypeOpaque,
+		Data: data,
+	})
+	return err
+}
@@ -0,0 +1,119 @@
+package passwordpolicy
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rancher/rancher/pkg/generated/norman/core/v1/fakes"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func setSetting(t *testing.T, setting settings.Setting, value string) {
+	old := setting.Get()
+	require.NoError(t, setting.Set(value))
+	t.Cleanup(func() { setting.Set(old) })
+}
+
+func TestValidate(t *testing.T) {
+	setSetting(t, settings.PasswordMinLength, "12")
+	setSetting(t, settings.PasswordMinCharacterClasses, "3")
+	setSetting(t, settings.PasswordDenyList, "Summer2021Summer, acme-corp-pass")
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		valid    bool
+	}{
+		{name: "valid", username: "admin", password: "correct-Horse-battery", valid: true},
+		{name: "too short", username: "admin", password: "sh0rt-Pass"},
+		{name: "too few classes", username: "admin", password: "onlylowercaseletters"},
+		{name: "username", username: "Administrator1!", password: "administrator1!"},
+		{name: "common password", username: "admin", password: "CorrectHorseBatteryStaple"},
+		{name: "deny list", username: "admin", password: "summer2021summer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.username, tt.password)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestExpired(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	user := &v3.User{
+		ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(now.Add(-100 * 24 * time.Hour))},
+		Password:   "hash",
+	}
+	assert.False(t, Expired(user, now), "no maximum age by default")
+
+	setSetting(t, settings.PasswordMaxAgeDays, "90")
+	assert.True(t, Expired(user, now))
+
+	user.Annotations = map[string]string{ChangedAtAnnotation: now.Add(-10 * 24 * time.Hour).Format(time.RFC3339)}
+	assert.False(t, Expired(user, now))
+}
+
+func TestSetPasswordHistory(t *testing.T) {
+	setSetting(t, settings.PasswordHistoryCount, "2")
+
+	secrets := map[string]*v1.Secret{}
+	m := NewManager(&fakes.SecretInterfaceMock{
+		GetNamespacedFunc: func(namespace string, name string, opts metav1.GetOptions) (*v1.Secret, error) {
+			if secret, ok := secrets[name]; ok {
+				return secret, nil
+			}
+			return nil, apierrors.NewNotFound(v1.Resource("secrets"), name)
+		},
+		CreateFunc: func(secret *v1.Secret) (*v1.Secret, error) {
+			secrets[secret.Name] = secret
+			return secret, nil
+		},
+		UpdateFunc: func(secret *v1.Secret) (*v1.Secret, error) {
+			secrets[secret.Name] = secret
+			return secret, nil
+		},
+	})
+
+	update := func(user *v3.User) (*v3.User, error) {
+		return user, nil
+	}
+	failedUpdate := func(user *v3.User) (*v3.User, error) {
+		return nil, apierrors.NewConflict(v3.Resource("users"), user.Name, nil)
+	}
+
+	user := &v3.User{ObjectMeta: metav1.ObjectMeta{Name: "u-abc"}, Username: "jane", MustChangePassword: true}
+	user, err := m.SetPassword(user, "first-password-1", update)
+	require.NoError(t, err)
+	assert.False(t, user.MustChangePassword)
+	assert.Contains(t, user.Annotations, ChangedAtAnnotation)
+
+	user, err = m.SetPassword(user, "second-password-2", update)
+	require.NoError(t, err)
+	_, err = m.SetPassword(user, "second-password-2", update)
+	assert.Error(t, err, "current password")
+	_, err = m.SetPassword(user, "first-password-1", update)
+	assert.Error(t, err, "previous password")
+
+	_, err = m.SetPassword(user, "not-saved-password-3", failedUpdate)
+	assert.Error(t, err)
+	history := string(secrets["password-history-u-abc"].Data[historyKey])
+	assert.Len(t, strings.Split(history, "\n"), 1, "history is only saved when the user was updated")
+
+	user, err = m.SetPassword(user, "third-password-3", update)
+	require.NoError(t, err)
+	_, err = m.SetPassword(user, "first-password-1", update)
+	assert.NoError(t, err, "only the last two passwords are kept")
+}
@@ -0,0 +1,21 @@
+package gitlab
+
+// Account defines properties a user on gitlab has
+type Account struct {
+	ID        int    `json:"id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Name      string `json:"name,omitempty"`
+	AvatarURL string `json:"avatar_url,omitempty"`
+	WebURL    string `json:"web_url,omitempty"`
+	State     string `json:"state,omitempty"`
+}
+
+// Group defines properties a group on gitlab has, subgroups included
+type Group struct {
+	ID        int    `json:"id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	FullName  string `json:"full_name,omitempty"`
+	FullPath  string `json:"full_path,omitempty"`
+	AvatarURL string `json:"avatar_url,omitempty"`
+	WebURL    string `json:"web_url,omitempty"`
+}
@@ -0,0 +1,210 @@
+package gitlab
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/sirupsen/logrus"
+	"github.com/tomnomnom/linkheader"
+	"golang.org/x/oauth2"
+)
+
+const (
+	gitlabAPI             = "/api/v4"
+	gitlabDefaultHostName = "https://gitlab.com"
+	// read_api is needed to list the groups of a user, read_user alone only covers the user itself
+	gitlabScope = "read_api"
+	// guest is the lowest access level that makes a user a member of a group
+	guestAccessLevel = "10"
+	searchPageSize   = "50"
+)
+
+// GClient implements a httpclient for gitlab
+type GClient struct {
+	httpClient *http.Client
+}
+
+func (g *GClient) oauthConfig(config *v32.GitlabConfig) *oauth2.Config {
+	hostName := hostURL(config)
+	return &oauth2.Config{
+		ClientID:     config.ClientID,
+		ClientSecret: config.ClientSecret,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   hostName + "/oauth/authorize",
+			TokenURL:  hostName + "/oauth/token",
+			AuthStyle: oauth2.AuthStyleInParams,
+		},
+		// gitlab requires the redirect uri of the authorize request to be repeated when exchanging the code
+		RedirectURL: config.RancherURL,
+		Scopes:      []string{gitlabScope},
+	}
+}
+
+func (g *GClient) context() context.Context {
+	return context.WithValue(context.Background(), oauth2.HTTPClient, g.httpClient)
+}
+
+func (g *GClient) getAccessToken(code string, config *v32.GitlabConfig) (*oauth2.Token, error) {
+	token, err := g.oauthConfig(config).Exchange(g.context(), code)
+	if err != nil {
+		logrus.Errorf("Gitlab getAccessToken: received error exchanging code, err: %v", err)
+		return nil, err
+	}
+	return token, nil
+}
+
+func (g *GClient) getUser(token *oauth2.Token, config *v32.GitlabConfig) (Account, error) {
+	var acct Account
+	endpoint := apiURL(config) + "/user"
+	if err := g.getInto(token, endpoint, config, &acct); err != nil {
+		logrus.Errorf("Gitlab getUser: GET url %v received error from gitlab, err: %v", endpoint, err)
+		return Account{}, err
+	}
+	return acct, nil
+}
+
+func (g *GClient) getUserByID(id string, token *oauth2.Token, config *v32.GitlabConfig) (Account, error) {
+	var acct Account
+	endpoint := apiURL(config) + "/users/" + url.PathEscape(id)
+	if err := g.getInto(token, endpoint, config, &acct); err != nil {
+		logrus.Errorf("Gitlab getUserByID: GET url %v received error from gitlab, err: %v", endpoint, err)
+		return Account{}, err
+	}
+	return acct, nil
+}
+
+func (g *GClient) getGroupByID(id string, token *oauth2.Token, config *v32.GitlabConfig) (Group, error) {
+	var group Group
+	endpoint := apiURL(config) + "/groups/" + url.PathEscape(id) + "?with_projects=false"
+	if err := g.getInto(token, endpoint, config, &group); err != nil {
+		logrus.Errorf("Gitlab getGroupByID: GET url %v received error from gitlab, err: %v", endpoint, err)
+		return Group{}, err
+	}
+	return group, nil
+}
+
+// getGroups returns all groups the user is a member of, including subgroups whose membership is inherited.
+func (g *GClient) getGroups(token *oauth2.Token, config *v32.GitlabConfig) ([]Group, error) {
+	var groups []Group
+
+	query := url.Values{}
+	query.Set("min_access_level", guestAccessLevel)
+	query.Set("per_page", "100")
+	nextURL := apiURL(config) + "/groups?" + query.Encode()
+	for nextURL != "" {
+		b, next, err := g.getFromGitlab(token, nextURL, config)
+		if err != nil {
+			logrus.Errorf("Gitlab getGroups: GET url %v received error from gitlab, err: %v", nextURL, err)
+			return nil, err
+		}
+		var page []Group
+		if err := json.Unmarshal(b, &page); err != nil {
+			logrus.Errorf("Gitlab getGroups: received error unmarshalling group array, err: %v", err)
+			return nil, err
+		}
+		groups = append(groups, page...)
+		nextURL = next
+	}
+
+	return groups, nil
+}
+
+func (g *GClient) searchUsers(searchTerm string, token *oauth2.Token, config *v32.GitlabConfig) ([]Account, error) {
+	var accts []Account
+	query := url.Values{}
+	query.Set("search", searchTerm)
+	query.Set("per_page", searchPageSize)
+	if err := g.getInto(token, apiURL(config)+"/users?"+query.Encode(), config, &accts); err != nil {
+		return nil, err
+	}
+	return accts, nil
+}
+
+func (g *GClient) searchGroups(searchTerm string, token *oauth2.Token, config *v32.GitlabConfig) ([]Group, error) {
+	var groups []Group
+	query := url.Values{}
+	query.Set("search", searchTerm)
+	query.Set("all_available", "true")
+	query.Set("per_page", searchPageSize)
+	if err := g.getInto(token, apiURL(config)+"/groups?"+query.Encode(), config, &groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func (g *GClient) getInto(token *oauth2.Token, url string, config *v32.GitlabConfig, obj interface{}) error {
+	b, _, err := g.getFromGitlab(token, url, config)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, obj)
+}
+
+// getFromGitlab returns the body of the response and the url of the next page, if any. Expired access tokens are
+// refreshed as long as token carries a refresh token, and token is updated in place so the caller can save it.
+func (g *GClient) getFromGitlab(token *oauth2.Token, url string, config *v32.GitlabConfig) ([]byte, string, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Add("Accept", "application/json")
+
+	current, err := g.oauthConfig(config).TokenSource(g.context(), token).Token()
+	if err != nil {
+		logrus.Errorf("Gitlab getFromGitlab: received error refreshing access token, err: %v", err)
+		return nil, "", err
+	}
+	*token = *current
+
+	resp, err := oauth2.NewClient(g.context(), oauth2.StaticTokenSource(current)).Do(req)
+	if err != nil {
+		logrus.Errorf("Received error from gitlab: %v", err)
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var body bytes.Buffer
+		io.Copy(&body, resp.Body)
+		return nil, "", fmt.Errorf("request failed, got status code: %d. Response: %s",
+			resp.StatusCode, body.Bytes())
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	return b, nextPage(resp), err
+}
+
+func nextPage(response *http.Response) string {
+	header := response.Header.Get("link")
+	if header == "" {
+		return ""
+	}
+	for _, link := range linkheader.Parse(header) {
+		if link.Rel == "next" {
+			return link.URL
+		}
+	}
+	return ""
+}
+
+func hostURL(config *v32.GitlabConfig) string {
+	if config.Hostname == "" {
+		return gitlabDefaultHostName
+	}
+	scheme := "http://"
+	if config.TLS {
+		scheme = "https://"
+	}
+	return scheme + config.Hostname
+}
+
+func apiURL(config *v32.GitlabConfig) string {
+	return hostURL(config) + gitlabAPI
+}
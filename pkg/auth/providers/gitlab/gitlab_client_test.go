@@ -0,0 +1,97 @@
+package gitlab
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestGetGroups(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "Bearer secret", req.Header.Get("Authorization"))
+		assert.Equal(t, "/api/v4/groups", req.URL.Path)
+		assert.Equal(t, guestAccessLevel, req.URL.Query().Get("min_access_level"))
+		if req.URL.Query().Get("page") == "" {
+			rw.Header().Set("Link", fmt.Sprintf(`<%s/api/v4/groups?min_access_level=10&page=2>; rel="next"`, server.URL))
+			fmt.Fprint(rw, `[{"id": 1, "name": "platform", "full_name": "Platform", "full_path": "platform"}]`)
+			return
+		}
+		fmt.Fprint(rw, `[{"id": 2, "name": "sre", "full_name": "Platform / SRE", "full_path": "platform/sre"}]`)
+	}))
+	defer server.Close()
+
+	g := &GClient{httpClient: server.Client()}
+	config := &v32.GitlabConfig{Hostname: strings.TrimPrefix(server.URL, "http://")}
+	groups, err := g.getGroups(&oauth2.Token{AccessToken: "secret"}, config)
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	assert.Equal(t, "platform", groups[0].FullPath)
+	assert.Equal(t, "platform/sre", groups[1].FullPath)
+}
+
+func TestGetFromGitlabRefreshesToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/oauth/token":
+			require.NoError(t, req.ParseForm())
+			assert.Equal(t, "old-refresh", req.PostForm.Get("refresh_token"))
+			rw.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(rw, `{"access_token": "new", "refresh_token": "new-refresh", "token_type": "bearer", "expires_in": 7200}`)
+		case "/api/v4/user":
+			assert.Equal(t, "Bearer new", req.Header.Get("Authorization"))
+			fmt.Fprint(rw, `{"id": 1, "username": "jane"}`)
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	g := &GClient{httpClient: server.Client()}
+	config := &v32.GitlabConfig{Hostname: strings.TrimPrefix(server.URL, "http://")}
+	token := &oauth2.Token{AccessToken: "old", RefreshToken: "old-refresh", Expiry: time.Now().Add(-time.Minute)}
+	_, err := g.getUser(token, config)
+	require.NoError(t, err)
+	assert.Equal(t, "new", token.AccessToken)
+	assert.Equal(t, "new-refresh", token.RefreshToken, "the rotated refresh token has to be saved")
+}
+
+func TestGitlabRedirectURL(t *testing.T) {
+	assert.Equal(t,
+		"https://gitlab.com/oauth/authorize?client_id=abc&redirect_uri=https%3A%2F%2Francher.example.com%2Fverify-auth&response_type=code&scope=read_api",
+		formGitlabRedirectURLFromMap(map[string]interface{}{
+			"clientId":   "abc",
+			"rancherUrl": "https://rancher.example.com/verify-auth",
+		}))
+	assert.Equal(t,
+		"https://git.example.com/oauth/authorize?client_id=abc&redirect_uri=&response_type=code&scope=read_api",
+		formGitlabRedirectURL(&v32.GitlabConfig{Hostname: "git.example.com", TLS: true, ClientID: "abc"}))
+}
+
+func TestParsePrincipalID(t *testing.T) {
+	principalType, id, err := parsePrincipalID("gitlab_group://42")
+	require.NoError(t, err)
+	assert.Equal(t, groupType, principalType)
+	assert.Equal(t, "42", id)
+
+	for _, principalID := range []string{"gitlab_user://", "github_user://42", "gitlab://42", "local://u-abc"} {
+		_, _, err := parsePrincipalID(principalID)
+		assert.Error(t, err, principalID)
+	}
+}
+
+func TestParseToken(t *testing.T) {
+	assert.Equal(t, "plain", parseToken("plain").AccessToken)
+
+	token := parseToken(`{"access_token": "abc", "refresh_token": "def"}`)
+	assert.Equal(t, "abc", token.AccessToken)
+	assert.Equal(t, "def", token.RefreshToken)
+}
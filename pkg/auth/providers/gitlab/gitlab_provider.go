@@ -0,0 +1,425 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/types"
+	"github.com/rancher/norman/types/convert"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/auth/providers/common"
+	"github.com/rancher/rancher/pkg/auth/tokens"
+	client "github.com/rancher/rancher/pkg/client/generated/management/v3"
+	publicclient "github.com/rancher/rancher/pkg/client/generated/management/v3public"
+	corev1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/types/config"
+	"github.com/rancher/rancher/pkg/user"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	Name = "gitlab"
+)
+
+const (
+	userType  = "user"
+	groupType = "group"
+)
+
+type glProvider struct {
+	ctx          context.Context
+	authConfigs  v3.AuthConfigInterface
+	secrets      corev1.SecretInterface
+	gitlabClient *GClient
+	userMGR      user.Manager
+	tokenMGR     *tokens.Manager
+}
+
+func Configure(ctx context.Context, mgmtCtx *config.ScaledContext, userMGR user.Manager, tokenMGR *tokens.Manager) common.AuthProvider {
+	gitlabClient := &GClient{
+		httpClient: &http.Client{},
+	}
+
+	return &glProvider{
+		ctx:          ctx,
+		authConfigs:  mgmtCtx.Management.AuthConfigs(""),
+		secrets:      mgmtCtx.Core.Secrets(""),
+		gitlabClient: gitlabClient,
+		userMGR:      userMGR,
+		tokenMGR:     tokenMGR,
+	}
+}
+
+func (g *glProvider) GetName() string {
+	return Name
+}
+
+func (g *glProvider) CustomizeSchema(schema *types.Schema) {
+	schema.ActionHandler = g.actionHandler
+	schema.Formatter = g.formatter
+}
+
+func (g *glProvider) TransformToAuthProvider(authConfig map[string]interface{}) (map[string]interface{}, error) {
+	p := common.TransformToAuthProvider(authConfig)
+	p[publicclient.GitlabProviderFieldRedirectURL] = formGitlabRedirectURLFromMap(authConfig)
+	return p, nil
+}
+
+func (g *glProvider) getGitlabConfigCR() (*v32.GitlabConfig, error) {
+	authConfigObj, err := g.authConfigs.ObjectClient().UnstructuredClient().Get(Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve GitlabConfig, error: %v", err)
+	}
+	u, ok := authConfigObj.(runtime.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("failed to retrieve GitlabConfig, cannot read k8s Unstructured data")
+	}
+	storedGitlabConfigMap := u.UnstructuredContent()
+
+	storedGitlabConfig := &v32.GitlabConfig{}
+	mapstructure.Decode(storedGitlabConfigMap, storedGitlabConfig)
+
+	metadataMap, ok := storedGitlabConfigMap["metadata"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("failed to retrieve GitlabConfig metadata, cannot read k8s Unstructured data")
+	}
+
+	typemeta := &metav1.ObjectMeta{}
+	mapstructure.Decode(metadataMap, typemeta)
+	storedGitlabConfig.ObjectMeta = *typemeta
+
+	if storedGitlabConfig.ClientSecret != "" {
+		value, err := common.ReadFromSecret(g.secrets, storedGitlabConfig.ClientSecret,
+			strings.ToLower(client.GitlabConfigFieldClientSecret))
+		if err != nil {
+			return nil, err
+		}
+		storedGitlabConfig.ClientSecret = value
+	}
+
+	return storedGitlabConfig, nil
+}
+
+func (g *glProvider) saveGitlabConfig(config *v32.GitlabConfig) error {
+	storedGitlabConfig, err := g.getGitlabConfigCR()
+	if err != nil {
+		return err
+	}
+	config.APIVersion = "management.cattle.io/v3"
+	config.Kind = v3.AuthConfigGroupVersionKind.Kind
+	config.Type = client.GitlabConfigType
+	config.ObjectMeta = storedGitlabConfig.ObjectMeta
+
+	secretInfo := convert.ToString(config.ClientSecret)
+	field := strings.ToLower(client.GitlabConfigFieldClientSecret)
+	if err := common.CreateOrUpdateSecrets(g.secrets, secretInfo, field, strings.ToLower(config.Type)); err != nil {
+		return err
+	}
+
+	config.ClientSecret = common.GetName(config.Type, field)
+
+	_, err = g.authConfigs.ObjectClient().Update(config.ObjectMeta.Name, config)
+	return err
+}
+
+func (g *glProvider) AuthenticateUser(ctx context.Context, input interface{}) (v3.Principal, []v3.Principal, string, error) {
+	login, ok := input.(*v32.GitlabLogin)
+	if !ok {
+		return v3.Principal{}, nil, "", errors.New("unexpected input type")
+	}
+	return g.LoginUser(login, nil, false)
+}
+
+func (g *glProvider) LoginUser(gitlabCredential *v32.GitlabLogin, config *v32.GitlabConfig, test bool) (v3.Principal, []v3.Principal, string, error) {
+	var err error
+	if config == nil {
+		config, err = g.getGitlabConfigCR()
+		if err != nil {
+			return v3.Principal{}, nil, "", err
+		}
+	}
+
+	token, err := g.gitlabClient.getAccessToken(gitlabCredential.Code, config)
+	if err != nil {
+		logrus.Infof("Error generating accessToken from gitlab %v", err)
+		return v3.Principal{}, nil, "", err
+	}
+
+	acct, err := g.gitlabClient.getUser(token, config)
+	if err != nil {
+		return v3.Principal{}, nil, "", err
+	}
+	userPrincipal := g.userToPrincipal(acct, nil)
+	userPrincipal.Me = true
+
+	groupPrincipals, err := g.groupPrincipals(token, config)
+	if err != nil {
+		return v3.Principal{}, nil, "", err
+	}
+
+	testAllowedPrincipals := config.AllowedPrincipalIDs
+	if test && config.AccessMode == "restricted" {
+		testAllowedPrincipals = append(testAllowedPrincipals, userPrincipal.Name)
+	}
+
+	allowed, err := g.userMGR.CheckAccess(config.AccessMode, testAllowedPrincipals, userPrincipal.Name, groupPrincipals)
+	if err != nil {
+		return v3.Principal{}, nil, "", err
+	}
+	if !allowed {
+		return v3.Principal{}, nil, "", httperror.NewAPIError(httperror.Unauthorized, "unauthorized")
+	}
+
+	// gitlab access tokens expire after two hours, so the whole token including the refresh token is kept
+	providerInfo, err := json.Marshal(token)
+	if err != nil {
+		return v3.Principal{}, nil, "", err
+	}
+	return userPrincipal, groupPrincipals, string(providerInfo), nil
+}
+
+func (g *glProvider) RefetchGroupPrincipals(principalID string, secret string) ([]v3.Principal, error) {
+	config, err := g.getGitlabConfigCR()
+	if err != nil {
+		return nil, err
+	}
+
+	token := parseToken(secret)
+	previous := *token
+	groupPrincipals, err := g.groupPrincipals(token, config)
+	if refreshed(&previous, token) {
+		user, userErr := g.userMGR.GetUserByPrincipalID(principalID)
+		if userErr != nil {
+			logrus.Errorf("Gitlab RefetchGroupPrincipals: failed to find user of %v to save refreshed access token, err: %v", principalID, userErr)
+		} else if user != nil {
+			g.saveAccessToken(user.Name, token)
+		}
+	}
+	return groupPrincipals, err
+}
+
+func (g *glProvider) groupPrincipals(token *oauth2.Token, config *v32.GitlabConfig) ([]v3.Principal, error) {
+	groups, err := g.gitlabClient.getGroups(token, config)
+	if err != nil {
+		return nil, err
+	}
+	var groupPrincipals []v3.Principal
+	for _, group := range groups {
+		groupPrincipal := g.groupToPrincipal(group, nil)
+		groupPrincipal.MemberOf = true
+		groupPrincipals = append(groupPrincipals, groupPrincipal)
+	}
+	return groupPrincipals, nil
+}
+
+func (g *glProvider) SearchPrincipals(searchKey, principalType string, token v3.Token) ([]v3.Principal, error) {
+	var principals []v3.Principal
+
+	config, err := g.getGitlabConfigCR()
+	if err != nil {
+		return principals, err
+	}
+
+	accessToken, err := g.accessToken(token)
+	if err != nil {
+		return nil, err
+	}
+	previous := *accessToken
+	defer func() {
+		if refreshed(&previous, accessToken) {
+			g.saveAccessToken(token.UserID, accessToken)
+		}
+	}()
+
+	if principalType == "" || principalType == userType {
+		accts, err := g.gitlabClient.searchUsers(searchKey, accessToken, config)
+		if err != nil {
+			logrus.Errorf("problem searching gitlab users: %v", err)
+		}
+		for _, acct := range accts {
+			principals = append(principals, g.userToPrincipal(acct, &token))
+		}
+	}
+
+	if principalType == "" || principalType == groupType {
+		groups, err := g.gitlabClient.searchGroups(searchKey, accessToken, config)
+		if err != nil {
+			logrus.Errorf("problem searching gitlab groups: %v", err)
+		}
+		for _, group := range groups {
+			principals = append(principals, g.groupToPrincipal(group, &token))
+		}
+	}
+
+	return principals, nil
+}
+
+func (g *glProvider) GetPrincipal(principalID string, token v3.Token) (v3.Principal, error) {
+	config, err := g.getGitlabConfigCR()
+	if err != nil {
+		return v3.Principal{}, err
+	}
+
+	accessToken, err := g.accessToken(token)
+	if err != nil {
+		return v3.Principal{}, err
+	}
+	previous := *accessToken
+	defer func() {
+		if refreshed(&previous, accessToken) {
+			g.saveAccessToken(token.UserID, accessToken)
+		}
+	}()
+
+	principalType, externalID, err := parsePrincipalID(principalID)
+	if err != nil {
+		return v3.Principal{}, err
+	}
+
+	switch principalType {
+	case userType:
+		acct, err := g.gitlabClient.getUserByID(externalID, accessToken, config)
+		if err != nil {
+			return v3.Principal{}, err
+		}
+		return g.userToPrincipal(acct, &token), nil
+	case groupType:
+		group, err := g.gitlabClient.getGroupByID(externalID, accessToken, config)
+		if err != nil {
+			return v3.Principal{}, err
+		}
+		return g.groupToPrincipal(group, &token), nil
+	default:
+		return v3.Principal{}, fmt.Errorf("Cannot get the gitlab account due to invalid externalIDType %v", principalType)
+	}
+}
+
+// accessToken returns the gitlab token stored for the rancher token.
+func (g *glProvider) accessToken(token v3.Token) (*oauth2.Token, error) {
+	secret, err := g.tokenMGR.GetSecret(token.UserID, token.AuthProvider, []*v3.Token{&token})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		secret = token.ProviderInfo["access_token"]
+	}
+	return parseToken(secret), nil
+}
+
+// saveAccessToken stores a token that was refreshed while it was used. GitLab rotates refresh tokens, so the stored
+// refresh token can't be used again once it was exchanged.
+func (g *glProvider) saveAccessToken(userID string, token *oauth2.Token) {
+	secret, err := json.Marshal(token)
+	if err == nil {
+		err = g.tokenMGR.UpdateSecret(userID, Name, string(secret))
+	}
+	// tokens of logins before provider secrets existed are only kept on the rancher token and are not refreshed
+	if err != nil && !apierrors.IsNotFound(err) {
+		logrus.Errorf("Gitlab: failed to save refreshed access token of user %v, err: %v", userID, err)
+	}
+}
+
+func refreshed(previous, current *oauth2.Token) bool {
+	return previous.AccessToken != current.AccessToken || previous.RefreshToken != current.RefreshToken
+}
+
+// parseToken accepts the json encoded token saved on login as well as a plain access token.
+func parseToken(secret string) *oauth2.Token {
+	token := &oauth2.Token{}
+	if err := json.Unmarshal([]byte(secret), token); err != nil || token.AccessToken == "" {
+		return &oauth2.Token{AccessToken: secret}
+	}
+	return token
+}
+
+// parsePrincipalID splits an id like gitlab_[user|group]://12345 into the principal type and the gitlab id.
+func parsePrincipalID(principalID string) (string, string, error) {
+	parts := strings.SplitN(principalID, ":", 2)
+	if len(parts) != 2 {
+		return "", "", errors.Errorf("invalid id %v", principalID)
+	}
+	externalID := strings.TrimPrefix(parts[1], "//")
+	parts = strings.SplitN(parts[0], "_", 2)
+	if len(parts) != 2 || parts[0] != Name || externalID == "" {
+		return "", "", errors.Errorf("invalid id %v", principalID)
+	}
+	return parts[1], externalID, nil
+}
+
+func (g *glProvider) userToPrincipal(acct Account, token *v3.Token) v3.Principal {
+	displayName := acct.Name
+	if displayName == "" {
+		displayName = acct.Username
+	}
+
+	princ := v3.Principal{
+		ObjectMeta:     metav1.ObjectMeta{Name: Name + "_" + userType + "://" + strconv.Itoa(acct.ID)},
+		DisplayName:    displayName,
+		LoginName:      acct.Username,
+		PrincipalType:  userType,
+		Provider:       Name,
+		ProfilePicture: acct.AvatarURL,
+		ProfileURL:     acct.WebURL,
+	}
+	if token != nil {
+		princ.Me = g.isThisUserMe(token.UserPrincipal, princ)
+	}
+	return princ
+}
+
+func (g *glProvider) groupToPrincipal(group Group, token *v3.Token) v3.Principal {
+	displayName := group.FullName
+	if displayName == "" {
+		displayName = group.Name
+	}
+
+	princ := v3.Principal{
+		ObjectMeta:     metav1.ObjectMeta{Name: Name + "_" + groupType + "://" + strconv.Itoa(group.ID)},
+		DisplayName:    displayName,
+		LoginName:      group.FullPath,
+		PrincipalType:  groupType,
+		Provider:       Name,
+		ProfilePicture: group.AvatarURL,
+		ProfileURL:     group.WebURL,
+	}
+	if token != nil {
+		princ.MemberOf = g.tokenMGR.IsMemberOf(*token, princ)
+	}
+	return princ
+}
+
+func (g *glProvider) isThisUserMe(me v3.Principal, other v3.Principal) bool {
+	return me.ObjectMeta.Name == other.ObjectMeta.Name && me.LoginName == other.LoginName && me.PrincipalType == other.PrincipalType
+}
+
+func (g *glProvider) CanAccessWithGroupProviders(userPrincipalID string, groupPrincipals []v3.Principal) (bool, error) {
+	config, err := g.getGitlabConfigCR()
+	if err != nil {
+		logrus.Errorf("Error fetching gitlab config: %v", err)
+		return false, err
+	}
+	allowed, err := g.userMGR.CheckAccess(config.AccessMode, config.AllowedPrincipalIDs, userPrincipalID, groupPrincipals)
+	if err != nil {
+		return false, err
+	}
+	return allowed, nil
+}
+
+func (g *glProvider) GetUserExtraAttributes(token *v3.Token) map[string][]string {
+	extras := make(map[string][]string)
+	extras["principalid"] = []string{token.UserPrincipal.Name}
+	extras["username"] = []string{token.UserPrincipal.LoginName}
+	return extras
+}
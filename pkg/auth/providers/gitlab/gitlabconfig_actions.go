@@ -0,0 +1,122 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/types"
+	"github.com/rancher/norman/types/convert"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/auth/providers/common"
+	client "github.com/rancher/rancher/pkg/client/generated/management/v3"
+)
+
+func (g *glProvider) formatter(apiContext *types.APIContext, resource *types.RawResource) {
+	common.AddCommonActions(apiContext, resource)
+	resource.AddAction(apiContext, "configureTest")
+	resource.AddAction(apiContext, "testAndApply")
+}
+
+func (g *glProvider) actionHandler(actionName string, action *types.Action, request *types.APIContext) error {
+	handled, err := common.HandleCommonAction(actionName, action, request, Name, g.authConfigs)
+	if err != nil {
+		return err
+	}
+	if handled {
+		return nil
+	}
+
+	if actionName == "configureTest" {
+		return g.configureTest(actionName, action, request)
+	} else if actionName == "testAndApply" {
+		return g.testAndApply(actionName, action, request)
+	}
+
+	return httperror.NewAPIError(httperror.ActionNotAvailable, "")
+}
+
+func (g *glProvider) configureTest(actionName string, action *types.Action, request *types.APIContext) error {
+	gitlabConfig := &v32.GitlabConfig{}
+	if err := json.NewDecoder(request.Request.Body).Decode(gitlabConfig); err != nil {
+		return httperror.NewAPIError(httperror.InvalidBodyContent,
+			fmt.Sprintf("Failed to parse body: %v", err))
+	}
+
+	data := map[string]interface{}{
+		"redirectUrl": formGitlabRedirectURL(gitlabConfig),
+		"type":        "gitlabConfigTestOutput",
+	}
+
+	request.WriteResponse(http.StatusOK, data)
+	return nil
+}
+
+func formGitlabRedirectURL(gitlabConfig *v32.GitlabConfig) string {
+	return gitlabRedirectURL(hostURL(gitlabConfig), gitlabConfig.ClientID, gitlabConfig.RancherURL)
+}
+
+func formGitlabRedirectURLFromMap(config map[string]interface{}) string {
+	hostname, _ := config[client.GitlabConfigFieldHostname].(string)
+	tls, _ := config[client.GitlabConfigFieldTLS].(bool)
+	clientID := convert.ToString(config[client.GitlabConfigFieldClientID])
+	rancherURL := convert.ToString(config[client.GitlabConfigFieldRancherURL])
+	return gitlabRedirectURL(hostURL(&v32.GitlabConfig{Hostname: hostname, TLS: tls}), clientID, rancherURL)
+}
+
+func gitlabRedirectURL(hostURL, clientID, rancherURL string) string {
+	query := url.Values{}
+	query.Set("client_id", clientID)
+	query.Set("response_type", "code")
+	query.Set("scope", gitlabScope)
+	query.Set("redirect_uri", rancherURL)
+	return hostURL + "/oauth/authorize?" + query.Encode()
+}
+
+func (g *glProvider) testAndApply(actionName string, action *types.Action, request *types.APIContext) error {
+	gitlabConfigApplyInput := &v32.GitlabConfigApplyInput{}
+	if err := json.NewDecoder(request.Request.Body).Decode(gitlabConfigApplyInput); err != nil {
+		return httperror.NewAPIError(httperror.InvalidBodyContent,
+			fmt.Sprintf("Failed to parse body: %v", err))
+	}
+	gitlabConfig := gitlabConfigApplyInput.GitlabConfig
+	gitlabLogin := &v32.GitlabLogin{
+		Code: gitlabConfigApplyInput.Code,
+	}
+
+	if gitlabConfig.ClientSecret != "" {
+		value, err := common.ReadFromSecret(g.secrets, gitlabConfig.ClientSecret,
+			strings.ToLower(client.GitlabConfigFieldClientSecret))
+		if err != nil {
+			return err
+		}
+		gitlabConfig.ClientSecret = value
+	}
+
+	//Call provider to testLogin
+	userPrincipal, groupPrincipals, providerInfo, err := g.LoginUser(gitlabLogin, &gitlabConfig, true)
+	if err != nil {
+		if httperror.IsAPIError(err) {
+			return err
+		}
+		return errors.Wrap(err, "server error while authenticating")
+	}
+
+	//if this works, save gitlabConfig CR adding enabled flag
+	user, err := g.userMGR.SetPrincipalOnCurrentUser(request, userPrincipal)
+	if err != nil {
+		return err
+	}
+
+	gitlabConfig.Enabled = gitlabConfigApplyInput.Enabled
+	err = g.saveGitlabConfig(&gitlabConfig)
+	if err != nil {
+		return httperror.NewAPIError(httperror.ServerError, fmt.Sprintf("Failed to save gitlab config: %v", err))
+	}
+
+	return g.tokenMGR.CreateTokenAndSetCookie(user.Name, userPrincipal, groupPrincipals, providerInfo, 0, "Token via Gitlab Configuration", request)
+}
@@ -3,15 +3,21 @@
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
+	"time"
 
 	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 
 	"github.com/pkg/errors"
 	"github.com/rancher/norman/httperror"
 	"github.com/rancher/norman/types"
+	"github.com/rancher/rancher/pkg/auth/lockout"
+	"github.com/rancher/rancher/pkg/auth/passwordpolicy"
 	"github.com/rancher/rancher/pkg/auth/providers/common"
 	"github.com/rancher/rancher/pkg/auth/tokens"
+	"github.com/rancher/rancher/pkg/auth/util"
+	corev1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/types/config"
 	"github.com/sirupsen/logrus"
@@ -38,6 +44,10 @@ type Provider struct {
 	groupIndexer cache.Indexer
 	tokenMGR     *tokens.Manager
 	invalidHash  []byte
+	secrets      corev1.SecretInterface
+	grbLister    v3.GlobalRoleBindingLister
+	lockout      *lockout.Manager
+	userClient   v3.UserInterface
 }
 
 func Configure(ctx context.Context, mgmtCtx *config.ScaledContext, tokenMGR *tokens.Manager) common.AuthProvider {
@@ -63,7 +73,12 @@ func Configure(ctx context.Context, mgmtCtx *config.ScaledContext, tokenMGR *tok
 		userLister:   mgmtCtx.Management.Users("").Controller().Lister(),
 		tokenMGR:     tokenMGR,
 		invalidHash:  invalidHash,
+		secrets:      mgmtCtx.Core.Secrets(""),
+		grbLister:    mgmtCtx.Management.GlobalRoleBindings("").Controller().Lister(),
+		lockout:      lockout.NewManager(mgmtCtx.Core, mgmtCtx.Management.Users("")),
+		userClient:   mgmtCtx.Management.Users(""),
 	}
+	go l.lockout.Purge(ctx)
 	return l
 }
 
@@ -101,25 +116,24 @@ func (l *Provider) getUser(username string) (*v3.User, error) {
 }
 
 func (l *Provider) AuthenticateUser(ctx context.Context, input interface{}) (v3.Principal, []v3.Principal, string, error) {
-	localInput, ok := input.(*v32.BasicLogin)
+	localInput, ok := input.(*v32.LocalLogin)
 	if !ok {
 		return v3.Principal{}, nil, "", httperror.NewAPIError(httperror.ServerError, "Unexpected input type")
 	}
 
-	username := localInput.Username
-	pwd := localInput.Password
-
-	user, err := l.getUser(username)
-
+	user, err := l.login(ctx, localInput, func(user *v3.User) error {
+		return l.verifyMFA(user, localInput)
+	})
 	if err != nil {
-		// If the user don't exist the password is evaluated
-		// to avoid user enumeration via timing attack (time based side-channel).
-		bcrypt.CompareHashAndPassword(l.invalidHash, []byte(pwd))
 		return v3.Principal{}, nil, "", err
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(pwd)); err != nil {
-		return v3.Principal{}, nil, "", httperror.WrapAPIError(err, httperror.Unauthorized, "authentication failed")
+	if !user.MustChangePassword && passwordpolicy.Expired(user, time.Now()) {
+		user = user.DeepCopy()
+		user.MustChangePassword = true
+		if _, err := l.userClient.Update(user); err != nil {
+			logrus.Warnf("failed to require user %s to change their expired password: %v", user.Name, err)
+		}
 	}
 
 	principalID := getLocalPrincipalID(user)
@@ -134,6 +148,44 @@ func (l *Provider) AuthenticateUser(ctx context.Context, input interface{}) (v3.
 	return userPrincipal, groupPrincipals, "", nil
 }
 
+// login checks the credentials of input, verify does additional checks once the password is verified. Failed
+// attempts count towards locking out the user and the source IP of the request.
+func (l *Provider) login(ctx context.Context, input *v32.LocalLogin, verify func(user *v3.User) error) (*v3.User, error) {
+	req, _ := ctx.Value(util.RequestKey).(*http.Request)
+	if err := l.lockout.Check(input.Username, req); err != nil {
+		return nil, err
+	}
+
+	user, err := l.authenticatePassword(input.Username, input.Password)
+	if err == nil {
+		err = verify(user)
+	}
+	if err != nil {
+		if apiErr, ok := err.(*httperror.APIError); ok && apiErr.Code == httperror.Unauthorized {
+			l.lockout.RecordFailure(input.Username, user, req)
+		}
+		return nil, err
+	}
+
+	l.lockout.RecordSuccess(user)
+	return user, nil
+}
+
+func (l *Provider) authenticatePassword(username, pwd string) (*v3.User, error) {
+	user, err := l.getUser(username)
+	if err != nil {
+		// If the user don't exist the password is evaluated
+		// to avoid user enumeration via timing attack (time based side-channel).
+		bcrypt.CompareHashAndPassword(l.invalidHash, []byte(pwd))
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(pwd)); err != nil {
+		return nil, httperror.WrapAPIError(err, httperror.Unauthorized, "authentication failed")
+	}
+	return user, nil
+}
+
 func getLocalPrincipalID(user *v3.User) string {
 	// TODO error condition handling: no principal, more than one that would match
 	var principalID string
@@ -0,0 +1,264 @@
+package local
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rancher/norman/httperror"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/auth/providers/common"
+	"github.com/rancher/rancher/pkg/auth/tokens"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	mfaSecretPrefix     = "mfa-"
+	mfaSecretKey        = "secret"
+	mfaEnrolledKey      = "enrolled"
+	mfaLastCounterKey   = "lastCounter"
+	mfaRecoveryCodesKey = "recoveryCodes"
+
+	mfaRequiredNone   = "none"
+	mfaRequiredAdmins = "admins"
+	mfaRequiredAll    = "all"
+
+	recoveryCodeCount    = 10
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+)
+
+var (
+	MFARequired           = httperror.ErrorCode{Code: "MFARequired", Status: 401}
+	MFAEnrollmentRequired = httperror.ErrorCode{Code: "MFAEnrollmentRequired", Status: 401}
+)
+
+// mfaState is the TOTP enrollment of a user, stored in a secret in the global data namespace. A secret that is not
+// enrolled is pending: the user started enrolling but has not logged in with a code yet.
+type mfaState struct {
+	secret        *v1.Secret
+	totpSecret    string
+	enrolled      bool
+	lastCounter   uint64
+	recoveryCodes []string
+}
+
+func mfaSecretName(user *v3.User) string {
+	return mfaSecretPrefix + user.Name
+}
+
+func (l *Provider) getMFA(user *v3.User) (*mfaState, error) {
+	secret, err := l.secrets.GetNamespaced(common.SecretsNamespace, mfaSecretName(user), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	state := &mfaState{
+		secret:     secret,
+		totpSecret: string(secret.Data[mfaSecretKey]),
+		enrolled:   string(secret.Data[mfaEnrolledKey]) == "true",
+	}
+	state.lastCounter, _ = strconv.ParseUint(string(secret.Data[mfaLastCounterKey]), 10, 64)
+	for _, code := range strings.Split(string(secret.Data[mfaRecoveryCodesKey]), "\n") {
+		if code != "" {
+			state.recoveryCodes = append(state.recoveryCodes, code)
+		}
+	}
+	return state, nil
+}
+
+func (l *Provider) saveMFA(state *mfaState) error {
+	secret := state.secret.DeepCopy()
+	secret.Data = map[string][]byte{
+		mfaSecretKey:        []byte(state.totpSecret),
+		mfaEnrolledKey:      []byte(strconv.FormatBool(state.enrolled)),
+		mfaLastCounterKey:   []byte(strconv.FormatUint(state.lastCounter, 10)),
+		mfaRecoveryCodesKey: []byte(strings.Join(state.recoveryCodes, "\n")),
+	}
+	if secret.ResourceVersion == "" {
+		_, err := l.secrets.Create(secret)
+		return err
+	}
+	// the resource version makes concurrent logins with the same code or recovery code conflict
+	_, err := l.secrets.Update(secret)
+	return err
+}
+
+// mfaRequired reports whether the auth-local-mfa-required setting requires user to use MFA.
+func (l *Provider) mfaRequired(user *v3.User) (bool, error) {
+	switch settings.AuthLocalMFARequired.Get() {
+	case mfaRequiredAll:
+		return true, nil
+	case mfaRequiredAdmins:
+		grbs, err := l.grbLister.List("", labels.Everything())
+		if err != nil {
+			return false, err
+		}
+		for _, grb := range grbs {
+			if grb.UserName == user.Name && grb.GlobalRoleName == "admin" {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// verifyMFA is called after the password of user was verified. Enrolled users have to provide a TOTP code or one of
+// their recovery codes. The first valid code completes a pending enrollment.
+func (l *Provider) verifyMFA(user *v3.User, input *v32.LocalLogin) error {
+	state, err := l.getMFA(user)
+	if err != nil {
+		return err
+	}
+
+	if state == nil || (!state.enrolled && input.TOTPCode == "") {
+		required, err := l.mfaRequired(user)
+		if err != nil {
+			return err
+		}
+		if required {
+			return httperror.NewAPIError(MFAEnrollmentRequired, "multi-factor authentication is required, enroll with the enrollMfa action")
+		}
+		return nil
+	}
+
+	switch {
+	case input.TOTPCode != "":
+		counter, ok := validateTOTP(state.totpSecret, input.TOTPCode, time.Now(), state.lastCounter)
+		if !ok {
+			return httperror.NewAPIError(httperror.Unauthorized, "authentication failed")
+		}
+		state.lastCounter = counter
+		state.enrolled = true
+	case input.RecoveryCode != "":
+		i := matchRecoveryCode(state.recoveryCodes, input.RecoveryCode)
+		if i < 0 {
+			return httperror.NewAPIError(httperror.Unauthorized, "authentication failed")
+		}
+		state.recoveryCodes = append(state.recoveryCodes[:i], state.recoveryCodes[i+1:]...)
+	default:
+		return httperror.NewAPIError(MFARequired, "a TOTP code or recovery code is required")
+	}
+
+	if err := l.saveMFA(state); err != nil {
+		if apierrors.IsConflict(err) {
+			return httperror.NewAPIError(httperror.Unauthorized, "authentication failed")
+		}
+		return err
+	}
+	return nil
+}
+
+// EnrollMFA starts TOTP enrollment for the user with the given credentials and returns the new secret and recovery
+// codes. Users that are already enrolled have to provide a valid code to enroll a new authenticator.
+func (l *Provider) EnrollMFA(ctx context.Context, input *v32.LocalLogin) (*v32.MFAEnrollOutput, error) {
+	user, err := l.login(ctx, input, func(user *v3.User) error {
+		state, err := l.getMFA(user)
+		if err != nil || state == nil || !state.enrolled {
+			return err
+		}
+		return l.verifyMFA(user, input)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// read after login, verifyMFA may have updated it
+	state, err := l.getMFA(user)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		state = &mfaState{
+			secret: &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      mfaSecretName(user),
+					Namespace: common.SecretsNamespace,
+					// removed with the user, an admin can reset the enrollment of a user by deleting it
+					OwnerReferences: []metav1.OwnerReference{{
+						APIVersion: "management.cattle.io/v3",
+						Kind:       "User",
+						Name:       user.Name,
+						UID:        user.UID,
+					}},
+				},
+				Type: v1.SecretTypeOpaque,
+			},
+		}
+	}
+
+	totpSecret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	state.totpSecret = totpSecret
+	state.enrolled = false
+	state.recoveryCodes = hashes
+	if err := l.saveMFA(state); err != nil {
+		return nil, err
+	}
+
+	return &v32.MFAEnrollOutput{
+		Secret:        totpSecret,
+		URL:           totpURL(totpSecret, user.Username),
+		RecoveryCodes: codes,
+	}, nil
+}
+
+// newRecoveryCodes returns recovery codes and their hashes, only the hashes are stored.
+func newRecoveryCodes() ([]string, []string, error) {
+	var codes, hashes []string
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := randomRecoveryCode()
+		if err != nil {
+			return nil, nil, err
+		}
+		hash, err := tokens.CreateSHA256Hash(code)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, hash)
+	}
+	return codes, hashes, nil
+}
+
+func randomRecoveryCode() (string, error) {
+	var b strings.Builder
+	for i := 0; i < 10; i++ {
+		if i == 5 {
+			b.WriteByte('-')
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(recoveryCodeAlphabet))))
+		if err != nil {
+			return "", fmt.Errorf("generating recovery code: %w", err)
+		}
+		b.WriteByte(recoveryCodeAlphabet[n.Int64()])
+	}
+	return b.String(), nil
+}
+
+func matchRecoveryCode(hashes []string, code string) int {
+	code = strings.ToLower(strings.TrimSpace(code))
+	for i, hash := range hashes {
+		if tokens.VerifySHA256Hash(hash, code) == nil {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,49 @@
+package local
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// test vectors from RFC 6238, truncated to 6 digits
+	key := []byte("12345678901234567890")
+	assert.Equal(t, "287082", totpCode(key, 59/totpPeriod))
+	assert.Equal(t, "081804", totpCode(key, 1111111109/totpPeriod))
+	assert.Equal(t, "005924", totpCode(key, 1234567890/totpPeriod))
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1111111109, 0)
+
+	counter, ok := validateTOTP(secret, "081804", now, 0)
+	require.True(t, ok)
+	assert.Equal(t, uint64(1111111109/totpPeriod), counter)
+
+	_, ok = validateTOTP(secret, "081804", now.Add(totpPeriod*time.Second), 0)
+	assert.True(t, ok, "code of the previous period should be accepted")
+
+	_, ok = validateTOTP(secret, "081804", now, counter)
+	assert.False(t, ok, "code should not be accepted twice")
+
+	_, ok = validateTOTP(secret, "081804", now.Add(3*totpPeriod*time.Second), 0)
+	assert.False(t, ok)
+
+	_, ok = validateTOTP(secret, "12345", now, 0)
+	assert.False(t, ok)
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := newRecoveryCodes()
+	require.NoError(t, err)
+	require.Len(t, codes, recoveryCodeCount)
+	require.Len(t, hashes, recoveryCodeCount)
+
+	assert.Equal(t, 3, matchRecoveryCode(hashes, codes[3]))
+	assert.Equal(t, 3, matchRecoveryCode(hashes, " "+codes[3]+" "))
+	assert.Equal(t, -1, matchRecoveryCode(hashes, "aaaaa-aaaaa"))
+}
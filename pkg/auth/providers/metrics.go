@@ -0,0 +1,39 @@
+package providers
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rancher/norman/httperror"
+)
+
+var (
+	authAttempts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: "auth",
+			Name:      "login_attempts_total",
+			Help:      "Total count of login attempts by auth provider and outcome (success, denied or error)",
+		},
+		[]string{"provider", "outcome"},
+	)
+)
+
+// Collectors returns the auth provider metrics for registration with Prometheus.
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{authAttempts}
+}
+
+func recordAuthAttempt(providerName string, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+		var apiError *httperror.APIError
+		if errors.As(err, &apiError) && apiError.Code.Status < 500 {
+			outcome = "denied"
+		}
+	}
+	authAttempts.With(prometheus.Labels{
+		"provider": providerName,
+		"outcome":  outcome,
+	}).Inc()
+}
@@ -10,6 +10,7 @@
 	"github.com/rancher/rancher/pkg/auth/providers/azure"
 	"github.com/rancher/rancher/pkg/auth/providers/common"
 	"github.com/rancher/rancher/pkg/auth/providers/github"
+	"github.com/rancher/rancher/pkg/auth/providers/gitlab"
 	"github.com/rancher/rancher/pkg/auth/providers/googleoauth"
 	"github.com/rancher/rancher/pkg/auth/providers/keycloakoidc"
 	"github.com/rancher/rancher/pkg/auth/providers/ldap"
@@ -67,6 +68,13 @@ func Configure(ctx context.Context, mgmt *config.ScaledContext) {
 	providersByType[client.GithubConfigType] = p
 	providersByType[publicclient.GithubProviderType] = p
 
+	p = gitlab.Configure(ctx, mgmt, userMGR, tokenMGR)
+	ProviderNames[gitlab.Name] = true
+	ProvidersWithSecrets[gitlab.Name] = true
+	providers[gitlab.Name] = p
+	providersByType[client.GitlabConfigType] = p
+	providersByType[publicclient.GitlabProviderType] = p
+
 	p = azure.Configure(ctx, mgmt, userMGR, tokenMGR)
 	ProviderNames[azure.Name] = true
 	ProvidersWithSecrets[azure.Name] = true
@@ -157,7 +165,9 @@ func IsValidUserExtraAttribute(key string) bool {
 }
 
 func AuthenticateUser(ctx context.Context, input interface{}, providerName string) (v3.Principal, []v3.Principal, string, error) {
-	return providers[providerName].AuthenticateUser(ctx, input)
+	userPrincipal, groupPrincipals, providerToken, err := providers[providerName].AuthenticateUser(ctx, input)
+	recordAuthAttempt(providerName, err)
+	return userPrincipal, groupPrincipals, providerToken, err
 }
 
 func GetPrincipal(principalID string, myToken v3.Token) (v3.Principal, error) {
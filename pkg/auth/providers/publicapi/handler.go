@@ -36,6 +36,7 @@ func NewHandler(ctx context.Context, mgmtCtx *config.ScaledContext, opts ...Serv
 	v3public.ActiveDirectoryProviderType,
 	v3public.AzureADProviderType,
 	v3public.GithubProviderType,
+	v3public.GitlabProviderType,
 	v3public.LocalProviderType,
 	v3public.OpenLdapProviderType,
 	v3public.FreeIpaProviderType,
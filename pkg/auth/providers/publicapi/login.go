@@ -20,6 +20,7 @@
 	"github.com/rancher/rancher/pkg/auth/providers/activedirectory"
 	"github.com/rancher/rancher/pkg/auth/providers/azure"
 	"github.com/rancher/rancher/pkg/auth/providers/github"
+	"github.com/rancher/rancher/pkg/auth/providers/gitlab"
 	"github.com/rancher/rancher/pkg/auth/providers/googleoauth"
 	"github.com/rancher/rancher/pkg/auth/providers/ldap"
 	"github.com/rancher/rancher/pkg/auth/providers/local"
@@ -52,6 +53,9 @@ type loginHandler struct {
 }
 
 func (h *loginHandler) login(actionName string, action *types.Action, request *types.APIContext) error {
+	if actionName == "enrollMfa" && request.Type == client.LocalProviderType {
+		return h.enrollMFA(request)
+	}
 	if actionName != "login" {
 		return httperror.NewAPIError(httperror.ActionNotAvailable, "")
 	}
@@ -123,11 +127,14 @@ func (h *loginHandler) createLoginToken(request *types.APIContext) (v3.Token, st
 	var providerName string
 	switch request.Type {
 	case client.LocalProviderType:
-		input = &v32.BasicLogin{}
+		input = &v32.LocalLogin{}
 		providerName = local.Name
 	case client.GithubProviderType:
 		input = &v32.GithubLogin{}
 		providerName = github.Name
+	case client.GitlabProviderType:
+		input = &v32.GitlabLogin{}
+		providerName = gitlab.Name
 	case client.ActiveDirectoryProviderType:
 		input = &v32.BasicLogin{}
 		providerName = activedirectory.Name
@@ -214,3 +221,36 @@ func (h *loginHandler) createLoginToken(request *types.APIContext) (v3.Token, st
 	rToken, unhashedTokenKey, err := h.tokenMGR.NewLoginToken(currUser.Name, userPrincipal, groupPrincipals, providerToken, ttl, description)
 	return rToken, unhashedTokenKey, responseType, err
 }
+
+func (h *loginHandler) enrollMFA(request *types.APIContext) error {
+	input := &v32.LocalLogin{}
+	if err := json.NewDecoder(request.Request.Body).Decode(input); err != nil {
+		return httperror.NewAPIError(httperror.InvalidBodyContent, "")
+	}
+
+	provider, err := providers.GetProvider(local.Name)
+	if err != nil {
+		return err
+	}
+	localProvider, ok := provider.(*local.Provider)
+	if !ok {
+		return httperror.NewAPIError(httperror.ServerError, "local provider is not available")
+	}
+
+	ctx := context.WithValue(request.Request.Context(), util.RequestKey, request.Request)
+	output, err := localProvider.EnrollMFA(ctx, input)
+	if err != nil {
+		if httperror.IsAPIError(err) {
+			return err
+		}
+		return httperror.WrapAPIError(err, httperror.ServerError, "Server error while enrolling MFA")
+	}
+
+	request.WriteResponse(http.StatusOK, map[string]interface{}{
+		"type":          client.MFAEnrollOutputType,
+		"secret":        output.Secret,
+		"url":           output.URL,
+		"recoveryCodes": output.RecoveryCodes,
+	})
+	return nil
+}
@@ -14,6 +14,7 @@
 
 var authConfigTypes = []string{
 	client.GithubConfigType,
+	client.GitlabConfigType,
 	client.LocalConfigType,
 	client.ActiveDirectoryConfigType,
 	client.AzureADConfigType,
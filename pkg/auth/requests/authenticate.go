@@ -4,9 +4,11 @@
 	"context"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/rancher/norman/httperror"
+	"github.com/rancher/rancher/pkg/auth/passwordpolicy"
 	"github.com/rancher/rancher/pkg/auth/providerrefresh"
 	"github.com/rancher/rancher/pkg/auth/providers"
 	"github.com/rancher/rancher/pkg/auth/tokens"
@@ -110,6 +112,10 @@ func (a *tokenAuthenticator) Authenticate(req *http.Request) (*AuthenticatorResp
 	if token.ClusterName != "" && token.ClusterName != a.clusterRouter(req) {
 		return nil, errors.Wrapf(ErrMustAuthenticate, "clusterID does not match")
 	}
+	// namespaces of project scoped tokens are checked by the cluster proxy
+	if err := tokens.CheckScope(token.Scope, tokens.ParseScopeRequest(req)); err != nil && err != tokens.ErrNamespaceCheckRequired {
+		return nil, errors.Wrapf(ErrMustAuthenticate, "request is not in the token scope: %v", err)
+	}
 
 	attribs, err := a.userAttributeLister.Get("", token.UserID)
 	if err != nil && !apierrors.IsNotFound(err) {
@@ -125,6 +131,11 @@ func (a *tokenAuthenticator) Authenticate(req *http.Request) (*AuthenticatorResp
 		return nil, errors.Wrap(ErrMustAuthenticate, "user is not enabled")
 	}
 
+	// local users with an expired password can only change it
+	if passwordpolicy.Expired(u, time.Now()) && !passwordChangeRequest(req) {
+		return nil, errors.Wrap(ErrMustAuthenticate, "password has expired and must be changed")
+	}
+
 	var groups []string
 	hitProvider := false
 	if attribs != nil {
@@ -167,6 +178,20 @@ func (a *tokenAuthenticator) Authenticate(req *http.Request) (*AuthenticatorResp
 	return authResp, nil
 }
 
+// passwordChangeRequest reports whether req is one of the requests needed to change an expired password: the
+// changepassword action, the lookup of the current user and logout.
+func passwordChangeRequest(req *http.Request) bool {
+	query := req.URL.Query()
+	switch strings.TrimSuffix(req.URL.Path, "/") {
+	case "/v3/users":
+		return strings.EqualFold(query.Get("action"), "changepassword") ||
+			(req.Method == http.MethodGet && query.Get("me") == "true")
+	case "/v3/tokens":
+		return query.Get("action") == "logout"
+	}
+	return false
+}
+
 func (a *tokenAuthenticator) TokenFromRequest(req *http.Request) (*v3.Token, error) {
 	tokenAuthValue := tokens.GetTokenAuthFromRequest(req)
 	if tokenAuthValue == "" {
@@ -0,0 +1,266 @@
+package scim
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// attributes returns the values of an attribute of a resource, lowercase attribute names are passed. Multi-valued
+// attributes return every value.
+type attributes func(attr string) []string
+
+// filter is a parsed SCIM filter expression (RFC 7644 section 3.4.2.2). All comparisons are case-insensitive, which
+// matches the case-exactness of the attributes Rancher supports.
+type filter interface {
+	match(attrs attributes) bool
+}
+
+type andFilter []filter
+
+func (f andFilter) match(attrs attributes) bool {
+	for _, sub := range f {
+		if !sub.match(attrs) {
+			return false
+		}
+	}
+	return true
+}
+
+type orFilter []filter
+
+func (f orFilter) match(attrs attributes) bool {
+	for _, sub := range f {
+		if sub.match(attrs) {
+			return true
+		}
+	}
+	return false
+}
+
+type notFilter struct {
+	filter
+}
+
+func (f notFilter) match(attrs attributes) bool {
+	return !f.filter.match(attrs)
+}
+
+type compareFilter struct {
+	attr  string
+	op    string
+	value string
+}
+
+func (f compareFilter) match(attrs attributes) bool {
+	values := attrs(f.attr)
+	if f.op == "pr" {
+		for _, v := range values {
+			if v != "" {
+				return true
+			}
+		}
+		return false
+	}
+	for _, v := range values {
+		v = strings.ToLower(v)
+		var ok bool
+		switch f.op {
+		case "eq":
+			ok = v == f.value
+		case "ne":
+			ok = v != f.value
+		case "co":
+			ok = strings.Contains(v, f.value)
+		case "sw":
+			ok = strings.HasPrefix(v, f.value)
+		case "ew":
+			ok = strings.HasSuffix(v, f.value)
+		case "gt":
+			ok = v > f.value
+		case "ge":
+			ok = v >= f.value
+		case "lt":
+			ok = v < f.value
+		case "le":
+			ok = v <= f.value
+		}
+		if ok {
+			return true
+		}
+	}
+	// ne also matches resources without the attribute
+	return f.op == "ne" && len(values) == 0
+}
+
+var compareOps = map[string]bool{
+	"eq": true, "ne": true, "co": true, "sw": true, "ew": true, "gt": true, "ge": true, "lt": true, "le": true,
+}
+
+// parseFilter parses a filter expression. Attribute names in the result are lowercase and stripped of the schema URN
+// of the core resources.
+func parseFilter(expr string) (filter, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens}
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in filter", p.tokens[p.pos])
+	}
+	return f, nil
+}
+
+type filterParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *filterParser) next() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	p.pos++
+	return p.tokens[p.pos-1]
+}
+
+func (p *filterParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) parseOr() (filter, error) {
+	var or orFilter
+	for {
+		f, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		or = append(or, f)
+		if !strings.EqualFold(p.peek(), "or") {
+			break
+		}
+		p.next()
+	}
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
+}
+
+func (p *filterParser) parseAnd() (filter, error) {
+	var and andFilter
+	for {
+		f, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, f)
+		if !strings.EqualFold(p.peek(), "and") {
+			break
+		}
+		p.next()
+	}
+	if len(and) == 1 {
+		return and[0], nil
+	}
+	return and, nil
+}
+
+func (p *filterParser) parseExpr() (filter, error) {
+	token := p.next()
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("unexpected end of filter")
+	case token == "(":
+		return p.parseGroup()
+	case strings.EqualFold(token, "not"):
+		if p.next() != "(" {
+			return nil, fmt.Errorf("expected ( after not")
+		}
+		f, err := p.parseGroup()
+		if err != nil {
+			return nil, err
+		}
+		return notFilter{f}, nil
+	}
+
+	attr := attributeName(token)
+	op := strings.ToLower(p.next())
+	if op == "pr" {
+		return compareFilter{attr: attr, op: op}, nil
+	}
+	if !compareOps[op] {
+		return nil, fmt.Errorf("unsupported operator %q in filter", op)
+	}
+	value := p.next()
+	if value == "" {
+		return nil, fmt.Errorf("missing value for %s in filter", token)
+	}
+	if strings.HasPrefix(value, `"`) {
+		value = strings.ReplaceAll(strings.Trim(value, `"`), `\"`, `"`)
+	}
+	return compareFilter{attr: attr, op: op, value: strings.ToLower(value)}, nil
+}
+
+func (p *filterParser) parseGroup() (filter, error) {
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.next() != ")" {
+		return nil, fmt.Errorf("missing ) in filter")
+	}
+	return f, nil
+}
+
+// attributeName normalizes a filter attribute path, so urn:ietf:params:scim:schemas:core:2.0:User:userName and
+// userName are the same attribute.
+func attributeName(path string) string {
+	for _, schema := range []string{userSchema, groupSchema} {
+		if len(path) > len(schema) && strings.EqualFold(path[:len(schema)], schema) {
+			path = strings.TrimPrefix(path[len(schema):], ":")
+		}
+	}
+	return strings.ToLower(path)
+}
+
+func tokenize(expr string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, string(c))
+			i++
+		case c == '"':
+			end := i + 1
+			for ; end < len(expr) && expr[end] != '"'; end++ {
+				if expr[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(expr) {
+				return nil, fmt.Errorf("unterminated string in filter")
+			}
+			tokens = append(tokens, expr[i:end+1])
+			i = end + 1
+		default:
+			end := i
+			for end < len(expr) && !unicode.IsSpace(rune(expr[end])) && expr[end] != '(' && expr[end] != ')' {
+				end++
+			}
+			tokens = append(tokens, expr[i:end])
+			i = end
+		}
+	}
+	return tokens, nil
+}
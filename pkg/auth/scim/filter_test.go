@@ -0,0 +1,58 @@
+package scim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter(t *testing.T) {
+	active := true
+	user := &User{
+		ID:          "u-abc",
+		UserName:    "Jane.Doe@example.com",
+		ExternalID:  "00u1abc",
+		DisplayName: "Jane Doe",
+		Active:      &active,
+		Groups:      []Member{{Value: "grp-1", Display: "Admins"}},
+	}
+
+	tests := []struct {
+		filter string
+		match  bool
+	}{
+		{filter: `userName eq "jane.doe@example.com"`, match: true},
+		{filter: `urn:ietf:params:scim:schemas:core:2.0:User:userName eq "Jane.Doe@example.com"`, match: true},
+		{filter: `userName eq "john@example.com"`},
+		{filter: `externalId eq "00u1abc" and active eq true`, match: true},
+		{filter: `externalId eq "00u1abc" and active eq false`},
+		{filter: `userName sw "john" or displayName co "doe"`, match: true},
+		{filter: `not (displayName ew "Doe")`},
+		{filter: `(userName eq "x" or userName eq "y") and active pr`},
+		{filter: `groups.value eq "grp-1"`, match: true},
+		{filter: `name.givenName pr`},
+		{filter: `title ne "manager"`, match: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			f, err := parseFilter(tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.match, f.match(user.attributes))
+		})
+	}
+}
+
+func TestFilterInvalid(t *testing.T) {
+	for _, expr := range []string{
+		`userName`,
+		`userName eq`,
+		`userName regex "j.*"`,
+		`userName eq "jane`,
+		`(userName eq "jane"`,
+		`userName eq "jane" extra`,
+	} {
+		_, err := parseFilter(expr)
+		assert.Error(t, err, expr)
+	}
+}
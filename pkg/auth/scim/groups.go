@@ -0,0 +1,539 @@
+package scim
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/retry"
+)
+
+func (h *handler) listGroups(rw http.ResponseWriter, req *http.Request) {
+	groups, err := h.managedGroups()
+	if err != nil {
+		writeServerError(rw, err)
+		return
+	}
+	members, err := h.groupMemberIDs()
+	if err != nil {
+		writeServerError(rw, err)
+		return
+	}
+
+	var resources []interface{}
+	for _, group := range groups {
+		resources = append(resources, toSCIMGroup(group, members[group.Name]))
+	}
+	page(rw, req, resources, func(resource interface{}) attributes {
+		return resource.(*Group).attributes
+	})
+}
+
+func (h *handler) getGroup(rw http.ResponseWriter, req *http.Request) {
+	group, err := h.managedGroup(mux.Vars(req)["id"])
+	if err != nil {
+		writeServerError(rw, err)
+		return
+	}
+	h.writeGroup(rw, http.StatusOK, group)
+}
+
+func (h *handler) createGroup(rw http.ResponseWriter, req *http.Request) {
+	var input Group
+	if !readJSON(rw, req, &input) {
+		return
+	}
+	if input.DisplayName == "" {
+		writeError(rw, http.StatusBadRequest, "invalidValue", "displayName is required")
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if ok, err := h.displayNameAvailable(input.DisplayName, ""); err != nil {
+		writeServerError(rw, err)
+		return
+	} else if !ok {
+		writeError(rw, http.StatusConflict, "uniqueness", fmt.Sprintf("displayName %s is already in use", input.DisplayName))
+		return
+	}
+	principals, err := h.memberPrincipals(input.Members)
+	if err != nil {
+		writeError(rw, http.StatusBadRequest, "invalidValue", err.Error())
+		return
+	}
+
+	group := &v3.Group{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "grp-",
+		},
+	}
+	applyGroup(group, &input, settings.SCIMProvider.Get())
+	group, err = h.groups.Create(group)
+	if err != nil {
+		writeServerError(rw, err)
+		return
+	}
+	if err := h.setMembers(group, principals); err != nil {
+		writeServerError(rw, err)
+		return
+	}
+	h.writeGroup(rw, http.StatusCreated, group)
+}
+
+func (h *handler) replaceGroup(rw http.ResponseWriter, req *http.Request) {
+	var input Group
+	if !readJSON(rw, req, &input) {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.modifyGroup(rw, mux.Vars(req)["id"], func(*Group) (*Group, error) {
+		return &input, nil
+	})
+}
+
+func (h *handler) patchGroup(rw http.ResponseWriter, req *http.Request) {
+	var patch PatchRequest
+	if !readJSON(rw, req, &patch) {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.modifyGroup(rw, mux.Vars(req)["id"], func(current *Group) (*Group, error) {
+		return current, patchGroup(current, patch.Operations)
+	})
+}
+
+// modifyGroup applies the SCIM representation returned by modify to the group with the given id.
+func (h *handler) modifyGroup(rw http.ResponseWriter, id string, modify func(current *Group) (*Group, error)) {
+	group, err := h.managedGroup(id)
+	if err != nil {
+		writeServerError(rw, err)
+		return
+	}
+	members, err := h.groupMemberIDs()
+	if err != nil {
+		writeServerError(rw, err)
+		return
+	}
+
+	input, err := modify(toSCIMGroup(group, members[group.Name]))
+	if err != nil {
+		writeError(rw, http.StatusBadRequest, "invalidValue", err.Error())
+		return
+	}
+	if input.DisplayName == "" {
+		writeError(rw, http.StatusBadRequest, "invalidValue", "displayName is required")
+		return
+	}
+	if ok, err := h.displayNameAvailable(input.DisplayName, group.Name); err != nil {
+		writeServerError(rw, err)
+		return
+	} else if !ok {
+		writeError(rw, http.StatusConflict, "uniqueness", fmt.Sprintf("displayName %s is already in use", input.DisplayName))
+		return
+	}
+	principals, err := h.memberPrincipals(input.Members)
+	if err != nil {
+		writeError(rw, http.StatusBadRequest, "invalidValue", err.Error())
+		return
+	}
+
+	provider := settings.SCIMProvider.Get()
+	oldPrincipal, oldDisplayName := group.Annotations[groupPrincipalAnnotation], group.DisplayName
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := h.groups.Get(group.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		current = current.DeepCopy()
+		applyGroup(current, input, provider)
+		group, err = h.groups.Update(current)
+		return err
+	})
+	if err != nil {
+		writeServerError(rw, err)
+		return
+	}
+
+	if err := h.setMembers(group, principals); err != nil {
+		writeServerError(rw, err)
+		return
+	}
+	if group.Annotations[groupPrincipalAnnotation] != oldPrincipal || group.DisplayName != oldDisplayName {
+		if err := h.syncGroupPrincipals(principals.List()...); err != nil {
+			writeServerError(rw, err)
+			return
+		}
+	}
+	h.writeGroup(rw, http.StatusOK, group)
+}
+
+func (h *handler) deleteGroup(rw http.ResponseWriter, req *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	group, err := h.managedGroup(mux.Vars(req)["id"])
+	if err != nil {
+		writeServerError(rw, err)
+		return
+	}
+	if err := h.setMembers(group, sets.NewString()); err != nil {
+		writeServerError(rw, err)
+		return
+	}
+	if err := h.groups.Delete(group.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		writeServerError(rw, err)
+		return
+	}
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) writeGroup(rw http.ResponseWriter, status int, group *v3.Group) {
+	members, err := h.groupMemberIDs()
+	if err != nil {
+		writeServerError(rw, err)
+		return
+	}
+	writeJSON(rw, status, toSCIMGroup(group, members[group.Name]))
+}
+
+func (h *handler) managedGroups() ([]*v3.Group, error) {
+	list, err := h.groups.List(metav1.ListOptions{LabelSelector: managedLabel + "=true"})
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]*v3.Group, 0, len(list.Items))
+	for i := range list.Items {
+		groups = append(groups, &list.Items[i])
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Name < groups[j].Name
+	})
+	return groups, nil
+}
+
+// managedGroup returns the group with the given id if it is managed through SCIM.
+func (h *handler) managedGroup(id string) (*v3.Group, error) {
+	group, err := h.groups.Get(id, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if group.Labels[managedLabel] != "true" {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Group: v3.GroupGroupVersionKind.Group, Resource: v3.GroupResource.Name}, id)
+	}
+	return group, nil
+}
+
+func (h *handler) displayNameAvailable(displayName, exceptID string) (bool, error) {
+	groups, err := h.managedGroups()
+	if err != nil {
+		return false, err
+	}
+	for _, group := range groups {
+		if group.Name != exceptID && strings.EqualFold(group.DisplayName, displayName) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (h *handler) managedGroupMembers() ([]*v3.GroupMember, error) {
+	list, err := h.groupMembers.List(metav1.ListOptions{LabelSelector: managedLabel + "=true"})
+	if err != nil {
+		return nil, err
+	}
+	members := make([]*v3.GroupMember, 0, len(list.Items))
+	for i := range list.Items {
+		members = append(members, &list.Items[i])
+	}
+	return members, nil
+}
+
+// groupMemberIDs returns the SCIM members of every group by group name.
+func (h *handler) groupMemberIDs() (map[string][]Member, error) {
+	users, err := h.managedUsers()
+	if err != nil {
+		return nil, err
+	}
+	provider := settings.SCIMProvider.Get()
+	usersByPrincipal := map[string]*v3.User{}
+	for _, user := range users {
+		usersByPrincipal[managedPrincipalID(provider, user)] = user
+	}
+
+	groupMembers, err := h.managedGroupMembers()
+	if err != nil {
+		return nil, err
+	}
+	result := map[string][]Member{}
+	for _, gm := range groupMembers {
+		user, ok := usersByPrincipal[gm.PrincipalID]
+		if !ok {
+			continue
+		}
+		result[gm.GroupName] = append(result[gm.GroupName], Member{
+			Value:   user.Name,
+			Display: user.DisplayName,
+			Ref:     location("Users", user.Name),
+		})
+	}
+	return result, nil
+}
+
+// memberships returns the groups of every user principal.
+func (h *handler) memberships() (map[string][]Member, error) {
+	groups, err := h.managedGroups()
+	if err != nil {
+		return nil, err
+	}
+	groupsByName := map[string]*v3.Group{}
+	for _, group := range groups {
+		groupsByName[group.Name] = group
+	}
+
+	groupMembers, err := h.managedGroupMembers()
+	if err != nil {
+		return nil, err
+	}
+	result := map[string][]Member{}
+	for _, gm := range groupMembers {
+		group, ok := groupsByName[gm.GroupName]
+		if !ok {
+			continue
+		}
+		result[gm.PrincipalID] = append(result[gm.PrincipalID], Member{
+			Value:   group.Name,
+			Display: group.DisplayName,
+			Ref:     location("Groups", group.Name),
+		})
+	}
+	return result, nil
+}
+
+// memberPrincipals resolves the SCIM user IDs of members to their principals.
+func (h *handler) memberPrincipals(members []Member) (sets.String, error) {
+	provider := settings.SCIMProvider.Get()
+	principals := sets.NewString()
+	for _, member := range members {
+		user, err := h.managedUser(member.Value)
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("member %s is not a user", member.Value)
+		} else if err != nil {
+			return nil, err
+		}
+		principalID := managedPrincipalID(provider, user)
+		if principalID == "" {
+			return nil, fmt.Errorf("user %s has no %s principal", member.Value, provider)
+		}
+		principals.Insert(principalID)
+	}
+	return principals, nil
+}
+
+// setMembers makes principals the members of group and updates the group principals of users that joined or left.
+func (h *handler) setMembers(group *v3.Group, principals sets.String) error {
+	groupMembers, err := h.managedGroupMembers()
+	if err != nil {
+		return err
+	}
+
+	changed := sets.NewString()
+	existing := sets.NewString()
+	for _, gm := range groupMembers {
+		if gm.GroupName != group.Name {
+			continue
+		}
+		existing.Insert(gm.PrincipalID)
+		if !principals.Has(gm.PrincipalID) {
+			if err := h.groupMembers.Delete(gm.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+			changed.Insert(gm.PrincipalID)
+		}
+	}
+	for _, principalID := range principals.Difference(existing).List() {
+		_, err := h.groupMembers.Create(&v3.GroupMember{
+			ObjectMeta: metav1.ObjectMeta{
+				GenerateName: "gm-",
+				Labels:       map[string]string{managedLabel: "true"},
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: "management.cattle.io/v3",
+					Kind:       "Group",
+					Name:       group.Name,
+					UID:        group.UID,
+				}},
+			},
+			GroupName:   group.Name,
+			PrincipalID: principalID,
+		})
+		if err != nil {
+			return err
+		}
+		changed.Insert(principalID)
+	}
+
+	return h.syncGroupPrincipals(changed.List()...)
+}
+
+// renameMemberPrincipal moves the group memberships of oldPrincipal to newPrincipal, or removes them if newPrincipal
+// is empty.
+func (h *handler) renameMemberPrincipal(oldPrincipal, newPrincipal string) error {
+	if oldPrincipal == "" {
+		return nil
+	}
+	groupMembers, err := h.managedGroupMembers()
+	if err != nil {
+		return err
+	}
+	for _, gm := range groupMembers {
+		if gm.PrincipalID != oldPrincipal {
+			continue
+		}
+		if err := h.groupMembers.Delete(gm.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		if newPrincipal == "" {
+			continue
+		}
+		gm = gm.DeepCopy()
+		gm.ObjectMeta = metav1.ObjectMeta{
+			GenerateName:    gm.GenerateName,
+			Labels:          gm.Labels,
+			OwnerReferences: gm.OwnerReferences,
+		}
+		gm.PrincipalID = newPrincipal
+		if _, err := h.groupMembers.Create(gm); err != nil {
+			return err
+		}
+	}
+	if newPrincipal == "" {
+		return nil
+	}
+	return h.syncGroupPrincipals(newPrincipal)
+}
+
+// syncGroupPrincipals sets the group principals of the SCIM provider on the user attributes of the users with the
+// given principals, so group based permissions apply without waiting for a login or refresh.
+func (h *handler) syncGroupPrincipals(principalIDs ...string) error {
+	if len(principalIDs) == 0 {
+		return nil
+	}
+	provider := settings.SCIMProvider.Get()
+	users, err := h.managedUsers()
+	if err != nil {
+		return err
+	}
+	groups, err := h.managedGroups()
+	if err != nil {
+		return err
+	}
+	groupsByName := map[string]*v3.Group{}
+	for _, group := range groups {
+		groupsByName[group.Name] = group
+	}
+	groupMembers, err := h.managedGroupMembers()
+	if err != nil {
+		return err
+	}
+
+	wanted := sets.NewString(principalIDs...)
+	for _, user := range users {
+		principalID := managedPrincipalID(provider, user)
+		if !wanted.Has(principalID) {
+			continue
+		}
+		var groupPrincipals []v3.Principal
+		for _, gm := range groupMembers {
+			group, ok := groupsByName[gm.GroupName]
+			if gm.PrincipalID != principalID || !ok {
+				continue
+			}
+			groupPrincipals = append(groupPrincipals, v3.Principal{
+				ObjectMeta:    metav1.ObjectMeta{Name: group.Annotations[groupPrincipalAnnotation]},
+				DisplayName:   group.DisplayName,
+				PrincipalType: "group",
+				Provider:      provider,
+			})
+		}
+		if err := h.tokenMGR.UserAttributeCreateOrUpdate(user.Name, provider, groupPrincipals); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyGroup(group *v3.Group, input *Group, provider string) {
+	if group.Labels == nil {
+		group.Labels = map[string]string{}
+	}
+	group.Labels[managedLabel] = "true"
+	if group.Annotations == nil {
+		group.Annotations = map[string]string{}
+	}
+	if input.ExternalID != "" {
+		group.Annotations[externalIDAnnotation] = input.ExternalID
+	} else {
+		delete(group.Annotations, externalIDAnnotation)
+	}
+	group.Annotations[groupPrincipalAnnotation] = groupPrincipalID(provider, input)
+	group.DisplayName = input.DisplayName
+}
+
+func toSCIMGroup(group *v3.Group, members []Member) *Group {
+	return &Group{
+		Schemas:     []string{groupSchema},
+		ID:          group.Name,
+		ExternalID:  group.Annotations[externalIDAnnotation],
+		DisplayName: group.DisplayName,
+		Members:     members,
+		Meta: &Meta{
+			ResourceType: "Group",
+			Created:      group.CreationTimestamp.UTC().Format(time.RFC3339),
+			Location:     location("Groups", group.Name),
+		},
+	}
+}
+
+func (g *Group) attributes(attr string) []string {
+	switch attr {
+	case "id":
+		return nonEmpty(g.ID)
+	case "externalid":
+		return nonEmpty(g.ExternalID)
+	case "displayname":
+		return nonEmpty(g.DisplayName)
+	case "members", "members.value":
+		var values []string
+		for _, member := range g.Members {
+			values = append(values, member.Value)
+		}
+		return values
+	case "meta.created":
+		if g.Meta != nil {
+			return nonEmpty(g.Meta.Created)
+		}
+	}
+	return nil
+}
+
+func groupPrincipalID(provider string, g *Group) string {
+	id := g.ExternalID
+	if id == "" {
+		id = g.DisplayName
+	}
+	return provider + "_group://" + id
+}
@@ -0,0 +1,221 @@
+// Package scim implements a SCIM 2.0 (RFC 7643, RFC 7644) server so identity providers can provision users and
+// groups ahead of their first login and deprovision them immediately.
+//
+// Provisioned users belong to the auth provider named by the scim-provider setting. Their principal is built from the
+// SCIM externalId, or the userName if there is none, so the identity provider has to send the same value the auth
+// provider uses as the user ID. Group memberships become the group principals of the users for that provider.
+package scim
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"github.com/rancher/rancher/pkg/auth/providers/common"
+	"github.com/rancher/rancher/pkg/auth/tokens"
+	corev1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/types/config"
+	"github.com/rancher/rancher/pkg/user"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	// PathPrefix is where the SCIM API is served.
+	PathPrefix = "/v1-scim/v2"
+
+	// the bearer token of the identity provider is the token key of this secret in the global data namespace
+	tokenSecretName = "scim-token"
+	tokenSecretKey  = "token"
+
+	managedLabel             = "auth.cattle.io/scim"
+	userNameAnnotation       = "auth.cattle.io/scim-user-name"
+	externalIDAnnotation     = "auth.cattle.io/scim-external-id"
+	groupPrincipalAnnotation = "auth.cattle.io/scim-group-principal"
+
+	contentType     = "application/scim+json"
+	defaultPageSize = 100
+)
+
+type handler struct {
+	// writes are serialized, group memberships of a user are recomputed from all groups after every change
+	mu sync.Mutex
+
+	userManager  user.Manager
+	tokenMGR     *tokens.Manager
+	users        v3.UserInterface
+	groups       v3.GroupInterface
+	groupMembers v3.GroupMemberInterface
+	secretLister corev1.SecretLister
+}
+
+func NewHandler(ctx context.Context, mgmt *config.ScaledContext) http.Handler {
+	h := &handler{
+		userManager:  mgmt.UserManager,
+		tokenMGR:     tokens.NewManager(ctx, mgmt),
+		users:        mgmt.Management.Users(""),
+		groups:       mgmt.Management.Groups(""),
+		groupMembers: mgmt.Management.GroupMembers(""),
+		secretLister: mgmt.Core.Secrets("").Controller().Lister(),
+	}
+
+	r := mux.NewRouter()
+	r.UseEncodedPath()
+	r.Path(PathPrefix + "/ServiceProviderConfig").Methods(http.MethodGet).HandlerFunc(h.serviceProviderConfig)
+	r.Path(PathPrefix + "/Users").Methods(http.MethodGet).HandlerFunc(h.listUsers)
+	r.Path(PathPrefix + "/Users").Methods(http.MethodPost).HandlerFunc(h.createUser)
+	r.Path(PathPrefix + "/Users/{id}").Methods(http.MethodGet).HandlerFunc(h.getUser)
+	r.Path(PathPrefix + "/Users/{id}").Methods(http.MethodPut).HandlerFunc(h.replaceUser)
+	r.Path(PathPrefix + "/Users/{id}").Methods(http.MethodPatch).HandlerFunc(h.patchUser)
+	r.Path(PathPrefix + "/Users/{id}").Methods(http.MethodDelete).HandlerFunc(h.deleteUser)
+	r.Path(PathPrefix + "/Groups").Methods(http.MethodGet).HandlerFunc(h.listGroups)
+	r.Path(PathPrefix + "/Groups").Methods(http.MethodPost).HandlerFunc(h.createGroup)
+	r.Path(PathPrefix + "/Groups/{id}").Methods(http.MethodGet).HandlerFunc(h.getGroup)
+	r.Path(PathPrefix + "/Groups/{id}").Methods(http.MethodPut).HandlerFunc(h.replaceGroup)
+	r.Path(PathPrefix + "/Groups/{id}").Methods(http.MethodPatch).HandlerFunc(h.patchGroup)
+	r.Path(PathPrefix + "/Groups/{id}").Methods(http.MethodDelete).HandlerFunc(h.deleteGroup)
+	r.NotFoundHandler = http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		writeError(rw, http.StatusNotFound, "", "resource not found")
+	})
+
+	return h.authenticate(r)
+}
+
+func (h *handler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if settings.SCIMProvider.Get() == "" {
+			writeError(rw, http.StatusNotFound, "", "SCIM provisioning is not enabled")
+			return
+		}
+
+		secret, err := h.secretLister.Get(common.SecretsNamespace, tokenSecretName)
+		if err != nil {
+			logrus.Debugf("[scim] failed to get token secret: %v", err)
+			writeError(rw, http.StatusUnauthorized, "", "invalid token")
+			return
+		}
+		expected := secret.Data[tokenSecretKey]
+		auth := req.Header.Get("Authorization")
+		if len(auth) < 7 || !strings.EqualFold(auth[:7], "Bearer ") || len(expected) == 0 ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimSpace(auth[7:])), expected) != 1 {
+			writeError(rw, http.StatusUnauthorized, "", "invalid token")
+			return
+		}
+
+		next.ServeHTTP(rw, req)
+	})
+}
+
+func (h *handler) serviceProviderConfig(rw http.ResponseWriter, req *http.Request) {
+	writeJSON(rw, http.StatusOK, map[string]interface{}{
+		"schemas":        []string{serviceProviderConfigSchema},
+		"patch":          map[string]bool{"supported": true},
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": defaultPageSize},
+		"changePassword": map[string]bool{"supported": false},
+		"sort":           map[string]bool{"supported": false},
+		"etag":           map[string]bool{"supported": false},
+		"authenticationSchemes": []map[string]string{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer Token",
+			"description": "The token of the scim-token secret in the cattle-global-data namespace",
+		}},
+	})
+}
+
+// page applies the filter, startIndex and count query parameters to resources and writes the list response.
+func page(rw http.ResponseWriter, req *http.Request, resources []interface{}, attrs func(interface{}) attributes) {
+	query := req.URL.Query()
+	if expr := query.Get("filter"); expr != "" {
+		f, err := parseFilter(expr)
+		if err != nil {
+			writeError(rw, http.StatusBadRequest, "invalidFilter", err.Error())
+			return
+		}
+		var filtered []interface{}
+		for _, resource := range resources {
+			if f.match(attrs(resource)) {
+				filtered = append(filtered, resource)
+			}
+		}
+		resources = filtered
+	}
+
+	total := len(resources)
+	startIndex, err := strconv.Atoi(query.Get("startIndex"))
+	if err != nil || startIndex < 1 {
+		startIndex = 1
+	}
+	count, err := strconv.Atoi(query.Get("count"))
+	if err != nil || count < 0 || count > defaultPageSize {
+		count = defaultPageSize
+	}
+	if startIndex > len(resources) {
+		resources = nil
+	} else {
+		resources = resources[startIndex-1:]
+	}
+	if len(resources) > count {
+		resources = resources[:count]
+	}
+	if resources == nil {
+		resources = []interface{}{}
+	}
+
+	writeJSON(rw, http.StatusOK, &ListResponse{
+		Schemas:      []string{listResponseSchema},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+func readJSON(rw http.ResponseWriter, req *http.Request, into interface{}) bool {
+	if err := json.NewDecoder(req.Body).Decode(into); err != nil {
+		writeError(rw, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(rw http.ResponseWriter, status int, obj interface{}) {
+	rw.Header().Set("Content-Type", contentType)
+	rw.WriteHeader(status)
+	if err := json.NewEncoder(rw).Encode(obj); err != nil {
+		logrus.Errorf("[scim] failed to write response: %v", err)
+	}
+}
+
+func writeError(rw http.ResponseWriter, status int, scimType, detail string) {
+	writeJSON(rw, status, &Error{
+		Schemas:  []string{errorSchema},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	})
+}
+
+// writeServerError writes err, errors of the Kubernetes API are mapped to their status.
+func writeServerError(rw http.ResponseWriter, err error) {
+	switch {
+	case apierrors.IsNotFound(err):
+		writeError(rw, http.StatusNotFound, "", "resource not found")
+	case apierrors.IsAlreadyExists(err), apierrors.IsConflict(err):
+		writeError(rw, http.StatusConflict, "uniqueness", err.Error())
+	default:
+		logrus.Errorf("[scim] %v", err)
+		writeError(rw, http.StatusInternalServerError, "", err.Error())
+	}
+}
+
+func location(resourceType, id string) string {
+	return settings.ServerURL.Get() + PathPrefix + "/" + resourceType + "/" + id
+}
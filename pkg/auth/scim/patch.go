@@ -0,0 +1,259 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// patchUser applies the operations of a PATCH request to u. Attributes Rancher doesn't store, such as emails, are
+// ignored so identity providers that always send them keep working.
+func patchUser(u *User, ops []PatchOperation) error {
+	for _, op := range ops {
+		opName := strings.ToLower(op.Op)
+		if opName != "add" && opName != "replace" && opName != "remove" {
+			return fmt.Errorf("unsupported patch operation %q", op.Op)
+		}
+
+		if op.Path != "" {
+			if err := setUserAttribute(u, op.Path, op.Value, opName == "remove"); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var values map[string]json.RawMessage
+		if err := json.Unmarshal(op.Value, &values); err != nil {
+			return fmt.Errorf("value of a patch operation without path must be an object: %v", err)
+		}
+		for attr, value := range values {
+			if err := setUserAttribute(u, attr, value, opName == "remove"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func setUserAttribute(u *User, path string, value json.RawMessage, remove bool) error {
+	attr := attributeName(path)
+	if attr == "name" {
+		var name Name
+		if !remove {
+			if err := json.Unmarshal(value, &name); err != nil {
+				return fmt.Errorf("invalid value for name: %v", err)
+			}
+		}
+		u.Name = &name
+		return nil
+	}
+
+	var s string
+	if !remove {
+		var err error
+		if s, err = stringValue(value); err != nil {
+			return fmt.Errorf("invalid value for %s: %v", path, err)
+		}
+	}
+
+	if strings.HasPrefix(attr, "name.") && u.Name == nil {
+		u.Name = &Name{}
+	}
+	switch attr {
+	case "active":
+		if remove {
+			return nil
+		}
+		active, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid value for active: %v", err)
+		}
+		u.Active = &active
+	case "username":
+		if s == "" {
+			return fmt.Errorf("userName is required")
+		}
+		u.UserName = s
+	case "externalid":
+		u.ExternalID = s
+	case "displayname":
+		u.DisplayName = s
+	case "name.formatted":
+		u.Name.Formatted = s
+	case "name.givenname":
+		u.Name.GivenName = s
+	case "name.familyname":
+		u.Name.FamilyName = s
+	}
+	return nil
+}
+
+// patchGroup applies the operations of a PATCH request to g.
+func patchGroup(g *Group, ops []PatchOperation) error {
+	for _, op := range ops {
+		opName := strings.ToLower(op.Op)
+		if opName != "add" && opName != "replace" && opName != "remove" {
+			return fmt.Errorf("unsupported patch operation %q", op.Op)
+		}
+
+		if op.Path != "" {
+			if err := setGroupAttribute(g, opName, op.Path, op.Value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var values map[string]json.RawMessage
+		if err := json.Unmarshal(op.Value, &values); err != nil {
+			return fmt.Errorf("value of a patch operation without path must be an object: %v", err)
+		}
+		for attr, value := range values {
+			if err := setGroupAttribute(g, opName, attr, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func setGroupAttribute(g *Group, op, path string, value json.RawMessage) error {
+	attr, valueFilter, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+
+	switch attr {
+	case "displayname", "externalid":
+		var s string
+		if op != "remove" {
+			if s, err = stringValue(value); err != nil {
+				return fmt.Errorf("invalid value for %s: %v", path, err)
+			}
+		}
+		if attr == "displayname" {
+			if s == "" {
+				return fmt.Errorf("displayName is required")
+			}
+			g.DisplayName = s
+		} else {
+			g.ExternalID = s
+		}
+	case "members":
+		var members []Member
+		if len(value) > 0 && string(value) != "null" {
+			if members, err = memberValues(value); err != nil {
+				return fmt.Errorf("invalid value for members: %v", err)
+			}
+		}
+		switch op {
+		case "add":
+			for _, member := range members {
+				if !hasMember(g.Members, member.Value) {
+					g.Members = append(g.Members, member)
+				}
+			}
+		case "replace":
+			g.Members = members
+		case "remove":
+			g.Members = removeMembers(g.Members, valueFilter, members)
+		}
+	}
+	return nil
+}
+
+// parsePath splits a patch path like members[value eq "2819c223"] into the attribute and the value filter.
+func parsePath(path string) (string, filter, error) {
+	open := strings.Index(path, "[")
+	if open < 0 {
+		return attributeName(path), nil, nil
+	}
+	if !strings.HasSuffix(path, "]") {
+		return "", nil, fmt.Errorf("invalid path %q", path)
+	}
+	f, err := parseFilter(path[open+1 : len(path)-1])
+	if err != nil {
+		return "", nil, err
+	}
+	return attributeName(path[:open]), f, nil
+}
+
+// removeMembers removes the members matching valueFilter or listed in values. Without either all members are removed.
+func removeMembers(members []Member, valueFilter filter, values []Member) []Member {
+	if valueFilter == nil && len(values) == 0 {
+		return nil
+	}
+	var result []Member
+	for _, member := range members {
+		remove := hasMember(values, member.Value)
+		if valueFilter != nil && valueFilter.match(member.attributes) {
+			remove = true
+		}
+		if !remove {
+			result = append(result, member)
+		}
+	}
+	return result
+}
+
+func hasMember(members []Member, value string) bool {
+	for _, member := range members {
+		if member.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (m Member) attributes(attr string) []string {
+	switch attr {
+	case "value":
+		return nonEmpty(m.Value)
+	case "display":
+		return nonEmpty(m.Display)
+	}
+	return nil
+}
+
+// memberValues accepts a list of members or a single member.
+func memberValues(value json.RawMessage) ([]Member, error) {
+	var members []Member
+	if err := json.Unmarshal(value, &members); err == nil {
+		return members, nil
+	}
+	var member Member
+	if err := json.Unmarshal(value, &member); err != nil {
+		return nil, err
+	}
+	return []Member{member}, nil
+}
+
+// stringValue accepts JSON strings, booleans and numbers. Some identity providers send booleans as strings such as
+// "False", others send them as JSON booleans.
+func stringValue(value json.RawMessage) (string, error) {
+	var v interface{}
+	if err := json.Unmarshal(value, &v); err != nil {
+		return "", err
+	}
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case nil:
+		return "", nil
+	}
+	return "", fmt.Errorf("expected a single value")
+}
+
+func nonEmpty(values ...string) []string {
+	var result []string
+	for _, v := range values {
+		if v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
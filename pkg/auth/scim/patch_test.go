@@ -0,0 +1,60 @@
+package scim
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func patchOperations(t *testing.T, body string) []PatchOperation {
+	var patch PatchRequest
+	require.NoError(t, json.Unmarshal([]byte(body), &patch))
+	return patch.Operations
+}
+
+func TestPatchUser(t *testing.T) {
+	active := true
+	user := &User{UserName: "jane@example.com", DisplayName: "Jane", Active: &active}
+
+	// Azure AD sends booleans as strings
+	require.NoError(t, patchUser(user, patchOperations(t, `{"Operations": [
+		{"op": "Replace", "path": "active", "value": "False"},
+		{"op": "Replace", "path": "displayName", "value": "Jane Doe"},
+		{"op": "Add", "path": "emails[type eq \"work\"].value", "value": "jane@example.com"}
+	]}`)))
+	assert.False(t, *user.Active)
+	assert.Equal(t, "Jane Doe", user.DisplayName)
+
+	// Okta sends operations without path
+	require.NoError(t, patchUser(user, patchOperations(t, `{"Operations": [
+		{"op": "replace", "value": {"active": true, "name": {"givenName": "Jane", "familyName": "Roe"}}}
+	]}`)))
+	assert.True(t, *user.Active)
+	assert.Equal(t, "Roe", user.Name.FamilyName)
+
+	assert.Error(t, patchUser(user, patchOperations(t, `{"Operations": [{"op": "move", "path": "active"}]}`)))
+	assert.Error(t, patchUser(user, patchOperations(t, `{"Operations": [{"op": "replace", "path": "userName", "value": ""}]}`)))
+}
+
+func TestPatchGroup(t *testing.T) {
+	group := &Group{DisplayName: "Admins", Members: []Member{{Value: "u-1"}, {Value: "u-2"}}}
+
+	require.NoError(t, patchGroup(group, patchOperations(t, `{"Operations": [
+		{"op": "add", "path": "members", "value": [{"value": "u-2"}, {"value": "u-3"}]},
+		{"op": "remove", "path": "members[value eq \"u-1\"]"}
+	]}`)))
+	assert.Equal(t, []Member{{Value: "u-2"}, {Value: "u-3"}}, group.Members)
+
+	// Azure AD removes members by value
+	require.NoError(t, patchGroup(group, patchOperations(t, `{"Operations": [
+		{"op": "Remove", "path": "members", "value": [{"value": "u-3"}]},
+		{"op": "Replace", "path": "displayName", "value": "Platform Admins"}
+	]}`)))
+	assert.Equal(t, []Member{{Value: "u-2"}}, group.Members)
+	assert.Equal(t, "Platform Admins", group.DisplayName)
+
+	require.NoError(t, patchGroup(group, patchOperations(t, `{"Operations": [{"op": "remove", "path": "members"}]}`)))
+	assert.Empty(t, group.Members)
+}
@@ -0,0 +1,374 @@
+package scim
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/retry"
+)
+
+func (h *handler) listUsers(rw http.ResponseWriter, req *http.Request) {
+	users, err := h.managedUsers()
+	if err != nil {
+		writeServerError(rw, err)
+		return
+	}
+	memberships, err := h.memberships()
+	if err != nil {
+		writeServerError(rw, err)
+		return
+	}
+
+	var resources []interface{}
+	for _, user := range users {
+		resources = append(resources, toSCIMUser(user, memberships))
+	}
+	page(rw, req, resources, func(resource interface{}) attributes {
+		return resource.(*User).attributes
+	})
+}
+
+func (h *handler) getUser(rw http.ResponseWriter, req *http.Request) {
+	user, err := h.managedUser(mux.Vars(req)["id"])
+	if err != nil {
+		writeServerError(rw, err)
+		return
+	}
+	h.writeUser(rw, http.StatusOK, user)
+}
+
+func (h *handler) createUser(rw http.ResponseWriter, req *http.Request) {
+	var input User
+	if !readJSON(rw, req, &input) {
+		return
+	}
+	if input.UserName == "" {
+		writeError(rw, http.StatusBadRequest, "invalidValue", "userName is required")
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if ok, err := h.userNameAvailable(input.UserName, ""); err != nil {
+		writeServerError(rw, err)
+		return
+	} else if !ok {
+		writeError(rw, http.StatusConflict, "uniqueness", fmt.Sprintf("userName %s is already in use", input.UserName))
+		return
+	}
+
+	// an existing user with the same principal, for example one that logged in before, is taken over
+	provider := settings.SCIMProvider.Get()
+	user, err := h.userManager.EnsureUser(userPrincipalID(provider, &input), displayName(&input))
+	if err != nil {
+		writeServerError(rw, err)
+		return
+	}
+	user, err = h.updateUser(user.Name, func(user *v3.User) {
+		applyUser(user, &input, provider)
+	})
+	if err != nil {
+		writeServerError(rw, err)
+		return
+	}
+	h.writeUser(rw, http.StatusCreated, user)
+}
+
+func (h *handler) replaceUser(rw http.ResponseWriter, req *http.Request) {
+	var input User
+	if !readJSON(rw, req, &input) {
+		return
+	}
+	if input.UserName == "" {
+		writeError(rw, http.StatusBadRequest, "invalidValue", "userName is required")
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.modifyUser(rw, mux.Vars(req)["id"], func(*User) (*User, error) {
+		return &input, nil
+	})
+}
+
+func (h *handler) patchUser(rw http.ResponseWriter, req *http.Request) {
+	var patch PatchRequest
+	if !readJSON(rw, req, &patch) {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.modifyUser(rw, mux.Vars(req)["id"], func(current *User) (*User, error) {
+		return current, patchUser(current, patch.Operations)
+	})
+}
+
+// modifyUser applies the SCIM representation returned by modify to the user with the given id.
+func (h *handler) modifyUser(rw http.ResponseWriter, id string, modify func(current *User) (*User, error)) {
+	user, err := h.managedUser(id)
+	if err != nil {
+		writeServerError(rw, err)
+		return
+	}
+
+	input, err := modify(toSCIMUser(user, nil))
+	if err != nil {
+		writeError(rw, http.StatusBadRequest, "invalidValue", err.Error())
+		return
+	}
+	if ok, err := h.userNameAvailable(input.UserName, user.Name); err != nil {
+		writeServerError(rw, err)
+		return
+	} else if !ok {
+		writeError(rw, http.StatusConflict, "uniqueness", fmt.Sprintf("userName %s is already in use", input.UserName))
+		return
+	}
+
+	provider := settings.SCIMProvider.Get()
+	oldPrincipal := managedPrincipalID(provider, user)
+	if principalID := userPrincipalID(provider, input); principalID != oldPrincipal {
+		if ok, err := h.principalAvailable(principalID, user.Name); err != nil {
+			writeServerError(rw, err)
+			return
+		} else if !ok {
+			writeError(rw, http.StatusConflict, "uniqueness", fmt.Sprintf("principal %s belongs to another user", principalID))
+			return
+		}
+	}
+	user, err = h.updateUser(user.Name, func(user *v3.User) {
+		applyUser(user, input, provider)
+	})
+	if err != nil {
+		writeServerError(rw, err)
+		return
+	}
+	if newPrincipal := managedPrincipalID(provider, user); newPrincipal != oldPrincipal {
+		if err := h.renameMemberPrincipal(oldPrincipal, newPrincipal); err != nil {
+			writeServerError(rw, err)
+			return
+		}
+	}
+	h.writeUser(rw, http.StatusOK, user)
+}
+
+func (h *handler) deleteUser(rw http.ResponseWriter, req *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	user, err := h.managedUser(mux.Vars(req)["id"])
+	if err != nil {
+		writeServerError(rw, err)
+		return
+	}
+	if err := h.renameMemberPrincipal(managedPrincipalID(settings.SCIMProvider.Get(), user), ""); err != nil {
+		writeServerError(rw, err)
+		return
+	}
+	if err := h.users.Delete(user.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		writeServerError(rw, err)
+		return
+	}
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) writeUser(rw http.ResponseWriter, status int, user *v3.User) {
+	memberships, err := h.memberships()
+	if err != nil {
+		writeServerError(rw, err)
+		return
+	}
+	writeJSON(rw, status, toSCIMUser(user, memberships))
+}
+
+func (h *handler) managedUsers() ([]*v3.User, error) {
+	list, err := h.users.List(metav1.ListOptions{LabelSelector: managedLabel + "=true"})
+	if err != nil {
+		return nil, err
+	}
+	users := make([]*v3.User, 0, len(list.Items))
+	for i := range list.Items {
+		users = append(users, &list.Items[i])
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Name < users[j].Name
+	})
+	return users, nil
+}
+
+// managedUser returns the user with the given id if it is managed through SCIM.
+func (h *handler) managedUser(id string) (*v3.User, error) {
+	user, err := h.users.Get(id, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if user.Labels[managedLabel] != "true" {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Group: v3.UserGroupVersionKind.Group, Resource: v3.UserResource.Name}, id)
+	}
+	return user, nil
+}
+
+func (h *handler) userNameAvailable(userName, exceptID string) (bool, error) {
+	users, err := h.managedUsers()
+	if err != nil {
+		return false, err
+	}
+	for _, user := range users {
+		if user.Name != exceptID && strings.EqualFold(user.Annotations[userNameAnnotation], userName) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// principalAvailable returns whether no user other than exceptID owns principalID. Logins resolve a principal to a
+// single user, so a principal must never be given to a second user.
+func (h *handler) principalAvailable(principalID, exceptID string) (bool, error) {
+	owner, err := h.userManager.GetUserByPrincipalID(principalID)
+	if err != nil {
+		return false, err
+	}
+	return owner == nil || owner.Name == exceptID, nil
+}
+
+func (h *handler) updateUser(name string, mutate func(user *v3.User)) (*v3.User, error) {
+	var result *v3.User
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		user, err := h.users.Get(name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		user = user.DeepCopy()
+		mutate(user)
+		result, err = h.users.Update(user)
+		return err
+	})
+	return result, err
+}
+
+// applyUser sets the attributes of input on user. Disabled users are rejected by the authenticator on their next
+// request, which is what makes deprovisioning immediate.
+func applyUser(user *v3.User, input *User, provider string) {
+	if user.Labels == nil {
+		user.Labels = map[string]string{}
+	}
+	user.Labels[managedLabel] = "true"
+	if user.Annotations == nil {
+		user.Annotations = map[string]string{}
+	}
+	user.Annotations[userNameAnnotation] = input.UserName
+	if input.ExternalID != "" {
+		user.Annotations[externalIDAnnotation] = input.ExternalID
+	} else {
+		delete(user.Annotations, externalIDAnnotation)
+	}
+	user.DisplayName = displayName(input)
+	if input.Active != nil {
+		enabled := *input.Active
+		user.Enabled = &enabled
+	}
+
+	principalID := userPrincipalID(provider, input)
+	prefix := provider + "_user://"
+	for i, id := range user.PrincipalIDs {
+		if strings.HasPrefix(id, prefix) {
+			user.PrincipalIDs[i] = principalID
+			return
+		}
+	}
+	user.PrincipalIDs = append(user.PrincipalIDs, principalID)
+}
+
+func toSCIMUser(user *v3.User, memberships map[string][]Member) *User {
+	active := user.Enabled == nil || *user.Enabled
+	return &User{
+		Schemas:     []string{userSchema},
+		ID:          user.Name,
+		ExternalID:  user.Annotations[externalIDAnnotation],
+		UserName:    user.Annotations[userNameAnnotation],
+		DisplayName: user.DisplayName,
+		Active:      &active,
+		Groups:      memberships[managedPrincipalID(settings.SCIMProvider.Get(), user)],
+		Meta: &Meta{
+			ResourceType: "User",
+			Created:      user.CreationTimestamp.UTC().Format(time.RFC3339),
+			Location:     location("Users", user.Name),
+		},
+	}
+}
+
+func (u *User) attributes(attr string) []string {
+	switch attr {
+	case "id":
+		return nonEmpty(u.ID)
+	case "externalid":
+		return nonEmpty(u.ExternalID)
+	case "username":
+		return nonEmpty(u.UserName)
+	case "displayname":
+		return nonEmpty(u.DisplayName)
+	case "active":
+		if u.Active != nil {
+			return []string{strconv.FormatBool(*u.Active)}
+		}
+	case "groups", "groups.value":
+		var values []string
+		for _, group := range u.Groups {
+			values = append(values, group.Value)
+		}
+		return values
+	case "groups.display":
+		var values []string
+		for _, group := range u.Groups {
+			values = append(values, group.Display)
+		}
+		return values
+	case "meta.created":
+		if u.Meta != nil {
+			return nonEmpty(u.Meta.Created)
+		}
+	}
+	return nil
+}
+
+func userPrincipalID(provider string, u *User) string {
+	id := u.ExternalID
+	if id == "" {
+		id = u.UserName
+	}
+	return provider + "_user://" + id
+}
+
+// managedPrincipalID returns the principal of user for the SCIM provider.
+func managedPrincipalID(provider string, user *v3.User) string {
+	prefix := provider + "_user://"
+	for _, id := range user.PrincipalIDs {
+		if strings.HasPrefix(id, prefix) {
+			return id
+		}
+	}
+	return ""
+}
+
+func displayName(u *User) string {
+	switch {
+	case u.DisplayName != "":
+		return u.DisplayName
+	case u.Name != nil && u.Name.Formatted != "":
+		return u.Name.Formatted
+	case u.Name != nil && (u.Name.GivenName != "" || u.Name.FamilyName != ""):
+		return strings.TrimSpace(u.Name.GivenName + " " + u.Name.FamilyName)
+	}
+	return u.UserName
+}
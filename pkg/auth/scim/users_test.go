@@ -0,0 +1,48 @@
+package scim
+
+import (
+	"testing"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type fakeUserManager struct {
+	user.Manager
+	owners map[string]string
+}
+
+func (f *fakeUserManager) GetUserByPrincipalID(principalName string) (*v3.User, error) {
+	name, ok := f.owners[principalName]
+	if !ok {
+		return nil, nil
+	}
+	return &v3.User{ObjectMeta: metav1.ObjectMeta{Name: name}}, nil
+}
+
+func TestPrincipalAvailable(t *testing.T) {
+	h := &handler{
+		userManager: &fakeUserManager{owners: map[string]string{
+			"okta_user://jane": "u-jane",
+			"local://u-admin":  "user-admin",
+		}},
+	}
+
+	for _, tt := range []struct {
+		principalID string
+		exceptID    string
+		expected    bool
+	}{
+		{"okta_user://john", "u-john", true},
+		{"okta_user://jane", "u-jane", true},
+		{"okta_user://jane", "u-john", false},
+		{"local://u-admin", "u-john", false},
+	} {
+		ok, err := h.principalAvailable(tt.principalID, tt.exceptID)
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, ok, tt.principalID)
+	}
+}
@@ -15,6 +15,7 @@
 	"github.com/rancher/rancher/pkg/auth/providers/publicapi"
 	"github.com/rancher/rancher/pkg/auth/providers/saml"
 	"github.com/rancher/rancher/pkg/auth/requests"
+	"github.com/rancher/rancher/pkg/auth/scim"
 	"github.com/rancher/rancher/pkg/auth/tokens"
 	"github.com/rancher/rancher/pkg/clusterrouter"
 	"github.com/rancher/rancher/pkg/features"
@@ -95,6 +96,7 @@ func newAPIManagement(ctx context.Context, scaledContext *config.ScaledContext)
 	root.UseEncodedPath()
 	root.PathPrefix("/v3-public").Handler(publicAPI)
 	root.PathPrefix("/v1-saml").Handler(saml)
+	root.PathPrefix("/v1-scim").Handler(scim.NewHandler(ctx, scaledContext))
 	root.NotFoundHandler = privateAPI
 
 	return func(next http.Handler) http.Handler {
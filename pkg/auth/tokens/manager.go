@@ -104,6 +104,11 @@ func (m *Manager) createDerivedToken(jsonInput clientv3.Token, tokenAuthValue st
 		return v3.Token{}, "", 500, fmt.Errorf("error validating max-ttl %v", err)
 	}
 
+	scope := tokenScopeFromInput(jsonInput.Scope)
+	if err := ValidateScope(scope); err != nil {
+		return v3.Token{}, "", http.StatusUnprocessableEntity, err
+	}
+
 	var unhashedTokenKey string
 	derivedToken := v3.Token{
 		UserPrincipal: token.UserPrincipal,
@@ -114,6 +119,7 @@ func (m *Manager) createDerivedToken(jsonInput clientv3.Token, tokenAuthValue st
 		ProviderInfo:  token.ProviderInfo,
 		Description:   jsonInput.Description,
 		ClusterName:   jsonInput.ClusterID,
+		Scope:         scope,
 	}
 	derivedToken, unhashedTokenKey, err = m.createToken(&derivedToken)
 
@@ -631,7 +637,7 @@ func (m *Manager) UserAttributeChanged(attribs *v32.UserAttribute, provider stri
 func (m *Manager) NewLoginToken(userID string, userPrincipal v3.Principal, groupPrincipals []v3.Principal, providerToken string, ttl int64, description string) (v3.Token, string, error) {
 	provider := userPrincipal.Provider
 	// Providers that use oauth need to create a secret for storing the access token.
-	if (provider == "github" || provider == "azuread" || provider == "googleoauth" || provider == "oidc" || provider == "keycloakoidc") && providerToken != "" {
+	if (provider == "github" || provider == "gitlab" || provider == "azuread" || provider == "googleoauth" || provider == "oidc" || provider == "keycloakoidc") && providerToken != "" {
 		err := m.CreateSecret(userID, provider, providerToken)
 		if err != nil {
 			return v3.Token{}, "", fmt.Errorf("unable to create secret: %s", err)
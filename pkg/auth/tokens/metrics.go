@@ -0,0 +1,37 @@
+package tokens
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	tokenCount = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Subsystem: "auth_token",
+			Name:      "tokens",
+			Help:      "Number of tokens as of the last purge run",
+		},
+	)
+
+	purgeRuns = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: "auth_token",
+			Name:      "purge_runs_total",
+			Help:      "Total count of expired token purge runs",
+		},
+	)
+
+	purgedTokens = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: "auth_token",
+			Name:      "purged_total",
+			Help:      "Total count of tokens deleted by the purge daemon by token type",
+		},
+		[]string{"type"},
+	)
+)
+
+// Collectors returns the token metrics for registration with Prometheus.
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{tokenCount, purgeRuns, purgedTokens}
+}
@@ -34,7 +34,10 @@ type purger struct {
 }
 
 func (p *purger) purge() {
+	purgeRuns.Inc()
+
 	allTokens, err := p.tokenLister.List("", labels.Everything())
+	listed := err == nil
 	if err != nil {
 		logrus.Errorf("Error listing tokens during purge: %v", err)
 	}
@@ -53,6 +56,10 @@ func (p *purger) purge() {
 	if count > 0 {
 		logrus.Infof("Purged %v expired tokens", count)
 	}
+	if listed {
+		tokenCount.Set(float64(len(allTokens) - count))
+	}
+	purgedTokens.WithLabelValues("token").Add(float64(count))
 
 	// saml tokens store encrypted token for login request from rancher cli
 	samlTokens, err := p.samlTokensLister.List(namespace.GlobalNamespace, labels.Everything())
@@ -75,4 +82,5 @@ func (p *purger) purge() {
 	if count > 0 {
 		logrus.Infof("Purged %v saml tokens", count)
 	}
+	purgedTokens.WithLabelValues("saml").Add(float64(count))
 }
@@ -0,0 +1,326 @@
+package tokens
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	clientv3 "github.com/rancher/rancher/pkg/client/generated/management/v3"
+)
+
+// ErrNamespaceCheckRequired is returned by CheckScope when a project scoped token is used for a namespaced request
+// through the cluster proxy. The request is allowed if the namespace belongs to one of the projects of the scope,
+// which only the cluster proxy can look up, see CheckScopeProject.
+var ErrNamespaceCheckRequired = errors.New("namespace must be checked against the projects of the token scope")
+
+// subresources that give write access to a workload even though they are requested with GET
+var writeSubresources = map[string]bool{
+	"attach":      true,
+	"exec":        true,
+	"portforward": true,
+	"proxy":       true,
+}
+
+// ScopeRequest is the part of a request that a token scope applies to.
+type ScopeRequest struct {
+	Method      string
+	ClusterID   string
+	ProjectID   string
+	Namespace   string
+	Group       string
+	Resource    string
+	Subresource string
+	Action      string
+	Shell       bool
+
+	// clusterProxy is set for requests to the kubernetes API of a cluster through /k8s/clusters.
+	clusterProxy bool
+	// discovery is set for requests to the root of an API, which list the available resource types.
+	discovery bool
+}
+
+// ParseScopeRequest extracts the cluster, project, namespace and resource type from the path of a norman (/v3),
+// steve (/v1), kubernetes (/api and /apis of the local cluster) or cluster proxy (/k8s/clusters) request.
+func ParseScopeRequest(req *http.Request) ScopeRequest {
+	query := req.URL.Query()
+	r := ScopeRequest{
+		Method: req.Method,
+		Action: query.Get("action"),
+		Shell:  query.Get("shell") == "true" || query.Get("link") == "shell",
+	}
+
+	var parts []string
+	for _, part := range strings.Split(req.URL.Path, "/") {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return r
+	}
+
+	switch parts[0] {
+	case "k8s":
+		if len(parts) < 3 || parts[1] != "clusters" {
+			return r
+		}
+		r.ClusterID = parts[2]
+		parseClusterProxyPath(parts[3:], &r)
+	case "v3":
+		parseNormanPath(parts[1:], &r)
+	case "v1":
+		r.ClusterID = "local"
+		parseStevePath(parts[1:], &r)
+	case "api", "apis":
+		// the kubernetes API of the local cluster, proxied by steve
+		r.ClusterID = "local"
+		parseKubernetesPath(parts, &r)
+	case "metrics":
+		if len(parts) > 1 {
+			r.ClusterID = parts[1]
+		}
+	}
+	return r
+}
+
+func parseClusterProxyPath(parts []string, r *ScopeRequest) {
+	r.clusterProxy = true
+	if len(parts) > 0 && parts[0] == "v1" {
+		// steve running in the downstream cluster
+		parseStevePath(parts[1:], r)
+		return
+	}
+	parseKubernetesPath(parts, r)
+}
+
+// parseStevePath parses the type of a steve request, which is <group>.<resource>, or only <resource> for the core
+// group.
+func parseStevePath(parts []string, r *ScopeRequest) {
+	if len(parts) == 0 {
+		r.discovery = true
+		return
+	}
+	if i := strings.LastIndex(parts[0], "."); i >= 0 {
+		r.Group = parts[0][:i]
+		r.Resource = parts[0][i+1:]
+		return
+	}
+	r.Resource = parts[0]
+}
+
+// parseKubernetesPath parses the path of a kubernetes API request, /api/<version>/... for the core group or
+// /apis/<group>/<version>/... for other groups.
+func parseKubernetesPath(parts []string, r *ScopeRequest) {
+	switch {
+	case len(parts) == 0 || parts[0] == "version" || parts[0] == "openapi":
+		r.discovery = true
+		return
+	case parts[0] == "api":
+		if len(parts) <= 2 {
+			r.discovery = true
+			return
+		}
+		parts = parts[2:]
+	case parts[0] == "apis":
+		if len(parts) <= 3 {
+			r.discovery = true
+			return
+		}
+		r.Group = parts[1]
+		parts = parts[3:]
+	default:
+		// other non-resource paths
+		return
+	}
+
+	if len(parts) >= 2 && parts[0] == "namespaces" {
+		r.Namespace = parts[1]
+		if len(parts) == 2 {
+			r.Resource = "namespaces"
+			return
+		}
+		parts = parts[2:]
+	}
+	if len(parts) > 0 {
+		r.Resource = parts[0]
+	}
+	if len(parts) > 2 {
+		r.Subresource = parts[2]
+	}
+}
+
+func parseNormanPath(parts []string, r *ScopeRequest) {
+	if len(parts) >= 2 {
+		switch parts[0] {
+		case "cluster":
+			r.ClusterID = parts[1]
+			parts = parts[2:]
+		case "project":
+			r.ProjectID = parts[1]
+			r.ClusterID = clusterOfProject(parts[1])
+			parts = parts[2:]
+		case "clusters":
+			r.ClusterID = parts[1]
+		case "projects":
+			r.ProjectID = parts[1]
+			r.ClusterID = clusterOfProject(parts[1])
+		}
+	}
+	if len(parts) > 0 {
+		r.Resource = parts[0]
+	} else {
+		r.discovery = true
+	}
+}
+
+func clusterOfProject(projectID string) string {
+	return strings.SplitN(projectID, ":", 2)[0]
+}
+
+func tokenScopeFromInput(scope *clientv3.TokenScope) *v32.TokenScope {
+	if scope == nil {
+		return nil
+	}
+	return &v32.TokenScope{
+		Clusters:  scope.Clusters,
+		Projects:  scope.Projects,
+		ReadOnly:  scope.ReadOnly,
+		Resources: scope.Resources,
+	}
+}
+
+// ValidateScope checks that a scope is well formed before a token is created with it.
+func ValidateScope(scope *v32.TokenScope) error {
+	if scope == nil {
+		return nil
+	}
+	for _, project := range scope.Projects {
+		parts := strings.SplitN(project, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("invalid project %q in token scope, must be <cluster>:<project>", project)
+		}
+		if len(scope.Clusters) > 0 && !contains(scope.Clusters, parts[0]) {
+			return fmt.Errorf("project %q in token scope belongs to a cluster that is not in the scope", project)
+		}
+	}
+	for _, resource := range scope.Resources {
+		if strings.TrimSpace(resource) == "" {
+			return errors.New("empty resource type in token scope")
+		}
+	}
+	return nil
+}
+
+// CheckScope returns an error if the request is outside of the scope of the token. Scoped tokens can not be used to
+// create other tokens, since those would not carry the scope.
+func CheckScope(scope *v32.TokenScope, r ScopeRequest) error {
+	if scope == nil {
+		return nil
+	}
+
+	if r.Method == http.MethodPost && ((r.Resource == "tokens" && r.Action == "") || r.Action == "generateKubeconfig") {
+		return errors.New("scoped tokens can not be used to create tokens")
+	}
+
+	if scope.ReadOnly && !isRead(r) {
+		return errors.New("token is read-only")
+	}
+
+	if len(scope.Resources) > 0 {
+		if r.Resource == "" {
+			// discovery is needed by clients such as kubectl, any other request must have a known resource type
+			if !r.discovery || !isRead(r) {
+				return errors.New("token is restricted to resource types")
+			}
+		} else if !resourceAllowed(scope.Resources, r) {
+			return fmt.Errorf("resource type %s is not in the token scope", r.Resource)
+		}
+	}
+
+	if len(scope.Clusters) == 0 && len(scope.Projects) == 0 {
+		return nil
+	}
+	if r.ClusterID == "" {
+		return errors.New("token is restricted to clusters")
+	}
+	if len(scope.Clusters) > 0 && !contains(scope.Clusters, r.ClusterID) {
+		return fmt.Errorf("cluster %s is not in the token scope", r.ClusterID)
+	}
+	if len(scope.Projects) == 0 {
+		return nil
+	}
+
+	switch {
+	case r.ProjectID != "":
+		if !contains(scope.Projects, r.ProjectID) {
+			return fmt.Errorf("project %s is not in the token scope", r.ProjectID)
+		}
+		return nil
+	case r.clusterProxy && r.discovery:
+		if !isRead(r) {
+			return errors.New("token is restricted to projects")
+		}
+		if !projectInCluster(scope.Projects, r.ClusterID) {
+			return fmt.Errorf("cluster %s is not in the token scope", r.ClusterID)
+		}
+		return nil
+	case r.clusterProxy && r.Namespace != "":
+		if !projectInCluster(scope.Projects, r.ClusterID) {
+			return fmt.Errorf("cluster %s is not in the token scope", r.ClusterID)
+		}
+		return ErrNamespaceCheckRequired
+	}
+	return errors.New("token is restricted to projects")
+}
+
+// CheckScopeProject returns an error if projectID, the project a namespace belongs to, is not in the scope.
+func CheckScopeProject(scope *v32.TokenScope, projectID string) error {
+	if scope == nil || len(scope.Projects) == 0 {
+		return nil
+	}
+	if projectID == "" || !contains(scope.Projects, projectID) {
+		return errors.New("namespace does not belong to a project in the token scope")
+	}
+	return nil
+}
+
+func isRead(r ScopeRequest) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return !r.Shell && !writeSubresources[r.Subresource]
+	}
+	return false
+}
+
+// resourceAllowed matches the resource type of a request against the scope by its plain name, like deployments, or
+// qualified with its group in kubernetes (deployments.apps) or steve (apps.deployments) form.
+func resourceAllowed(allowed []string, r ScopeRequest) bool {
+	for _, resource := range allowed {
+		if strings.EqualFold(resource, r.Resource) ||
+			(r.Group != "" && (strings.EqualFold(resource, r.Resource+"."+r.Group) ||
+				strings.EqualFold(resource, r.Group+"."+r.Resource))) {
+			return true
+		}
+	}
+	return false
+}
+
+func projectInCluster(projects []string, clusterID string) bool {
+	for _, project := range projects {
+		if clusterOfProject(project) == clusterID {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,209 @@
+package tokens
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckScope(t *testing.T) {
+	deployOnly := &v32.TokenScope{
+		Projects:  []string{"c-abc:p-web"},
+		Resources: []string{"deployments", "services", "workloads"},
+	}
+	readOnly := &v32.TokenScope{
+		Clusters: []string{"c-abc"},
+		ReadOnly: true,
+	}
+
+	tests := []struct {
+		name   string
+		scope  *v32.TokenScope
+		method string
+		uri    string
+		err    error
+		denied bool
+	}{
+		{
+			name:   "unscoped",
+			method: http.MethodDelete,
+			uri:    "/v3/clusters/c-abc",
+		},
+		{
+			name:   "read-only get",
+			scope:  readOnly,
+			method: http.MethodGet,
+			uri:    "/k8s/clusters/c-abc/api/v1/namespaces/default/pods",
+		},
+		{
+			name:   "read-only create",
+			scope:  readOnly,
+			method: http.MethodPost,
+			uri:    "/k8s/clusters/c-abc/api/v1/namespaces/default/pods",
+			denied: true,
+		},
+		{
+			name:   "read-only exec",
+			scope:  readOnly,
+			method: http.MethodGet,
+			uri:    "/k8s/clusters/c-abc/api/v1/namespaces/default/pods/web/exec",
+			denied: true,
+		},
+		{
+			name:   "read-only shell",
+			scope:  readOnly,
+			method: http.MethodGet,
+			uri:    "/v3/clusters/c-abc?shell=true",
+			denied: true,
+		},
+		{
+			name:   "other cluster",
+			scope:  readOnly,
+			method: http.MethodGet,
+			uri:    "/k8s/clusters/c-other/api/v1/pods",
+			denied: true,
+		},
+		{
+			name:   "global resource",
+			scope:  readOnly,
+			method: http.MethodGet,
+			uri:    "/v3/users",
+			denied: true,
+		},
+		{
+			name:   "project workloads",
+			scope:  deployOnly,
+			method: http.MethodPost,
+			uri:    "/v3/project/c-abc:p-web/workloads",
+		},
+		{
+			name:   "other project",
+			scope:  deployOnly,
+			method: http.MethodPost,
+			uri:    "/v3/project/c-abc:p-db/workloads",
+			denied: true,
+		},
+		{
+			name:   "resource not allowed",
+			scope:  deployOnly,
+			method: http.MethodGet,
+			uri:    "/v3/project/c-abc:p-web/secrets",
+			denied: true,
+		},
+		{
+			name:   "namespaced proxy request",
+			scope:  deployOnly,
+			method: http.MethodPatch,
+			uri:    "/k8s/clusters/c-abc/apis/apps/v1/namespaces/web/deployments/api",
+			err:    ErrNamespaceCheckRequired,
+		},
+		{
+			name:   "cluster scoped proxy request",
+			scope:  deployOnly,
+			method: http.MethodGet,
+			uri:    "/k8s/clusters/c-abc/api/v1/nodes",
+			denied: true,
+		},
+		{
+			name:   "discovery",
+			scope:  deployOnly,
+			method: http.MethodGet,
+			uri:    "/k8s/clusters/c-abc/apis",
+		},
+		{
+			name:   "local kubernetes API secrets",
+			scope:  &v32.TokenScope{Resources: []string{"deployments"}},
+			method: http.MethodGet,
+			uri:    "/api/v1/namespaces/x/secrets",
+			denied: true,
+		},
+		{
+			name:   "local kubernetes API deployments",
+			scope:  &v32.TokenScope{Resources: []string{"deployments"}},
+			method: http.MethodGet,
+			uri:    "/apis/apps/v1/namespaces/x/deployments",
+		},
+		{
+			name:   "local kubernetes API deployments in project scope",
+			scope:  deployOnly,
+			method: http.MethodGet,
+			uri:    "/apis/apps/v1/namespaces/x/deployments",
+			denied: true,
+		},
+		{
+			name:   "local kubernetes API discovery",
+			scope:  &v32.TokenScope{Resources: []string{"deployments"}},
+			method: http.MethodGet,
+			uri:    "/apis/apps/v1",
+		},
+		{
+			name:   "steve type with group",
+			scope:  &v32.TokenScope{Resources: []string{"deployments"}},
+			method: http.MethodGet,
+			uri:    "/v1/apps.deployments",
+		},
+		{
+			name:   "steve type with group in scope",
+			scope:  &v32.TokenScope{Resources: []string{"apps.deployments"}},
+			method: http.MethodGet,
+			uri:    "/k8s/clusters/c-abc/apis/apps/v1/deployments",
+		},
+		{
+			name:   "steve secrets",
+			scope:  &v32.TokenScope{Resources: []string{"deployments"}},
+			method: http.MethodGet,
+			uri:    "/v1/secrets",
+			denied: true,
+		},
+		{
+			name:   "unknown resource type",
+			scope:  &v32.TokenScope{Resources: []string{"deployments"}},
+			method: http.MethodGet,
+			uri:    "/k8s/clusters/c-abc/healthz",
+			denied: true,
+		},
+		{
+			name:   "create token",
+			scope:  &v32.TokenScope{Resources: []string{"tokens"}},
+			method: http.MethodPost,
+			uri:    "/v3/tokens",
+			denied: true,
+		},
+		{
+			name:   "generate kubeconfig",
+			scope:  readOnly,
+			method: http.MethodPost,
+			uri:    "/v3/clusters/c-abc?action=generateKubeconfig",
+			denied: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckScope(tt.scope, ParseScopeRequest(httptest.NewRequest(tt.method, tt.uri, nil)))
+			if tt.denied {
+				assert.Error(t, err)
+			} else {
+				assert.Equal(t, tt.err, err)
+			}
+		})
+	}
+}
+
+func TestCheckScopeProject(t *testing.T) {
+	scope := &v32.TokenScope{Projects: []string{"c-abc:p-web"}}
+	assert.NoError(t, CheckScopeProject(scope, "c-abc:p-web"))
+	assert.Error(t, CheckScopeProject(scope, "c-abc:p-db"))
+	assert.Error(t, CheckScopeProject(scope, ""))
+	assert.NoError(t, CheckScopeProject(&v32.TokenScope{Clusters: []string{"c-abc"}}, ""))
+}
+
+func TestValidateScope(t *testing.T) {
+	assert.NoError(t, ValidateScope(nil))
+	assert.NoError(t, ValidateScope(&v32.TokenScope{Clusters: []string{"c-abc"}, Projects: []string{"c-abc:p-web"}}))
+	assert.Error(t, ValidateScope(&v32.TokenScope{Projects: []string{"p-web"}}))
+	assert.Error(t, ValidateScope(&v32.TokenScope{Clusters: []string{"c-other"}, Projects: []string{"c-abc:p-web"}}))
+	assert.Error(t, ValidateScope(&v32.TokenScope{Resources: []string{" "}}))
+}
@@ -0,0 +1,102 @@
+package content
+
+import (
+	"fmt"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	v1 "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/catalogv2"
+	helmhttp "github.com/rancher/rancher/pkg/catalogv2/http"
+	"github.com/rancher/rancher/pkg/catalogv2/oci"
+	"github.com/rancher/rancher/pkg/catalogv2/verify"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"github.com/sirupsen/logrus"
+)
+
+// Verify checks the signature of a chart archive according to the verification policy of its repo. It returns
+// nil if the repo does not verify charts, and an error alongside the result if the policy requires a valid
+// signature and the chart has none.
+func (c *Manager) Verify(namespace, name, chartName, version string, chart []byte) (*v1.ChartVerification, error) {
+	repo, err := c.getRepo(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := v1.VerificationPolicyNone
+	if repo.spec.Verification != nil && repo.spec.Verification.Policy != "" {
+		policy = repo.spec.Verification.Policy
+	}
+	if policy == v1.VerificationPolicyNone {
+		return nil, nil
+	}
+
+	result := &v1.ChartVerification{
+		Chart:   chartName,
+		Version: version,
+	}
+	err = c.verify(namespace, name, repo, chart, result)
+	if err == nil {
+		result.Verified = true
+		return result, nil
+	}
+
+	result.Message = err.Error()
+	if policy == v1.VerificationPolicyRequire {
+		return result, apierror.NewAPIError(validation.PermissionDenied,
+			fmt.Sprintf("chart %s version %s failed %s verification: %s", chartName, version, result.Method, result.Message))
+	}
+	logrus.Warnf("chart %s version %s of repo %s failed %s verification: %s", chartName, version, name, result.Method, result.Message)
+	return result, nil
+}
+
+func (c *Manager) verify(namespace, name string, repo repoDef, chart []byte, result *v1.ChartVerification) error {
+	if repo.status.Commit != "" {
+		result.Method = verify.MethodPGP
+		return fmt.Errorf("provenance files are not supported for git repos")
+	}
+
+	keyring := repo.spec.Verification.KeyringSecret
+	if keyring == nil {
+		return fmt.Errorf("no keyring secret configured")
+	}
+	keyringNamespace := keyring.Namespace
+	if namespace != "" {
+		keyringNamespace = namespace
+	}
+	keys, err := c.secrets.Get(keyringNamespace, keyring.Name)
+	if err != nil {
+		return fmt.Errorf("failed to get keyring secret %s/%s: %w", keyringNamespace, keyring.Name, err)
+	}
+
+	index, err := c.Index(namespace, name)
+	if err != nil {
+		return err
+	}
+	chartVersion, err := index.Get(result.Chart, result.Version)
+	if err != nil {
+		return err
+	}
+
+	secret, err := catalogv2.GetSecret(c.secrets, repo.spec, repo.metadata.Namespace)
+	if err != nil {
+		return err
+	}
+
+	if oci.IsOCI(repo.status.URL) {
+		result.Method = verify.MethodCosign
+		signed, err := oci.Signatures(secret, repo.spec.CABundle, repo.spec.InsecureSkipTLSverify, chartVersion)
+		if err != nil {
+			return err
+		}
+		result.Signer, err = verify.Cosign(keys.Data[verify.CosignKey], chart, signed)
+		return err
+	}
+
+	result.Method = verify.MethodPGP
+	prov, err := helmhttp.Provenance(secret, repo.status.URL, repo.spec.CABundle, repo.spec.InsecureSkipTLSverify, chartVersion)
+	if err != nil {
+		return fmt.Errorf("failed to download provenance file: %w", err)
+	}
+	result.Signer, err = verify.PGP(keys.Data[verify.KeyringKey], chart, prov)
+	return err
+}
@@ -0,0 +1,109 @@
+package helm
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/rancher/rancher/pkg/api/steve/catalog/types"
+	"github.com/rancher/wrangler/pkg/yaml"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+type resourceKey struct {
+	apiVersion string
+	kind       string
+	namespace  string
+	name       string
+}
+
+// Diff compares the resources of two release manifests. Resources are matched by apiVersion, kind, namespace and
+// name, and changed resources carry the JSON merge patch turning the current resource into the rendered one.
+func Diff(current, rendered string) ([]types.ResourceDiff, error) {
+	currentObjs, err := manifestObjects(current)
+	if err != nil {
+		return nil, err
+	}
+	renderedObjs, err := manifestObjects(rendered)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []types.ResourceDiff
+	for key, obj := range renderedObjs {
+		old, ok := currentObjs[key]
+		if !ok {
+			result = append(result, resourceDiff(key, "added", ""))
+			continue
+		}
+
+		oldData, err := json.Marshal(old.Object)
+		if err != nil {
+			return nil, err
+		}
+		newData, err := json.Marshal(obj.Object)
+		if err != nil {
+			return nil, err
+		}
+		patch, err := jsonpatch.CreateMergePatch(oldData, newData)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(patch, []byte("{}")) {
+			result = append(result, resourceDiff(key, "changed", string(patch)))
+		}
+	}
+	for key := range currentObjs {
+		if _, ok := renderedObjs[key]; !ok {
+			result = append(result, resourceDiff(key, "removed", ""))
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Kind != result[j].Kind {
+			return result[i].Kind < result[j].Kind
+		}
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].APIVersion < result[j].APIVersion
+	})
+	return result, nil
+}
+
+func resourceDiff(key resourceKey, change, patch string) types.ResourceDiff {
+	return types.ResourceDiff{
+		APIVersion: key.apiVersion,
+		Kind:       key.kind,
+		Namespace:  key.namespace,
+		Name:       key.name,
+		Change:     change,
+		Patch:      patch,
+	}
+}
+
+func manifestObjects(manifest string) (map[resourceKey]*unstructured.Unstructured, error) {
+	objs, err := yaml.ToObjects(bytes.NewReader([]byte(manifest)))
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[resourceKey]*unstructured.Unstructured{}
+	for _, obj := range objs {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		result[resourceKey{
+			apiVersion: u.GetAPIVersion(),
+			kind:       u.GetKind(),
+			namespace:  u.GetNamespace(),
+			name:       u.GetName(),
+		}] = u
+	}
+	return result, nil
+}
@@ -79,6 +79,29 @@ func ToRelease(obj runtime.Object, isNamespaced IsNamespaced) (*v1.ReleaseSpec,
 	return nil, ErrNotHelmRelease
 }
 
+// ToManifest returns the rendered manifest stored in a helm 3 release secret or configmap
+func ToManifest(obj runtime.Object) (string, error) {
+	releaseData, err := getReleaseDataAndKind(obj)
+	if err != nil {
+		return "", err
+	}
+
+	meta, err := meta.Accessor(obj)
+	if err != nil {
+		return "", err
+	}
+
+	if !isHelm3(meta.GetLabels()) {
+		return "", ErrNotHelmRelease
+	}
+
+	release, err := decodeHelm3(releaseData)
+	if err != nil {
+		return "", err
+	}
+	return release.Manifest, nil
+}
+
 func getReleaseDataAndKind(obj runtime.Object) (string, error) {
 	switch t := obj.(type) {
 	case *unstructured.Unstructured:
@@ -0,0 +1,78 @@
+package helm
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"k8s.io/apimachinery/pkg/version"
+)
+
+// Render renders a chart archive the way helm install or upgrade would, without a connection to the cluster, and
+// returns the manifest helm would store in the release. Hooks are left out as they are not part of the release
+// manifest, and CRDs of the crds directory are only included on install. revision is the revision the release will
+// have, which templates can read as .Release.Revision.
+func Render(chartData []byte, releaseName, namespace string, values map[string]interface{}, isUpgrade bool, revision int, kubeVersion *version.Info) (string, error) {
+	chart, err := loader.LoadArchive(bytes.NewReader(chartData))
+	if err != nil {
+		return "", err
+	}
+
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+	if err := chartutil.ProcessDependencies(chart, values); err != nil {
+		return "", err
+	}
+
+	caps := *chartutil.DefaultCapabilities
+	if kubeVersion != nil {
+		caps.KubeVersion = chartutil.KubeVersion{
+			Version: kubeVersion.GitVersion,
+			Major:   kubeVersion.Major,
+			Minor:   kubeVersion.Minor,
+		}
+	}
+
+	renderValues, err := chartutil.ToRenderValues(chart, values, chartutil.ReleaseOptions{
+		Name:      releaseName,
+		Namespace: namespace,
+		Revision:  revision,
+		IsInstall: !isUpgrade,
+		IsUpgrade: isUpgrade,
+	}, &caps)
+	if err != nil {
+		return "", err
+	}
+
+	files, err := engine.Render(chart, renderValues)
+	if err != nil {
+		return "", err
+	}
+	for name := range files {
+		if path.Base(name) == "NOTES.txt" {
+			delete(files, name)
+		}
+	}
+
+	_, manifests, err := releaseutil.SortManifests(files, caps.APIVersions, releaseutil.InstallOrder)
+	if err != nil {
+		return "", err
+	}
+
+	b := &strings.Builder{}
+	if !isUpgrade {
+		for _, crd := range chart.CRDObjects() {
+			fmt.Fprintf(b, "---\n# Source: %s\n%s\n", crd.Name, string(crd.File.Data))
+		}
+	}
+	for _, m := range manifests {
+		fmt.Fprintf(b, "---\n# Source: %s\n%s\n", m.Name, m.Content)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,116 @@
+package helm
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/version"
+)
+
+func chartArchive(t *testing.T, files map[string]string) []byte {
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name: "app/" + name,
+			Mode: 0644,
+			Size: int64(len(content)),
+		}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+var testChart = map[string]string{
+	"Chart.yaml":  "apiVersion: v2\nname: app\nversion: 1.0.0\n",
+	"values.yaml": "replicas: 1\nservice: true\n",
+	"templates/deployment.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ .Release.Name }}
+  namespace: {{ .Release.Namespace }}
+spec:
+  replicas: {{ .Values.replicas }}
+`,
+	"templates/service.yaml": `{{- if .Values.service }}
+apiVersion: v1
+kind: Service
+metadata:
+  name: {{ .Release.Name }}
+  namespace: {{ .Release.Namespace }}
+{{- end }}
+`,
+	"templates/hook.yaml": `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: {{ .Release.Name }}-hook
+  annotations:
+    helm.sh/hook: pre-install
+`,
+	"templates/NOTES.txt": "Installed {{ .Release.Name }} on {{ .Capabilities.KubeVersion.Version }}",
+}
+
+func TestRenderAndDiff(t *testing.T) {
+	chart := chartArchive(t, testChart)
+	kubeVersion := &version.Info{GitVersion: "v1.21.3", Major: "1", Minor: "21"}
+
+	current, err := Render(chart, "app", "apps", nil, false, 1, kubeVersion)
+	require.NoError(t, err)
+	assert.Contains(t, current, "# Source: app/templates/deployment.yaml")
+	assert.Contains(t, current, "# Source: app/templates/service.yaml")
+	assert.NotContains(t, current, "kind: Job")
+	assert.NotContains(t, current, "Installed")
+
+	diff, err := Diff("", current)
+	require.NoError(t, err)
+	require.Len(t, diff, 2)
+	assert.Equal(t, "Deployment", diff[0].Kind)
+	assert.Equal(t, "added", diff[0].Change)
+	assert.Equal(t, "Service", diff[1].Kind)
+
+	rendered, err := Render(chart, "app", "apps", map[string]interface{}{
+		"replicas": 3,
+		"service":  false,
+	}, true, 2, kubeVersion)
+	require.NoError(t, err)
+
+	diff, err = Diff(current, rendered)
+	require.NoError(t, err)
+	require.Len(t, diff, 2)
+	assert.Equal(t, "Deployment", diff[0].Kind)
+	assert.Equal(t, "apps", diff[0].Namespace)
+	assert.Equal(t, "app", diff[0].Name)
+	assert.Equal(t, "changed", diff[0].Change)
+	assert.JSONEq(t, `{"spec":{"replicas":3}}`, diff[0].Patch)
+	assert.Equal(t, "Service", diff[1].Kind)
+	assert.Equal(t, "removed", diff[1].Change)
+
+	diff, err = Diff(current, current)
+	require.NoError(t, err)
+	assert.Empty(t, diff)
+}
+
+func TestRenderRevision(t *testing.T) {
+	chart := chartArchive(t, map[string]string{
+		"Chart.yaml": "apiVersion: v2\nname: app\nversion: 1.0.0\n",
+		"templates/configmap.yaml": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ .Release.Name }}
+data:
+  revision: "{{ .Release.Revision }}"
+`,
+	})
+
+	rendered, err := Render(chart, "app", "apps", nil, true, 4, nil)
+	require.NoError(t, err)
+	assert.Contains(t, rendered, `revision: "4"`)
+}
@@ -0,0 +1,182 @@
+package helmop
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+
+	types2 "github.com/rancher/rancher/pkg/api/steve/catalog/types"
+	"github.com/rancher/rancher/pkg/catalogv2/helm"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/version"
+)
+
+type dryRunChart struct {
+	chartName   string
+	version     string
+	releaseName string
+	annotations map[string]string
+	values      map[string]interface{}
+}
+
+// DryRun renders the charts of an install or upgrade request and diffs them against the deployed releases, without
+// launching an operation pod.
+func (s *Operations) DryRun(ctx context.Context, action, repoNamespace, repoName string, options io.Reader) (*types2.ChartDryRunOutput, error) {
+	var (
+		charts          []dryRunChart
+		targetNamespace string
+	)
+
+	switch action {
+	case "install":
+		installArgs := &types2.ChartInstallAction{}
+		if err := json.NewDecoder(options).Decode(installArgs); err != nil {
+			return nil, err
+		}
+		targetNamespace = installArgs.Namespace
+		for _, chart := range installArgs.Charts {
+			charts = append(charts, dryRunChart{
+				chartName:   chart.ChartName,
+				version:     chart.Version,
+				releaseName: chart.ReleaseName,
+				annotations: chart.Annotations,
+				values:      chart.Values,
+			})
+		}
+	case "upgrade":
+		upgradeArgs := &types2.ChartUpgradeAction{}
+		if err := json.NewDecoder(options).Decode(upgradeArgs); err != nil {
+			return nil, err
+		}
+		targetNamespace = upgradeArgs.Namespace
+		for _, chart := range upgradeArgs.Charts {
+			charts = append(charts, dryRunChart{
+				chartName:   chart.ChartName,
+				version:     chart.Version,
+				releaseName: chart.ReleaseName,
+				annotations: chart.Annotations,
+				values:      chart.Values,
+			})
+		}
+	default:
+		return nil, fmt.Errorf("dry run is not supported for %s", action)
+	}
+
+	kubeVersion, err := s.kubeVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	output := &types2.ChartDryRunOutput{}
+	for _, chart := range charts {
+		result, err := s.dryRunChart(ctx, repoNamespace, repoName, namespace(targetNamespace), chart, kubeVersion)
+		if err != nil {
+			return nil, err
+		}
+		output.Charts = append(output.Charts, *result)
+	}
+	return output, nil
+}
+
+func (s *Operations) dryRunChart(ctx context.Context, repoNamespace, repoName, namespace string, chart dryRunChart, kubeVersion *version.Info) (*types2.ChartDryRun, error) {
+	chartReader, err := s.contentManager.Chart(repoNamespace, repoName, chart.chartName, chart.version)
+	if err != nil {
+		return nil, err
+	}
+	chartData, err := ioutil.ReadAll(chartReader)
+	chartReader.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	// a chart the repo policy rejects fails the dry run like it fails the install
+	verification, err := s.contentManager.Verify(repoNamespace, repoName, chart.chartName, chart.version, chartData)
+	if err != nil {
+		return nil, err
+	}
+
+	chartData, err = injectAnnotation(chartData, chart.annotations)
+	if err != nil {
+		return nil, err
+	}
+
+	// helm generates the name of single chart installs without one, the chart name stands in for it
+	releaseName := chart.releaseName
+	if releaseName == "" {
+		releaseName = chart.chartName
+	}
+
+	current, revision, err := s.deployedManifest(ctx, namespace, releaseName)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := helm.Render(chartData, releaseName, namespace, chart.values, current != "", revision, kubeVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := helm.Diff(current, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types2.ChartDryRun{
+		ChartName:    chart.chartName,
+		Version:      chart.version,
+		ReleaseName:  releaseName,
+		Namespace:    namespace,
+		Manifest:     manifest,
+		Diff:         diff,
+		Verification: verification,
+	}, nil
+}
+
+// deployedManifest returns the manifest of the latest deployed revision of a release, or an empty string if the
+// release is not installed, and the revision the next install or upgrade of the release gets.
+func (s *Operations) deployedManifest(ctx context.Context, namespace, releaseName string) (string, int, error) {
+	secrets, err := s.releaseSecrets(ctx, namespace, releaseName)
+	if err != nil {
+		return "", 0, err
+	}
+
+	deployed, next := releaseRevisions(secrets)
+	if deployed < 0 {
+		// the dry run renders an install, which starts the release at revision 1
+		return "", 1, nil
+	}
+
+	manifest, err := helm.ToManifest(&secrets[deployed])
+	return manifest, next, err
+}
+
+// releaseRevisions returns the index of the latest deployed revision of the release secrets, or -1 if none is
+// deployed, and the next revision. Like helm, the next revision follows the latest one of any status, so that a
+// failed upgrade still counts.
+func releaseRevisions(secrets []corev1.Secret) (int, int) {
+	deployed, deployedRevision, latestRevision := -1, 0, 0
+	for i, secret := range secrets {
+		revision, err := strconv.Atoi(secret.Labels["version"])
+		if err != nil {
+			continue
+		}
+		if revision > latestRevision {
+			latestRevision = revision
+		}
+		if secret.Labels["status"] == "deployed" && revision > deployedRevision {
+			deployed, deployedRevision = i, revision
+		}
+	}
+	return deployed, latestRevision + 1
+}
+
+func (s *Operations) kubeVersion() (*version.Info, error) {
+	client, err := s.cg.AdminK8sInterface()
+	if err != nil {
+		return nil, err
+	}
+	return client.Discovery().ServerVersion()
+}
@@ -0,0 +1,40 @@
+package helmop
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestReleaseRevisions(t *testing.T) {
+	release := func(revision, status string) corev1.Secret {
+		return corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					"version": revision,
+					"status":  status,
+				},
+			},
+		}
+	}
+
+	deployed, next := releaseRevisions(nil)
+	assert.Equal(t, -1, deployed)
+	assert.Equal(t, 1, next)
+
+	deployed, next = releaseRevisions([]corev1.Secret{
+		release("1", "superseded"),
+		release("3", "failed"),
+		release("2", "deployed"),
+	})
+	assert.Equal(t, 2, deployed)
+	assert.Equal(t, 4, next)
+
+	deployed, next = releaseRevisions([]corev1.Secret{
+		release("1", "uninstalled"),
+	})
+	assert.Equal(t, -1, deployed)
+	assert.Equal(t, 2, next)
+}
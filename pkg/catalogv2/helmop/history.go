@@ -0,0 +1,77 @@
+package helmop
+
+import (
+	"context"
+	"sort"
+
+	"github.com/rancher/apiserver/pkg/types"
+	types2 "github.com/rancher/rancher/pkg/api/steve/catalog/types"
+	"github.com/rancher/rancher/pkg/catalogv2/helm"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// History lists the revisions of the release of an app, newest first. The release secrets are read with the
+// credentials of the user, so only users that could run helm history themselves see it.
+func (s *Operations) History(ctx context.Context, namespace, name string) (*types2.ReleaseHistory, error) {
+	rel, err := s.apps.Get(namespace, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	secrets, err := s.releaseSecrets(ctx, namespace, rel.Spec.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	history := &types2.ReleaseHistory{}
+	for i := range secrets {
+		spec, err := helm.ToRelease(&secrets[i], nil)
+		if err != nil {
+			logrus.Errorf("Failed to decode helm release secret %s/%s: %v", namespace, secrets[i].Name, err)
+			continue
+		}
+
+		revision := types2.ReleaseRevision{
+			Revision: spec.Version,
+		}
+		if spec.Info != nil {
+			revision.Status = string(spec.Info.Status)
+			revision.Description = spec.Info.Description
+			revision.Updated = spec.Info.LastDeployed
+		}
+		if spec.Chart != nil && spec.Chart.Metadata != nil {
+			revision.ChartName = spec.Chart.Metadata.Name
+			revision.ChartVersion = spec.Chart.Metadata.Version
+			revision.AppVersion = spec.Chart.Metadata.AppVersion
+		}
+		history.Revisions = append(history.Revisions, revision)
+	}
+
+	sort.Slice(history.Revisions, func(i, j int) bool {
+		return history.Revisions[i].Revision > history.Revisions[j].Revision
+	})
+
+	return history, nil
+}
+
+// releaseSecrets lists the helm 3 release secrets of a release with the credentials of the user
+func (s *Operations) releaseSecrets(ctx context.Context, namespace, releaseName string) ([]corev1.Secret, error) {
+	client, err := s.cg.K8sInterface(types.GetAPIContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	secrets, err := client.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{
+			"owner": "helm",
+			"name":  releaseName,
+		}).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return secrets.Items, nil
+}
@@ -0,0 +1,48 @@
+package helmop
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	operationsStarted = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: "helm_operation",
+			Name:      "started_total",
+			Help:      "Total count of helm operations by action and whether the operation pod could be launched",
+		},
+		[]string{"action", "result"},
+	)
+
+	operationsCompleted = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: "helm_operation",
+			Name:      "completed_total",
+			Help:      "Total count of finished helm operations by action and outcome (success or failure)",
+		},
+		[]string{"action", "outcome"},
+	)
+)
+
+// Collectors returns the helm operation metrics for registration with Prometheus.
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{operationsStarted, operationsCompleted}
+}
+
+// RecordOperationCompleted counts a finished operation. It is called by the operation controller once the helm
+// container of the operation pod terminates.
+func RecordOperationCompleted(action string, success bool) {
+	outcome := "success"
+	if !success {
+		outcome = "failure"
+	}
+	operationsCompleted.WithLabelValues(action, outcome).Inc()
+}
+
+func recordOperationStarted(action string, err error) {
+	result := "launched"
+	if err != nil {
+		result = "failed"
+	}
+	operationsStarted.WithLabelValues(action, result).Inc()
+}
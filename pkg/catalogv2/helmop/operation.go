@@ -14,6 +14,7 @@
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 	"unicode/utf8"
@@ -96,7 +97,9 @@ func NewOperations(
 	}
 }
 
-func (s *Operations) Uninstall(ctx context.Context, user user.Info, namespace, name string, options io.Reader) (*catalog.Operation, error) {
+func (s *Operations) Uninstall(ctx context.Context, user user.Info, namespace, name string, options io.Reader) (op *catalog.Operation, err error) {
+	defer func() { recordOperationStarted("uninstall", err) }()
+
 	status, cmds, err := s.getUninstallArgs(namespace, name, options)
 	if err != nil {
 		return nil, err
@@ -110,7 +113,25 @@ func (s *Operations) Uninstall(ctx context.Context, user user.Info, namespace, n
 	return s.createOperation(ctx, user, status, cmds)
 }
 
-func (s *Operations) Upgrade(ctx context.Context, user user.Info, namespace, name string, options io.Reader) (*catalog.Operation, error) {
+func (s *Operations) Rollback(ctx context.Context, user user.Info, namespace, name string, options io.Reader) (op *catalog.Operation, err error) {
+	defer func() { recordOperationStarted("rollback", err) }()
+
+	status, cmds, err := s.getRollbackArgs(namespace, name, options)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err = s.getUser(user, namespace, name, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.createOperation(ctx, user, status, cmds)
+}
+
+func (s *Operations) Upgrade(ctx context.Context, user user.Info, namespace, name string, options io.Reader) (op *catalog.Operation, err error) {
+	defer func() { recordOperationStarted("upgrade", err) }()
+
 	status, cmds, err := s.getUpgradeCommand(namespace, name, options)
 	if err != nil {
 		return nil, err
@@ -124,7 +145,9 @@ func (s *Operations) Upgrade(ctx context.Context, user user.Info, namespace, nam
 	return s.createOperation(ctx, user, status, cmds)
 }
 
-func (s *Operations) Install(ctx context.Context, user user.Info, namespace, name string, options io.Reader) (*catalog.Operation, error) {
+func (s *Operations) Install(ctx context.Context, user user.Info, namespace, name string, options io.Reader) (op *catalog.Operation, err error) {
+	defer func() { recordOperationStarted("install", err) }()
+
 	status, cmds, err := s.getInstallCommand(namespace, name, options)
 	if err != nil {
 		return nil, err
@@ -294,6 +317,42 @@ func (s *Operations) getUninstallArgs(appNamespace, appName string, body io.Read
 	return status, Commands{cmd}, nil
 }
 
+func (s *Operations) getRollbackArgs(appNamespace, appName string, body io.Reader) (catalog.OperationStatus, Commands, error) {
+	rel, err := s.apps.Get(appNamespace, appName, metav1.GetOptions{})
+	if err != nil {
+		return catalog.OperationStatus{}, nil, err
+	}
+
+	rollbackArgs := &types2.ChartRollbackAction{}
+	if err := json.NewDecoder(body).Decode(rollbackArgs); err != nil {
+		return catalog.OperationStatus{}, nil, err
+	}
+	if rollbackArgs.Revision < 0 || (rollbackArgs.Revision > 0 && rollbackArgs.Revision >= rel.Spec.Version) {
+		return catalog.OperationStatus{}, nil, validation.ErrorCode{
+			Code:   "InvalidRevision",
+			Status: http.StatusUnprocessableEntity,
+		}
+	}
+
+	cmd := Command{
+		Operation: "rollback",
+		ArgObjects: []interface{}{
+			rollbackArgs,
+		},
+		ReleaseName:      rel.Spec.Name,
+		ReleaseNamespace: rel.Namespace,
+		Revision:         rollbackArgs.Revision,
+	}
+
+	status := catalog.OperationStatus{
+		Action:    cmd.Operation,
+		Release:   rel.Spec.Name,
+		Namespace: appNamespace,
+	}
+
+	return status, Commands{cmd}, nil
+}
+
 func (s *Operations) getUpgradeCommand(repoNamespace, repoName string, body io.Reader) (catalog.OperationStatus, Commands, error) {
 	var (
 		upgradeArgs = &types2.ChartUpgradeAction{}
@@ -342,6 +401,8 @@ type Command struct {
 	Chart            []byte
 	ReleaseName      string
 	ReleaseNamespace string
+	Revision         int
+	Verification     *catalog.ChartVerification
 }
 
 type Commands []Command
@@ -419,6 +480,7 @@ func (c Command) renderArgs() ([]string, error) {
 	delete(dataMap, "releaseName")
 	delete(dataMap, "chartName")
 	delete(dataMap, "projectId")
+	delete(dataMap, "revision")
 	if v, ok := dataMap["disableOpenAPIValidation"]; ok {
 		delete(dataMap, "disableOpenAPIValidation")
 		dataMap["disableOpenapiValidation"] = v
@@ -447,6 +509,9 @@ func (c Command) renderArgs() ([]string, error) {
 	if c.ReleaseName != "" {
 		args = append(args, c.ReleaseName)
 	}
+	if c.Revision > 0 {
+		args = append(args, strconv.Itoa(c.Revision))
+	}
 	if len(c.Chart) > 0 {
 		args = append(args, filepath.Join(helmDataPath, c.ChartFile))
 	}
@@ -545,15 +610,21 @@ func (s *Operations) getChartCommand(namespace, name, chartName, chartVersion st
 		return Command{}, err
 	}
 
+	verification, err := s.contentManager.Verify(namespace, name, chartName, chartVersion, chartData)
+	if err != nil {
+		return Command{}, err
+	}
+
 	chartData, err = injectAnnotation(chartData, annotations)
 	if err != nil {
 		return Command{}, err
 	}
 
 	c := Command{
-		ValuesFile: fmt.Sprintf("values-%s-%s.yaml", chartName, sanitizeVersion(chartVersion)),
-		ChartFile:  fmt.Sprintf("%s-%s.tgz", chartName, sanitizeVersion(chartVersion)),
-		Chart:      chartData,
+		ValuesFile:   fmt.Sprintf("values-%s-%s.yaml", chartName, sanitizeVersion(chartVersion)),
+		ChartFile:    fmt.Sprintf("%s-%s.tgz", chartName, sanitizeVersion(chartVersion)),
+		Chart:        chartData,
+		Verification: verification,
 	}
 
 	if len(values) > 0 {
@@ -624,7 +695,7 @@ func namespace(ns string) string {
 }
 
 func (s *Operations) createOperation(ctx context.Context, user user.Info, status catalog.OperationStatus, cmds Commands) (*catalog.Operation, error) {
-	if status.Action != "uninstall" {
+	if status.Action != "uninstall" && status.Action != "rollback" {
 		_, err := s.createNamespace(ctx, status.Namespace, status.ProjectID)
 		if err != nil {
 			return nil, err
@@ -647,6 +718,12 @@ func (s *Operations) createOperation(ctx context.Context, user user.Info, status
 		return nil, err
 	}
 
+	for _, cmd := range cmds {
+		if cmd.Verification != nil {
+			status.Verifications = append(status.Verifications, *cmd.Verification)
+		}
+	}
+
 	status.Token = pod.Labels[podimpersonation.TokenLabel]
 	status.PodName = pod.Name
 	status.PodNamespace = pod.Namespace
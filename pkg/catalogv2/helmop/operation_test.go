@@ -0,0 +1,47 @@
+package helmop
+
+import (
+	"testing"
+	"time"
+
+	types2 "github.com/rancher/rancher/pkg/api/steve/catalog/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRollbackArgs(t *testing.T) {
+	cmd := Command{
+		Operation: "rollback",
+		ArgObjects: []interface{}{
+			&types2.ChartRollbackAction{
+				Revision:      3,
+				Timeout:       &metav1.Duration{Duration: 5 * time.Minute},
+				Wait:          true,
+				CleanupOnFail: true,
+			},
+		},
+		ReleaseName:      "app",
+		ReleaseNamespace: "apps",
+		Revision:         3,
+	}
+
+	args, err := cmd.renderArgs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"rollback",
+		"--cleanup-on-fail=true",
+		"--namespace=apps",
+		"--timeout=5m0s",
+		"--wait=true",
+		"app",
+		"3",
+	}, args)
+
+	// the previous revision is the default of helm
+	cmd.ArgObjects = []interface{}{&types2.ChartRollbackAction{}}
+	cmd.Revision = 0
+	args, err = cmd.renderArgs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"rollback", "--namespace=apps", "app"}, args)
+}
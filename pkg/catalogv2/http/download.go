@@ -76,7 +76,59 @@ func Chart(secret *corev1.Secret, repoURL string, caBundle []byte, insecureSkipT
 	}
 	defer client.CloseIdleConnections()
 
-	u, err := url.Parse(chart.URLs[0])
+	u, err := chartURL(repoURL, chart.URLs[0])
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	return ioutil.NopCloser(bytes.NewBuffer(data)), err
+}
+
+// Provenance returns the provenance file helm publishes next to the chart archive, at the URL of the chart
+// with a .prov extension.
+func Provenance(secret *corev1.Secret, repoURL string, caBundle []byte, insecureSkipTLSVerify bool, chart *repo.ChartVersion) ([]byte, error) {
+	if len(chart.URLs) == 0 {
+		return nil, fmt.Errorf("failed to find chartName %s version %s: %w", chart.Name, chart.Version, validation.NotFound)
+	}
+
+	client, err := HelmClient(secret, caBundle, insecureSkipTLSVerify)
+	if err != nil {
+		return nil, err
+	}
+	defer client.CloseIdleConnections()
+
+	u, err := chartURL(repoURL, chart.URLs[0])
+	if err != nil {
+		return nil, err
+	}
+	u.Path += ".prov"
+	u.RawPath = ""
+
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		defer ioutil.ReadAll(resp.Body)
+		return nil, validation.ErrorCode{
+			Status: resp.StatusCode,
+		}
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+func chartURL(repoURL, ref string) (*url.URL, error) {
+	u, err := url.Parse(ref)
 	if err != nil {
 		return nil, err
 	}
@@ -93,15 +145,7 @@ func Chart(secret *corev1.Secret, repoURL string, caBundle []byte, insecureSkipT
 		// contain an access credential.
 		u.RawQuery = base.RawQuery
 	}
-
-	resp, err := client.Get(u.String())
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	data, err := ioutil.ReadAll(resp.Body)
-	return ioutil.NopCloser(bytes.NewBuffer(data)), err
+	return u, nil
 }
 
 func DownloadIndex(secret *corev1.Secret, repoURL string, caBundle []byte, insecureSkipTLSVerify bool) (*repo.IndexFile, error) {
@@ -0,0 +1,197 @@
+package oci
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rancher/rancher/pkg/registryauth"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// client talks to the distribution API of a single registry. It answers the basic and bearer authentication
+// challenges of the registry with the credentials of the repo, if any.
+type client struct {
+	http     *http.Client
+	host     string
+	username string
+	password string
+	insecure bool
+
+	lock   sync.Mutex
+	tokens map[string]string
+}
+
+func newClient(secret *corev1.Secret, host string, caBundle []byte, insecureSkipTLSVerify bool) (*client, error) {
+	var (
+		username  string
+		password  string
+		tlsConfig = &tls.Config{
+			InsecureSkipVerify: insecureSkipTLSVerify,
+		}
+	)
+
+	if secret != nil {
+		switch secret.Type {
+		case corev1.SecretTypeBasicAuth:
+			username = string(secret.Data[corev1.BasicAuthUsernameKey])
+			password = string(secret.Data[corev1.BasicAuthPasswordKey])
+		case corev1.SecretTypeTLS:
+			cert, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+			if err != nil {
+				return nil, err
+			}
+			tlsConfig.Certificates = append(tlsConfig.Certificates, cert)
+		}
+	}
+
+	if len(caBundle) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		// accept a PEM bundle as well as the single DER certificate http repos expect
+		if !pool.AppendCertsFromPEM(caBundle) {
+			cert, err := x509.ParseCertificate(caBundle)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse CA bundle: %w", err)
+			}
+			pool.AddCert(cert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &client{
+		http: &http.Client{
+			Transport: transport,
+			Timeout:   30 * time.Second,
+		},
+		host:     host,
+		username: username,
+		password: password,
+		insecure: insecureSkipTLSVerify,
+		tokens:   map[string]string{},
+	}, nil
+}
+
+func (c *client) url(path string) string {
+	return "https://" + c.host + path
+}
+
+// get calls path on the registry, authenticating if the registry asks to. scope is the token scope the request
+// needs, the one of the challenge is used if it is empty.
+func (c *client) get(method, path, scope string, accept ...string) (*http.Response, error) {
+	resp, err := c.do(method, c.url(path), accept, func(req *http.Request) {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		if token := c.tokens[scope]; token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	})
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	resp.Body.Close()
+
+	scheme, params := registryauth.ParseChallenge(resp.Header.Get("WWW-Authenticate"))
+	switch scheme {
+	case "basic":
+		if c.username == "" && c.password == "" {
+			return nil, validation.Unauthorized
+		}
+		return c.do(method, c.url(path), accept, func(req *http.Request) {
+			req.SetBasicAuth(c.username, c.password)
+		})
+	case "bearer":
+		if scope == "" {
+			scope = params["scope"]
+		}
+		token, err := c.token(params["realm"], params["service"], scope)
+		if err != nil {
+			return nil, err
+		}
+		c.lock.Lock()
+		c.tokens[scope] = token
+		c.lock.Unlock()
+		return c.do(method, c.url(path), accept, func(req *http.Request) {
+			req.Header.Set("Authorization", "Bearer "+token)
+		})
+	default:
+		return nil, fmt.Errorf("unsupported authentication challenge [%s] from %s", resp.Header.Get("WWW-Authenticate"), c.host)
+	}
+}
+
+func (c *client) do(method, url string, accept []string, modify func(*http.Request)) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(accept) > 0 {
+		req.Header.Set("Accept", strings.Join(accept, ", "))
+	}
+	modify(req)
+	return c.http.Do(req)
+}
+
+// token requests a token from the realm of a bearer challenge. The realm is named by the registry, a repo with
+// credentials fails rather than sending them to a realm rejected by registryauth.CheckRealm.
+func (c *client) token(realm, service, scope string) (string, error) {
+	u, err := registryauth.ParseRealm(realm)
+	if err != nil {
+		return "", fmt.Errorf("%v from %s", err, c.host)
+	}
+	credentials := c.username != "" || c.password != ""
+	if credentials {
+		if err := registryauth.CheckRealm(&url.URL{Scheme: "https", Host: c.host}, u, c.insecure); err != nil {
+			return "", err
+		}
+	}
+	query := u.Query()
+	if service != "" {
+		query.Set("service", service)
+	}
+	if scope != "" {
+		query.Set("scope", scope)
+	}
+	u.RawQuery = query.Encode()
+
+	resp, err := c.do(http.MethodGet, u.String(), nil, func(req *http.Request) {
+		if credentials {
+			req.SetBasicAuth(c.username, c.password)
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, _ = ioutil.ReadAll(resp.Body)
+		return "", validation.ErrorCode{
+			Status: resp.StatusCode,
+		}
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to parse token response from %s: %w", u.Host, err)
+	}
+	if token.Token != "" {
+		return token.Token, nil
+	}
+	return token.AccessToken, nil
+}
@@ -0,0 +1,107 @@
+package oci
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strings"
+
+	helmhttp "github.com/rancher/rancher/pkg/catalogv2/http"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"github.com/sirupsen/logrus"
+	"helm.sh/helm/v3/pkg/repo"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const scheme = "oci"
+
+// IsOCI returns whether the repo URL points to an OCI registry rather than a http index
+func IsOCI(repoURL string) bool {
+	u, err := url.Parse(repoURL)
+	return err == nil && u.Scheme == scheme
+}
+
+// parseRef splits an oci:// URL into the registry host, the repository path and the tag, if any.
+func parseRef(ref string) (string, string, string, error) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", "", "", err
+	}
+	if u.Scheme != scheme || u.Host == "" {
+		return "", "", "", fmt.Errorf("invalid OCI URL [%s]", ref)
+	}
+
+	var (
+		name = strings.Trim(u.Path, "/")
+		tag  string
+	)
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		name, tag = name[:i], name[i+1:]
+	}
+	return u.Host, name, tag, nil
+}
+
+func Icon(secret *corev1.Secret, repoURL string, caBundle []byte, insecureSkipTLSVerify bool, chart *repo.ChartVersion) (io.ReadCloser, string, error) {
+	// Charts in a registry carry no files beside the chart archive, so only icons hosted elsewhere can be served.
+	// The credentials of the registry are not sent to that host.
+	u, err := url.Parse(chart.Icon)
+	if err != nil || !u.IsAbs() || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, "", fmt.Errorf("failed to find icon of chartName %s version %s: %w", chart.Name, chart.Version, validation.NotFound)
+	}
+	return helmhttp.Icon(nil, repoURL, nil, false, chart)
+}
+
+func Chart(secret *corev1.Secret, repoURL string, caBundle []byte, insecureSkipTLSVerify bool, chart *repo.ChartVersion) (io.ReadCloser, error) {
+	if len(chart.URLs) == 0 {
+		return nil, fmt.Errorf("failed to find chartName %s version %s: %w", chart.Name, chart.Version, validation.NotFound)
+	}
+
+	host, name, tag, err := parseRef(chart.URLs[0])
+	if err != nil {
+		return nil, err
+	}
+	if tag == "" {
+		return nil, fmt.Errorf("failed to find tag of chartName %s version %s in [%s]", chart.Name, chart.Version, chart.URLs[0])
+	}
+
+	client, err := newClient(secret, host, caBundle, insecureSkipTLSVerify)
+	if err != nil {
+		return nil, err
+	}
+	defer client.http.CloseIdleConnections()
+
+	m, err := client.manifest(name, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	layer, err := chartLayer(m)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", chart.URLs[0], err)
+	}
+
+	data, err := client.verifiedBlob(name, layer)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.NopCloser(bytes.NewBuffer(data)), nil
+}
+
+func DownloadIndex(secret *corev1.Secret, repoURL string, caBundle []byte, insecureSkipTLSVerify bool) (*repo.IndexFile, error) {
+	host, path, _, err := parseRef(repoURL)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newClient(secret, host, caBundle, insecureSkipTLSVerify)
+	if err != nil {
+		return nil, err
+	}
+	defer client.http.CloseIdleConnections()
+
+	logrus.Infof("Building repo index from tags of %s", repoURL)
+	return client.buildIndex(repoURL, path)
+}
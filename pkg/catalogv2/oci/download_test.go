@@ -0,0 +1,226 @@
+package oci
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/repo"
+	corev1 "k8s.io/api/core/v1"
+)
+
+type blob struct {
+	digest string
+	data   []byte
+}
+
+func newBlob(data []byte) blob {
+	sum := sha256.Sum256(data)
+	return blob{
+		digest: "sha256:" + hex.EncodeToString(sum[:]),
+		data:   data,
+	}
+}
+
+// fakeRegistry serves the charts/app repository with bearer authentication
+func fakeRegistry(t *testing.T, tags map[string]*chart.Metadata, content []byte) (*httptest.Server, map[string]int) {
+	var (
+		requests  = map[string]int{}
+		blobs     = map[string]blob{}
+		manifests = map[string]blob{}
+		layer     = newBlob(content)
+	)
+	blobs[layer.digest] = layer
+
+	for tag, metadata := range tags {
+		data, err := json.Marshal(metadata)
+		require.NoError(t, err)
+		config := newBlob(data)
+		blobs[config.digest] = config
+
+		data, err = json.Marshal(manifest{
+			Config: descriptor{MediaType: helmConfigType, Digest: config.digest, Size: int64(len(config.data))},
+			Layers: []descriptor{{MediaType: helmChartLayerType, Digest: layer.digest, Size: int64(len(layer.data))}},
+		})
+		require.NoError(t, err)
+		manifests[tag] = newBlob(data)
+		manifests[manifests[tag].digest] = manifests[tag]
+	}
+
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/token" {
+			user, pass, ok := req.BasicAuth()
+			if !ok || user != "user" || pass != "pass" || req.URL.Query().Get("scope") != "repository:charts/app:pull" {
+				rw.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = rw.Write([]byte(`{"token": "secret-token"}`))
+			return
+		}
+
+		if req.Header.Get("Authorization") != "Bearer secret-token" {
+			rw.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:charts/app:pull"`, server.URL))
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		requests[req.Method+" "+req.URL.Path]++
+		switch {
+		case req.URL.Path == "/v2/charts/app/tags/list":
+			var names []string
+			for tag := range tags {
+				names = append(names, tag)
+			}
+			_ = json.NewEncoder(rw).Encode(map[string]interface{}{"name": "charts/app", "tags": append(names, "latest")})
+		case strings.HasPrefix(req.URL.Path, "/v2/charts/app/manifests/"):
+			m, ok := manifests[strings.TrimPrefix(req.URL.Path, "/v2/charts/app/manifests/")]
+			if !ok {
+				rw.WriteHeader(http.StatusNotFound)
+				return
+			}
+			rw.Header().Set("Docker-Content-Digest", m.digest)
+			rw.Header().Set("Content-Type", manifestMediaType)
+			if req.Method == http.MethodGet {
+				_, _ = rw.Write(m.data)
+			}
+		case strings.HasPrefix(req.URL.Path, "/v2/charts/app/blobs/"):
+			b, ok := blobs[strings.TrimPrefix(req.URL.Path, "/v2/charts/app/blobs/")]
+			if !ok {
+				rw.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = rw.Write(b.data)
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return server, requests
+}
+
+func caBundle(server *httptest.Server) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+}
+
+func basicAuth() *corev1.Secret {
+	return &corev1.Secret{
+		Type: corev1.SecretTypeBasicAuth,
+		Data: map[string][]byte{
+			corev1.BasicAuthUsernameKey: []byte("user"),
+			corev1.BasicAuthPasswordKey: []byte("pass"),
+		},
+	}
+}
+
+func TestIsOCI(t *testing.T) {
+	assert.True(t, IsOCI("oci://harbor.example.com/charts"))
+	assert.False(t, IsOCI("https://charts.example.com"))
+	assert.False(t, IsOCI(""))
+}
+
+func TestDownloadIndexAndChart(t *testing.T) {
+	content := []byte("chart archive")
+	server, requests := fakeRegistry(t, map[string]*chart.Metadata{
+		"1.0.0":       {APIVersion: "v2", Name: "app", Version: "1.0.0"},
+		"1.1.0_build": {APIVersion: "v2", Name: "app", Version: "1.1.0+build"},
+	}, content)
+	defer server.Close()
+
+	repoURL := "oci://" + strings.TrimPrefix(server.URL, "https://") + "/charts/app"
+	index, err := DownloadIndex(basicAuth(), repoURL, caBundle(server), false)
+	require.NoError(t, err)
+
+	versions := index.Entries["app"]
+	require.Len(t, versions, 2)
+	assert.Equal(t, "1.1.0+build", versions[0].Version)
+	assert.Equal(t, repoURL+":1.1.0_build", versions[0].URLs[0])
+	assert.Equal(t, newBlob(content).digest, versions[0].Digest)
+	assert.Equal(t, "1.0.0", versions[1].Version)
+	assert.Equal(t, 2, requests["GET /v2/charts/app/manifests/1.0.0"]+requests["GET /v2/charts/app/manifests/1.1.0_build"])
+
+	// unchanged tags are not fetched again
+	_, err = DownloadIndex(basicAuth(), repoURL, caBundle(server), false)
+	require.NoError(t, err)
+	assert.Equal(t, 2, requests["GET /v2/charts/app/manifests/1.0.0"]+requests["GET /v2/charts/app/manifests/1.1.0_build"])
+	assert.Equal(t, 4, requests["HEAD /v2/charts/app/manifests/1.0.0"]+requests["HEAD /v2/charts/app/manifests/1.1.0_build"])
+
+	reader, err := Chart(basicAuth(), repoURL, caBundle(server), false, versions[1])
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal(content, data))
+}
+
+func TestDownloadIndexUnauthorized(t *testing.T) {
+	server, _ := fakeRegistry(t, map[string]*chart.Metadata{
+		"1.0.0": {APIVersion: "v2", Name: "app", Version: "1.0.0"},
+	}, []byte("chart archive"))
+	defer server.Close()
+
+	repoURL := "oci://" + strings.TrimPrefix(server.URL, "https://") + "/charts/app"
+	_, err := DownloadIndex(nil, repoURL, caBundle(server), false)
+	assert.Error(t, err)
+}
+
+func TestChartMissingTag(t *testing.T) {
+	server, _ := fakeRegistry(t, map[string]*chart.Metadata{
+		"1.0.0": {APIVersion: "v2", Name: "app", Version: "1.0.0"},
+	}, []byte("chart archive"))
+	defer server.Close()
+
+	repoURL := "oci://" + strings.TrimPrefix(server.URL, "https://") + "/charts/app"
+	_, err := Chart(basicAuth(), repoURL, caBundle(server), false, &repo.ChartVersion{
+		Metadata: &chart.Metadata{Name: "app", Version: "1.0.0"},
+		URLs:     []string{repoURL + ":2.0.0"},
+	})
+	assert.Error(t, err)
+}
+
+func TestSignaturesUnsigned(t *testing.T) {
+	content := []byte("chart archive")
+	server, requests := fakeRegistry(t, map[string]*chart.Metadata{
+		"1.0.0": {APIVersion: "v2", Name: "app", Version: "1.0.0"},
+	}, content)
+	defer server.Close()
+
+	repoURL := "oci://" + strings.TrimPrefix(server.URL, "https://") + "/charts/app"
+	signed, err := Signatures(basicAuth(), caBundle(server), false, &repo.ChartVersion{
+		Metadata: &chart.Metadata{Name: "app", Version: "1.0.0"},
+		URLs:     []string{repoURL + ":1.0.0"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, newBlob(content).digest, signed.ChartDigest)
+	assert.Empty(t, signed.Signatures)
+	assert.Equal(t, 1, requests["GET /v2/charts/app/manifests/"+signed.ManifestDigest])
+	assert.Equal(t, 1, requests["GET /v2/charts/app/manifests/"+strings.Replace(signed.ManifestDigest, ":", "-", 1)+".sig"])
+}
+
+func TestTokenForeignRealm(t *testing.T) {
+	var realmRequests int
+	realm := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		realmRequests++
+		_, _ = rw.Write([]byte(`{"token": "secret-token"}`))
+	}))
+	defer realm.Close()
+	registry := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry"`, realm.URL))
+		rw.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer registry.Close()
+
+	_, err := DownloadIndex(basicAuth(), "oci://"+strings.TrimPrefix(registry.URL, "https://")+"/charts/app", caBundle(registry), true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is not served by the registry host")
+	assert.Equal(t, 0, realmRequests)
+}
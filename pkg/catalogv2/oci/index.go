@@ -0,0 +1,334 @@
+package oci
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"github.com/sirupsen/logrus"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+const (
+	manifestMediaType  = "application/vnd.oci.image.manifest.v1+json"
+	helmConfigType     = "application/vnd.cncf.helm.config.v1+json"
+	helmChartLayerType = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+)
+
+var (
+	errNotChart = errors.New("not a helm chart")
+
+	nextLink = regexp.MustCompile(`<([^>]+)>\s*;\s*rel="?next"?`)
+
+	// chartCache holds the chart versions of the last index built for each repo, keyed by the digest of their
+	// manifest, so that only new or re-pushed tags are fetched on refresh.
+	chartCacheLock sync.Mutex
+	chartCache     = map[string]map[string]*repo.ChartVersion{}
+)
+
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type manifest struct {
+	Config      descriptor        `json:"config"`
+	Layers      []descriptor      `json:"layers"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+func isNotFound(err error) bool {
+	var code validation.ErrorCode
+	return errors.As(err, &code) && code.Status == http.StatusNotFound
+}
+
+func pullScope(name string) string {
+	return "repository:" + name + ":pull"
+}
+
+// repositories returns the repositories of the repo URL: the path itself if it is a chart repository, or the ones
+// under it in the catalog of the registry otherwise.
+func (c *client) repositories(path string) ([]string, map[string][]string, error) {
+	tags, err := c.tags(path)
+	if err == nil {
+		return []string{path}, map[string][]string{path: tags}, nil
+	} else if !isNotFound(err) {
+		return nil, nil, err
+	}
+
+	var (
+		names  []string
+		prefix = path + "/"
+		result = map[string][]string{}
+	)
+	if path == "" {
+		prefix = ""
+	}
+
+	err = c.list("/v2/_catalog", "registry:catalog:*", func(body []byte) error {
+		var catalog struct {
+			Repositories []string `json:"repositories"`
+		}
+		if err := json.Unmarshal(body, &catalog); err != nil {
+			return err
+		}
+		for _, name := range catalog.Repositories {
+			if strings.HasPrefix(name, prefix) {
+				names = append(names, name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, name := range names {
+		tags, err := c.tags(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		result[name] = tags
+	}
+	return names, result, nil
+}
+
+func (c *client) tags(name string) ([]string, error) {
+	if name == "" {
+		return nil, validation.NotFound
+	}
+
+	var tags []string
+	err := c.list("/v2/"+name+"/tags/list", pullScope(name), func(body []byte) error {
+		var list struct {
+			Tags []string `json:"tags"`
+		}
+		if err := json.Unmarshal(body, &list); err != nil {
+			return err
+		}
+		tags = append(tags, list.Tags...)
+		return nil
+	})
+	return tags, err
+}
+
+// list calls a paginated endpoint of the registry, following the Link header to the next page.
+func (c *client) list(path, scope string, page func([]byte) error) error {
+	for path != "" {
+		resp, err := c.get(http.MethodGet, path, scope)
+		if err != nil {
+			return err
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return validation.ErrorCode{
+				Status: resp.StatusCode,
+			}
+		}
+		if err := page(body); err != nil {
+			return fmt.Errorf("failed to parse response from %s%s: %w", c.host, path, err)
+		}
+
+		path = ""
+		if match := nextLink.FindStringSubmatch(resp.Header.Get("Link")); match != nil {
+			path = match[1]
+		}
+	}
+	return nil
+}
+
+// digest returns the digest of the manifest of a tag without downloading it
+func (c *client) digest(name, tag string) (string, error) {
+	resp, err := c.get(http.MethodHead, "/v2/"+name+"/manifests/"+tag, pullScope(name), manifestMediaType)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", validation.ErrorCode{
+			Status: resp.StatusCode,
+		}
+	}
+	return resp.Header.Get("Docker-Content-Digest"), nil
+}
+
+func (c *client) manifest(name, reference string) (*manifest, error) {
+	resp, err := c.get(http.MethodGet, "/v2/"+name+"/manifests/"+reference, pullScope(name), manifestMediaType)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, _ = ioutil.ReadAll(resp.Body)
+		return nil, validation.ErrorCode{
+			Status: resp.StatusCode,
+		}
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// manifests fetched by digest are checked against it, so that a signed digest covers the layers too
+	if strings.HasPrefix(reference, "sha256:") {
+		digest := sha256.Sum256(data)
+		if actual := "sha256:" + hex.EncodeToString(digest[:]); actual != reference {
+			return nil, fmt.Errorf("digest %s of manifest of %s/%s does not match %s", actual, c.host, name, reference)
+		}
+	}
+
+	m := &manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest of %s/%s:%s: %w", c.host, name, reference, err)
+	}
+	return m, nil
+}
+
+func (c *client) blob(name string, desc descriptor) (*http.Response, error) {
+	resp, err := c.get(http.MethodGet, "/v2/"+name+"/blobs/"+desc.Digest, pullScope(name))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		_, _ = ioutil.ReadAll(resp.Body)
+		return nil, validation.ErrorCode{
+			Status: resp.StatusCode,
+		}
+	}
+	return resp, nil
+}
+
+// verifiedBlob downloads a blob, failing if its content does not match its digest
+func (c *client) verifiedBlob(name string, desc descriptor) ([]byte, error) {
+	resp, err := c.blob(name, desc)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256(data)
+	if actual := "sha256:" + hex.EncodeToString(digest[:]); actual != desc.Digest {
+		return nil, fmt.Errorf("digest %s of %s/%s does not match %s", actual, c.host, name, desc.Digest)
+	}
+	return data, nil
+}
+
+// chartLayer returns the chart content layer of a manifest, failing if the manifest is not a helm chart
+func chartLayer(m *manifest) (descriptor, error) {
+	if m.Config.MediaType != helmConfigType {
+		return descriptor{}, fmt.Errorf("%w: config media type %s is not %s", errNotChart, m.Config.MediaType, helmConfigType)
+	}
+	for _, layer := range m.Layers {
+		if layer.MediaType == helmChartLayerType {
+			return layer, nil
+		}
+	}
+	return descriptor{}, fmt.Errorf("%w: no layer of media type %s", errNotChart, helmChartLayerType)
+}
+
+func (c *client) chartVersion(name, tag string) (*repo.ChartVersion, error) {
+	m, err := c.manifest(name, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	layer, err := chartLayer(m)
+	if err != nil {
+		return nil, fmt.Errorf("%s/%s:%s: %w", c.host, name, tag, err)
+	}
+
+	resp, err := c.blob(name, m.Config)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	metadata := &chart.Metadata{}
+	if err := json.NewDecoder(resp.Body).Decode(metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse chart metadata of %s/%s:%s: %w", c.host, name, tag, err)
+	}
+
+	return &repo.ChartVersion{
+		Metadata: metadata,
+		Digest:   layer.Digest,
+	}, nil
+}
+
+// buildIndex synthesizes a helm index from the semver tags of the repositories. Helm pushes the "+" of build
+// metadata as "_" since it is not allowed in tags.
+func (c *client) buildIndex(repoURL, path string) (*repo.IndexFile, error) {
+	names, tags, err := c.repositories(path)
+	if err != nil {
+		return nil, err
+	}
+
+	chartCacheLock.Lock()
+	cached := chartCache[repoURL]
+	chartCacheLock.Unlock()
+
+	var (
+		index   = repo.NewIndexFile()
+		current = map[string]*repo.ChartVersion{}
+	)
+	for _, name := range names {
+		for _, tag := range tags[name] {
+			if _, err := semver.StrictNewVersion(strings.ReplaceAll(tag, "_", "+")); err != nil {
+				continue
+			}
+
+			digest, err := c.digest(name, tag)
+			if err != nil {
+				return nil, err
+			}
+
+			version, ok := cached[digest]
+			if !ok || digest == "" {
+				version, err = c.chartVersion(name, tag)
+				if errors.Is(err, errNotChart) {
+					logrus.Debugf("Skipping %v", err)
+					continue
+				} else if err != nil {
+					return nil, err
+				}
+			}
+			if digest != "" {
+				current[digest] = version
+			}
+
+			cp := *version
+			cp.URLs = []string{fmt.Sprintf("oci://%s/%s:%s", c.host, name, tag)}
+			index.Entries[cp.Name] = append(index.Entries[cp.Name], &cp)
+		}
+	}
+
+	chartCacheLock.Lock()
+	chartCache[repoURL] = current
+	chartCacheLock.Unlock()
+
+	index.SortEntries()
+	return index, nil
+}
@@ -0,0 +1,100 @@
+package oci
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"helm.sh/helm/v3/pkg/repo"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+
+// Signature is a cosign signature of a chart. Payload is the simple signing document naming the manifest digest
+// that was signed.
+type Signature struct {
+	Payload   []byte
+	Signature []byte
+}
+
+// SignedChart is the manifest digest of a chart tag, the digest of the chart archive that manifest points to and
+// the cosign signatures stored for it.
+type SignedChart struct {
+	ManifestDigest string
+	ChartDigest    string
+	Signatures     []Signature
+}
+
+// Signatures returns the cosign signatures of a chart, stored in the registry under the sha256-<digest>.sig tag of
+// the chart repository.
+func Signatures(secret *corev1.Secret, caBundle []byte, insecureSkipTLSVerify bool, chart *repo.ChartVersion) (*SignedChart, error) {
+	if len(chart.URLs) == 0 {
+		return nil, fmt.Errorf("failed to find chartName %s version %s: %w", chart.Name, chart.Version, validation.NotFound)
+	}
+
+	host, name, tag, err := parseRef(chart.URLs[0])
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newClient(secret, host, caBundle, insecureSkipTLSVerify)
+	if err != nil {
+		return nil, err
+	}
+	defer client.http.CloseIdleConnections()
+
+	digest, err := client.digest(name, tag)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(digest, "sha256:") {
+		return nil, fmt.Errorf("registry %s returned no digest for %s", host, chart.URLs[0])
+	}
+
+	m, err := client.manifest(name, digest)
+	if err != nil {
+		return nil, err
+	}
+	layer, err := chartLayer(m)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", chart.URLs[0], err)
+	}
+
+	result := &SignedChart{
+		ManifestDigest: digest,
+		ChartDigest:    layer.Digest,
+	}
+
+	m, err = client.manifest(name, strings.Replace(digest, ":", "-", 1)+".sig")
+	if isNotFound(err) {
+		return result, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, layer := range m.Layers {
+		encoded, ok := layer.Annotations[cosignSignatureAnnotation]
+		if !ok {
+			continue
+		}
+
+		signature, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode signature of %s: %w", chart.URLs[0], err)
+		}
+
+		payload, err := client.verifiedBlob(name, layer)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Signatures = append(result.Signatures, Signature{
+			Payload:   payload,
+			Signature: signature,
+		})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,176 @@
+package verify
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/rancher/rancher/pkg/catalogv2/oci"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/clearsign"
+	"helm.sh/helm/v3/pkg/provenance"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// KeyringKey is the key of the keyring secret holding the PGP public keys provenance files are checked against
+	KeyringKey = "keyring"
+	// CosignKey is the key of the keyring secret holding the PEM encoded cosign public keys
+	CosignKey = "cosign.pub"
+
+	MethodPGP    = "pgp"
+	MethodCosign = "cosign"
+)
+
+// PGP checks a helm provenance file against the keyring and the chart archive, and returns the identity of the key
+// that signed it.
+func PGP(keyring, chart, prov []byte) (string, error) {
+	if len(keyring) == 0 {
+		return "", errors.New("no PGP keyring configured")
+	}
+
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(keyring))
+	if err != nil {
+		entities, err = openpgp.ReadKeyRing(bytes.NewReader(keyring))
+		if err != nil {
+			return "", fmt.Errorf("failed to read PGP keyring: %w", err)
+		}
+	}
+
+	block, _ := clearsign.Decode(prov)
+	if block == nil {
+		return "", errors.New("signature block not found in provenance file")
+	}
+
+	signer, err := openpgp.CheckDetachedSignature(entities, bytes.NewBuffer(block.Bytes), block.ArmoredSignature.Body)
+	if err != nil {
+		return "", err
+	}
+
+	// the message is the chart metadata and the sums, split by the YAML document end marker
+	parts := bytes.Split(block.Plaintext, []byte("\n...\n"))
+	if len(parts) < 2 {
+		return "", errors.New("provenance message block must have at least two parts")
+	}
+	sums := &provenance.SumCollection{}
+	if err := yaml.Unmarshal(parts[1], sums); err != nil {
+		return "", fmt.Errorf("failed to parse provenance sums: %w", err)
+	}
+
+	sum := digest(chart)
+	for _, fileSum := range sums.Files {
+		if fileSum == sum {
+			return identity(signer), nil
+		}
+	}
+	return "", fmt.Errorf("provenance file does not contain sum %s of the chart", sum)
+}
+
+func identity(entity *openpgp.Entity) string {
+	var names []string
+	for name := range entity.Identities {
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint)
+	}
+	sort.Strings(names)
+	return names[0]
+}
+
+type simpleSigning struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+}
+
+// Cosign checks that one of the signatures of a chart of an OCI repo was made by one of the PEM encoded public
+// keys, and that it is for the manifest pointing to the chart archive. It returns the fingerprint of the key.
+func Cosign(keys, chart []byte, signed *oci.SignedChart) (string, error) {
+	publicKeys, err := parsePublicKeys(keys)
+	if err != nil {
+		return "", err
+	}
+
+	if sum := digest(chart); sum != signed.ChartDigest {
+		return "", fmt.Errorf("chart digest %s does not match %s of the signed manifest", sum, signed.ChartDigest)
+	}
+
+	if len(signed.Signatures) == 0 {
+		return "", fmt.Errorf("no cosign signature found for %s", signed.ManifestDigest)
+	}
+
+	for _, signature := range signed.Signatures {
+		for fingerprint, key := range publicKeys {
+			if !verifySignature(key, signature.Payload, signature.Signature) {
+				continue
+			}
+
+			payload := &simpleSigning{}
+			if err := json.Unmarshal(signature.Payload, payload); err != nil {
+				return "", fmt.Errorf("failed to parse cosign payload: %w", err)
+			}
+			if payload.Critical.Image.DockerManifestDigest != signed.ManifestDigest {
+				return "", fmt.Errorf("cosign signature is for %s, not %s", payload.Critical.Image.DockerManifestDigest, signed.ManifestDigest)
+			}
+			return fingerprint, nil
+		}
+	}
+
+	return "", fmt.Errorf("no cosign signature of %s matches the configured keys", signed.ManifestDigest)
+}
+
+// parsePublicKeys returns the public keys of a PEM bundle by the sha256 fingerprint of their DER encoding
+func parsePublicKeys(data []byte) (map[string]crypto.PublicKey, error) {
+	keys := map[string]crypto.PublicKey{}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "PUBLIC KEY" {
+			continue
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse cosign public key: %w", err)
+		}
+		keys[digest(block.Bytes)] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no cosign public key configured")
+	}
+	return keys, nil
+}
+
+func verifySignature(key crypto.PublicKey, payload, signature []byte) bool {
+	hash := sha256.Sum256(payload)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(k, hash[:], signature)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, hash[:], signature) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, payload, signature)
+	}
+	return false
+}
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,122 @@
+package verify
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"testing"
+
+	"github.com/rancher/rancher/pkg/catalogv2/oci"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/clearsign"
+)
+
+func pgpKey(t *testing.T, name string) (*openpgp.Entity, []byte) {
+	entity, err := openpgp.NewEntity(name, "", name+"@example.com", nil)
+	require.NoError(t, err)
+
+	keyring := &bytes.Buffer{}
+	w, err := armor.Encode(keyring, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.Serialize(w))
+	require.NoError(t, w.Close())
+	return entity, keyring.Bytes()
+}
+
+func provenanceFile(t *testing.T, signer *openpgp.Entity, chart []byte) []byte {
+	message := fmt.Sprintf("apiVersion: v2\nname: app\nversion: 1.0.0\n\n...\nfiles:\n  app-1.0.0.tgz: %s\n", digest(chart))
+
+	prov := &bytes.Buffer{}
+	w, err := clearsign.Encode(prov, signer.PrivateKey, nil)
+	require.NoError(t, err)
+	_, err = w.Write([]byte(message))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return prov.Bytes()
+}
+
+func TestPGP(t *testing.T) {
+	chart := []byte("chart archive")
+	signer, keyring := pgpKey(t, "signer")
+	prov := provenanceFile(t, signer, chart)
+
+	identity, err := PGP(keyring, chart, prov)
+	require.NoError(t, err)
+	assert.Equal(t, "signer <signer@example.com>", identity)
+
+	_, err = PGP(keyring, []byte("tampered archive"), prov)
+	assert.Error(t, err)
+
+	_, other := pgpKey(t, "other")
+	_, err = PGP(other, chart, prov)
+	assert.Error(t, err)
+
+	_, err = PGP(nil, chart, prov)
+	assert.Error(t, err)
+}
+
+func cosignKey(t *testing.T) (*ecdsa.PrivateKey, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	return key, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func cosignSignature(t *testing.T, key *ecdsa.PrivateKey, manifestDigest string) oci.Signature {
+	payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"harbor.example.com/charts/app"},"image":{"docker-manifest-digest":"%s"},"type":"cosign container image signature"},"optional":null}`, manifestDigest))
+	hash := sha256.Sum256(payload)
+	signature, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	require.NoError(t, err)
+	return oci.Signature{
+		Payload:   payload,
+		Signature: signature,
+	}
+}
+
+func TestCosign(t *testing.T) {
+	chart := []byte("chart archive")
+	key, publicKey := cosignKey(t)
+	manifestDigest := digest([]byte("manifest"))
+
+	signed := &oci.SignedChart{
+		ManifestDigest: manifestDigest,
+		ChartDigest:    digest(chart),
+		Signatures:     []oci.Signature{cosignSignature(t, key, manifestDigest)},
+	}
+
+	fingerprint, err := Cosign(publicKey, chart, signed)
+	require.NoError(t, err)
+	block, _ := pem.Decode(publicKey)
+	assert.Equal(t, digest(block.Bytes), fingerprint)
+
+	// the archive must be the one of the signed manifest
+	_, err = Cosign(publicKey, []byte("tampered archive"), signed)
+	assert.Error(t, err)
+
+	// signatures of other manifests are refused
+	_, err = Cosign(publicKey, chart, &oci.SignedChart{
+		ManifestDigest: manifestDigest,
+		ChartDigest:    digest(chart),
+		Signatures:     []oci.Signature{cosignSignature(t, key, digest([]byte("other manifest")))},
+	})
+	assert.Error(t, err)
+
+	_, otherKey := cosignKey(t)
+	_, err = Cosign(otherKey, chart, signed)
+	assert.Error(t, err)
+
+	_, err = Cosign(publicKey, chart, &oci.SignedChart{
+		ManifestDigest: manifestDigest,
+		ChartDigest:    digest(chart),
+	})
+	assert.Error(t, err)
+}
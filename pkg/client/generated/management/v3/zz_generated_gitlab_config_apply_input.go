@@ -0,0 +1,14 @@
+package client
+
+const (
+	GitlabConfigApplyInputType              = "gitlabConfigApplyInput"
+	GitlabConfigApplyInputFieldCode         = "code"
+	GitlabConfigApplyInputFieldEnabled      = "enabled"
+	GitlabConfigApplyInputFieldGitlabConfig = "gitlabConfig"
+)
+
+type GitlabConfigApplyInput struct {
+	Code         string        `json:"code,omitempty" yaml:"code,omitempty"`
+	Enabled      bool          `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	GitlabConfig *GitlabConfig `json:"gitlabConfig,omitempty" yaml:"gitlabConfig,omitempty"`
+}
@@ -0,0 +1,10 @@
+package client
+
+const (
+	GitlabConfigTestOutputType             = "gitlabConfigTestOutput"
+	GitlabConfigTestOutputFieldRedirectURL = "redirectUrl"
+)
+
+type GitlabConfigTestOutput struct {
+	RedirectURL string `json:"redirectUrl,omitempty" yaml:"redirectUrl,omitempty"`
+}
@@ -0,0 +1,16 @@
+package client
+
+const (
+	TokenScopeType           = "tokenScope"
+	TokenScopeFieldClusters  = "clusters"
+	TokenScopeFieldProjects  = "projects"
+	TokenScopeFieldReadOnly  = "readOnly"
+	TokenScopeFieldResources = "resources"
+)
+
+type TokenScope struct {
+	Clusters  []string `json:"clusters,omitempty" yaml:"clusters,omitempty"`
+	Projects  []string `json:"projects,omitempty" yaml:"projects,omitempty"`
+	ReadOnly  bool     `json:"readOnly,omitempty" yaml:"readOnly,omitempty"`
+	Resources []string `json:"resources,omitempty" yaml:"resources,omitempty"`
+}
@@ -73,6 +73,8 @@ type UserOperations interface {
 
 	ActionSetpassword(resource *User, input *SetPasswordInput) (*User, error)
 
+	ActionUnlock(resource *User) (*User, error)
+
 	CollectionActionChangepassword(resource *UserCollection, input *ChangePasswordInput) error
 
 	CollectionActionRefreshauthprovideraccess(resource *UserCollection) error
@@ -158,6 +160,12 @@ func (c *UserClient) ActionSetpassword(resource *User, input *SetPasswordInput)
 	return resp, err
 }
 
+func (c *UserClient) ActionUnlock(resource *User) (*User, error) {
+	resp := &User{}
+	err := c.apiClient.Ops.DoAction(UserType, "unlock", &resource.Resource, nil, resp)
+	return resp, err
+}
+
 func (c *UserClient) CollectionActionChangepassword(resource *UserCollection, input *ChangePasswordInput) error {
 	err := c.apiClient.Ops.DoCollectionAction(UserType, "changepassword", &resource.Collection, input, nil)
 	return err
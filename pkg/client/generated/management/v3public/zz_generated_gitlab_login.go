@@ -0,0 +1,16 @@
+package client
+
+const (
+	GitlabLoginType              = "gitlabLogin"
+	GitlabLoginFieldCode         = "code"
+	GitlabLoginFieldDescription  = "description"
+	GitlabLoginFieldResponseType = "responseType"
+	GitlabLoginFieldTTLMillis    = "ttl"
+)
+
+type GitlabLogin struct {
+	Code         string `json:"code,omitempty" yaml:"code,omitempty"`
+	Description  string `json:"description,omitempty" yaml:"description,omitempty"`
+	ResponseType string `json:"responseType,omitempty" yaml:"responseType,omitempty"`
+	TTLMillis    int64  `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+}
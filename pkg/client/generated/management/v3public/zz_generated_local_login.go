@@ -0,0 +1,22 @@
+package client
+
+const (
+	LocalLoginType              = "localLogin"
+	LocalLoginFieldDescription  = "description"
+	LocalLoginFieldPassword     = "password"
+	LocalLoginFieldRecoveryCode = "recoveryCode"
+	LocalLoginFieldResponseType = "responseType"
+	LocalLoginFieldTOTPCode     = "totpCode"
+	LocalLoginFieldTTLMillis    = "ttl"
+	LocalLoginFieldUsername     = "username"
+)
+
+type LocalLogin struct {
+	Description  string `json:"description,omitempty" yaml:"description,omitempty"`
+	Password     string `json:"password,omitempty" yaml:"password,omitempty"`
+	RecoveryCode string `json:"recoveryCode,omitempty" yaml:"recoveryCode,omitempty"`
+	ResponseType string `json:"responseType,omitempty" yaml:"responseType,omitempty"`
+	TOTPCode     string `json:"totpCode,omitempty" yaml:"totpCode,omitempty"`
+	TTLMillis    int64  `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+	Username     string `json:"username,omitempty" yaml:"username,omitempty"`
+}
@@ -0,0 +1,14 @@
+package client
+
+const (
+	MFAEnrollOutputType               = "mfaEnrollOutput"
+	MFAEnrollOutputFieldRecoveryCodes = "recoveryCodes"
+	MFAEnrollOutputFieldSecret        = "secret"
+	MFAEnrollOutputFieldURL           = "url"
+)
+
+type MFAEnrollOutput struct {
+	RecoveryCodes []string `json:"recoveryCodes,omitempty" yaml:"recoveryCodes,omitempty"`
+	Secret        string   `json:"secret,omitempty" yaml:"secret,omitempty"`
+	URL           string   `json:"url,omitempty" yaml:"url,omitempty"`
+}
@@ -0,0 +1,547 @@
+package helm
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rancher/lasso/pkg/client"
+	"github.com/rancher/rancher/pkg/api/steve/catalog/types"
+	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/catalogv2/content"
+	"github.com/rancher/rancher/pkg/catalogv2/helmop"
+	catalogcontrollers "github.com/rancher/rancher/pkg/generated/controllers/catalog.cattle.io/v1"
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	"github.com/rancher/wrangler/pkg/condition"
+	"github.com/rancher/wrangler/pkg/data"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/pkg/relatedresource"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	appInstallByRepo    = "catalog.cattle.io/appinstall-by-repo"
+	appInstallByRelease = "catalog.cattle.io/appinstall-by-release"
+	appInstallByValues  = "catalog.cattle.io/appinstall-by-values"
+
+	defaultValuesKey = "values.yaml"
+)
+
+var (
+	operationTimeout      = 10 * time.Minute
+	operationPollInterval = 15 * time.Second
+)
+
+type appInstallHandler struct {
+	ctx                 context.Context
+	contentManager      *content.Manager
+	operations          *helmop.Operations
+	sharedClientFactory client.SharedClientFactory
+	appInstalls         catalogcontrollers.AppInstallController
+	apps                catalogcontrollers.AppCache
+	ops                 catalogcontrollers.OperationCache
+	pods                corecontrollers.PodCache
+	secrets             corecontrollers.SecretCache
+	configMaps          corecontrollers.ConfigMapCache
+	users               mgmtcontrollers.UserCache
+	userAttributes      mgmtcontrollers.UserAttributeCache
+}
+
+func RegisterAppInstalls(ctx context.Context,
+	contentManager *content.Manager,
+	operations *helmop.Operations,
+	sharedClientFactory client.SharedClientFactory,
+	configMaps corecontrollers.ConfigMapController,
+	secrets corecontrollers.SecretController,
+	pods corecontrollers.PodCache,
+	clusterRepos catalogcontrollers.ClusterRepoController,
+	apps catalogcontrollers.AppController,
+	ops catalogcontrollers.OperationCache,
+	users mgmtcontrollers.UserCache,
+	userAttributes mgmtcontrollers.UserAttributeCache,
+	appInstalls catalogcontrollers.AppInstallController) {
+	h := &appInstallHandler{
+		ctx:                 ctx,
+		contentManager:      contentManager,
+		operations:          operations,
+		sharedClientFactory: sharedClientFactory,
+		appInstalls:         appInstalls,
+		apps:                apps.Cache(),
+		ops:                 ops,
+		pods:                pods,
+		secrets:             secrets.Cache(),
+		configMaps:          configMaps.Cache(),
+		users:               users,
+		userAttributes:      userAttributes,
+	}
+
+	appInstalls.Cache().AddIndexer(appInstallByRepo, func(obj *catalog.AppInstall) ([]string, error) {
+		return []string{obj.Spec.RepoName}, nil
+	})
+	appInstalls.Cache().AddIndexer(appInstallByRelease, func(obj *catalog.AppInstall) ([]string, error) {
+		namespace, releaseName := release(obj)
+		return []string{namespace + "/" + releaseName}, nil
+	})
+	appInstalls.Cache().AddIndexer(appInstallByValues, func(obj *catalog.AppInstall) ([]string, error) {
+		namespace, _ := release(obj)
+		var result []string
+		for _, ref := range obj.Spec.ValuesFrom {
+			if ref.SecretKeyRef != nil {
+				result = append(result, "secret:"+namespace+"/"+ref.SecretKeyRef.Name)
+			}
+			if ref.ConfigMapKeyRef != nil {
+				result = append(result, "configmap:"+namespace+"/"+ref.ConfigMapKeyRef.Name)
+			}
+		}
+		return result, nil
+	})
+
+	catalogcontrollers.RegisterAppInstallStatusHandler(ctx, appInstalls,
+		condition.Cond(catalog.AppInstallReconciled), "helm-appinstall", h.OnChange)
+	appInstalls.OnRemove(ctx, "helm-appinstall-remove", h.OnRemove)
+
+	relatedresource.WatchClusterScoped(ctx, "helm-appinstall", h.resolve, appInstalls,
+		clusterRepos, apps, secrets, configMaps)
+}
+
+// resolve enqueues the AppInstalls of a ClusterRepo when its index is updated, of a release when it changes and of
+// the secrets and config maps their values are read from
+func (h *appInstallHandler) resolve(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+	var indexName, key string
+	switch obj.(type) {
+	case *catalog.ClusterRepo:
+		indexName, key = appInstallByRepo, name
+	case *catalog.App:
+		indexName, key = appInstallByRelease, namespace+"/"+name
+	case *corev1.Secret:
+		indexName, key = appInstallByValues, "secret:"+namespace+"/"+name
+	case *corev1.ConfigMap:
+		indexName, key = appInstallByValues, "configmap:"+namespace+"/"+name
+	default:
+		return nil, nil
+	}
+
+	appInstalls, err := h.appInstalls.Cache().GetByIndex(indexName, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []relatedresource.Key
+	for _, appInstall := range appInstalls {
+		result = append(result, relatedresource.NewKey("", appInstall.Name))
+	}
+	return result, nil
+}
+
+func (h *appInstallHandler) OnChange(appInstall *catalog.AppInstall, status catalog.AppInstallStatus) (catalog.AppInstallStatus, error) {
+	status.ObservedGeneration = appInstall.Generation
+	namespace, releaseName := release(appInstall)
+
+	operationUser, err := h.operationUser(status.Creator)
+	if err != nil {
+		return status, err
+	}
+
+	version, err := h.version(&appInstall.Spec)
+	if err != nil {
+		return status, err
+	}
+
+	values, err := h.values(namespace, &appInstall.Spec)
+	if err != nil {
+		return status, err
+	}
+
+	hash, err := desiredHash(&appInstall.Spec, version, values)
+	if err != nil {
+		return status, err
+	}
+
+	app, err := h.apps.Get(namespace, releaseName)
+	if apierrors.IsNotFound(err) {
+		app = nil
+	} else if err != nil {
+		return status, err
+	}
+
+	if ok, err := isDeployed(app, appInstall.Spec.Chart, version, values); err != nil {
+		return status, err
+	} else if ok {
+		status.Version = version
+		status.Drift, err = h.drift(namespace, releaseName)
+		if err != nil {
+			return status, err
+		}
+		h.appInstalls.EnqueueAfter(appInstall.Name, interval)
+		return status, nil
+	}
+
+	last, err := h.lastOperation(&status)
+	if err != nil {
+		return status, err
+	}
+	if last.running || isPending(app) {
+		h.appInstalls.EnqueueAfter(appInstall.Name, operationPollInterval)
+		return status, nil
+	}
+
+	if status.AppliedHash == hash {
+		// The same version and values are not applied again as long as the pod of the failed operation exists
+		if last.err != nil {
+			return status, last.err
+		}
+		// The release was deployed by the last operation, but the app was not updated yet. Apps deployed after
+		// the operation finished were changed by someone else and are reverted.
+		if !last.finished.IsZero() && !deployedAfter(app, last.finished) {
+			h.appInstalls.EnqueueAfter(appInstall.Name, operationPollInterval)
+			return status, nil
+		}
+	}
+
+	op, err := h.deploy(operationUser, appInstall, app, version, values)
+	if err != nil {
+		return status, err
+	}
+
+	status.OperationName = op.Name
+	status.OperationNamespace = op.Namespace
+	status.AppliedHash = hash
+	status.Drift = nil
+	h.appInstalls.EnqueueAfter(appInstall.Name, operationPollInterval)
+	return status, nil
+}
+
+// OnRemove uninstalls the release of an AppInstall. Releases the AppInstall never ran an operation for are kept.
+func (h *appInstallHandler) OnRemove(key string, appInstall *catalog.AppInstall) (*catalog.AppInstall, error) {
+	if appInstall.Status.AppliedHash == "" {
+		return appInstall, nil
+	}
+
+	namespace, releaseName := release(appInstall)
+	app, err := h.apps.Get(namespace, releaseName)
+	if apierrors.IsNotFound(err) {
+		return appInstall, nil
+	} else if err != nil {
+		return appInstall, err
+	}
+	if app.Spec.Info != nil && app.Spec.Info.Status == catalog.StatusUninstalling {
+		return appInstall, nil
+	}
+
+	operationUser, err := h.operationUser(appInstall.Status.Creator)
+	if err != nil {
+		return appInstall, err
+	}
+
+	uninstall, err := json.Marshal(types.ChartUninstallAction{
+		Timeout: &metav1.Duration{Duration: operationTimeout},
+	})
+	if err != nil {
+		return appInstall, err
+	}
+
+	_, err = h.operations.Uninstall(h.ctx, operationUser, namespace, releaseName, bytes.NewBuffer(uninstall))
+	return appInstall, err
+}
+
+// operationUser returns the user the operations of an AppInstall impersonate, with the groups the user is
+// authenticated with, so that creating an AppInstall grants no more than installing the chart directly
+func (h *appInstallHandler) operationUser(creatorID string) (user.Info, error) {
+	if creatorID == "" {
+		return nil, fmt.Errorf("AppInstall has no creator, only AppInstalls created through the rancher API are reconciled")
+	}
+
+	u, err := h.users.Get(creatorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup creator %s: %w", creatorID, err)
+	}
+
+	var groups []string
+	attribs, err := h.userAttributes.Get(creatorID)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	} else if err == nil {
+		for _, principals := range attribs.GroupPrincipals {
+			for _, principal := range principals.Items {
+				groups = append(groups, strings.TrimPrefix(principal.Name, "local://"))
+			}
+		}
+	}
+	groups = append(groups, user.AllAuthenticated, "system:cattle:authenticated")
+
+	info := &user.DefaultInfo{
+		Name:   u.Name,
+		UID:    u.Name,
+		Groups: groups,
+		Extra:  map[string][]string{"username": {u.Username}},
+	}
+	if len(u.PrincipalIDs) > 0 {
+		info.Extra["principalid"] = u.PrincipalIDs
+	}
+	return info, nil
+}
+
+func release(appInstall *catalog.AppInstall) (string, string) {
+	namespace := appInstall.Spec.Namespace
+	if namespace == "" {
+		namespace = "default"
+	}
+	releaseName := appInstall.Spec.ReleaseName
+	if releaseName == "" {
+		releaseName = appInstall.Name
+	}
+	return namespace, releaseName
+}
+
+// version returns the newest chart version of the repo index matching the version constraint
+func (h *appInstallHandler) version(spec *catalog.AppInstallSpec) (string, error) {
+	index, err := h.contentManager.Index("", spec.RepoName)
+	if err != nil {
+		return "", err
+	}
+
+	chart, err := index.Get(spec.Chart, spec.Version)
+	if err != nil {
+		return "", err
+	}
+	return chart.Version, nil
+}
+
+// values merges the values.yaml documents of ValuesFrom in order, and the values of the spec over them. The values
+// end up in the release, which anyone who can read the release namespace can see, so they are only read from
+// secrets and config maps of that namespace.
+func (h *appInstallHandler) values(namespace string, spec *catalog.AppInstallSpec) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+	for _, ref := range spec.ValuesFrom {
+		var (
+			doc []byte
+			err error
+		)
+		switch {
+		case ref.SecretKeyRef != nil:
+			doc, err = h.secretValues(namespace, ref.SecretKeyRef)
+		case ref.ConfigMapKeyRef != nil:
+			doc, err = h.configMapValues(namespace, ref.ConfigMapKeyRef)
+		default:
+			err = fmt.Errorf("valuesFrom requires a secretKeyRef or a configMapKeyRef")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		values := map[string]interface{}{}
+		if err := yaml.Unmarshal(doc, &values); err != nil {
+			return nil, err
+		}
+		result = data.MergeMaps(result, values)
+	}
+
+	return data.MergeMaps(result, spec.Values), nil
+}
+
+func (h *appInstallHandler) secretValues(namespace string, ref *catalog.KeyReference) ([]byte, error) {
+	if err := checkValuesNamespace(namespace, ref); err != nil {
+		return nil, err
+	}
+	secret, err := h.secrets.Get(namespace, ref.Name)
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := secret.Data[valuesKey(ref)]
+	if !ok {
+		return nil, fmt.Errorf("secret %s/%s has no key %s", namespace, ref.Name, valuesKey(ref))
+	}
+	return doc, nil
+}
+
+func (h *appInstallHandler) configMapValues(namespace string, ref *catalog.KeyReference) ([]byte, error) {
+	if err := checkValuesNamespace(namespace, ref); err != nil {
+		return nil, err
+	}
+	configMap, err := h.configMaps.Get(namespace, ref.Name)
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := configMap.Data[valuesKey(ref)]
+	if !ok {
+		return nil, fmt.Errorf("config map %s/%s has no key %s", namespace, ref.Name, valuesKey(ref))
+	}
+	return []byte(doc), nil
+}
+
+func checkValuesNamespace(namespace string, ref *catalog.KeyReference) error {
+	if ref.Namespace != "" && ref.Namespace != namespace {
+		return fmt.Errorf("valuesFrom %s/%s is not in the release namespace %s", ref.Namespace, ref.Name, namespace)
+	}
+	return nil
+}
+
+func valuesKey(ref *catalog.KeyReference) string {
+	if ref.Key == "" {
+		return defaultValuesKey
+	}
+	return ref.Key
+}
+
+// desiredHash identifies the release an operation was run for
+func desiredHash(spec *catalog.AppInstallSpec, version string, values map[string]interface{}) (string, error) {
+	namespace, releaseName := release(&catalog.AppInstall{Spec: *spec})
+	desired, err := json.Marshal([]interface{}{
+		spec.RepoName,
+		spec.Chart,
+		version,
+		namespace,
+		releaseName,
+		values,
+	})
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(desired)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// isDeployed returns whether the release is deployed with the chart version and values of the AppInstall
+func isDeployed(app *catalog.App, chart, version string, values map[string]interface{}) (bool, error) {
+	if app == nil || app.Spec.Info == nil || app.Spec.Info.Status != catalog.StatusDeployed {
+		return false, nil
+	}
+	if app.Spec.Chart == nil || app.Spec.Chart.Metadata == nil ||
+		app.Spec.Chart.Metadata.Name != chart || app.Spec.Chart.Metadata.Version != version {
+		return false, nil
+	}
+
+	if len(app.Spec.Values) == 0 && len(values) == 0 {
+		return true, nil
+	}
+
+	// values are compared as JSON, the numbers of the release and of the spec are not decoded to the same types
+	deployed, err := json.Marshal(map[string]interface{}(app.Spec.Values))
+	if err != nil {
+		return false, err
+	}
+	desired, err := json.Marshal(values)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(deployed, desired), nil
+}
+
+func isPending(app *catalog.App) bool {
+	if app == nil || app.Spec.Info == nil {
+		return false
+	}
+	switch app.Spec.Info.Status {
+	case catalog.StatusPendingInstall, catalog.StatusPendingUpgrade, catalog.StatusPendingRollback, catalog.StatusUninstalling:
+		return true
+	}
+	return false
+}
+
+func deployedAfter(app *catalog.App, t time.Time) bool {
+	return app != nil && app.Spec.Info != nil && app.Spec.Info.LastDeployed != nil &&
+		app.Spec.Info.LastDeployed.After(t)
+}
+
+type operationResult struct {
+	running bool
+	// finished is zero if the operation or its pod no longer exists
+	finished time.Time
+	err      error
+}
+
+// lastOperation looks up the outcome of the last operation of an AppInstall through the helm container of its pod
+func (h *appInstallHandler) lastOperation(status *catalog.AppInstallStatus) (operationResult, error) {
+	if status.OperationName == "" {
+		return operationResult{}, nil
+	}
+
+	op, err := h.ops.Get(status.OperationNamespace, status.OperationName)
+	if apierrors.IsNotFound(err) {
+		return operationResult{}, nil
+	} else if err != nil {
+		return operationResult{}, err
+	}
+	if op.Status.PodName == "" {
+		return operationResult{running: true}, nil
+	}
+
+	pod, err := h.pods.Get(op.Status.PodNamespace, op.Status.PodName)
+	if apierrors.IsNotFound(err) {
+		return operationResult{}, nil
+	} else if err != nil {
+		return operationResult{}, err
+	}
+
+	for _, container := range pod.Status.ContainerStatuses {
+		if container.Name != "helm" || container.State.Terminated == nil {
+			continue
+		}
+		result := operationResult{
+			finished: container.State.Terminated.FinishedAt.Time,
+		}
+		if container.State.Terminated.ExitCode != 0 {
+			result.err = fmt.Errorf("operation %s/%s failed, pod %s/%s exited %d", op.Namespace, op.Name,
+				pod.Namespace, pod.Name, container.State.Terminated.ExitCode)
+		}
+		return result, nil
+	}
+	return operationResult{running: true}, nil
+}
+
+// deploy installs the release if it does not exist and upgrades it otherwise, as only installs create the namespace
+// in a project
+func (h *appInstallHandler) deploy(operationUser user.Info, appInstall *catalog.AppInstall, app *catalog.App, version string, values map[string]interface{}) (*catalog.Operation, error) {
+	namespace, releaseName := release(appInstall)
+
+	if app == nil {
+		install, err := json.Marshal(types.ChartInstallAction{
+			Timeout:   &metav1.Duration{Duration: operationTimeout},
+			Wait:      true,
+			Namespace: namespace,
+			ProjectID: appInstall.Spec.ProjectID,
+			Charts: []types.ChartInstall{
+				{
+					ChartName:   appInstall.Spec.Chart,
+					Version:     version,
+					ReleaseName: releaseName,
+					Values:      values,
+				},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		return h.operations.Install(h.ctx, operationUser, "", appInstall.Spec.RepoName, bytes.NewBuffer(install))
+	}
+
+	upgrade, err := json.Marshal(types.ChartUpgradeAction{
+		Timeout:   &metav1.Duration{Duration: operationTimeout},
+		Wait:      true,
+		Install:   true,
+		Namespace: namespace,
+		Charts: []types.ChartUpgrade{
+			{
+				ChartName:   appInstall.Spec.Chart,
+				Version:     version,
+				ReleaseName: releaseName,
+				Values:      values,
+				ResetValues: true,
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return h.operations.Upgrade(h.ctx, operationUser, "", appInstall.Spec.RepoName, bytes.NewBuffer(upgrade))
+}
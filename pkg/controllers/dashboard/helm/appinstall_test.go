@@ -0,0 +1,305 @@
+package helm
+
+import (
+	"testing"
+
+	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+type fakeSecretCache struct {
+	corecontrollers.SecretCache
+	secrets map[string]*corev1.Secret
+}
+
+func (f *fakeSecretCache) Get(namespace, name string) (*corev1.Secret, error) {
+	if secret, ok := f.secrets[namespace+"/"+name]; ok {
+		return secret, nil
+	}
+	return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, name)
+}
+
+type fakeUserCache struct {
+	mgmtcontrollers.UserCache
+	users map[string]*v3.User
+}
+
+func (f *fakeUserCache) Get(name string) (*v3.User, error) {
+	if u, ok := f.users[name]; ok {
+		return u, nil
+	}
+	return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "users"}, name)
+}
+
+type fakeUserAttributeCache struct {
+	mgmtcontrollers.UserAttributeCache
+	attributes map[string]*v3.UserAttribute
+}
+
+func (f *fakeUserAttributeCache) Get(name string) (*v3.UserAttribute, error) {
+	if attribs, ok := f.attributes[name]; ok {
+		return attribs, nil
+	}
+	return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "userattributes"}, name)
+}
+
+func TestOperationUser(t *testing.T) {
+	h := &appInstallHandler{
+		users: &fakeUserCache{users: map[string]*v3.User{
+			"u-user": {
+				ObjectMeta:   metav1.ObjectMeta{Name: "u-user"},
+				Username:     "user",
+				PrincipalIDs: []string{"local://u-user"},
+			},
+		}},
+		userAttributes: &fakeUserAttributeCache{attributes: map[string]*v3.UserAttribute{
+			"u-user": {
+				GroupPrincipals: map[string]v3.Principals{
+					"local": {Items: []v3.Principal{{ObjectMeta: metav1.ObjectMeta{Name: "local://g-devs"}}}},
+				},
+			},
+		}},
+	}
+
+	info, err := h.operationUser("u-user")
+	require.NoError(t, err)
+	assert.Equal(t, &user.DefaultInfo{
+		Name:   "u-user",
+		UID:    "u-user",
+		Groups: []string{"g-devs", user.AllAuthenticated, "system:cattle:authenticated"},
+		Extra: map[string][]string{
+			"username":    {"user"},
+			"principalid": {"local://u-user"},
+		},
+	}, info)
+	assert.NotContains(t, info.GetGroups(), user.SystemPrivilegedGroup)
+
+	_, err = h.operationUser("")
+	assert.Error(t, err)
+
+	_, err = h.operationUser("u-missing")
+	assert.Error(t, err)
+}
+
+func TestValuesFrom(t *testing.T) {
+	h := &appInstallHandler{
+		secrets: &fakeSecretCache{secrets: map[string]*corev1.Secret{
+			"apps/values": {
+				Data: map[string][]byte{"values.yaml": []byte("replicas: 2\nimage: app")},
+			},
+			"cattle-system/tls-rancher": {
+				Data: map[string][]byte{"values.yaml": []byte("key: secret")},
+			},
+		}},
+	}
+
+	values, err := h.values("apps", &catalog.AppInstallSpec{
+		ValuesFrom: []catalog.ValuesReference{
+			{SecretKeyRef: &catalog.KeyReference{Name: "values"}},
+			{SecretKeyRef: &catalog.KeyReference{Name: "values", Namespace: "apps"}},
+		},
+		Values: map[string]interface{}{"replicas": int64(3)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"replicas": int64(3), "image": "app"}, values)
+
+	_, err = h.values("apps", &catalog.AppInstallSpec{
+		ValuesFrom: []catalog.ValuesReference{
+			{SecretKeyRef: &catalog.KeyReference{Name: "tls-rancher", Namespace: "cattle-system"}},
+		},
+	})
+	assert.EqualError(t, err, "valuesFrom cattle-system/tls-rancher is not in the release namespace apps")
+}
+
+func TestIsDeployed(t *testing.T) {
+	deployed := func(version string, values map[string]interface{}) *catalog.App {
+		return &catalog.App{
+			Spec: catalog.ReleaseSpec{
+				Info: &catalog.Info{
+					Status: catalog.StatusDeployed,
+				},
+				Chart: &catalog.Chart{
+					Metadata: &catalog.Metadata{
+						Name:    "app",
+						Version: version,
+					},
+				},
+				Values: values,
+			},
+		}
+	}
+
+	tests := []struct {
+		name           string
+		app            *catalog.App
+		values         map[string]interface{}
+		expectedResult bool
+	}{
+		{
+			"not installed",
+			nil,
+			nil,
+			false,
+		},
+		{
+			"no values",
+			deployed("1.0.0", nil),
+			map[string]interface{}{},
+			true,
+		},
+		{
+			"numbers decoded differently",
+			deployed("1.0.0", map[string]interface{}{"replicas": float64(3)}),
+			map[string]interface{}{"replicas": int64(3)},
+			true,
+		},
+		{
+			"values changed",
+			deployed("1.0.0", map[string]interface{}{"replicas": float64(3)}),
+			map[string]interface{}{"replicas": int64(2)},
+			false,
+		},
+		{
+			"other version",
+			deployed("0.9.0", nil),
+			nil,
+			false,
+		},
+		{
+			"failed",
+			&catalog.App{
+				Spec: catalog.ReleaseSpec{
+					Info: &catalog.Info{
+						Status: catalog.StatusFailed,
+					},
+				},
+			},
+			nil,
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := isDeployed(tt.app, "app", "1.0.0", tt.values)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedResult, result)
+		})
+	}
+}
+
+func TestDriftPatch(t *testing.T) {
+	desired := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":              "app",
+			"namespace":         "apps",
+			"creationTimestamp": nil,
+		},
+		"spec": map[string]interface{}{
+			"replicas": int64(1),
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{
+							"name":  "app",
+							"image": "app:1.0.0",
+						},
+					},
+				},
+			},
+		},
+	}}
+	live := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":              "app",
+			"namespace":         "apps",
+			"creationTimestamp": "2021-07-01T00:00:00Z",
+			"uid":               "1234",
+		},
+		"spec": map[string]interface{}{
+			"replicas":             int64(1),
+			"revisionHistoryLimit": int64(10),
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{
+							"name":            "app",
+							"image":           "app:1.0.0",
+							"imagePullPolicy": "IfNotPresent",
+						},
+					},
+				},
+			},
+		},
+		"status": map[string]interface{}{
+			"replicas": int64(1),
+		},
+	}}
+
+	patch, err := driftPatch(desired, live)
+	require.NoError(t, err)
+	assert.Empty(t, patch)
+
+	unstructured.SetNestedField(live.Object, int64(5), "spec", "replicas")
+	patch, err = driftPatch(desired, live)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"spec":{"replicas":1}}`, patch)
+
+	unstructured.SetNestedSlice(live.Object, []interface{}{
+		map[string]interface{}{
+			"name":  "app",
+			"image": "app:2.0.0",
+		},
+	}, "spec", "template", "spec", "containers")
+	patch, err = driftPatch(desired, live)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"spec":{"replicas":1,"template":{"spec":{"containers":[{"name":"app","image":"app:1.0.0"}]}}}}`, patch)
+
+	secret := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata": map[string]interface{}{
+			"name": "app",
+		},
+		"stringData": map[string]interface{}{
+			"password": "secret",
+		},
+	}}
+	liveSecret := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata": map[string]interface{}{
+			"name": "app",
+		},
+		"data": map[string]interface{}{
+			"password": "c2VjcmV0",
+		},
+		"type": "Opaque",
+	}}
+
+	patch, err = driftPatch(secret, liveSecret)
+	require.NoError(t, err)
+	assert.Empty(t, patch)
+
+	// the values of secrets never end up in the status
+	unstructured.SetNestedField(liveSecret.Object, "b3RoZXI=", "data", "password")
+	unstructured.SetNestedField(liveSecret.Object, "Opaque", "type")
+	patch, err = driftPatch(secret, liveSecret)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"data":{"password":"[redacted]"}}`, patch)
+	assert.NotContains(t, patch, "c2VjcmV0")
+}
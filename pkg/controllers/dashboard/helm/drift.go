@@ -0,0 +1,242 @@
+package helm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+
+	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/catalogv2/helm"
+	"github.com/rancher/wrangler/pkg/yaml"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const redacted = "[redacted]"
+
+// drift compares the resources of the manifest helm last deployed for a release to the resources in the cluster
+func (h *appInstallHandler) drift(namespace, releaseName string) ([]catalog.ResourceDrift, error) {
+	manifest, err := h.deployedManifest(namespace, releaseName)
+	if err != nil || manifest == "" {
+		return nil, err
+	}
+
+	objs, err := yaml.ToObjects(bytes.NewReader([]byte(manifest)))
+	if err != nil {
+		return nil, err
+	}
+
+	var result []catalog.ResourceDrift
+	for _, obj := range objs {
+		desired, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+
+		gvk := desired.GroupVersionKind()
+		_, namespaced, err := h.sharedClientFactory.ResourceForGVK(gvk)
+		if err != nil {
+			return nil, err
+		}
+		if !namespaced {
+			desired.SetNamespace("")
+		} else if desired.GetNamespace() == "" {
+			desired.SetNamespace(namespace)
+		}
+
+		client, err := h.sharedClientFactory.ForKind(gvk)
+		if err != nil {
+			return nil, err
+		}
+
+		drift := catalog.ResourceDrift{
+			APIVersion: desired.GetAPIVersion(),
+			Kind:       desired.GetKind(),
+			Namespace:  desired.GetNamespace(),
+			Name:       desired.GetName(),
+		}
+
+		live := &unstructured.Unstructured{}
+		err = client.Get(h.ctx, desired.GetNamespace(), desired.GetName(), live, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			drift.Change = "missing"
+			result = append(result, drift)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		patch, err := driftPatch(desired, live)
+		if err != nil {
+			return nil, err
+		}
+		if patch != "" {
+			drift.Change = "modified"
+			drift.Patch = patch
+			result = append(result, drift)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Kind != result[j].Kind {
+			return result[i].Kind < result[j].Kind
+		}
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// driftPatch returns the JSON merge patch restoring the fields of the live resource that were set by the chart, or
+// an empty string if all of them are unchanged. Fields added to the live resource by the api server or other
+// controllers are not drift, so maps only compare the keys of the manifest and lists compare their items one by one.
+// The values of secrets are redacted, the patch is saved in the status of the AppInstall.
+func driftPatch(desired, live *unstructured.Unstructured) (string, error) {
+	patch, drifted := restore(secretData(desired.Object), live.Object)
+	if !drifted {
+		return "", nil
+	}
+	if desired.GetKind() == "Secret" {
+		redactSecretData(patch)
+	}
+	data, err := json.Marshal(patch)
+	return string(data), err
+}
+
+// redactSecretData replaces the values of the data of a secret patch, which only tells which keys drifted
+func redactSecretData(patch map[string]interface{}) {
+	data, ok := patch["data"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for k := range data {
+		data[k] = redacted
+	}
+}
+
+// restore returns the fields of desired that differ in live. Lists are restored as a whole, like in a merge patch.
+func restore(desired, live map[string]interface{}) (map[string]interface{}, bool) {
+	patch := map[string]interface{}{}
+	for k, v := range desired {
+		// null fields, like the creationTimestamp: null of generated manifests, are not set by the chart
+		if v == nil {
+			continue
+		}
+		liveValue, ok := live[k]
+		if desiredMap, isMap := v.(map[string]interface{}); isMap {
+			liveMap, _ := liveValue.(map[string]interface{})
+			if fields, drifted := restore(desiredMap, liveMap); drifted {
+				patch[k] = fields
+			}
+			continue
+		}
+		if !ok || !matches(v, liveValue) {
+			patch[k] = v
+		}
+	}
+	return patch, len(patch) > 0
+}
+
+func matches(desired, live interface{}) bool {
+	switch desired := desired.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		liveMap, ok := live.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		_, drifted := restore(desired, liveMap)
+		return !drifted
+	case []interface{}:
+		liveSlice, ok := live.([]interface{})
+		if !ok || len(desired) != len(liveSlice) {
+			return false
+		}
+		for i := range desired {
+			if !matches(desired[i], liveSlice[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if desiredNumber, ok := number(desired); ok {
+		liveNumber, ok := number(live)
+		return ok && desiredNumber == liveNumber
+	}
+	return reflect.DeepEqual(desired, live)
+}
+
+func number(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// secretData moves the stringData of a secret to its data, as the api server does
+func secretData(obj map[string]interface{}) map[string]interface{} {
+	stringData, ok := obj["stringData"].(map[string]interface{})
+	if !ok || obj["kind"] != "Secret" {
+		return obj
+	}
+
+	result := map[string]interface{}{}
+	for k, v := range obj {
+		if k != "stringData" {
+			result[k] = v
+		}
+	}
+	secretData, _ := obj["data"].(map[string]interface{})
+	mergedData := map[string]interface{}{}
+	for k, v := range secretData {
+		mergedData[k] = v
+	}
+	for k, v := range stringData {
+		if s, ok := v.(string); ok {
+			mergedData[k] = base64.StdEncoding.EncodeToString([]byte(s))
+		}
+	}
+	result["data"] = mergedData
+	return result
+}
+
+// deployedManifest returns the manifest of the latest deployed revision of a release, or an empty string if there is
+// none
+func (h *appInstallHandler) deployedManifest(namespace, releaseName string) (string, error) {
+	secrets, err := h.secrets.List(namespace, labels.SelectorFromSet(labels.Set{
+		"owner":  "helm",
+		"name":   releaseName,
+		"status": "deployed",
+	}))
+	if err != nil {
+		return "", err
+	}
+
+	latest, latestRevision := -1, 0
+	for i, secret := range secrets {
+		revision, err := strconv.Atoi(secret.Labels["version"])
+		if err != nil || revision <= latestRevision {
+			continue
+		}
+		latest, latestRevision = i, revision
+	}
+	if latest < 0 {
+		return "", nil
+	}
+
+	return helm.ToManifest(secrets[latest])
+}
@@ -5,6 +5,7 @@
 	"fmt"
 
 	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/catalogv2/helmop"
 	catalogcontrollers "github.com/rancher/rancher/pkg/generated/controllers/catalog.cattle.io/v1"
 	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
 	"github.com/rancher/wrangler/pkg/kstatus"
@@ -82,6 +83,10 @@ func (o *operationHandler) onOperationChange(operation *catalog.Operation, statu
 			status.PodCreated = true
 			kstatus.SetTransitioning(&status, "running operation")
 		} else if container.State.Terminated != nil {
+			if !kstatus.Reconciling.IsFalse(&status) {
+				// first time the terminated pod is observed
+				helmop.RecordOperationCompleted(status.Action, container.State.Terminated.ExitCode == 0)
+			}
 			status.PodCreated = true
 			if container.State.Terminated.ExitCode == 0 {
 				kstatus.SetActive(&status)
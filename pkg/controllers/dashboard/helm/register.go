@@ -22,4 +22,17 @@ func Register(ctx context.Context, wrangler *wrangler.Context) {
 		wrangler.K8s,
 		wrangler.Core.Pod(),
 		wrangler.Catalog.Operation())
+	RegisterAppInstalls(ctx,
+		wrangler.CatalogContentManager,
+		wrangler.HelmOperations,
+		wrangler.ControllerFactory.SharedCacheFactory().SharedClientFactory(),
+		wrangler.Core.ConfigMap(),
+		wrangler.Core.Secret(),
+		wrangler.Core.Pod().Cache(),
+		wrangler.Catalog.ClusterRepo(),
+		wrangler.Catalog.App(),
+		wrangler.Catalog.Operation().Cache(),
+		wrangler.Mgmt.User().Cache(),
+		wrangler.Mgmt.UserAttribute().Cache(),
+		wrangler.Catalog.AppInstall())
 }
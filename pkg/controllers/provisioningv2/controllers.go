@@ -9,10 +9,12 @@
 	"github.com/rancher/rancher/pkg/controllers/provisioningv2/managedchart"
 	"github.com/rancher/rancher/pkg/controllers/provisioningv2/rke2/bootstrap"
 	"github.com/rancher/rancher/pkg/controllers/provisioningv2/rke2/dynamicschema"
+	"github.com/rancher/rancher/pkg/controllers/provisioningv2/rke2/etcdsnapshotrecord"
 	"github.com/rancher/rancher/pkg/controllers/provisioningv2/rke2/machinedrain"
 	"github.com/rancher/rancher/pkg/controllers/provisioningv2/rke2/machinenodelookup"
 	"github.com/rancher/rancher/pkg/controllers/provisioningv2/rke2/machineorphan"
 	"github.com/rancher/rancher/pkg/controllers/provisioningv2/rke2/machineprovision"
+	"github.com/rancher/rancher/pkg/controllers/provisioningv2/rke2/machineremediation"
 	"github.com/rancher/rancher/pkg/controllers/provisioningv2/rke2/machinestatus"
 	"github.com/rancher/rancher/pkg/controllers/provisioningv2/rke2/managesystemagent"
 	"github.com/rancher/rancher/pkg/controllers/provisioningv2/rke2/planner"
@@ -52,11 +54,13 @@ func Register(ctx context.Context, clients *wrangler.Context) error {
 		planner.Register(ctx, clients, rkePlanner)
 		planstatus.Register(ctx, clients)
 		machinestatus.Register(ctx, clients)
+		machineremediation.Register(ctx, clients)
 		unmanaged.Register(ctx, clients)
 		rkecontrolplane.Register(ctx, clients)
 		managesystemagent.Register(ctx, clients)
 		machinedrain.Register(ctx, clients)
 		machineorphan.Register(ctx, clients)
+		etcdsnapshotrecord.Register(ctx, clients)
 	}
 
 	if features.EmbeddedClusterAPI.Enabled() {
@@ -0,0 +1,471 @@
+package etcdsnapshotrecord
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	provisioningcontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
+	rkecontroller "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/provisioningv2/rke2/planner"
+	"github.com/rancher/rancher/pkg/wrangler"
+	"github.com/rancher/wrangler/pkg/apply"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/pkg/name"
+	"github.com/rancher/wrangler/pkg/relatedresource"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// The snapshots of a cluster are listed in its status from the configmap the runtime keeps downstream. This handler
+// turns every entry into an ETCDSnapshotRecord, then verifies that the snapshot still exists and matches the checksum
+// recorded the first time it was read. S3 objects are compared by their ETag, local files by the checksums the planner
+// reads on their node when they are created and every verify interval. Records of snapshots the runtime stops listing
+// are kept as missing, unless the snapshot was deleted by a retention. Records also keep the configuration of the
+// cluster that was in effect when the snapshot was taken, a restore can roll the cluster back to it.
+
+type handler struct {
+	ctx                  context.Context
+	apply                apply.Apply
+	clusters             provisioningcontrollers.ClusterClient
+	clusterCache         provisioningcontrollers.ClusterCache
+	records              rkecontroller.ETCDSnapshotRecordController
+	recordCache          rkecontroller.ETCDSnapshotRecordCache
+	rkeControlPlaneCache rkecontroller.RKEControlPlaneCache
+	secretCache          corecontrollers.SecretCache
+}
+
+func Register(ctx context.Context, clients *wrangler.Context) {
+	h := handler{
+		ctx: ctx,
+		apply: clients.Apply.WithSetID("etcd-snapshot-record").
+			WithCacheTypes(clients.RKE.ETCDSnapshotRecord()),
+		clusters:             clients.Provisioning.Cluster(),
+		clusterCache:         clients.Provisioning.Cluster().Cache(),
+		records:              clients.RKE.ETCDSnapshotRecord(),
+		recordCache:          clients.RKE.ETCDSnapshotRecord().Cache(),
+		rkeControlPlaneCache: clients.RKE.RKEControlPlane().Cache(),
+		secretCache:          clients.Core.Secret().Cache(),
+	}
+
+	clients.Provisioning.Cluster().OnChange(ctx, "etcd-snapshot-record", h.OnClusterChange)
+	clients.RKE.ETCDSnapshotRecord().OnChange(ctx, "etcd-snapshot-record-verify", h.OnChange)
+
+	relatedresource.Watch(ctx, "etcd-snapshot-record-trigger", func(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+		cp, ok := obj.(*rkev1.RKEControlPlane)
+		if !ok {
+			return nil, nil
+		}
+		records, err := h.recordCache.List(namespace, labels.Everything())
+		if err != nil {
+			return nil, err
+		}
+		var result []relatedresource.Key
+		for _, record := range records {
+			if record.Spec.ClusterName == cp.Spec.ClusterName && record.Spec.Snapshot.S3 == nil {
+				result = append(result, relatedresource.Key{
+					Namespace: record.Namespace,
+					Name:      record.Name,
+				})
+			}
+		}
+		return result, nil
+	}, clients.RKE.ETCDSnapshotRecord(), clients.RKE.RKEControlPlane())
+}
+
+func recordName(cluster *rancherv1.Cluster, snapshot rkev1.ETCDSnapshot) string {
+	location := "local"
+	if snapshot.S3 != nil {
+		location = "s3"
+	}
+	return name.SafeConcatName(cluster.Name, snapshot.Name, location)
+}
+
+func records(cluster *rancherv1.Cluster) []runtime.Object {
+	var result []runtime.Object
+	for _, snapshot := range cluster.Status.ETCDSnapshots {
+		result = append(result, &rkev1.ETCDSnapshotRecord{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      recordName(cluster, snapshot),
+				Namespace: cluster.Namespace,
+			},
+			Spec: rkev1.ETCDSnapshotRecordSpec{
+				ClusterName: cluster.Name,
+				Snapshot:    snapshot,
+			},
+		})
+	}
+	return result
+}
+
+func (h *handler) OnClusterChange(key string, cluster *rancherv1.Cluster) (*rancherv1.Cluster, error) {
+	if cluster == nil || cluster.DeletionTimestamp != nil || cluster.Spec.RKEConfig == nil {
+		return cluster, nil
+	}
+
+	// records of snapshots the runtime no longer lists are kept, see removeUnlisted
+	if err := h.apply.WithOwner(cluster).WithNoDelete().ApplyObjects(records(cluster)...); err != nil {
+		return cluster, err
+	}
+
+	if err := h.removeUnlisted(cluster); err != nil {
+		return cluster, err
+	}
+
+	if err := h.enforceS3Retention(cluster); err != nil {
+		return cluster, err
+	}
+
+	return h.restoreRKEConfig(cluster)
+}
+
+// restoreRKEConfig rolls the spec of cluster back to the configuration recorded with the snapshot it restores, if
+// asked to. The planner waits for the rollback before it shuts etcd down.
+func (h *handler) restoreRKEConfig(cluster *rancherv1.Cluster) (*rancherv1.Cluster, error) {
+	restore := cluster.Spec.RKEConfig.ETCDSnapshotRestore
+	if restore == nil || !planner.RestoresRKEConfig(restore) {
+		return cluster, nil
+	}
+
+	controlPlane, err := h.rkeControlPlaneCache.Get(cluster.Namespace, cluster.Name)
+	if apierror.IsNotFound(err) {
+		return cluster, nil
+	} else if err != nil {
+		return cluster, err
+	}
+
+	// Once etcd is shut down the rollback is done, the user may change the spec again
+	if controlPlane.Status.ETCDSnapshotRestore != nil &&
+		equality.Semantic.DeepEqual(*restore, *controlPlane.Status.ETCDSnapshotRestore) &&
+		controlPlane.Status.ETCDSnapshotRestorePhase != "" &&
+		controlPlane.Status.ETCDSnapshotRestorePhase != rkev1.ETCDSnapshotPhaseStarted {
+		return cluster, nil
+	}
+
+	record, err := planner.FindETCDSnapshotRecord(h.recordCache, cluster.Namespace, cluster.Name, restore)
+	if err != nil {
+		return cluster, err
+	}
+	if record.Status.AppliedSpec == nil {
+		return cluster, fmt.Errorf("etcd snapshot [%s] was taken before the configuration of the cluster was recorded, it can only be restored with restoreRKEConfig none",
+			record.Spec.Snapshot.Name)
+	}
+
+	rolledBack, changed := rollbackSpec(cluster, record.Status.AppliedSpec, restore.RestoreRKEConfig)
+	if !changed {
+		return cluster, nil
+	}
+	logrus.Infof("[etcdsnapshotrecord] restoring configuration of cluster %s/%s from etcd snapshot %s", cluster.Namespace,
+		cluster.Name, record.Spec.Snapshot.Name)
+	return h.clusters.Update(rolledBack)
+}
+
+func rollbackSpec(cluster *rancherv1.Cluster, appliedSpec *rkev1.AppliedSpec, mode rkev1.ETCDSnapshotRestoreRKEConfig) (*rancherv1.Cluster, bool) {
+	result := cluster.DeepCopy()
+	result.Spec.KubernetesVersion = appliedSpec.KubernetesVersion
+	if mode == rkev1.ETCDSnapshotRestoreRKEConfigAll && appliedSpec.RKEConfig != nil {
+		result.Spec.RKEConfig.RKEClusterSpecCommon = *appliedSpec.RKEConfig.DeepCopy()
+	}
+	return result, !equality.Semantic.DeepEqual(cluster.Spec, result.Spec)
+}
+
+// defaultSnapshotRetention is the snapshot retention of the runtime if the cluster doesn't set one
+const defaultSnapshotRetention = 5
+
+// retentions returns how many local snapshots the runtime keeps on every node and how many snapshots it keeps in S3.
+func retentions(cluster *rancherv1.Cluster) (local int, s3 int) {
+	local, s3 = defaultSnapshotRetention, defaultSnapshotRetention
+	if etcd := cluster.Spec.RKEConfig.ETCD; etcd != nil && etcd.SnapshotRetention > 0 {
+		local, s3 = etcd.SnapshotRetention, etcd.SnapshotRetention
+	}
+	if etcd := cluster.Spec.RKEConfig.ETCD; etcd != nil && etcd.S3SnapshotRetention > 0 {
+		s3 = etcd.S3SnapshotRetention
+	}
+	return local, s3
+}
+
+// expired returns the names of the records past the retention of their location, the newest local snapshots are kept
+// on every node.
+func expired(records []*rkev1.ETCDSnapshotRecord, localRetention, s3Retention int) map[string]bool {
+	locations := map[string][]*rkev1.ETCDSnapshotRecord{}
+	for _, record := range records {
+		location := "s3"
+		if record.Spec.Snapshot.S3 == nil {
+			location = "local/" + record.Spec.Snapshot.NodeName
+		}
+		locations[location] = append(locations[location], record)
+	}
+
+	result := map[string]bool{}
+	for location, records := range locations {
+		retention := localRetention
+		if location == "s3" {
+			retention = s3Retention
+		}
+		for _, record := range toPrune(records, retention) {
+			result[record.Name] = true
+		}
+	}
+	return result
+}
+
+// removeUnlisted handles the records of the snapshots the runtime no longer lists. Snapshots pruned by rancher or past
+// the retention of their location were deleted on purpose and their record is removed, any other snapshot is missing.
+func (h *handler) removeUnlisted(cluster *rancherv1.Cluster) error {
+	all, err := h.recordCache.List(cluster.Namespace, labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	var clusterRecords []*rkev1.ETCDSnapshotRecord
+	for _, record := range all {
+		if record.Spec.ClusterName == cluster.Name {
+			clusterRecords = append(clusterRecords, record)
+		}
+	}
+
+	localRetention, s3Retention := retentions(cluster)
+	expiredRecords := expired(clusterRecords, localRetention, s3Retention)
+	for _, record := range clusterRecords {
+		if listed(cluster, record) {
+			continue
+		}
+		if record.Status.State == rkev1.ETCDSnapshotRecordStatePruned || expiredRecords[record.Name] {
+			if err := h.records.Delete(record.Namespace, record.Name, nil); err != nil && !apierror.IsNotFound(err) {
+				return err
+			}
+			continue
+		}
+		if record.Status.State != rkev1.ETCDSnapshotRecordStateMissing {
+			record = record.DeepCopy()
+			record.Status.State = rkev1.ETCDSnapshotRecordStateMissing
+			record.Status.Message = "snapshot is no longer listed by the cluster"
+			if _, err := h.records.UpdateStatus(record); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func listed(cluster *rancherv1.Cluster, record *rkev1.ETCDSnapshotRecord) bool {
+	for _, snapshot := range cluster.Status.ETCDSnapshots {
+		if recordName(cluster, snapshot) == record.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// enforceS3Retention deletes the oldest S3 snapshots of cluster past the configured retention and marks their record
+// as pruned. The record is removed once the runtime drops the snapshot from its list.
+func (h *handler) enforceS3Retention(cluster *rancherv1.Cluster) error {
+	etcd := cluster.Spec.RKEConfig.ETCD
+	if etcd == nil {
+		return nil
+	}
+	retention := etcd.S3SnapshotRetention
+	if retention == 0 {
+		retention = etcd.SnapshotRetention
+	}
+	if retention <= 0 {
+		return nil
+	}
+
+	var kept []*rkev1.ETCDSnapshotRecord
+	for _, snapshot := range cluster.Status.ETCDSnapshots {
+		if snapshot.S3 == nil {
+			continue
+		}
+		record, err := h.recordCache.Get(cluster.Namespace, recordName(cluster, snapshot))
+		if apierror.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if record.Status.State != rkev1.ETCDSnapshotRecordStatePruned {
+			kept = append(kept, record)
+		}
+	}
+
+	for _, record := range toPrune(kept, retention) {
+		if err := h.pruneS3(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// toPrune returns the records past the newest retention ones.
+func toPrune(records []*rkev1.ETCDSnapshotRecord, retention int) []*rkev1.ETCDSnapshotRecord {
+	if len(records) <= retention {
+		return nil
+	}
+	sorted := append([]*rkev1.ETCDSnapshotRecord{}, records...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return createdAt(sorted[i]).After(createdAt(sorted[j]))
+	})
+	return sorted[retention:]
+}
+
+func createdAt(record *rkev1.ETCDSnapshotRecord) time.Time {
+	if record.Spec.Snapshot.CreatedAt == nil {
+		return time.Time{}
+	}
+	return record.Spec.Snapshot.CreatedAt.Time
+}
+
+func (h *handler) pruneS3(record *rkev1.ETCDSnapshotRecord) error {
+	controlPlane, err := h.rkeControlPlaneCache.Get(record.Namespace, record.Spec.ClusterName)
+	if err != nil {
+		return err
+	}
+
+	client, bucket, key, err := h.s3Object(controlPlane, record)
+	if err != nil {
+		return err
+	}
+	if err := removeObject(h.ctx, client, bucket, key); err != nil {
+		return fmt.Errorf("failed to prune etcd snapshot [%s] from bucket [%s]: %w", key, bucket, err)
+	}
+	logrus.Infof("[etcdsnapshotrecord] pruned etcd snapshot %s of cluster %s/%s from bucket %s", key,
+		record.Namespace, record.Spec.ClusterName, bucket)
+
+	record = record.DeepCopy()
+	record.Status.State = rkev1.ETCDSnapshotRecordStatePruned
+	record.Status.Message = "deleted from S3 by the snapshot retention of the cluster"
+	_, err = h.records.UpdateStatus(record)
+	return err
+}
+
+func (h *handler) OnChange(key string, record *rkev1.ETCDSnapshotRecord) (*rkev1.ETCDSnapshotRecord, error) {
+	if record == nil || record.DeletionTimestamp != nil || record.Status.State == rkev1.ETCDSnapshotRecordStatePruned {
+		return record, nil
+	}
+
+	cluster, err := h.clusterCache.Get(record.Namespace, record.Spec.ClusterName)
+	if apierror.IsNotFound(err) {
+		return record, nil
+	} else if err != nil {
+		return record, err
+	}
+	// the snapshot of the record is missing, there is nothing left to verify
+	if !listed(cluster, record) {
+		return record, nil
+	}
+
+	controlPlane, err := h.rkeControlPlaneCache.Get(record.Namespace, record.Spec.ClusterName)
+	if apierror.IsNotFound(err) {
+		return record, nil
+	} else if err != nil {
+		return record, err
+	}
+
+	// Recorded before anything else, the verification of the snapshot can fail for a while
+	if record.Status.AppliedSpec == nil {
+		if appliedSpec := appliedSpecAt(controlPlane.Status.AppliedSpecHistory, record.Spec.Snapshot.CreatedAt); appliedSpec != nil {
+			record = record.DeepCopy()
+			record.Status.AppliedSpec = appliedSpec
+			if record, err = h.records.UpdateStatus(record); err != nil {
+				return record, err
+			}
+		}
+	}
+
+	var status rkev1.ETCDSnapshotRecordStatus
+	if record.Spec.Snapshot.S3 == nil {
+		status = verifyLocal(controlPlane.Status.ETCDSnapshotChecksums, record)
+	} else {
+		interval := planner.SnapshotVerifyInterval()
+		if last, err := time.Parse(time.RFC3339, record.Status.LastVerified); err == nil {
+			if remaining := last.Add(interval).Sub(time.Now()); remaining > 0 {
+				h.records.EnqueueAfter(record.Namespace, record.Name, remaining)
+				return record, nil
+			}
+		}
+		status, err = h.verifyS3(controlPlane, record)
+		if err != nil {
+			return record, err
+		}
+		status.LastVerified = time.Now().UTC().Format(time.RFC3339)
+		h.records.EnqueueAfter(record.Namespace, record.Name, interval)
+	}
+
+	if equality.Semantic.DeepEqual(status, record.Status) {
+		return record, nil
+	}
+	if status.LastVerified == record.Status.LastVerified {
+		status.LastVerified = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	record = record.DeepCopy()
+	record.Status = status
+	return h.records.UpdateStatus(record)
+}
+
+// appliedSpecAt returns the last spec applied before a snapshot was taken, nil if the snapshot is older than history.
+func appliedSpecAt(history []rkev1.AppliedSpec, createdAt *metav1.Time) *rkev1.AppliedSpec {
+	if createdAt == nil {
+		return nil
+	}
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].AppliedAt != nil && !history[i].AppliedAt.After(createdAt.Time) {
+			return history[i].DeepCopy()
+		}
+	}
+	return nil
+}
+
+// verifyLocal compares the checksum last read on the node of a local snapshot with the one recorded for it.
+func verifyLocal(checksums []rkev1.ETCDSnapshotChecksum, record *rkev1.ETCDSnapshotRecord) rkev1.ETCDSnapshotRecordStatus {
+	status := record.Status
+	snapshot := record.Spec.Snapshot
+
+	var (
+		current   *rkev1.ETCDSnapshotChecksum
+		nodeFound bool
+	)
+	for i, checksum := range checksums {
+		if checksum.NodeName != snapshot.NodeName {
+			continue
+		}
+		nodeFound = true
+		if checksum.Name == snapshot.Name {
+			current = &checksums[i]
+		}
+	}
+
+	if current == nil {
+		if status.Checksum != "" && nodeFound {
+			status.State = rkev1.ETCDSnapshotRecordStateMissing
+			status.Message = fmt.Sprintf("snapshot is no longer on node [%s]", snapshot.NodeName)
+		} else if status.Checksum == "" {
+			status.State = rkev1.ETCDSnapshotRecordStateUnverified
+			status.Message = fmt.Sprintf("checksum has not been read on node [%s] yet", snapshot.NodeName)
+		}
+		return status
+	}
+
+	return compare(status, current.Checksum)
+}
+
+func compare(status rkev1.ETCDSnapshotRecordStatus, checksum string) rkev1.ETCDSnapshotRecordStatus {
+	if status.Checksum == "" {
+		status.Checksum = checksum
+	}
+	if status.Checksum != checksum {
+		status.State = rkev1.ETCDSnapshotRecordStateCorrupt
+		status.Message = fmt.Sprintf("checksum [%s] does not match the recorded checksum", checksum)
+	} else {
+		status.State = rkev1.ETCDSnapshotRecordStateVerified
+		status.Message = ""
+	}
+	return status
+}
@@ -0,0 +1,198 @@
+package etcdsnapshotrecord
+
+import (
+	"testing"
+	"time"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func localRecord(name, nodeName, checksum string) *rkev1.ETCDSnapshotRecord {
+	return &rkev1.ETCDSnapshotRecord{
+		Spec: rkev1.ETCDSnapshotRecordSpec{
+			Snapshot: rkev1.ETCDSnapshot{
+				Name:     name,
+				NodeName: nodeName,
+			},
+		},
+		Status: rkev1.ETCDSnapshotRecordStatus{
+			Checksum: checksum,
+		},
+	}
+}
+
+func TestVerifyLocal(t *testing.T) {
+	checksums := []rkev1.ETCDSnapshotChecksum{
+		{Name: "snap-1", NodeName: "node1", Checksum: "aaa"},
+		{Name: "snap-2", NodeName: "node1", Checksum: "bbb"},
+	}
+
+	tests := []struct {
+		name   string
+		record *rkev1.ETCDSnapshotRecord
+		state  rkev1.ETCDSnapshotRecordState
+		sum    string
+	}{
+		{"first read records the checksum", localRecord("snap-1", "node1", ""), rkev1.ETCDSnapshotRecordStateVerified, "aaa"},
+		{"matching checksum", localRecord("snap-2", "node1", "bbb"), rkev1.ETCDSnapshotRecordStateVerified, "bbb"},
+		{"changed checksum", localRecord("snap-2", "node1", "ccc"), rkev1.ETCDSnapshotRecordStateCorrupt, "ccc"},
+		{"deleted from node", localRecord("snap-0", "node1", "ddd"), rkev1.ETCDSnapshotRecordStateMissing, "ddd"},
+		{"never read", localRecord("snap-3", "node2", ""), rkev1.ETCDSnapshotRecordStateUnverified, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := verifyLocal(checksums, tt.record)
+			assert.Equal(t, tt.state, status.State)
+			assert.Equal(t, tt.sum, status.Checksum)
+		})
+	}
+}
+
+func TestToPrune(t *testing.T) {
+	now := time.Now()
+	record := func(name string, age time.Duration) *rkev1.ETCDSnapshotRecord {
+		createdAt := metav1.NewTime(now.Add(-age))
+		return &rkev1.ETCDSnapshotRecord{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: rkev1.ETCDSnapshotRecordSpec{
+				Snapshot: rkev1.ETCDSnapshot{Name: name, CreatedAt: &createdAt},
+			},
+		}
+	}
+	oldest, old, recent := record("oldest", 3*time.Hour), record("old", 2*time.Hour), record("recent", time.Hour)
+
+	assert.Equal(t, []*rkev1.ETCDSnapshotRecord{old, oldest}, toPrune([]*rkev1.ETCDSnapshotRecord{old, recent, oldest}, 1))
+	assert.Nil(t, toPrune([]*rkev1.ETCDSnapshotRecord{old, recent}, 2))
+}
+
+func TestRollbackSpec(t *testing.T) {
+	cluster := &rancherv1.Cluster{
+		Spec: rancherv1.ClusterSpec{
+			KubernetesVersion: "v1.21.5+rke2r2",
+			RKEConfig: &rancherv1.RKEConfig{
+				RKEClusterSpecCommon: rkev1.RKEClusterSpecCommon{
+					AdditionalManifest: "new",
+				},
+				ETCDSnapshotRestore: &rkev1.ETCDSnapshot{
+					Name:             "snap-1",
+					RestoreRKEConfig: rkev1.ETCDSnapshotRestoreRKEConfigAll,
+				},
+			},
+		},
+	}
+	appliedSpec := &rkev1.AppliedSpec{
+		KubernetesVersion: "v1.20.11+rke2r2",
+		RKEConfig: &rkev1.RKEClusterSpecCommon{
+			AdditionalManifest: "old",
+		},
+	}
+
+	result, changed := rollbackSpec(cluster, appliedSpec, rkev1.ETCDSnapshotRestoreRKEConfigKubernetesVersion)
+	assert.True(t, changed)
+	assert.Equal(t, "v1.20.11+rke2r2", result.Spec.KubernetesVersion)
+	assert.Equal(t, "new", result.Spec.RKEConfig.AdditionalManifest)
+
+	result, changed = rollbackSpec(cluster, appliedSpec, rkev1.ETCDSnapshotRestoreRKEConfigAll)
+	assert.True(t, changed)
+	assert.Equal(t, "old", result.Spec.RKEConfig.AdditionalManifest)
+	assert.Equal(t, cluster.Spec.RKEConfig.ETCDSnapshotRestore, result.Spec.RKEConfig.ETCDSnapshotRestore)
+
+	_, changed = rollbackSpec(result, appliedSpec, rkev1.ETCDSnapshotRestoreRKEConfigAll)
+	assert.False(t, changed)
+}
+
+func TestExpired(t *testing.T) {
+	now := time.Now()
+	record := func(name, nodeName string, s3 bool, age time.Duration) *rkev1.ETCDSnapshotRecord {
+		createdAt := metav1.NewTime(now.Add(-age))
+		record := &rkev1.ETCDSnapshotRecord{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: rkev1.ETCDSnapshotRecordSpec{
+				Snapshot: rkev1.ETCDSnapshot{Name: name, NodeName: nodeName, CreatedAt: &createdAt},
+			},
+		}
+		if s3 {
+			record.Spec.Snapshot.S3 = &rkev1.ETCDSnapshotS3{Bucket: "snapshots"}
+		}
+		return record
+	}
+
+	records := []*rkev1.ETCDSnapshotRecord{
+		record("node1-old", "node1", false, 2*time.Hour),
+		record("node1-new", "node1", false, time.Hour),
+		record("node2-old", "node2", false, 3*time.Hour),
+		record("s3-old", "node1", true, 2*time.Hour),
+		record("s3-new", "node2", true, time.Hour),
+	}
+
+	// local snapshots are kept on every node
+	assert.Equal(t, map[string]bool{"node1-old": true, "s3-old": true}, expired(records, 1, 1))
+	assert.Equal(t, map[string]bool{"node1-old": true}, expired(records, 1, 2))
+	assert.Empty(t, expired(records, 2, 2))
+}
+
+func TestS3Config(t *testing.T) {
+	controlPlane := &rkev1.RKEControlPlane{
+		Spec: rkev1.RKEControlPlaneSpec{
+			RKEClusterSpecCommon: rkev1.RKEClusterSpecCommon{
+				ETCD: &rkev1.ETCD{
+					S3: &rkev1.ETCDSnapshotS3{
+						Bucket:              "snapshots",
+						Endpoint:            "http://minio.example.com:9000",
+						SkipSSLVerify:       true,
+						CloudCredentialName: "cc-abc",
+					},
+				},
+			},
+		},
+	}
+
+	s3 := s3Config(controlPlane, rkev1.ETCDSnapshot{S3: &rkev1.ETCDSnapshotS3{Bucket: "snapshots", Folder: "prod"}})
+	assert.Equal(t, rkev1.ETCDSnapshotS3{
+		Bucket:              "snapshots",
+		Folder:              "prod",
+		Endpoint:            "http://minio.example.com:9000",
+		SkipSSLVerify:       true,
+		CloudCredentialName: "cc-abc",
+	}, s3)
+
+	// other buckets don't use the configuration of the cluster
+	s3 = s3Config(controlPlane, rkev1.ETCDSnapshot{S3: &rkev1.ETCDSnapshotS3{Bucket: "other"}})
+	assert.Equal(t, rkev1.ETCDSnapshotS3{Bucket: "other"}, s3)
+
+	endpoint, secure := s3Endpoint("http://minio.example.com:9000")
+	assert.Equal(t, "minio.example.com:9000", endpoint)
+	assert.False(t, secure)
+
+	endpoint, secure = s3Endpoint("https://minio.example.com")
+	assert.Equal(t, "minio.example.com", endpoint)
+	assert.True(t, secure)
+
+	endpoint, secure = s3Endpoint("")
+	assert.Equal(t, defaultS3Endpoint, endpoint)
+	assert.True(t, secure)
+}
+
+func TestAppliedSpecAt(t *testing.T) {
+	at := func(hour int) *metav1.Time {
+		t := metav1.NewTime(time.Date(2021, 10, 1, hour, 0, 0, 0, time.UTC))
+		return &t
+	}
+	history := []rkev1.AppliedSpec{
+		{KubernetesVersion: "v1.20.11+rke2r2", AppliedAt: at(2)},
+		{KubernetesVersion: "v1.21.5+rke2r2", AppliedAt: at(4)},
+	}
+
+	// snapshots taken before the first recorded spec have no known configuration
+	assert.Nil(t, appliedSpecAt(history, at(1)))
+	assert.Nil(t, appliedSpecAt(history, nil))
+
+	assert.Equal(t, "v1.20.11+rke2r2", appliedSpecAt(history, at(2)).KubernetesVersion)
+	// a snapshot taken before an upgrade finished rolling out keeps the previous version
+	assert.Equal(t, "v1.20.11+rke2r2", appliedSpecAt(history, at(3)).KubernetesVersion)
+	assert.Equal(t, "v1.21.5+rke2r2", appliedSpecAt(history, at(5)).KubernetesVersion)
+}
@@ -0,0 +1,141 @@
+package etcdsnapshotrecord
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	minio "github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/provisioningv2/rke2/planner"
+)
+
+const (
+	defaultS3Endpoint = "s3.amazonaws.com"
+	s3Timeout         = 5 * time.Minute
+)
+
+// s3Config returns the S3 configuration of a snapshot. The runtime doesn't list every setting of the bucket, the
+// missing ones are taken from the S3 configuration of the cluster if the snapshot is in its bucket.
+func s3Config(controlPlane *rkev1.RKEControlPlane, snapshot rkev1.ETCDSnapshot) rkev1.ETCDSnapshotS3 {
+	result := *snapshot.S3
+	if controlPlane.Spec.ETCD == nil || controlPlane.Spec.ETCD.S3 == nil || controlPlane.Spec.ETCD.S3.Bucket != result.Bucket {
+		return result
+	}
+
+	cluster := controlPlane.Spec.ETCD.S3
+	if result.Endpoint == "" {
+		result.Endpoint = cluster.Endpoint
+	}
+	if result.EndpointCA == "" {
+		result.EndpointCA = cluster.EndpointCA
+	}
+	if result.Region == "" {
+		result.Region = cluster.Region
+	}
+	if result.CloudCredentialName == "" {
+		result.CloudCredentialName = cluster.CloudCredentialName
+	}
+	result.SkipSSLVerify = result.SkipSSLVerify || cluster.SkipSSLVerify
+	return result
+}
+
+// s3Endpoint returns the host of an endpoint and whether it is reached over https, which is the default.
+func s3Endpoint(endpoint string) (string, bool) {
+	if endpoint == "" {
+		return defaultS3Endpoint, true
+	}
+	if strings.HasPrefix(endpoint, "http://") {
+		return strings.TrimPrefix(endpoint, "http://"), false
+	}
+	return strings.TrimPrefix(endpoint, "https://"), true
+}
+
+// s3Object returns a client for the bucket of an S3 snapshot and the key of the snapshot in it. Snapshots use the
+// cloud credential of the cluster unless they name their own.
+func (h *handler) s3Object(controlPlane *rkev1.RKEControlPlane, record *rkev1.ETCDSnapshotRecord) (*minio.Client, string, string, error) {
+	s3 := s3Config(controlPlane, record.Spec.Snapshot)
+
+	cred, err := planner.GetS3Credential(h.secretCache, controlPlane.Namespace, s3.CloudCredentialName, s3.Region)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	var (
+		creds            *credentials.Credentials
+		endpoint, secure = s3Endpoint(s3.Endpoint)
+		tr               = &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+		}
+	)
+	// no access credentials, we assume IAM roles
+	if cred.AccessKey == "" || cred.SecretKey == "" {
+		creds = credentials.NewIAM("")
+	} else {
+		creds = credentials.NewStatic(cred.AccessKey, cred.SecretKey, "", credentials.SignatureDefault)
+	}
+
+	if s3.EndpointCA != "" || s3.SkipSSLVerify {
+		tlsConfig := &tls.Config{
+			InsecureSkipVerify: s3.SkipSSLVerify,
+		}
+		if s3.EndpointCA != "" {
+			tlsConfig.RootCAs = x509.NewCertPool()
+			tlsConfig.RootCAs.AppendCertsFromPEM([]byte(s3.EndpointCA))
+		}
+		tr.TLSClientConfig = tlsConfig
+	}
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:     creds,
+		Region:    cred.Region,
+		Secure:    secure,
+		Transport: tr,
+	})
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	return client, s3.Bucket, path.Join(s3.Folder, record.Spec.Snapshot.Name), nil
+}
+
+// verifyS3 compares the ETag of the object of an S3 snapshot with the one recorded the first time it was read. The
+// object is never downloaded, S3 computes the ETag from the content of the object when it is uploaded.
+func (h *handler) verifyS3(controlPlane *rkev1.RKEControlPlane, record *rkev1.ETCDSnapshotRecord) (rkev1.ETCDSnapshotRecordStatus, error) {
+	status := record.Status
+
+	client, bucket, key, err := h.s3Object(controlPlane, record)
+	if err != nil {
+		return status, err
+	}
+
+	ctx, cancel := context.WithTimeout(h.ctx, s3Timeout)
+	defer cancel()
+
+	info, err := client.StatObject(ctx, bucket, key, minio.StatObjectOptions{})
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		status.State = rkev1.ETCDSnapshotRecordStateMissing
+		status.Message = fmt.Sprintf("object [%s] not found in bucket [%s]", key, bucket)
+		return status, nil
+	} else if err != nil {
+		return status, fmt.Errorf("failed to read etcd snapshot [%s] from bucket [%s]: %w", key, bucket, err)
+	}
+
+	return compare(status, info.ETag), nil
+}
+
+func removeObject(ctx context.Context, client *minio.Client, bucket, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, s3Timeout)
+	defer cancel()
+	return client.RemoveObject(ctx, bucket, key, minio.RemoveObjectOptions{})
+}
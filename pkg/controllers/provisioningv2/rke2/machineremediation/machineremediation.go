@@ -0,0 +1,190 @@
+package machineremediation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/controllers/provisioningv2/rke2/machinestatus"
+	capicontrollers "github.com/rancher/rancher/pkg/generated/controllers/cluster.x-k8s.io/v1alpha4"
+	provisioningcontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/provisioningv2/rke2/planner"
+	"github.com/rancher/rancher/pkg/wrangler"
+	"github.com/rancher/wrangler/pkg/name"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
+)
+
+// The MachineHealthCheck of a pool replaces machines whose node is unhealthy, this handler does the same for machines
+// whose plan probes keep failing. Both mark the machine as waiting for remediation and the MachineSet deletes it.
+
+type handler struct {
+	machines         capicontrollers.MachineController
+	machineCache     capicontrollers.MachineCache
+	provClusterCache provisioningcontrollers.ClusterCache
+}
+
+func Register(ctx context.Context, clients *wrangler.Context) {
+	h := handler{
+		machines:         clients.CAPI.Machine(),
+		machineCache:     clients.CAPI.Machine().Cache(),
+		provClusterCache: clients.Provisioning.Cluster().Cache(),
+	}
+	clients.CAPI.Machine().OnChange(ctx, "machine-remediation", h.OnChange)
+}
+
+func (h *handler) OnChange(key string, machine *capi.Machine) (*capi.Machine, error) {
+	if machine == nil || machine.DeletionTimestamp != nil || machine.Labels[capi.MachineDeploymentLabelName] == "" ||
+		waitingForRemediation(machine) {
+		return machine, nil
+	}
+
+	unhealthySince, ok := probesUnhealthySince(machine)
+	if !ok {
+		return machine, nil
+	}
+
+	healthCheck, err := h.getHealthCheck(machine)
+	if err != nil || healthCheck == nil || healthCheck.UnhealthyProbesTimeout == nil {
+		return machine, err
+	}
+
+	if remaining := unhealthySince.Add(healthCheck.UnhealthyProbesTimeout.Duration).Sub(time.Now()); remaining > 0 {
+		h.machines.EnqueueAfter(machine.Namespace, machine.Name, remaining)
+		return machine, nil
+	}
+
+	poolMachines, err := h.machineCache.List(machine.Namespace, labels.SelectorFromSet(map[string]string{
+		capi.MachineDeploymentLabelName: machine.Labels[capi.MachineDeploymentLabelName],
+	}))
+	if err != nil {
+		return machine, err
+	}
+
+	allowed, err := remediationAllowed(healthCheck, poolMachines, machine)
+	if err != nil {
+		return machine, err
+	}
+	if !allowed {
+		logrus.Infof("[machineremediation] not replacing machine %s/%s with failing probes, too many machines of the pool are unhealthy",
+			machine.Namespace, machine.Name)
+		h.machines.EnqueueAfter(machine.Namespace, machine.Name, time.Minute)
+		return machine, nil
+	}
+
+	logrus.Infof("[machineremediation] replacing machine %s/%s, its probes have been failing for more than %s",
+		machine.Namespace, machine.Name, healthCheck.UnhealthyProbesTimeout.Duration)
+	machine = machine.DeepCopy()
+	setCondition(machine, capi.Condition{
+		Type:               capi.MachineOwnerRemediatedCondition,
+		Status:             corev1.ConditionFalse,
+		Severity:           capi.ConditionSeverityWarning,
+		LastTransitionTime: metav1.Now(),
+		Reason:             capi.WaitingForRemediationReason,
+		Message:            fmt.Sprintf("probes failing for more than %s", healthCheck.UnhealthyProbesTimeout.Duration),
+	})
+	return h.machines.UpdateStatus(machine)
+}
+
+// getHealthCheck returns the health check configuration of the pool of the machine, nil if it has none. Machines of
+// etcd and control plane pools are never replaced, as that can lose the quorum of etcd or all of its data.
+func (h *handler) getHealthCheck(machine *capi.Machine) (*rancherv1.RKEMachinePoolHealthCheck, error) {
+	cluster, err := h.provClusterCache.Get(machine.Namespace, machine.Spec.ClusterName)
+	if apierror.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if cluster.Spec.RKEConfig == nil {
+		return nil, nil
+	}
+
+	for _, machinePool := range cluster.Spec.RKEConfig.MachinePools {
+		if name.SafeConcatName(cluster.Name, machinePool.Name) == machine.Labels[capi.MachineDeploymentLabelName] {
+			if machinePool.EtcdRole || machinePool.ControlPlaneRole {
+				return nil, nil
+			}
+			return machinePool.HealthCheck, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// probesUnhealthySince returns since when the machine is provisioned but reports failing probes.
+func probesUnhealthySince(machine *capi.Machine) (time.Time, bool) {
+	for _, cond := range machine.Status.Conditions {
+		if string(cond.Type) == string(machinestatus.Provisioned) {
+			return cond.LastTransitionTime.Time, cond.Reason == planner.UnHealthyProbes
+		}
+	}
+	return time.Time{}, false
+}
+
+func waitingForRemediation(machine *capi.Machine) bool {
+	for _, cond := range machine.Status.Conditions {
+		if cond.Type == capi.MachineOwnerRemediatedCondition {
+			return cond.Status == corev1.ConditionFalse
+		}
+	}
+	return false
+}
+
+func isUnhealthy(machine *capi.Machine, probesTimeout time.Duration, now time.Time) bool {
+	if waitingForRemediation(machine) {
+		return true
+	}
+	for _, cond := range machine.Status.Conditions {
+		if cond.Type == capi.MachineHealthCheckSuccededCondition && cond.Status == corev1.ConditionFalse {
+			return true
+		}
+	}
+	since, ok := probesUnhealthySince(machine)
+	return ok && !since.Add(probesTimeout).After(now)
+}
+
+// remediationAllowed follows the MachineHealthCheck: no machine is replaced while more machines of the pool than
+// MaxUnhealthy are unhealthy, be it because of their node or their probes.
+func remediationAllowed(healthCheck *rancherv1.RKEMachinePoolHealthCheck, poolMachines []*capi.Machine, machine *capi.Machine) (bool, error) {
+	var (
+		now       = time.Now()
+		total     int
+		unhealthy = 1
+	)
+	for _, poolMachine := range poolMachines {
+		if poolMachine.DeletionTimestamp != nil {
+			continue
+		}
+		total++
+		if poolMachine.Name != machine.Name && isUnhealthy(poolMachine, healthCheck.UnhealthyProbesTimeout.Duration, now) {
+			unhealthy++
+		}
+	}
+
+	maxUnhealthy := intstr.FromInt(1)
+	if healthCheck.MaxUnhealthy != nil {
+		maxUnhealthy = *healthCheck.MaxUnhealthy
+	}
+	limit, err := intstr.GetScaledValueFromIntOrPercent(&maxUnhealthy, total, false)
+	if err != nil {
+		return false, fmt.Errorf("invalid maxUnhealthy [%s]: %w", maxUnhealthy.String(), err)
+	}
+	return unhealthy <= limit, nil
+}
+
+func setCondition(machine *capi.Machine, newCond capi.Condition) {
+	for i, cond := range machine.Status.Conditions {
+		if cond.Type == newCond.Type {
+			machine.Status.Conditions[i] = newCond
+			return
+		}
+	}
+	machine.Status.Conditions = append(machine.Status.Conditions, newCond)
+}
@@ -0,0 +1,73 @@
+package machineremediation
+
+import (
+	"testing"
+	"time"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/controllers/provisioningv2/rke2/machinestatus"
+	"github.com/rancher/rancher/pkg/provisioningv2/rke2/planner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
+)
+
+func testMachine(name string, conditions ...capi.Condition) *capi.Machine {
+	return &capi.Machine{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status:     capi.MachineStatus{Conditions: conditions},
+	}
+}
+
+func TestRemediationAllowed(t *testing.T) {
+	failingProbes := capi.Condition{
+		Type:               capi.ConditionType(machinestatus.Provisioned),
+		Status:             corev1.ConditionUnknown,
+		Reason:             planner.UnHealthyProbes,
+		LastTransitionTime: metav1.NewTime(time.Now().Add(-time.Hour)),
+	}
+	nodeUnhealthy := capi.Condition{
+		Type:   capi.MachineHealthCheckSuccededCondition,
+		Status: corev1.ConditionFalse,
+	}
+
+	machines := []*capi.Machine{
+		testMachine("m1", failingProbes),
+		testMachine("m2", nodeUnhealthy),
+		testMachine("m3"),
+		testMachine("m4"),
+	}
+	healthCheck := &rancherv1.RKEMachinePoolHealthCheck{
+		UnhealthyProbesTimeout: &metav1.Duration{Duration: time.Minute},
+	}
+
+	// only one machine can be unhealthy by default
+	allowed, err := remediationAllowed(healthCheck, machines, machines[0])
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	allowed, err = remediationAllowed(healthCheck, machines[:1], machines[0])
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	maxUnhealthy := intstr.FromString("25%")
+	healthCheck.MaxUnhealthy = &maxUnhealthy
+	allowed, err = remediationAllowed(healthCheck, machines, machines[0])
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	maxUnhealthy = intstr.FromInt(2)
+	allowed, err = remediationAllowed(healthCheck, machines, machines[0])
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	// probes that didn't fail for long enough don't count
+	healthCheck.UnhealthyProbesTimeout.Duration = 2 * time.Hour
+	maxUnhealthy = intstr.FromInt(1)
+	allowed, err = remediationAllowed(healthCheck, machines, machines[1])
+	require.NoError(t, err)
+	assert.True(t, allowed)
+}
@@ -3,22 +3,36 @@
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
 	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
 	"github.com/rancher/rancher/pkg/controllers/provisioningv2/rke2/bootstrap"
 	v1 "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
 	"github.com/rancher/rancher/pkg/provisioningv2/rke2/planner"
 	"github.com/rancher/rancher/pkg/wrangler"
 	"github.com/rancher/wrangler/pkg/condition"
+	"github.com/rancher/wrangler/pkg/generic"
 	"github.com/rancher/wrangler/pkg/relatedresource"
 	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
 )
 
 const (
-	Provisioned = condition.Cond("Provisioned")
+	Provisioned          = condition.Cond("Provisioned")
+	CertificatesExpiring = condition.Cond("CertificatesExpiring")
+
+	// certificateExpirationWarning is how long before a certificate expires CertificatesExpiring is set
+	certificateExpirationWarning = 30 * 24 * time.Hour
+
+	// appliedSpecHistoryLimit is how many applied specs are kept, the configuration of snapshots taken before the
+	// oldest one is unknown
+	appliedSpecHistoryLimit = 10
 )
 
 type handler struct {
@@ -45,15 +59,26 @@ func Register(ctx context.Context, clients *wrangler.Context, planner *planner.P
 				Namespace: machine.Namespace,
 				Name:      machine.Spec.ClusterName,
 			}}, nil
+		} else if cluster, ok := obj.(*rancherv1.Cluster); ok {
+			// the snapshots listed by the cluster are checksummed on their nodes
+			return []relatedresource.Key{{
+				Namespace: cluster.Namespace,
+				Name:      cluster.Name,
+			}}, nil
 		}
 		return nil, nil
-	}, clients.RKE.RKEControlPlane(), clients.Core.Secret(), clients.CAPI.Machine())
+	}, clients.RKE.RKEControlPlane(), clients.Core.Secret(), clients.CAPI.Machine(), clients.Provisioning.Cluster())
 }
 
 func (h *handler) OnChange(cluster *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus) (rkev1.RKEControlPlaneStatus, error) {
 	status.ObservedGeneration = cluster.Generation
 
-	err := h.planner.Process(cluster)
+	status, err := h.setCertificateExpiration(cluster, status)
+	if err != nil {
+		return status, err
+	}
+
+	err = h.planner.Process(cluster)
 	var errWaiting planner.ErrWaiting
 	if errors.As(err, &errWaiting) {
 		logrus.Infof("rkecluster %s/%s: %v", cluster.Namespace, cluster.Name, err)
@@ -63,6 +88,67 @@ func (h *handler) OnChange(cluster *rkev1.RKEControlPlane, status rkev1.RKEContr
 		return status, nil
 	}
 
+	if err == nil {
+		// Everything was rolled out, snapshots taken from now on are tied to this configuration
+		status = recordAppliedSpec(cluster, status, metav1.Now())
+	}
+
 	Provisioned.SetError(&status, "", err)
 	return status, err
 }
+
+// recordAppliedSpec sets the spec of cluster as its applied spec and adds it to the history of applied specs if it
+// changed, the oldest specs are dropped past appliedSpecHistoryLimit.
+func recordAppliedSpec(cluster *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus, now metav1.Time) rkev1.RKEControlPlaneStatus {
+	appliedSpec := rkev1.AppliedSpec{
+		KubernetesVersion: cluster.Spec.KubernetesVersion,
+		RKEConfig:         cluster.Spec.RKEClusterSpecCommon.DeepCopy(),
+		AppliedAt:         &now,
+	}
+
+	history := status.AppliedSpecHistory
+	if len(history) > 0 {
+		last := history[len(history)-1]
+		if last.KubernetesVersion == appliedSpec.KubernetesVersion && equality.Semantic.DeepEqual(last.RKEConfig, appliedSpec.RKEConfig) {
+			status.AppliedSpec = last.DeepCopy()
+			return status
+		}
+	}
+
+	history = append(append([]rkev1.AppliedSpec{}, history...), appliedSpec)
+	if len(history) > appliedSpecHistoryLimit {
+		history = history[len(history)-appliedSpecHistoryLimit:]
+	}
+	status.AppliedSpec = appliedSpec.DeepCopy()
+	status.AppliedSpecHistory = history
+	return status
+}
+
+func (h *handler) setCertificateExpiration(cluster *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus) (rkev1.RKEControlPlaneStatus, error) {
+	expiration, err := h.planner.CertificateExpiration(cluster)
+	if errors.Is(err, generic.ErrSkip) {
+		return status, nil
+	} else if err != nil {
+		return status, err
+	}
+
+	status.CertificateExpiration = expiration
+	if len(expiration) == 0 {
+		CertificatesExpiring.SetStatus(&status, "Unknown")
+		CertificatesExpiring.Message(&status, "")
+		return status, nil
+	}
+
+	// expiration is sorted by date, the first certificate expires first
+	first := expiration[0]
+	expirationDate, err := time.Parse(time.RFC3339, first.ExpirationDate)
+	if err == nil && time.Until(expirationDate) < certificateExpirationWarning {
+		CertificatesExpiring.True(&status)
+		CertificatesExpiring.Message(&status, fmt.Sprintf("certificate %s of machine %s expires at %s, rotate the certificates of the cluster",
+			first.Certificate, first.MachineName, first.ExpirationDate))
+	} else {
+		CertificatesExpiring.False(&status)
+		CertificatesExpiring.Message(&status, "")
+	}
+	return status, nil
+}
@@ -0,0 +1,42 @@
+package planner
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRecordAppliedSpec(t *testing.T) {
+	cluster := &rkev1.RKEControlPlane{
+		Spec: rkev1.RKEControlPlaneSpec{
+			KubernetesVersion: "v1.20.11+rke2r2",
+		},
+	}
+	first := metav1.NewTime(time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC))
+
+	status := recordAppliedSpec(cluster, rkev1.RKEControlPlaneStatus{}, first)
+	assert.Len(t, status.AppliedSpecHistory, 1)
+	assert.Equal(t, &first, status.AppliedSpec.AppliedAt)
+
+	// an unchanged spec keeps the time it was first applied
+	status = recordAppliedSpec(cluster, status, metav1.NewTime(first.Add(time.Hour)))
+	assert.Len(t, status.AppliedSpecHistory, 1)
+	assert.Equal(t, &first, status.AppliedSpec.AppliedAt)
+
+	cluster.Spec.KubernetesVersion = "v1.21.5+rke2r2"
+	status = recordAppliedSpec(cluster, status, metav1.NewTime(first.Add(2*time.Hour)))
+	assert.Len(t, status.AppliedSpecHistory, 2)
+	assert.Equal(t, "v1.20.11+rke2r2", status.AppliedSpecHistory[0].KubernetesVersion)
+	assert.Equal(t, "v1.21.5+rke2r2", status.AppliedSpec.KubernetesVersion)
+
+	for i := 0; i < appliedSpecHistoryLimit; i++ {
+		cluster.Spec.KubernetesVersion = fmt.Sprintf("v1.21.%d+rke2r1", i+6)
+		status = recordAppliedSpec(cluster, status, metav1.NewTime(first.Add(time.Duration(i+3)*time.Hour)))
+	}
+	assert.Len(t, status.AppliedSpecHistory, appliedSpecHistoryLimit)
+	assert.Equal(t, "v1.21.6+rke2r1", status.AppliedSpecHistory[0].KubernetesVersion)
+}
@@ -0,0 +1,258 @@
+package provisioningcluster
+
+import (
+	"fmt"
+	"time"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/wrangler/pkg/name"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
+)
+
+const (
+	autoscalerMinSizeAnnotation = "cluster.x-k8s.io/cluster-api-autoscaler-node-group-min-size"
+	autoscalerMaxSizeAnnotation = "cluster.x-k8s.io/cluster-api-autoscaler-node-group-max-size"
+	autoscalerKubeconfigPath    = "/etc/kubernetes/workload"
+)
+
+func validateAutoscaling(machinePool rancherv1.RKEMachinePool) error {
+	if machinePool.EtcdRole || machinePool.ControlPlaneRole {
+		return fmt.Errorf("invalid autoscaling of machinePool [%s], etcd and control plane pools can't be autoscaled", machinePool.Name)
+	}
+	autoscaling := machinePool.Autoscaling
+	// the clusterapi provider of the autoscaler can't scale a machine deployment up from zero replicas
+	if autoscaling.MinSize < 1 {
+		return fmt.Errorf("invalid autoscaling of machinePool [%s], minSize must be at least 1", machinePool.Name)
+	}
+	if autoscaling.MaxSize < autoscaling.MinSize {
+		return fmt.Errorf("invalid autoscaling of machinePool [%s], maxSize must be at least minSize", machinePool.Name)
+	}
+	return nil
+}
+
+// autoscaledReplicas keeps the replicas the autoscaler set on the existing machine deployment so that applying the
+// cluster doesn't undo a scale, the quantity of the pool is only the initial size.
+func autoscaledReplicas(machinePool rancherv1.RKEMachinePool, existing *capi.MachineDeployment) *int32 {
+	replicas := machinePool.Autoscaling.MinSize
+	if existing != nil && existing.Spec.Replicas != nil {
+		replicas = *existing.Spec.Replicas
+	} else if machinePool.Quantity != nil {
+		replicas = *machinePool.Quantity
+	}
+
+	if replicas < machinePool.Autoscaling.MinSize {
+		replicas = machinePool.Autoscaling.MinSize
+	} else if replicas > machinePool.Autoscaling.MaxSize {
+		replicas = machinePool.Autoscaling.MaxSize
+	}
+	return &replicas
+}
+
+func autoscalerAnnotations(machinePool rancherv1.RKEMachinePool) map[string]string {
+	annotations := map[string]string{}
+	for k, v := range machinePool.MachineDeploymentAnnotations {
+		annotations[k] = v
+	}
+	annotations[autoscalerMinSizeAnnotation] = fmt.Sprint(machinePool.Autoscaling.MinSize)
+	annotations[autoscalerMaxSizeAnnotation] = fmt.Sprint(machinePool.Autoscaling.MaxSize)
+	return annotations
+}
+
+// clusterAutoscaler runs the Cluster API provider of the cluster autoscaler in the local cluster. It watches the
+// nodes of the cluster through its kubeconfig and scales the machine deployments of the cluster that carry the
+// min and max size annotations.
+func clusterAutoscaler(cluster *rancherv1.Cluster, capiCluster *capi.Cluster) []runtime.Object {
+	var (
+		autoscalerName = name.SafeConcatName(cluster.Name, "cluster-autoscaler")
+		// cluster scoped objects are shared by all namespaces, so they carry the namespace in their name
+		clusterRoleName = name.SafeConcatName(cluster.Namespace, cluster.Name, "cluster-autoscaler")
+		labels          = map[string]string{
+			"app":                 "cluster-autoscaler",
+			capi.ClusterLabelName: capiCluster.Name,
+		}
+		replicas int32 = 1
+	)
+
+	serviceAccount := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: cluster.Namespace,
+			Name:      autoscalerName,
+		},
+	}
+
+	// the autoscaler watches the machines of every namespace, it can only change the ones of its cluster
+	clusterRole := &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: clusterRoleName,
+		},
+		Rules: []rbacv1.PolicyRule{
+			{
+				APIGroups: []string{capi.GroupVersion.Group},
+				Resources: []string{"machinedeployments", "machinedeployments/scale", "machinesets", "machines", "machinepools"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+			{
+				APIGroups: []string{"rke-machine.cattle.io"},
+				Resources: []string{"*"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+		},
+	}
+
+	clusterRoleBinding := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: clusterRoleName,
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     clusterRoleName,
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      rbacv1.ServiceAccountKind,
+				Namespace: cluster.Namespace,
+				Name:      autoscalerName,
+			},
+		},
+	}
+
+	role := &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: cluster.Namespace,
+			Name:      autoscalerName,
+		},
+		Rules: []rbacv1.PolicyRule{
+			{
+				APIGroups: []string{capi.GroupVersion.Group},
+				Resources: []string{"machinedeployments", "machinedeployments/scale", "machines"},
+				Verbs:     []string{"update", "patch"},
+			},
+		},
+	}
+
+	roleBinding := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: cluster.Namespace,
+			Name:      autoscalerName,
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "Role",
+			Name:     autoscalerName,
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      rbacv1.ServiceAccountKind,
+				Namespace: cluster.Namespace,
+				Name:      autoscalerName,
+			},
+		},
+	}
+
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: cluster.Namespace,
+			Name:      autoscalerName,
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					ServiceAccountName: autoscalerName,
+					Containers: []corev1.Container{
+						{
+							Name:  "cluster-autoscaler",
+							Image: settings.PrefixPrivateRegistry(settings.ClusterAutoscalerImage.Get()),
+							Command: []string{
+								"/cluster-autoscaler",
+								"--cloud-provider=clusterapi",
+								"--kubeconfig=" + autoscalerKubeconfigPath + "/value",
+								"--clusterapi-cloud-config-authoritative",
+								fmt.Sprintf("--node-group-auto-discovery=clusterapi:namespace=%s,clusterName=%s", capiCluster.Namespace, capiCluster.Name),
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      "kubeconfig",
+									MountPath: autoscalerKubeconfigPath,
+									ReadOnly:  true,
+								},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "kubeconfig",
+							VolumeSource: corev1.VolumeSource{
+								Secret: &corev1.SecretVolumeSource{
+									SecretName: name.SafeConcatName(cluster.Name, "kubeconfig"),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	return []runtime.Object{serviceAccount, clusterRole, clusterRoleBinding, role, roleBinding, deployment}
+}
+
+// machinePoolStatuses reports the size of every pool from its machine deployment, a change of the replicas since the
+// last report is recorded as a scale.
+func machinePoolStatuses(cluster *rancherv1.Cluster, previous []rancherv1.MachinePoolStatus,
+	getMachineDeployment func(namespace, name string) (*capi.MachineDeployment, error), now metav1.Time) ([]rancherv1.MachinePoolStatus, error) {
+	previousByName := map[string]rancherv1.MachinePoolStatus{}
+	for _, status := range previous {
+		previousByName[status.Name] = status
+	}
+
+	var result []rancherv1.MachinePoolStatus
+	for _, machinePool := range cluster.Spec.RKEConfig.MachinePools {
+		machineDeployment, err := getMachineDeployment(cluster.Namespace, name.SafeConcatName(cluster.Name, machinePool.Name))
+		if apierror.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		status := rancherv1.MachinePoolStatus{
+			Name:              machinePool.Name,
+			ReadyReplicas:     machineDeployment.Status.ReadyReplicas,
+			AvailableReplicas: machineDeployment.Status.AvailableReplicas,
+		}
+		if machineDeployment.Spec.Replicas != nil {
+			status.Replicas = *machineDeployment.Spec.Replicas
+		}
+		if machinePool.Autoscaling != nil {
+			status.MinSize = machinePool.Autoscaling.MinSize
+			status.MaxSize = machinePool.Autoscaling.MaxSize
+		}
+
+		if last, ok := previousByName[machinePool.Name]; ok {
+			status.LastScaleTime = last.LastScaleTime
+			status.LastScaleFrom = last.LastScaleFrom
+			if last.Replicas != status.Replicas {
+				status.LastScaleTime = now.UTC().Format(time.RFC3339)
+				status.LastScaleFrom = last.Replicas
+			}
+		}
+		result = append(result, status)
+	}
+
+	return result, nil
+}
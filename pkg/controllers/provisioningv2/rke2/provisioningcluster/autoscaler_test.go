@@ -0,0 +1,129 @@
+package provisioningcluster
+
+import (
+	"testing"
+	"time"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
+)
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func TestAutoscaledReplicas(t *testing.T) {
+	machinePool := rancherv1.RKEMachinePool{
+		Name:        "pool",
+		Quantity:    int32Ptr(3),
+		Autoscaling: &rancherv1.RKEMachinePoolAutoscaling{MinSize: 2, MaxSize: 5},
+	}
+	assert.Equal(t, int32(3), *autoscaledReplicas(machinePool, nil))
+
+	existing := &capi.MachineDeployment{Spec: capi.MachineDeploymentSpec{Replicas: int32Ptr(4)}}
+	assert.Equal(t, int32(4), *autoscaledReplicas(machinePool, existing))
+
+	existing.Spec.Replicas = int32Ptr(8)
+	assert.Equal(t, int32(5), *autoscaledReplicas(machinePool, existing))
+
+	machinePool.Quantity = nil
+	assert.Equal(t, int32(2), *autoscaledReplicas(machinePool, nil))
+
+	assert.NoError(t, validateAutoscaling(machinePool))
+	machinePool.Autoscaling.MaxSize = 1
+	assert.Error(t, validateAutoscaling(machinePool))
+}
+
+func TestValidateAutoscaling(t *testing.T) {
+	tests := []struct {
+		name        string
+		machinePool rancherv1.RKEMachinePool
+		valid       bool
+	}{
+		{
+			name:        "worker",
+			machinePool: rancherv1.RKEMachinePool{WorkerRole: true, Autoscaling: &rancherv1.RKEMachinePoolAutoscaling{MinSize: 1, MaxSize: 3}},
+			valid:       true,
+		},
+		{
+			name:        "min size zero",
+			machinePool: rancherv1.RKEMachinePool{WorkerRole: true, Autoscaling: &rancherv1.RKEMachinePoolAutoscaling{MinSize: 0, MaxSize: 3}},
+		},
+		{
+			name:        "max size below min size",
+			machinePool: rancherv1.RKEMachinePool{WorkerRole: true, Autoscaling: &rancherv1.RKEMachinePoolAutoscaling{MinSize: 3, MaxSize: 2}},
+		},
+		{
+			name:        "etcd",
+			machinePool: rancherv1.RKEMachinePool{EtcdRole: true, Autoscaling: &rancherv1.RKEMachinePoolAutoscaling{MinSize: 1, MaxSize: 3}},
+		},
+		{
+			name:        "control plane",
+			machinePool: rancherv1.RKEMachinePool{ControlPlaneRole: true, WorkerRole: true, Autoscaling: &rancherv1.RKEMachinePoolAutoscaling{MinSize: 1, MaxSize: 3}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAutoscaling(tt.machinePool)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateHealthCheck(t *testing.T) {
+	healthCheck := &rancherv1.RKEMachinePoolHealthCheck{}
+	assert.NoError(t, validateHealthCheck(rancherv1.RKEMachinePool{Name: "worker", WorkerRole: true, HealthCheck: healthCheck}))
+	assert.Error(t, validateHealthCheck(rancherv1.RKEMachinePool{Name: "etcd", EtcdRole: true, HealthCheck: healthCheck}))
+	assert.Error(t, validateHealthCheck(rancherv1.RKEMachinePool{Name: "cp", ControlPlaneRole: true, WorkerRole: true, HealthCheck: healthCheck}))
+}
+
+func TestMachinePoolStatuses(t *testing.T) {
+	cluster := &rancherv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "c"},
+		Spec: rancherv1.ClusterSpec{
+			RKEConfig: &rancherv1.RKEConfig{
+				MachinePools: []rancherv1.RKEMachinePool{
+					{Name: "batch", Autoscaling: &rancherv1.RKEMachinePoolAutoscaling{MinSize: 1, MaxSize: 10}},
+					{Name: "missing"},
+				},
+			},
+		},
+	}
+	machineDeployment := &capi.MachineDeployment{
+		Spec:   capi.MachineDeploymentSpec{Replicas: int32Ptr(2)},
+		Status: capi.MachineDeploymentStatus{ReadyReplicas: 2, AvailableReplicas: 2},
+	}
+	get := func(namespace, name string) (*capi.MachineDeployment, error) {
+		if name == "c-batch" {
+			return machineDeployment, nil
+		}
+		return nil, apierror.NewNotFound(schema.GroupResource{}, name)
+	}
+	now := metav1.NewTime(time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	statuses, err := machinePoolStatuses(cluster, nil, get, now)
+	require.NoError(t, err)
+	assert.Equal(t, []rancherv1.MachinePoolStatus{
+		{Name: "batch", Replicas: 2, ReadyReplicas: 2, AvailableReplicas: 2, MinSize: 1, MaxSize: 10},
+	}, statuses)
+
+	machineDeployment.Spec.Replicas = int32Ptr(6)
+	statuses, err = machinePoolStatuses(cluster, statuses, get, now)
+	require.NoError(t, err)
+	assert.Equal(t, "2021-07-01T00:00:00Z", statuses[0].LastScaleTime)
+	assert.Equal(t, int32(2), statuses[0].LastScaleFrom)
+
+	statuses, err = machinePoolStatuses(cluster, statuses, get, metav1.NewTime(now.Add(time.Hour)))
+	require.NoError(t, err)
+	assert.Equal(t, "2021-07-01T00:00:00Z", statuses[0].LastScaleTime)
+}
@@ -20,35 +20,40 @@
 	corev1 "k8s.io/api/core/v1"
 	apierror "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
 )
 
 const (
-	byNodeInfra = "by-node-infra"
-	Provisioned = condition.Cond("Provisioned")
+	byNodeInfra          = "by-node-infra"
+	Provisioned          = condition.Cond("Provisioned")
+	CertificatesExpiring = condition.Cond("CertificatesExpiring")
 )
 
 type handler struct {
-	dynamic           *dynamic.Controller
-	dynamicSchema     mgmtcontroller.DynamicSchemaCache
-	clusterCache      rocontrollers.ClusterCache
-	clusterController rocontrollers.ClusterController
-	secretCache       corecontrollers.SecretCache
-	secretClient      corecontrollers.SecretClient
-	capiClusters      capicontrollers.ClusterCache
-	rkeControlPlane   rkecontroller.RKEControlPlaneCache
+	dynamic            *dynamic.Controller
+	dynamicSchema      mgmtcontroller.DynamicSchemaCache
+	clusterCache       rocontrollers.ClusterCache
+	clusterController  rocontrollers.ClusterController
+	secretCache        corecontrollers.SecretCache
+	secretClient       corecontrollers.SecretClient
+	capiClusters       capicontrollers.ClusterCache
+	machineDeployments capicontrollers.MachineDeploymentCache
+	rkeControlPlane    rkecontroller.RKEControlPlaneCache
 }
 
 func Register(ctx context.Context, clients *wrangler.Context) {
 	h := handler{
-		dynamic:           clients.Dynamic,
-		secretCache:       clients.Core.Secret().Cache(),
-		secretClient:      clients.Core.Secret(),
-		clusterCache:      clients.Provisioning.Cluster().Cache(),
-		clusterController: clients.Provisioning.Cluster(),
-		capiClusters:      clients.CAPI.Cluster().Cache(),
-		rkeControlPlane:   clients.RKE.RKEControlPlane().Cache(),
+		dynamic:            clients.Dynamic,
+		secretCache:        clients.Core.Secret().Cache(),
+		secretClient:       clients.Core.Secret(),
+		clusterCache:       clients.Provisioning.Cluster().Cache(),
+		clusterController:  clients.Provisioning.Cluster(),
+		capiClusters:       clients.CAPI.Cluster().Cache(),
+		machineDeployments: clients.CAPI.MachineDeployment().Cache(),
+		rkeControlPlane:    clients.RKE.RKEControlPlane().Cache(),
 	}
 
 	if features.MCM.Enabled() {
@@ -69,6 +74,12 @@ func Register(ctx context.Context, clients *wrangler.Context) {
 				clients.RKE.RKEControlPlane(),
 				clients.RKE.RKECluster(),
 				clients.RKE.RKEBootstrapTemplate(),
+				clients.Core.ServiceAccount(),
+				clients.RBAC.ClusterRole(),
+				clients.RBAC.ClusterRoleBinding(),
+				clients.RBAC.Role(),
+				clients.RBAC.RoleBinding(),
+				clients.Apps.Deployment(),
 			),
 		"RKECluster",
 		"rke-cluster",
@@ -82,8 +93,14 @@ func Register(ctx context.Context, clients *wrangler.Context) {
 				Name:      cp.Spec.ClusterName,
 			}}, nil
 		}
+		if md, ok := obj.(*capi.MachineDeployment); ok {
+			return []relatedresource.Key{{
+				Namespace: namespace,
+				Name:      md.Spec.ClusterName,
+			}}, nil
+		}
 		return nil, nil
-	}, clients.Provisioning.Cluster(), clients.RKE.RKEControlPlane())
+	}, clients.Provisioning.Cluster(), clients.RKE.RKEControlPlane(), clients.CAPI.MachineDeployment())
 }
 
 func byNodeInfraIndex(obj *rancherv1.Cluster) ([]string, error) {
@@ -161,7 +178,12 @@ func (h *handler) OnRancherClusterChange(obj *rancherv1.Cluster, status rancherv
 		return nil, status, err
 	}
 
-	objs, err := objects(obj, h.dynamic, h.dynamicSchema, h.secretCache)
+	status.MachinePools, err = machinePoolStatuses(obj, status.MachinePools, h.machineDeployments.Get, metav1.Now())
+	if err != nil {
+		return nil, status, err
+	}
+
+	objs, err := objects(obj, h.dynamic, h.dynamicSchema, h.secretCache, h.machineDeployments)
 	return objs, status, err
 }
 
@@ -188,5 +210,10 @@ func (h *handler) updateClusterProvisioningStatus(cluster *rancherv1.Cluster, st
 	Provisioned.SetStatus(&status, Provisioned.GetStatus(cp))
 	Provisioned.Reason(&status, Provisioned.GetReason(cp))
 	Provisioned.Message(&status, Provisioned.GetMessage(cp))
+	if CertificatesExpiring.GetStatus(cp) != "" {
+		CertificatesExpiring.SetStatus(&status, CertificatesExpiring.GetStatus(cp))
+		CertificatesExpiring.Message(&status, CertificatesExpiring.GetMessage(cp))
+	}
+	status.CertificateExpiration = cp.Status.CertificateExpiration
 	return status, nil
 }
@@ -4,10 +4,12 @@
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/rancher/lasso/pkg/dynamic"
 	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
 	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	capicontrollers "github.com/rancher/rancher/pkg/generated/controllers/cluster.x-k8s.io/v1alpha4"
 	mgmtcontroller "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/provisioningv2/rke2/planner"
 	"github.com/rancher/wrangler/pkg/data"
@@ -21,6 +23,7 @@
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
 )
 
@@ -33,7 +36,8 @@ func getInfraRef(rkeCluster *rkev1.RKECluster) *corev1.ObjectReference {
 	return infraRef
 }
 
-func objects(cluster *rancherv1.Cluster, dynamic *dynamic.Controller, dynamicSchema mgmtcontroller.DynamicSchemaCache, secrets v1.SecretCache) (result []runtime.Object, _ error) {
+func objects(cluster *rancherv1.Cluster, dynamic *dynamic.Controller, dynamicSchema mgmtcontroller.DynamicSchemaCache, secrets v1.SecretCache,
+	machineDeploymentCache capicontrollers.MachineDeploymentCache) (result []runtime.Object, _ error) {
 	infraRef := cluster.Spec.RKEConfig.InfrastructureRef
 	if infraRef == nil {
 		rkeCluster := rkeCluster(cluster)
@@ -41,13 +45,13 @@ func objects(cluster *rancherv1.Cluster, dynamic *dynamic.Controller, dynamicSch
 		result = append(result, rkeCluster)
 	}
 
-	rkeControlPlane := rkeControlPlane(cluster)
+	rkeControlPlane := RKEControlPlane(cluster)
 	result = append(result, rkeControlPlane)
 
 	capiCluster := capiCluster(cluster, rkeControlPlane, infraRef)
 	result = append(result, capiCluster)
 
-	machineDeployments, err := machineDeployments(cluster, capiCluster, dynamic, dynamicSchema, secrets)
+	machineDeployments, err := machineDeployments(cluster, capiCluster, dynamic, dynamicSchema, secrets, machineDeploymentCache)
 	if err != nil {
 		return nil, err
 	}
@@ -133,8 +137,11 @@ func toMachineTemplate(machinePoolName string, cluster *rancherv1.Cluster, machi
 }
 
 func machineDeployments(cluster *rancherv1.Cluster, capiCluster *capi.Cluster, dynamic *dynamic.Controller,
-	dynamicSchema mgmtcontroller.DynamicSchemaCache, secrets v1.SecretCache) (result []runtime.Object, _ error) {
-	bootstrapName := name.SafeConcatName(cluster.Name, "bootstrap", "template")
+	dynamicSchema mgmtcontroller.DynamicSchemaCache, secrets v1.SecretCache, machineDeploymentCache capicontrollers.MachineDeploymentCache) (result []runtime.Object, _ error) {
+	var (
+		bootstrapName = name.SafeConcatName(cluster.Name, "bootstrap", "template")
+		autoscaling   bool
+	)
 
 	if dynamicSchema == nil {
 		return nil, nil
@@ -240,6 +247,21 @@ func machineDeployments(cluster *rancherv1.Cluster, capiCluster *capi.Cluster, d
 				Paused: machinePool.Paused,
 			},
 		}
+		if machinePool.Autoscaling != nil {
+			if err := validateAutoscaling(machinePool); err != nil {
+				return nil, err
+			}
+			existing, err := machineDeploymentCache.Get(cluster.Namespace, machinePoolName)
+			if apierror.IsNotFound(err) {
+				existing = nil
+			} else if err != nil {
+				return nil, err
+			}
+			machineDeployment.Spec.Replicas = autoscaledReplicas(machinePool, existing)
+			machineDeployment.Annotations = autoscalerAnnotations(machinePool)
+			autoscaling = true
+		}
+
 		if machinePool.RollingUpdate != nil {
 			machineDeployment.Spec.Strategy = &capi.MachineDeploymentStrategy{
 				Type: capi.RollingUpdateMachineDeploymentStrategyType,
@@ -276,11 +298,81 @@ func machineDeployments(cluster *rancherv1.Cluster, capiCluster *capi.Cluster, d
 		}
 
 		result = append(result, machineDeployment)
+
+		if machinePool.HealthCheck != nil {
+			if err := validateHealthCheck(machinePool); err != nil {
+				return nil, err
+			}
+			result = append(result, machineHealthCheck(capiCluster, machinePoolName, machinePool.HealthCheck))
+		}
+	}
+
+	if autoscaling {
+		result = append(result, clusterAutoscaler(cluster, capiCluster)...)
 	}
 
 	return result, nil
 }
 
+// validateHealthCheck refuses health checks on etcd and control plane pools, replacing their machines can lose the
+// quorum of etcd or all of its data.
+func validateHealthCheck(machinePool rancherv1.RKEMachinePool) error {
+	if machinePool.EtcdRole || machinePool.ControlPlaneRole {
+		return fmt.Errorf("invalid healthCheck of machinePool [%s], machines of etcd and control plane pools are never replaced automatically", machinePool.Name)
+	}
+	return nil
+}
+
+func machineHealthCheck(capiCluster *capi.Cluster, machinePoolName string, healthCheck *rancherv1.RKEMachinePoolHealthCheck) *capi.MachineHealthCheck {
+	unhealthyNodeTimeout := healthCheck.UnhealthyNodeTimeout
+	if unhealthyNodeTimeout == nil {
+		unhealthyNodeTimeout = &metav1.Duration{Duration: 5 * time.Minute}
+	}
+
+	nodeStartupTimeout := healthCheck.NodeStartupTimeout
+	if nodeStartupTimeout == nil {
+		nodeStartupTimeout = &metav1.Duration{Duration: 10 * time.Minute}
+	}
+
+	maxUnhealthy := healthCheck.MaxUnhealthy
+	if maxUnhealthy == nil {
+		one := intstr.FromInt(1)
+		maxUnhealthy = &one
+	}
+
+	return &capi.MachineHealthCheck{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: capiCluster.Namespace,
+			Name:      machinePoolName,
+			Labels: map[string]string{
+				capi.ClusterLabelName: capiCluster.Name,
+			},
+		},
+		Spec: capi.MachineHealthCheckSpec{
+			ClusterName: capiCluster.Name,
+			Selector: metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					capi.MachineDeploymentLabelName: machinePoolName,
+				},
+			},
+			UnhealthyConditions: []capi.UnhealthyCondition{
+				{
+					Type:    corev1.NodeReady,
+					Status:  corev1.ConditionFalse,
+					Timeout: *unhealthyNodeTimeout,
+				},
+				{
+					Type:    corev1.NodeReady,
+					Status:  corev1.ConditionUnknown,
+					Timeout: *unhealthyNodeTimeout,
+				},
+			},
+			MaxUnhealthy:       maxUnhealthy,
+			NodeStartupTimeout: nodeStartupTimeout,
+		},
+	}
+}
+
 func assign(labels map[string]string, key string, value interface{}) error {
 	data, err := json.Marshal(value)
 	if err != nil {
@@ -299,7 +391,8 @@ func rkeCluster(cluster *rancherv1.Cluster) *rkev1.RKECluster {
 	}
 }
 
-func rkeControlPlane(cluster *rancherv1.Cluster) *rkev1.RKEControlPlane {
+// RKEControlPlane returns the RKEControlPlane generated for a provisioning cluster.
+func RKEControlPlane(cluster *rancherv1.Cluster) *rkev1.RKEControlPlane {
 	return &rkev1.RKEControlPlane{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      cluster.Name,
@@ -312,6 +405,8 @@ func rkeControlPlane(cluster *rancherv1.Cluster) *rkev1.RKEControlPlane {
 			RKEClusterSpecCommon:  *cluster.Spec.RKEConfig.RKEClusterSpecCommon.DeepCopy(),
 			ETCDSnapshotRestore:   cluster.Spec.RKEConfig.ETCDSnapshotRestore.DeepCopy(),
 			ETCDSnapshotCreate:    cluster.Spec.RKEConfig.ETCDSnapshotCreate.DeepCopy(),
+			RotateCertificates:    cluster.Spec.RKEConfig.RotateCertificates.DeepCopy(),
+			RotateEncryptionKeys:  cluster.Spec.RKEConfig.RotateEncryptionKeys.DeepCopy(),
 			KubernetesVersion:     cluster.Spec.KubernetesVersion,
 			ManagementClusterName: cluster.Status.ClusterName,
 			AgentEnvVars:          cluster.Spec.AgentEnvVars,
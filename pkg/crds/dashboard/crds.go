@@ -96,6 +96,17 @@ func List(cfg *rest.Config) (_ []crd.CRD, err error) {
 				WithColumn("Release Version", ".spec.version").
 				WithColumn("Status", ".spec.info.status")
 		}),
+		newCRD(&catalogv1.AppInstall{}, func(c crd.CRD) crd.CRD {
+			c.NonNamespace = true
+			return c.
+				WithStatus().
+				WithCategories("catalog").
+				WithColumn("Chart", ".spec.chart").
+				WithColumn("Constraint", ".spec.version").
+				WithColumn("Version", ".status.version").
+				WithColumn("Release Name", ".spec.releaseName").
+				WithColumn("Namespace", ".spec.namespace")
+		}),
 	}
 
 	if features.Fleet.Enabled() {
@@ -104,6 +104,13 @@ func rke2() []crd.CRD {
 			}
 			return clusterIndexed(c)
 		}),
+		newRKECRD(&rkev1.ETCDSnapshotRecord{}, func(c crd.CRD) crd.CRD {
+			return clusterIndexed(c).
+				WithColumn("Cluster", ".spec.clusterName").
+				WithColumn("Node", ".spec.snapshot.nodeName").
+				WithColumn("State", ".status.state").
+				WithColumn("Last Verified", ".status.lastVerified")
+		}),
 	}
 }
 
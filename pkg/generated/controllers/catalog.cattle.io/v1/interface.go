@@ -31,6 +31,7 @@ func init() {
 
 type Interface interface {
 	App() AppController
+	AppInstall() AppInstallController
 	ClusterRepo() ClusterRepoController
 	Operation() OperationController
 }
@@ -48,6 +49,9 @@ type version struct {
 func (c *version) App() AppController {
 	return NewAppController(schema.GroupVersionKind{Group: "catalog.cattle.io", Version: "v1", Kind: "App"}, "apps", true, c.controllerFactory)
 }
+func (c *version) AppInstall() AppInstallController {
+	return NewAppInstallController(schema.GroupVersionKind{Group: "catalog.cattle.io", Version: "v1", Kind: "AppInstall"}, "appinstalls", false, c.controllerFactory)
+}
 func (c *version) ClusterRepo() ClusterRepoController {
 	return NewClusterRepoController(schema.GroupVersionKind{Group: "catalog.cattle.io", Version: "v1", Kind: "ClusterRepo"}, "clusterrepos", false, c.controllerFactory)
 }
@@ -61,6 +61,7 @@ type Interface interface {
 	FleetWorkspace() FleetWorkspaceController
 	FreeIpaProvider() FreeIpaProviderController
 	GithubProvider() GithubProviderController
+	GitlabProvider() GitlabProviderController
 	GlobalDns() GlobalDnsController
 	GlobalDnsProvider() GlobalDnsProviderController
 	GlobalRole() GlobalRoleController
@@ -212,6 +213,9 @@ func (c *version) FreeIpaProvider() FreeIpaProviderController {
 func (c *version) GithubProvider() GithubProviderController {
 	return NewGithubProviderController(schema.GroupVersionKind{Group: "management.cattle.io", Version: "v3", Kind: "GithubProvider"}, "githubproviders", false, c.controllerFactory)
 }
+func (c *version) GitlabProvider() GitlabProviderController {
+	return NewGitlabProviderController(schema.GroupVersionKind{Group: "management.cattle.io", Version: "v3", Kind: "GitlabProvider"}, "gitlabproviders", false, c.controllerFactory)
+}
 func (c *version) GlobalDns() GlobalDnsController {
 	return NewGlobalDnsController(schema.GroupVersionKind{Group: "management.cattle.io", Version: "v3", Kind: "GlobalDns"}, "globaldnses", true, c.controllerFactory)
 }
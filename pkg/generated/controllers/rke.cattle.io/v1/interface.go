@@ -31,6 +31,7 @@ func init() {
 
 type Interface interface {
 	CustomMachine() CustomMachineController
+	ETCDSnapshotRecord() ETCDSnapshotRecordController
 	RKEBootstrap() RKEBootstrapController
 	RKEBootstrapTemplate() RKEBootstrapTemplateController
 	RKECluster() RKEClusterController
@@ -50,6 +51,9 @@ type version struct {
 func (c *version) CustomMachine() CustomMachineController {
 	return NewCustomMachineController(schema.GroupVersionKind{Group: "rke.cattle.io", Version: "v1", Kind: "CustomMachine"}, "custommachines", true, c.controllerFactory)
 }
+func (c *version) ETCDSnapshotRecord() ETCDSnapshotRecordController {
+	return NewETCDSnapshotRecordController(schema.GroupVersionKind{Group: "rke.cattle.io", Version: "v1", Kind: "ETCDSnapshotRecord"}, "etcdsnapshotrecords", true, c.controllerFactory)
+}
 func (c *version) RKEBootstrap() RKEBootstrapController {
 	return NewRKEBootstrapController(schema.GroupVersionKind{Group: "rke.cattle.io", Version: "v1", Kind: "RKEBootstrap"}, "rkebootstraps", true, c.controllerFactory)
 }
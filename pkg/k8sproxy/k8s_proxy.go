@@ -10,7 +10,12 @@
 )
 
 func New(scaledContext *config.ScaledContext, dialer dialer.Factory, clusterContextGetter clusterrouter.ClusterContextGetter) http.Handler {
-	return clusterrouter.New(&scaledContext.RESTConfig, k8slookup.New(scaledContext, true), dialer,
-		scaledContext.Management.Clusters("").Controller().Lister(),
-		clusterContextGetter)
+	return &scopeHandler{
+		tokenLister:          scaledContext.Management.Tokens("").Controller().Lister(),
+		tokenClient:          scaledContext.Management.Tokens(""),
+		clusterContextGetter: clusterContextGetter,
+		next: clusterrouter.New(&scaledContext.RESTConfig, k8slookup.New(scaledContext, true), dialer,
+			scaledContext.Management.Clusters("").Controller().Lister(),
+			clusterContextGetter),
+	}
 }
@@ -0,0 +1,77 @@
+package k8sproxy
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/rancher/rancher/pkg/auth/tokens"
+	"github.com/rancher/rancher/pkg/auth/util"
+	"github.com/rancher/rancher/pkg/clusterrouter"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/project"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// scopeHandler enforces the projects of a scoped token on namespaced requests. Everything else in the scope is
+// enforced when the token is authenticated, but the project of a namespace can only be looked up in the cluster.
+type scopeHandler struct {
+	tokenLister          v3.TokenLister
+	tokenClient          v3.TokenInterface
+	clusterContextGetter clusterrouter.ClusterContextGetter
+	next                 http.Handler
+}
+
+func (h *scopeHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	// the token was verified by the authenticator, it only has to be looked up here
+	tokenName, _ := tokens.SplitTokenParts(tokens.GetTokenAuthFromRequest(req))
+	if tokenName == "" {
+		h.next.ServeHTTP(rw, req)
+		return
+	}
+	token, err := h.tokenLister.Get("", tokenName)
+	if apierrors.IsNotFound(err) {
+		// the authenticator falls back to the api server for tokens that are not cached yet, and so does the scope
+		token, err = h.tokenClient.Get(tokenName, metav1.GetOptions{})
+	}
+	if apierrors.IsNotFound(err) {
+		util.ReturnHTTPError(rw, req, http.StatusUnauthorized, "token not found")
+		return
+	} else if err != nil {
+		util.ReturnHTTPError(rw, req, http.StatusInternalServerError, fmt.Sprintf("failed to look up token scope: %v", err))
+		return
+	}
+	if token.Scope == nil || len(token.Scope.Projects) == 0 {
+		h.next.ServeHTTP(rw, req)
+		return
+	}
+
+	scopeRequest := tokens.ParseScopeRequest(req)
+	if scopeRequest.Namespace == "" {
+		h.next.ServeHTTP(rw, req)
+		return
+	}
+
+	projectID, err := h.namespaceProject(scopeRequest.ClusterID, scopeRequest.Namespace)
+	if err != nil {
+		logrus.Debugf("failed to look up project of namespace %s in cluster %s: %v", scopeRequest.Namespace, scopeRequest.ClusterID, err)
+	}
+	if err := tokens.CheckScopeProject(token.Scope, projectID); err != nil {
+		util.ReturnHTTPError(rw, req, http.StatusForbidden, err.Error())
+		return
+	}
+	h.next.ServeHTTP(rw, req)
+}
+
+func (h *scopeHandler) namespaceProject(clusterID, namespace string) (string, error) {
+	userContext, err := h.clusterContextGetter.UserContext(clusterID)
+	if err != nil {
+		return "", err
+	}
+	ns, err := userContext.Core.Namespaces("").Controller().Lister().Get("", namespace)
+	if err != nil {
+		return "", err
+	}
+	return ns.Annotations[project.ProjectIDAnn], nil
+}
@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	apiRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: "api",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of API requests served by the norman (/v3), steve (/v1) and cluster proxy (/k8s/clusters) servers",
+			Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+		},
+		[]string{"server", "route", "method", "code"},
+	)
+)
+
+// APIMiddleware records the latency and count of API requests by server, route template, method and status code.
+// Routes are reduced to the API prefix and resource type so label cardinality stays bounded. Watches and websocket
+// connections are not recorded since their duration is the lifetime of the connection.
+func APIMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if !prometheusMetrics || isLongRunning(req) {
+			next.ServeHTTP(rw, req)
+			return
+		}
+
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: rw, statusCode: http.StatusOK}
+		next.ServeHTTP(sw, req)
+
+		server, route := apiRoute(req.URL.Path, sw.statusCode)
+		apiRequestDuration.With(prometheus.Labels{
+			"server": server,
+			"route":  route,
+			"method": req.Method,
+			"code":   strconv.Itoa(sw.statusCode),
+		}).Observe(time.Since(start).Seconds())
+	})
+}
+
+func isLongRunning(req *http.Request) bool {
+	return req.URL.Query().Get("watch") == "true" ||
+		strings.EqualFold(req.Header.Get("Upgrade"), "websocket")
+}
+
+// apiRoute returns the server handling path and a route template for it. Resource types from the URL are only used
+// when the request did not 404, so arbitrary URLs cannot create new label values.
+func apiRoute(path string, code int) (string, string) {
+	var parts []string
+	for _, part := range strings.Split(path, "/") {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return "other", "/"
+	}
+
+	var (
+		server string
+		prefix = "/" + parts[0]
+		rest   = parts[1:]
+	)
+	switch parts[0] {
+	case "v3", "v3-public":
+		server = "norman"
+		if len(rest) >= 2 && (rest[0] == "cluster" || rest[0] == "project") {
+			prefix += "/" + rest[0] + "/{id}"
+			rest = rest[2:]
+		}
+	case "v1":
+		server = "steve"
+	case "k8s":
+		if len(rest) >= 2 && rest[0] == "clusters" {
+			return "proxy", "/k8s/clusters/{cluster}"
+		}
+		return "other", "other"
+	default:
+		return "other", "other"
+	}
+
+	switch {
+	case len(rest) == 0:
+		return server, prefix
+	case code == http.StatusNotFound:
+		return server, prefix + "/{unknown}"
+	case len(rest) == 1:
+		return server, prefix + "/" + rest[0]
+	}
+	return server, prefix + "/" + rest[0] + "/{id}"
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+}
+
+func (s *statusWriter) WriteHeader(statusCode int) {
+	if !s.wroteHeader {
+		s.statusCode = statusCode
+		s.wroteHeader = true
+	}
+	s.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (s *statusWriter) Write(body []byte) (int, error) {
+	s.wroteHeader = true
+	return s.ResponseWriter.Write(body)
+}
+
+func (s *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := s.ResponseWriter.(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+	return nil, nil, fmt.Errorf("upstream ResponseWriter of type %v does not implement http.Hijacker", reflect.TypeOf(s.ResponseWriter))
+}
+
+func (s *statusWriter) CloseNotify() <-chan bool {
+	if cn, ok := s.ResponseWriter.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return make(<-chan bool)
+}
+
+func (s *statusWriter) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
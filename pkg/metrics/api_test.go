@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIRoute(t *testing.T) {
+	tests := []struct {
+		path   string
+		code   int
+		server string
+		route  string
+	}{
+		{"/v3", http.StatusOK, "norman", "/v3"},
+		{"/v3/clusters", http.StatusOK, "norman", "/v3/clusters"},
+		{"/v3/clusters/c-abc", http.StatusOK, "norman", "/v3/clusters/{id}"},
+		{"/v3/project/c-abc:p-xyz/workloads/deployment:default:web", http.StatusOK, "norman", "/v3/project/{id}/workloads/{id}"},
+		{"/v3/random-1234", http.StatusNotFound, "norman", "/v3/{unknown}"},
+		{"/v3-public/authProviders", http.StatusOK, "norman", "/v3-public/authProviders"},
+		{"/v1/management.cattle.io.clusters/local", http.StatusOK, "steve", "/v1/management.cattle.io.clusters/{id}"},
+		{"/k8s/clusters/c-abc/api/v1/pods", http.StatusOK, "proxy", "/k8s/clusters/{cluster}"},
+		{"/dashboard/index.html", http.StatusOK, "other", "other"},
+		{"/", http.StatusOK, "other", "/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			server, route := apiRoute(tt.path, tt.code)
+			assert.Equal(t, tt.server, server)
+			assert.Equal(t, tt.route, route)
+		})
+	}
+}
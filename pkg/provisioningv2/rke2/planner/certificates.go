@@ -0,0 +1,253 @@
+package planner
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	rkecontroller "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/wrangler"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
+)
+
+// checkCertificatesInstruction saves the certificates of a node, not the keys, so rancher can report when they expire
+const checkCertificatesInstruction = "check-certificates"
+
+type certificateRotation struct {
+	controlPlane rkecontroller.RKEControlPlaneClient
+	secrets      corecontrollers.SecretCache
+	store        *PlanStore
+}
+
+func newCertificateRotation(clients *wrangler.Context, store *PlanStore) *certificateRotation {
+	return &certificateRotation{
+		controlPlane: clients.RKE.RKEControlPlane(),
+		secrets:      clients.Core.Secret().Cache(),
+		store:        store,
+	}
+}
+
+func checkCertificatesInstructionFor(controlPlane *rkev1.RKEControlPlane) plan.Instruction {
+	runtime := GetRuntime(controlPlane.Spec.KubernetesVersion)
+	return plan.Instruction{
+		Name:       checkCertificatesInstruction,
+		Image:      getInstallerImage(controlPlane),
+		Command:    "sh",
+		SaveOutput: true,
+		Args: []string{
+			"-c",
+			fmt.Sprintf(`for f in /var/lib/rancher/%[1]s/server/tls/*.crt /var/lib/rancher/%[1]s/agent/*.crt; do `+
+				`[ -f "$f" ] && echo "# $f" && cat "$f"; done; true`, runtime),
+		},
+	}
+}
+
+// withCertificateCheck adds the check certificates instruction to a desired plan that differs from the current plan
+// of the node, so certificates are checked whenever a node gets a new plan anyway. A plan that only differs from the
+// current one by the check is in sync, adding the check alone must never cause nodes to be planned again.
+func withCertificateCheck(controlPlane *rkev1.RKEControlPlane, current *plan.NodePlan, desired plan.NodePlan) plan.NodePlan {
+	checked := desired
+	checked.Instructions = append(append([]plan.Instruction{}, desired.Instructions...), checkCertificatesInstructionFor(controlPlane))
+	if current != nil && (equality.Semantic.DeepEqual(*current, desired) || equality.Semantic.DeepEqual(*current, checked)) {
+		return *current
+	}
+	return checked
+}
+
+func (r *certificateRotation) serverPlan(controlPlane *rkev1.RKEControlPlane, rotate *rkev1.RotateCertificates) (plan.NodePlan, error) {
+	image := getInstallerImage(controlPlane)
+	args := []string{
+		"certificate",
+		"rotate",
+	}
+	for _, service := range rotate.Services {
+		args = append(args, fmt.Sprintf("--service=%s", service))
+	}
+
+	return commonNodePlan(r.secrets, controlPlane, plan.NodePlan{
+		Instructions: []plan.Instruction{
+			{
+				Name:    "shutdown",
+				Image:   image,
+				Command: "systemctl",
+				Args: []string{
+					"stop", GetRuntimeServerUnit(controlPlane.Spec.KubernetesVersion),
+				},
+			},
+			{
+				Name:    "rotate",
+				Image:   image,
+				Command: GetRuntimeCommand(controlPlane.Spec.KubernetesVersion),
+				Args:    args,
+			},
+			{
+				Name:    "start",
+				Image:   image,
+				Command: "systemctl",
+				Args: []string{
+					"start", GetRuntimeServerUnit(controlPlane.Spec.KubernetesVersion),
+				},
+			},
+			checkCertificatesInstructionFor(controlPlane),
+		},
+	})
+}
+
+// agentPlan restarts the agent, agents request new client certificates from the servers when they start
+func (r *certificateRotation) agentPlan(controlPlane *rkev1.RKEControlPlane) (plan.NodePlan, error) {
+	return commonNodePlan(r.secrets, controlPlane, plan.NodePlan{
+		Instructions: []plan.Instruction{
+			{
+				Name:    "restart",
+				Image:   getInstallerImage(controlPlane),
+				Command: "systemctl",
+				Args: []string{
+					"restart", GetRuntimeAgentUnit(controlPlane.Spec.KubernetesVersion),
+				},
+			},
+			checkCertificatesInstructionFor(controlPlane),
+		},
+	})
+}
+
+func (r *certificateRotation) setGeneration(controlPlane *rkev1.RKEControlPlane, generation int64) error {
+	controlPlane = controlPlane.DeepCopy()
+	controlPlane.Status.CertificateRotationGeneration = generation
+	_, err := r.controlPlane.UpdateStatus(controlPlane)
+	if err != nil {
+		return err
+	}
+	return ErrWaiting("refreshing certificate rotation state")
+}
+
+// Rotate rotates the certificates of the etcd nodes, then the control plane nodes and then restarts the workers. A
+// tier is only started once the previous one is done.
+func (r *certificateRotation) Rotate(controlPlane *rkev1.RKEControlPlane, clusterPlan *plan.Plan) error {
+	rotate := controlPlane.Spec.RotateCertificates
+	if rotate == nil || rotate.Generation == controlPlane.Status.CertificateRotationGeneration {
+		return nil
+	}
+
+	serverPlan, err := r.serverPlan(controlPlane, rotate)
+	if err != nil {
+		return err
+	}
+
+	agentPlan, err := r.agentPlan(controlPlane)
+	if err != nil {
+		return err
+	}
+
+	strategy := controlPlane.Spec.UpgradeStrategy
+	if err := rollOutPlan(r.store, clusterPlan, isEtcd, serverPlan, strategy.ControlPlaneConcurrency,
+		"rotating certificates of etcd"); err != nil {
+		return err
+	}
+	if err := rollOutPlan(r.store, clusterPlan, func(machine *capi.Machine) bool {
+		return isControlPlane(machine) && !isEtcd(machine)
+	}, serverPlan, strategy.ControlPlaneConcurrency, "rotating certificates of control plane"); err != nil {
+		return err
+	}
+	if err := rollOutPlan(r.store, clusterPlan, isOnlyWorker, agentPlan, strategy.WorkerConcurrency,
+		"rotating certificates of worker"); err != nil {
+		return err
+	}
+
+	return r.setGeneration(controlPlane, rotate.Generation)
+}
+
+// CertificateExpiration returns the certificate that expires first for every machine that reported its certificates.
+func (p *Planner) CertificateExpiration(controlPlane *rkev1.RKEControlPlane) ([]rkev1.CertificateExpiration, error) {
+	cluster, err := p.getCAPICluster(controlPlane)
+	if err != nil {
+		return nil, err
+	}
+
+	clusterPlan, err := p.store.Load(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []rkev1.CertificateExpiration
+	for name, machine := range clusterPlan.Machines {
+		node := clusterPlan.Nodes[name]
+		if node == nil || len(node.Output[checkCertificatesInstruction]) == 0 {
+			continue
+		}
+		certificate, notAfter, ok := firstExpiringCertificate(node.Output[checkCertificatesInstruction])
+		if !ok {
+			continue
+		}
+		expiration := rkev1.CertificateExpiration{
+			MachineName:    name,
+			Certificate:    certificate,
+			ExpirationDate: notAfter.UTC().Format(time.RFC3339),
+		}
+		if machine.Status.NodeRef != nil {
+			expiration.NodeName = machine.Status.NodeRef.Name
+		}
+		result = append(result, expiration)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ExpirationDate != result[j].ExpirationDate {
+			return result[i].ExpirationDate < result[j].ExpirationDate
+		}
+		return result[i].MachineName < result[j].MachineName
+	})
+	return result, nil
+}
+
+// firstExpiringCertificate parses the output of the check certificates instruction and returns the name of the leaf
+// certificate that expires first. Certificate authorities are ignored because they are not rotated.
+func firstExpiringCertificate(output []byte) (string, time.Time, bool) {
+	var (
+		name     string
+		first    string
+		notAfter time.Time
+		found    bool
+		data     []byte
+	)
+
+	check := func() {
+		for len(data) > 0 {
+			var block *pem.Block
+			block, data = pem.Decode(data)
+			if block == nil {
+				break
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil || cert.IsCA {
+				continue
+			}
+			if !found || cert.NotAfter.Before(notAfter) {
+				first, notAfter, found = name, cert.NotAfter, true
+			}
+		}
+		data = nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "# ") {
+			check()
+			name = strings.TrimSuffix(filepath.Base(strings.TrimPrefix(line, "# ")), ".crt")
+			continue
+		}
+		data = append(append(data, line...), '\n')
+	}
+	check()
+
+	return first, notAfter, found
+}
@@ -0,0 +1,78 @@
+package planner
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testCertificate(t *testing.T, notAfter time.Time, ca bool) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test"},
+		NotBefore:             notAfter.Add(-time.Hour),
+		NotAfter:              notAfter,
+		IsCA:                  ca,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestFirstExpiringCertificate(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	output := "# /var/lib/rancher/rke2/server/tls/server-ca.crt\n" + testCertificate(t, now.Add(time.Hour), true) +
+		"# /var/lib/rancher/rke2/server/tls/serving-kube-apiserver.crt\n" + testCertificate(t, now.Add(48*time.Hour), false) +
+		testCertificate(t, now.Add(72*time.Hour), true) +
+		"# /var/lib/rancher/rke2/agent/client-kubelet.crt\n" + testCertificate(t, now.Add(24*time.Hour), false) +
+		"# /var/lib/rancher/rke2/agent/broken.crt\nnot a certificate\n"
+
+	name, notAfter, ok := firstExpiringCertificate([]byte(output))
+	require.True(t, ok)
+	assert.Equal(t, "client-kubelet", name)
+	assert.True(t, notAfter.Equal(now.Add(24*time.Hour)))
+
+	_, _, ok = firstExpiringCertificate([]byte("# /var/lib/rancher/rke2/server/tls/server-ca.crt\n" + testCertificate(t, now, true)))
+	assert.False(t, ok)
+}
+
+func TestWithCertificateCheck(t *testing.T) {
+	controlPlane := &rkev1.RKEControlPlane{
+		Spec: rkev1.RKEControlPlaneSpec{
+			KubernetesVersion: "v1.21.4+rke2r2",
+		},
+	}
+	install := plan.NodePlan{
+		Instructions: []plan.Instruction{{Name: "install"}},
+	}
+	check := checkCertificatesInstructionFor(controlPlane)
+	checked := plan.NodePlan{
+		Instructions: []plan.Instruction{{Name: "install"}, check},
+	}
+
+	assert.Equal(t, checked, withCertificateCheck(controlPlane, nil, install), "new nodes are checked")
+	assert.Equal(t, install, withCertificateCheck(controlPlane, &install, install), "unchanged plans are not replaced")
+	assert.Equal(t, checked, withCertificateCheck(controlPlane, &checked, install))
+
+	upgrade := plan.NodePlan{
+		Instructions: []plan.Instruction{{Name: "upgrade"}},
+	}
+	result := withCertificateCheck(controlPlane, &install, upgrade)
+	assert.Equal(t, []plan.Instruction{{Name: "upgrade"}, check}, result.Instructions, "changed plans are checked")
+	assert.Len(t, upgrade.Instructions, 1)
+}
@@ -0,0 +1,220 @@
+package planner
+
+import (
+	"fmt"
+	"strconv"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	rkecontroller "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/wrangler"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
+)
+
+// encryptionKeyRotation runs the secrets-encrypt prepare, rotate and reencrypt commands on one control plane node,
+// the leader, and restarts all servers after each of them. Every step is recorded in the status of the control plane
+// so a rotation resumes where it left off.
+type encryptionKeyRotation struct {
+	controlPlane rkecontroller.RKEControlPlaneClient
+	secrets      corecontrollers.SecretCache
+	store        *PlanStore
+}
+
+func newEncryptionKeyRotation(clients *wrangler.Context, store *PlanStore) *encryptionKeyRotation {
+	return &encryptionKeyRotation{
+		controlPlane: clients.RKE.RKEControlPlane(),
+		secrets:      clients.Core.Secret().Cache(),
+		store:        store,
+	}
+}
+
+func (e *encryptionKeyRotation) setState(controlPlane *rkev1.RKEControlPlane, spec *rkev1.RotateEncryptionKeys, phase rkev1.RotateEncryptionKeysPhase, leader string) error {
+	controlPlane = controlPlane.DeepCopy()
+	controlPlane.Status.RotateEncryptionKeys = spec
+	controlPlane.Status.RotateEncryptionKeysPhase = phase
+	controlPlane.Status.RotateEncryptionKeysLeader = leader
+	_, err := e.controlPlane.UpdateStatus(controlPlane)
+	if err != nil {
+		return err
+	}
+	return ErrWaiting("refreshing encryption key rotation state")
+}
+
+func (e *encryptionKeyRotation) setPhase(controlPlane *rkev1.RKEControlPlane, phase rkev1.RotateEncryptionKeysPhase) error {
+	return e.setState(controlPlane, controlPlane.Status.RotateEncryptionKeys, phase, controlPlane.Status.RotateEncryptionKeysLeader)
+}
+
+func (e *encryptionKeyRotation) resetState(controlPlane *rkev1.RKEControlPlane) error {
+	if controlPlane.Status.RotateEncryptionKeys == nil && controlPlane.Status.RotateEncryptionKeysPhase == "" {
+		return nil
+	}
+	return e.setState(controlPlane, nil, "", "")
+}
+
+func isServer(machine *capi.Machine) bool {
+	return isEtcd(machine) || isControlPlane(machine)
+}
+
+// leader returns the first control plane machine that is provisioned
+func leader(clusterPlan *plan.Plan) string {
+	for _, entry := range collect(clusterPlan, isControlPlane) {
+		if entry.Machine.DeletionTimestamp == nil && entry.Plan != nil && entry.Plan.AppliedPlan != nil {
+			return entry.Machine.Name
+		}
+	}
+	return ""
+}
+
+// phaseEnv marks the plans of a phase so that they differ from the plans of the previous phase and rotation
+func phaseEnv(controlPlane *rkev1.RKEControlPlane) []string {
+	return []string{
+		fmt.Sprintf("ROTATE_ENCRYPTION_KEYS_GENERATION=%s", strconv.FormatInt(controlPlane.Status.RotateEncryptionKeys.Generation, 10)),
+		fmt.Sprintf("ROTATE_ENCRYPTION_KEYS_PHASE=%s", controlPlane.Status.RotateEncryptionKeysPhase),
+	}
+}
+
+func (e *encryptionKeyRotation) commandPlan(controlPlane *rkev1.RKEControlPlane, command string) (plan.NodePlan, error) {
+	image := getInstallerImage(controlPlane)
+	runtime := GetRuntimeCommand(controlPlane.Spec.KubernetesVersion)
+	instructions := []plan.Instruction{
+		{
+			Name:    "secrets-encrypt-" + command,
+			Image:   image,
+			Command: runtime,
+			Env:     phaseEnv(controlPlane),
+			Args:    []string{"secrets-encrypt", command},
+		},
+	}
+
+	if command == "reencrypt" {
+		// reencrypt returns right away, the secrets are rewritten in the background
+		instructions = append(instructions, plan.Instruction{
+			Name:    "secrets-encrypt-wait",
+			Image:   image,
+			Command: "sh",
+			Env:     phaseEnv(controlPlane),
+			Args: []string{
+				"-c",
+				fmt.Sprintf("for i in $(seq 1 120); do %s secrets-encrypt status | grep -q reencrypt_finished && exit 0; sleep 5; done; exit 1", runtime),
+			},
+		})
+	}
+
+	return commonNodePlan(e.secrets, controlPlane, plan.NodePlan{
+		Instructions: instructions,
+	})
+}
+
+func (e *encryptionKeyRotation) restartPlan(controlPlane *rkev1.RKEControlPlane) (plan.NodePlan, error) {
+	return commonNodePlan(e.secrets, controlPlane, plan.NodePlan{
+		Instructions: []plan.Instruction{
+			{
+				Name:    "restart",
+				Image:   getInstallerImage(controlPlane),
+				Command: "systemctl",
+				Env:     phaseEnv(controlPlane),
+				Args: []string{
+					"restart", GetRuntimeServerUnit(controlPlane.Spec.KubernetesVersion),
+				},
+			},
+		},
+	})
+}
+
+func (e *encryptionKeyRotation) runCommand(controlPlane *rkev1.RKEControlPlane, clusterPlan *plan.Plan, command string) error {
+	leaderName := controlPlane.Status.RotateEncryptionKeysLeader
+	machine, ok := clusterPlan.Machines[leaderName]
+	if !ok {
+		return fmt.Errorf("encryption key rotation leader machine [%s] no longer exists, set a new rotation generation to start over", leaderName)
+	}
+
+	commandPlan, err := e.commandPlan(controlPlane, command)
+	if err != nil {
+		return err
+	}
+
+	return assignAndCheckPlan(e.store, "secrets-encrypt "+command, planEntry{
+		Machine: machine,
+		Plan:    clusterPlan.Nodes[leaderName],
+	}, commandPlan)
+}
+
+// restartServers restarts the leader first and then the other servers
+func (e *encryptionKeyRotation) restartServers(controlPlane *rkev1.RKEControlPlane, clusterPlan *plan.Plan) error {
+	restartPlan, err := e.restartPlan(controlPlane)
+	if err != nil {
+		return err
+	}
+
+	leaderName := controlPlane.Status.RotateEncryptionKeysLeader
+	if err := rollOutPlan(e.store, clusterPlan, func(machine *capi.Machine) bool {
+		return machine.Name == leaderName
+	}, restartPlan, "1", "restarting encryption key rotation leader"); err != nil {
+		return err
+	}
+
+	return rollOutPlan(e.store, clusterPlan, func(machine *capi.Machine) bool {
+		return machine.Name != leaderName && isServer(machine)
+	}, restartPlan, controlPlane.Spec.UpgradeStrategy.ControlPlaneConcurrency, "restarting server")
+}
+
+func (e *encryptionKeyRotation) Rotate(controlPlane *rkev1.RKEControlPlane, clusterPlan *plan.Plan) error {
+	spec := controlPlane.Spec.RotateEncryptionKeys
+	if spec == nil {
+		return e.resetState(controlPlane)
+	}
+
+	if controlPlane.Status.RotateEncryptionKeys == nil || controlPlane.Status.RotateEncryptionKeys.Generation != spec.Generation {
+		if GetRuntime(controlPlane.Spec.KubernetesVersion) == RuntimeK3S && !secretsEncryptionEnabled(controlPlane) {
+			return fmt.Errorf("secrets-encryption must be enabled to rotate the encryption keys of a k3s cluster")
+		}
+		leaderName := leader(clusterPlan)
+		if leaderName == "" {
+			return ErrWaiting("waiting for a control plane node to rotate the encryption keys on")
+		}
+		return e.setState(controlPlane, spec.DeepCopy(), rkev1.RotateEncryptionKeysPhasePrepare, leaderName)
+	}
+
+	switch controlPlane.Status.RotateEncryptionKeysPhase {
+	case rkev1.RotateEncryptionKeysPhasePrepare:
+		if err := e.runCommand(controlPlane, clusterPlan, "prepare"); err != nil {
+			return err
+		}
+		return e.setPhase(controlPlane, rkev1.RotateEncryptionKeysPhasePostPrepareRestart)
+	case rkev1.RotateEncryptionKeysPhasePostPrepareRestart:
+		if err := e.restartServers(controlPlane, clusterPlan); err != nil {
+			return err
+		}
+		return e.setPhase(controlPlane, rkev1.RotateEncryptionKeysPhaseRotate)
+	case rkev1.RotateEncryptionKeysPhaseRotate:
+		if err := e.runCommand(controlPlane, clusterPlan, "rotate"); err != nil {
+			return err
+		}
+		return e.setPhase(controlPlane, rkev1.RotateEncryptionKeysPhasePostRotateRestart)
+	case rkev1.RotateEncryptionKeysPhasePostRotateRestart:
+		if err := e.restartServers(controlPlane, clusterPlan); err != nil {
+			return err
+		}
+		return e.setPhase(controlPlane, rkev1.RotateEncryptionKeysPhaseReencrypt)
+	case rkev1.RotateEncryptionKeysPhaseReencrypt:
+		if err := e.runCommand(controlPlane, clusterPlan, "reencrypt"); err != nil {
+			return err
+		}
+		return e.setPhase(controlPlane, rkev1.RotateEncryptionKeysPhasePostReencryptRestart)
+	case rkev1.RotateEncryptionKeysPhasePostReencryptRestart:
+		if err := e.restartServers(controlPlane, clusterPlan); err != nil {
+			return err
+		}
+		return e.setPhase(controlPlane, rkev1.RotateEncryptionKeysPhaseDone)
+	case rkev1.RotateEncryptionKeysPhaseDone:
+		return nil
+	default:
+		return e.setPhase(controlPlane, rkev1.RotateEncryptionKeysPhasePrepare)
+	}
+}
+
+func secretsEncryptionEnabled(controlPlane *rkev1.RKEControlPlane) bool {
+	enabled, _ := controlPlane.Spec.MachineGlobalConfig.Data["secrets-encryption"].(bool)
+	return enabled
+}
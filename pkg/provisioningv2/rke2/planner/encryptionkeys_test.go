@@ -0,0 +1,381 @@
+package planner
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	rkecontroller "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
+)
+
+func TestEncryptionKeyRotationLeader(t *testing.T) {
+	clusterPlan := &plan.Plan{
+		Machines: map[string]*capi.Machine{},
+		Nodes:    map[string]*plan.Node{},
+	}
+	for _, name := range []string{"cp1", "cp2", "cp3"} {
+		clusterPlan.Machines[name] = &capi.Machine{ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{ControlPlaneRoleLabel: "true"},
+		}}
+		clusterPlan.Nodes[name] = &plan.Node{AppliedPlan: &plan.NodePlan{}}
+	}
+	clusterPlan.Machines["etcd1"] = &capi.Machine{ObjectMeta: metav1.ObjectMeta{
+		Name:   "etcd1",
+		Labels: map[string]string{EtcdRoleLabel: "true"},
+	}}
+	clusterPlan.Nodes["etcd1"] = &plan.Node{AppliedPlan: &plan.NodePlan{}}
+
+	// machines that never applied a plan can't run secrets-encrypt
+	clusterPlan.Nodes["cp1"].AppliedPlan = nil
+	assert.Equal(t, "cp2", leader(clusterPlan))
+
+	now := metav1.Now()
+	clusterPlan.Machines["cp2"].DeletionTimestamp = &now
+	assert.Equal(t, "cp3", leader(clusterPlan))
+
+	delete(clusterPlan.Machines, "cp3")
+	assert.Equal(t, "", leader(clusterPlan))
+}
+
+// rotationCluster is a control plane and its machines. Status updates and plans assigned by the rotation are
+// applied to it the way the controllers and the system agent would.
+type rotationCluster struct {
+	t            *testing.T
+	controlPlane *rkev1.RKEControlPlane
+	plan         *plan.Plan
+	statusErr    error
+	phases       []rkev1.RotateEncryptionKeysPhase
+	// assigned are the machines and first instruction of every plan assigned, in order
+	assigned [][2]string
+}
+
+func newRotationCluster(t *testing.T) *rotationCluster {
+	c := &rotationCluster{
+		t: t,
+		controlPlane: &rkev1.RKEControlPlane{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "fleet-default",
+			},
+			Spec: rkev1.RKEControlPlaneSpec{
+				KubernetesVersion: "v1.21.4+rke2r2",
+			},
+		},
+		plan: &plan.Plan{
+			Machines: map[string]*capi.Machine{},
+			Nodes:    map[string]*plan.Node{},
+		},
+	}
+	c.addMachine("cp1", ControlPlaneRoleLabel, EtcdRoleLabel)
+	c.addMachine("cp2", ControlPlaneRoleLabel)
+	c.addMachine("etcd1", EtcdRoleLabel)
+	c.addMachine("worker1", WorkerRoleLabel)
+	return c
+}
+
+func (c *rotationCluster) addMachine(name string, roles ...string) {
+	machine := &capi.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: c.controlPlane.Namespace,
+			Labels:    map[string]string{},
+		},
+		Spec: capi.MachineSpec{
+			Bootstrap: capi.Bootstrap{
+				ConfigRef: &corev1.ObjectReference{
+					Kind: "RKEBootstrap",
+					Name: name,
+				},
+			},
+		},
+	}
+	for _, role := range roles {
+		machine.Labels[role] = "true"
+	}
+	c.plan.Machines[name] = machine
+	c.plan.Nodes[name] = &plan.Node{
+		AppliedPlan: &plan.NodePlan{},
+		InSync:      true,
+	}
+}
+
+func (c *rotationCluster) rotation() *encryptionKeyRotation {
+	return &encryptionKeyRotation{
+		controlPlane: &fakeControlPlanes{cluster: c},
+		store: &PlanStore{
+			secrets: &fakePlanSecrets{cluster: c},
+		},
+	}
+}
+
+type fakeControlPlanes struct {
+	rkecontroller.RKEControlPlaneClient
+	cluster *rotationCluster
+}
+
+func (f *fakeControlPlanes) UpdateStatus(controlPlane *rkev1.RKEControlPlane) (*rkev1.RKEControlPlane, error) {
+	c := f.cluster
+	if c.statusErr != nil {
+		return nil, c.statusErr
+	}
+	c.controlPlane = controlPlane
+	c.phases = append(c.phases, controlPlane.Status.RotateEncryptionKeysPhase)
+	return controlPlane, nil
+}
+
+type fakePlanSecrets struct {
+	corecontrollers.SecretClient
+	cluster *rotationCluster
+}
+
+func (f *fakePlanSecrets) Get(namespace, name string, opts metav1.GetOptions) (*corev1.Secret, error) {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+	}, nil
+}
+
+func (f *fakePlanSecrets) Update(secret *corev1.Secret) (*corev1.Secret, error) {
+	c := f.cluster
+	for name, machine := range c.plan.Machines {
+		if PlanSecretFromBootstrapName(machine.Spec.Bootstrap.ConfigRef.Name) != secret.Name {
+			continue
+		}
+		var nodePlan plan.NodePlan
+		require.NoError(c.t, json.Unmarshal(secret.Data["plan"], &nodePlan))
+		c.plan.Nodes[name].Plan = nodePlan
+		c.plan.Nodes[name].InSync = false
+		c.assigned = append(c.assigned, [2]string{name, nodePlan.Instructions[0].Name})
+		return secret, nil
+	}
+	c.t.Fatalf("unexpected plan secret %s", secret.Name)
+	return nil, nil
+}
+
+// apply has the system agent of every machine apply its plan
+func (c *rotationCluster) apply() {
+	for _, node := range c.plan.Nodes {
+		applied := node.Plan
+		node.AppliedPlan = &applied
+		node.InSync = true
+	}
+}
+
+func isWaiting(err error) bool {
+	var errWaiting ErrWaiting
+	return errors.As(err, &errWaiting)
+}
+
+func TestEncryptionKeyRotationPhases(t *testing.T) {
+	c := newRotationCluster(t)
+	c.controlPlane.Spec.RotateEncryptionKeys = &rkev1.RotateEncryptionKeys{Generation: 1}
+	rotation := c.rotation()
+
+	for i := 0; i < 50 && c.controlPlane.Status.RotateEncryptionKeysPhase != rkev1.RotateEncryptionKeysPhaseDone; i++ {
+		err := rotation.Rotate(c.controlPlane, c.plan)
+		require.True(t, isWaiting(err), "step %d: %v", i, err)
+		c.apply()
+	}
+
+	assert.Equal(t, []rkev1.RotateEncryptionKeysPhase{
+		rkev1.RotateEncryptionKeysPhasePrepare,
+		rkev1.RotateEncryptionKeysPhasePostPrepareRestart,
+		rkev1.RotateEncryptionKeysPhaseRotate,
+		rkev1.RotateEncryptionKeysPhasePostRotateRestart,
+		rkev1.RotateEncryptionKeysPhaseReencrypt,
+		rkev1.RotateEncryptionKeysPhasePostReencryptRestart,
+		rkev1.RotateEncryptionKeysPhaseDone,
+	}, c.phases)
+	assert.Equal(t, "cp1", c.controlPlane.Status.RotateEncryptionKeysLeader)
+
+	// the leader runs every command and restarts first, the other servers restart one at a time and workers are
+	// left alone
+	restarts := [][2]string{{"cp1", "restart"}, {"cp2", "restart"}, {"etcd1", "restart"}}
+	var expected [][2]string
+	for _, command := range []string{"prepare", "rotate", "reencrypt"} {
+		expected = append(expected, [2]string{"cp1", "secrets-encrypt-" + command})
+		expected = append(expected, restarts...)
+	}
+	assert.Equal(t, expected, c.assigned)
+	assert.Len(t, c.plan.Nodes["cp1"].Plan.Instructions, 1)
+
+	require.NoError(t, rotation.Rotate(c.controlPlane, c.plan), "a finished rotation is done")
+
+	// removing the rotation from the spec resets the state
+	c.controlPlane.Spec.RotateEncryptionKeys = nil
+	assert.True(t, isWaiting(rotation.Rotate(c.controlPlane, c.plan)))
+	assert.Nil(t, c.controlPlane.Status.RotateEncryptionKeys)
+	assert.Empty(t, c.controlPlane.Status.RotateEncryptionKeysPhase)
+	assert.Empty(t, c.controlPlane.Status.RotateEncryptionKeysLeader)
+}
+
+func TestEncryptionKeyRotationWaitsForPlans(t *testing.T) {
+	tests := []struct {
+		name  string
+		phase rkev1.RotateEncryptionKeysPhase
+		// nodes that applied the plan of the phase
+		applied []string
+		// nodes that were assigned the plan of the phase but didn't apply it, because it is running or failed
+		failed    []string
+		nextPhase rkev1.RotateEncryptionKeysPhase
+	}{
+		{
+			name:  "command not assigned",
+			phase: rkev1.RotateEncryptionKeysPhaseRotate,
+		},
+		{
+			name:   "command failed",
+			phase:  rkev1.RotateEncryptionKeysPhaseRotate,
+			failed: []string{"cp1"},
+		},
+		{
+			name:      "command applied",
+			phase:     rkev1.RotateEncryptionKeysPhaseRotate,
+			applied:   []string{"cp1"},
+			nextPhase: rkev1.RotateEncryptionKeysPhasePostRotateRestart,
+		},
+		{
+			name:  "leader restart failed",
+			phase: rkev1.RotateEncryptionKeysPhasePostRotateRestart,
+			// the other servers are only restarted once the leader restarted
+			failed: []string{"cp1"},
+		},
+		{
+			name:    "server restart failed",
+			phase:   rkev1.RotateEncryptionKeysPhasePostPrepareRestart,
+			applied: []string{"cp1", "cp2"},
+			failed:  []string{"etcd1"},
+		},
+		{
+			name:      "servers restarted",
+			phase:     rkev1.RotateEncryptionKeysPhasePostReencryptRestart,
+			applied:   []string{"cp1", "cp2", "etcd1"},
+			nextPhase: rkev1.RotateEncryptionKeysPhaseDone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newRotationCluster(t)
+			spec := &rkev1.RotateEncryptionKeys{Generation: 1}
+			c.controlPlane.Spec.RotateEncryptionKeys = spec
+			c.controlPlane.Status.RotateEncryptionKeys = spec.DeepCopy()
+			c.controlPlane.Status.RotateEncryptionKeysPhase = tt.phase
+			c.controlPlane.Status.RotateEncryptionKeysLeader = "cp1"
+			rotation := c.rotation()
+
+			var (
+				phasePlan plan.NodePlan
+				err       error
+			)
+			switch tt.phase {
+			case rkev1.RotateEncryptionKeysPhasePostPrepareRestart, rkev1.RotateEncryptionKeysPhasePostRotateRestart,
+				rkev1.RotateEncryptionKeysPhasePostReencryptRestart:
+				phasePlan, err = rotation.restartPlan(c.controlPlane)
+			default:
+				phasePlan, err = rotation.commandPlan(c.controlPlane, "rotate")
+			}
+			require.NoError(t, err)
+			for _, name := range tt.applied {
+				c.plan.Nodes[name].Plan = phasePlan
+				c.plan.Nodes[name].AppliedPlan = &phasePlan
+			}
+			for _, name := range tt.failed {
+				c.plan.Nodes[name].Plan = phasePlan
+				c.plan.Nodes[name].InSync = false
+			}
+
+			err = rotation.Rotate(c.controlPlane, c.plan)
+			assert.True(t, isWaiting(err), "%v", err)
+			if tt.nextPhase == "" {
+				assert.Empty(t, c.phases)
+			} else {
+				assert.Equal(t, []rkev1.RotateEncryptionKeysPhase{tt.nextPhase}, c.phases)
+			}
+			if len(tt.failed) > 0 {
+				assert.Empty(t, c.assigned, "no plan is assigned while a node of the phase has not applied its plan")
+			}
+		})
+	}
+}
+
+func TestEncryptionKeyRotationRetry(t *testing.T) {
+	c := newRotationCluster(t)
+	c.controlPlane.Spec.RotateEncryptionKeys = &rkev1.RotateEncryptionKeys{Generation: 1}
+	c.controlPlane.Status.RotateEncryptionKeys = &rkev1.RotateEncryptionKeys{Generation: 1}
+	c.controlPlane.Status.RotateEncryptionKeysPhase = rkev1.RotateEncryptionKeysPhaseReencrypt
+	c.controlPlane.Status.RotateEncryptionKeysLeader = "cp1"
+	rotation := c.rotation()
+
+	// the leader was deleted in the middle of the rotation
+	delete(c.plan.Machines, "cp1")
+	err := rotation.Rotate(c.controlPlane, c.plan)
+	require.Error(t, err)
+	assert.False(t, isWaiting(err))
+	assert.Empty(t, c.phases)
+
+	// a new generation starts over with a new leader
+	c.controlPlane.Spec.RotateEncryptionKeys = &rkev1.RotateEncryptionKeys{Generation: 2}
+	assert.True(t, isWaiting(rotation.Rotate(c.controlPlane, c.plan)))
+	assert.Equal(t, []rkev1.RotateEncryptionKeysPhase{rkev1.RotateEncryptionKeysPhasePrepare}, c.phases)
+	assert.Equal(t, "cp2", c.controlPlane.Status.RotateEncryptionKeysLeader)
+	assert.Equal(t, int64(2), c.controlPlane.Status.RotateEncryptionKeys.Generation)
+
+	// a phase that is not known is started from the beginning
+	c.controlPlane.Status.RotateEncryptionKeysPhase = "Unknown"
+	assert.True(t, isWaiting(rotation.Rotate(c.controlPlane, c.plan)))
+	assert.Equal(t, rkev1.RotateEncryptionKeysPhasePrepare, c.controlPlane.Status.RotateEncryptionKeysPhase)
+
+	// the phase is not advanced if the status can't be saved, the step is repeated on the next reconcile
+	c.controlPlane.Status.RotateEncryptionKeysPhase = rkev1.RotateEncryptionKeysPhaseRotate
+	commandPlan, err := rotation.commandPlan(c.controlPlane, "rotate")
+	require.NoError(t, err)
+	c.plan.Nodes["cp2"].Plan = commandPlan
+	c.plan.Nodes["cp2"].AppliedPlan = &commandPlan
+	c.statusErr = apierrors.NewConflict(rkev1.Resource("rkecontrolplanes"), "test", nil)
+	err = rotation.Rotate(c.controlPlane, c.plan)
+	assert.True(t, apierrors.IsConflict(err))
+	assert.Equal(t, rkev1.RotateEncryptionKeysPhaseRotate, c.controlPlane.Status.RotateEncryptionKeysPhase)
+
+	c.statusErr = nil
+	assert.True(t, isWaiting(rotation.Rotate(c.controlPlane, c.plan)))
+	assert.Equal(t, rkev1.RotateEncryptionKeysPhasePostRotateRestart, c.controlPlane.Status.RotateEncryptionKeysPhase)
+}
+
+func TestEncryptionKeyRotationStart(t *testing.T) {
+	c := newRotationCluster(t)
+	rotation := c.rotation()
+
+	require.NoError(t, rotation.Rotate(c.controlPlane, c.plan), "nothing to do without a rotation")
+	assert.Empty(t, c.phases)
+
+	c.controlPlane.Spec.RotateEncryptionKeys = &rkev1.RotateEncryptionKeys{Generation: 1}
+	for _, node := range c.plan.Nodes {
+		node.AppliedPlan = nil
+	}
+	assert.True(t, isWaiting(rotation.Rotate(c.controlPlane, c.plan)), "no provisioned control plane node")
+	assert.Empty(t, c.phases)
+
+	c.controlPlane.Spec.KubernetesVersion = "v1.21.4+k3s1"
+	err := rotation.Rotate(c.controlPlane, c.plan)
+	require.Error(t, err)
+	assert.False(t, isWaiting(err), "k3s requires secrets-encryption")
+
+	c.controlPlane.Spec.MachineGlobalConfig.Data = map[string]interface{}{"secrets-encryption": true}
+	c.plan.Nodes["cp2"].AppliedPlan = &plan.NodePlan{}
+	assert.True(t, isWaiting(rotation.Rotate(c.controlPlane, c.plan)))
+	assert.Equal(t, []rkev1.RotateEncryptionKeysPhase{rkev1.RotateEncryptionKeysPhasePrepare}, c.phases)
+	assert.Equal(t, "cp2", c.controlPlane.Status.RotateEncryptionKeysLeader)
+}
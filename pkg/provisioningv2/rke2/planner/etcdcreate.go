@@ -1,7 +1,12 @@
 package planner
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
+	"path"
+	"sort"
+	"strings"
 
 	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
 	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
@@ -12,6 +17,10 @@
 	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
 )
 
+// snapshotChecksumsInstruction saves the sha256 of every local snapshot of a node, so they can be compared to the
+// checksums read later on.
+const snapshotChecksumsInstruction = "snapshot-checksums"
+
 type etcdCreate struct {
 	controlPlane rkecontroller.RKEControlPlaneClient
 	secrets      corecontrollers.SecretCache
@@ -56,7 +65,7 @@ func (e *etcdCreate) startOrRestartCreate(controlPlane *rkev1.RKEControlPlane, s
 	return nil
 }
 
-func (e *etcdCreate) etcdCreate(controlPlane *rkev1.RKEControlPlane, clusterPlan *plan.Plan, snapshot *rkev1.ETCDSnapshotCreate) error {
+func (e *etcdCreate) etcdCreate(controlPlane *rkev1.RKEControlPlane, clusterPlan *plan.Plan, snapshot *rkev1.ETCDSnapshotCreate) ([]rkev1.ETCDSnapshotChecksum, error) {
 	servers := collect(clusterPlan, func(machine *capi.Machine) bool {
 		if !isEtcd(machine) || machine.Status.NodeRef == nil {
 			return false
@@ -66,16 +75,52 @@ func (e *etcdCreate) etcdCreate(controlPlane *rkev1.RKEControlPlane, clusterPlan
 	})
 
 	if len(servers) == 0 {
-		return fmt.Errorf("failed to find node to perform etcd snapshot")
+		return nil, fmt.Errorf("failed to find node to perform etcd snapshot")
 	}
 
 	server := servers[0]
 	createPlan, err := e.createPlan(controlPlane, snapshot, server.Machine.Status.NodeRef.Name)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if err := assignAndCheckPlan(e.store, "etcd snapshot", server, createPlan); err != nil {
+		return nil, err
 	}
 
-	return assignAndCheckPlan(e.store, "etcd snapshot", server, createPlan)
+	return parseSnapshotChecksums(server.Machine.Status.NodeRef.Name, server.Plan.Output[snapshotChecksumsInstruction]), nil
+}
+
+// parseSnapshotChecksums parses the output of sha256sum run on the snapshot directory of nodeName.
+func parseSnapshotChecksums(nodeName string, output []byte) (result []rkev1.ETCDSnapshotChecksum) {
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || len(fields[0]) != 64 {
+			continue
+		}
+		result = append(result, rkev1.ETCDSnapshotChecksum{
+			Name:     path.Base(fields[1]),
+			NodeName: nodeName,
+			Checksum: fields[0],
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// mergeSnapshotChecksums replaces the checksums of nodeName with checksums, snapshots that were deleted from the node
+// are dropped with it.
+func mergeSnapshotChecksums(existing []rkev1.ETCDSnapshotChecksum, nodeName string, checksums []rkev1.ETCDSnapshotChecksum) []rkev1.ETCDSnapshotChecksum {
+	var result []rkev1.ETCDSnapshotChecksum
+	for _, checksum := range existing {
+		if checksum.NodeName != nodeName {
+			result = append(result, checksum)
+		}
+	}
+	return append(result, checksums...)
 }
 
 func (e *etcdCreate) createPlan(controlPlane *rkev1.RKEControlPlane, snapshot *rkev1.ETCDSnapshotCreate, nodeName string) (plan.NodePlan, error) {
@@ -97,13 +142,16 @@ func (e *etcdCreate) createPlan(controlPlane *rkev1.RKEControlPlane, snapshot *r
 
 	return commonNodePlan(e.secrets, controlPlane, plan.NodePlan{
 		Files: s3Files,
-		Instructions: []plan.Instruction{{
-			Name:    "create",
-			Image:   getInstallerImage(controlPlane),
-			Command: GetRuntimeCommand(controlPlane.Spec.KubernetesVersion),
-			Env:     s3Env,
-			Args:    append(args, s3Args...),
-		}},
+		Instructions: []plan.Instruction{
+			{
+				Name:    "create",
+				Image:   getInstallerImage(controlPlane),
+				Command: GetRuntimeCommand(controlPlane.Spec.KubernetesVersion),
+				Env:     s3Env,
+				Args:    append(args, s3Args...),
+			},
+			snapshotChecksumsInstructionFor(controlPlane),
+		},
 	})
 }
 
@@ -122,9 +170,15 @@ func (e *etcdCreate) createSnapshot(controlPlane *rkev1.RKEControlPlane, cluster
 
 	switch controlPlane.Status.ETCDSnapshotCreatePhase {
 	case rkev1.ETCDSnapshotPhaseStarted:
-		if err := e.etcdCreate(controlPlane, clusterPlan, snapshot); err != nil {
+		checksums, err := e.etcdCreate(controlPlane, clusterPlan, snapshot)
+		if err != nil {
 			return err
 		}
+		if len(checksums) > 0 {
+			controlPlane = controlPlane.DeepCopy()
+			controlPlane.Status.ETCDSnapshotChecksums = mergeSnapshotChecksums(controlPlane.Status.ETCDSnapshotChecksums,
+				checksums[0].NodeName, checksums)
+		}
 		return e.setState(controlPlane, snapshot, rkev1.ETCDSnapshotPhaseFinished)
 	case rkev1.ETCDSnapshotPhaseFinished:
 		return nil
@@ -0,0 +1,43 @@
+package planner
+
+import (
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSnapshotChecksums(t *testing.T) {
+	output := []byte(`b5bb9d8014a0f9b1d61e21e796d78dccdf1352f23cd32812f4850b878ae4944c  /var/lib/rancher/rke2/server/db/snapshots/on-demand-node1-1634567890
+sha256sum: /var/lib/rancher/rke2/server/db/snapshots/tmp: Is a directory
+7d865e959b2466918c9863afca942d0fb89d7c9ac0c99bafc3749504ded97730  /var/lib/rancher/rke2/server/db/snapshots/etcd-snapshot-node1-1634500000
+`)
+
+	assert.Equal(t, []rkev1.ETCDSnapshotChecksum{
+		{
+			Name:     "etcd-snapshot-node1-1634500000",
+			NodeName: "node1",
+			Checksum: "7d865e959b2466918c9863afca942d0fb89d7c9ac0c99bafc3749504ded97730",
+		},
+		{
+			Name:     "on-demand-node1-1634567890",
+			NodeName: "node1",
+			Checksum: "b5bb9d8014a0f9b1d61e21e796d78dccdf1352f23cd32812f4850b878ae4944c",
+		},
+	}, parseSnapshotChecksums("node1", output))
+	assert.Nil(t, parseSnapshotChecksums("node1", nil))
+}
+
+func TestMergeSnapshotChecksums(t *testing.T) {
+	existing := []rkev1.ETCDSnapshotChecksum{
+		{Name: "a", NodeName: "node1", Checksum: "1"},
+		{Name: "b", NodeName: "node2", Checksum: "2"},
+	}
+
+	assert.Equal(t, []rkev1.ETCDSnapshotChecksum{
+		{Name: "b", NodeName: "node2", Checksum: "2"},
+		{Name: "c", NodeName: "node1", Checksum: "3"},
+	}, mergeSnapshotChecksums(existing, "node1", []rkev1.ETCDSnapshotChecksum{
+		{Name: "c", NodeName: "node1", Checksum: "3"},
+	}))
+}
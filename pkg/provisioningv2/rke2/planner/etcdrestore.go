@@ -10,20 +10,23 @@
 	"github.com/rancher/rancher/pkg/wrangler"
 	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 type etcdRestore struct {
-	controlPlane rkecontroller.RKEControlPlaneClient
-	secrets      corecontrollers.SecretCache
-	s3Args       *s3Args
-	store        *PlanStore
+	controlPlane    rkecontroller.RKEControlPlaneClient
+	secrets         corecontrollers.SecretCache
+	snapshotRecords rkecontroller.ETCDSnapshotRecordCache
+	s3Args          *s3Args
+	store           *PlanStore
 }
 
 func newETCDRestore(clients *wrangler.Context, store *PlanStore) *etcdRestore {
 	return &etcdRestore{
-		controlPlane: clients.RKE.RKEControlPlane(),
-		secrets:      clients.Core.Secret().Cache(),
-		store:        store,
+		controlPlane:    clients.RKE.RKEControlPlane(),
+		secrets:         clients.Core.Secret().Cache(),
+		snapshotRecords: clients.RKE.ETCDSnapshotRecord().Cache(),
+		store:           store,
 		s3Args: &s3Args{
 			secretCache: clients.Core.Secret().Cache(),
 			prefix:      "etcd-",
@@ -57,14 +60,102 @@ func (e *etcdRestore) startOrRestartRestore(controlPlane *rkev1.RKEControlPlane)
 	return nil
 }
 
+// FindETCDSnapshotRecord returns the ETCDSnapshotRecord of clusterName that snapshot refers to, either by name or by
+// matching the snapshot itself.
+func FindETCDSnapshotRecord(records rkecontroller.ETCDSnapshotRecordCache, namespace, clusterName string, snapshot *rkev1.ETCDSnapshot) (*rkev1.ETCDSnapshotRecord, error) {
+	if snapshot.Record != "" {
+		record, err := records.Get(namespace, snapshot.Record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lookup etcd snapshot record [%s]: %w", snapshot.Record, err)
+		}
+		if record.Spec.ClusterName != clusterName {
+			return nil, fmt.Errorf("etcd snapshot record [%s] belongs to cluster [%s]", record.Name, record.Spec.ClusterName)
+		}
+		return record, nil
+	}
+
+	all, err := records.List(namespace, labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range all {
+		if record.Spec.ClusterName == clusterName &&
+			record.Spec.Snapshot.Name == snapshot.Name &&
+			(record.Spec.Snapshot.S3 == nil) == (snapshot.S3 == nil) &&
+			(snapshot.S3 != nil || snapshot.NodeName == "" || record.Spec.Snapshot.NodeName == snapshot.NodeName) {
+			return record, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to find etcd snapshot record of snapshot [%s]", snapshot.Name)
+}
+
+// RestoresRKEConfig returns true if restoring snapshot also rolls the configuration of the cluster back.
+func RestoresRKEConfig(snapshot *rkev1.ETCDSnapshot) bool {
+	return snapshot.RestoreRKEConfig == rkev1.ETCDSnapshotRestoreRKEConfigKubernetesVersion ||
+		snapshot.RestoreRKEConfig == rkev1.ETCDSnapshotRestoreRKEConfigAll
+}
+
+// appliedSpecRestored returns true once the spec of controlPlane was rolled back to the configuration recorded with
+// the snapshot being restored.
+func appliedSpecRestored(controlPlane *rkev1.RKEControlPlane, appliedSpec *rkev1.AppliedSpec, mode rkev1.ETCDSnapshotRestoreRKEConfig) bool {
+	if controlPlane.Spec.KubernetesVersion != appliedSpec.KubernetesVersion {
+		return false
+	}
+	if mode == rkev1.ETCDSnapshotRestoreRKEConfigAll && appliedSpec.RKEConfig != nil {
+		return equality.Semantic.DeepEqual(controlPlane.Spec.RKEClusterSpecCommon, *appliedSpec.RKEConfig)
+	}
+	return true
+}
+
+// snapshot returns the snapshot to restore, resolving the ETCDSnapshotRecord it refers to if any. Snapshots whose
+// last verification failed are refused before the control plane is shut down. If the configuration of the cluster is
+// restored too, it waits for the spec to be rolled back first.
+func (e *etcdRestore) snapshot(controlPlane *rkev1.RKEControlPlane) (*rkev1.ETCDSnapshot, error) {
+	snapshot := controlPlane.Spec.ETCDSnapshotRestore
+	if snapshot.Record == "" && !RestoresRKEConfig(snapshot) {
+		return snapshot, nil
+	}
+
+	record, err := FindETCDSnapshotRecord(e.snapshotRecords, controlPlane.Namespace, controlPlane.Spec.ClusterName, snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	switch record.Status.State {
+	case rkev1.ETCDSnapshotRecordStateMissing, rkev1.ETCDSnapshotRecordStateCorrupt, rkev1.ETCDSnapshotRecordStatePruned:
+		return nil, fmt.Errorf("refusing to restore etcd snapshot [%s], it is %s: %s", record.Spec.Snapshot.Name,
+			strings.ToLower(string(record.Status.State)), record.Status.Message)
+	}
+
+	if RestoresRKEConfig(snapshot) {
+		if record.Status.AppliedSpec == nil {
+			return nil, fmt.Errorf("etcd snapshot [%s] was taken before the configuration of the cluster was recorded, it can only be restored with restoreRKEConfig none",
+				record.Spec.Snapshot.Name)
+		}
+		if !appliedSpecRestored(controlPlane, record.Status.AppliedSpec, snapshot.RestoreRKEConfig) {
+			return nil, ErrWaiting(fmt.Sprintf("waiting for the cluster configuration of etcd snapshot [%s] to be restored", record.Spec.Snapshot.Name))
+		}
+	}
+
+	if snapshot.Record == "" {
+		return snapshot, nil
+	}
+	return record.Spec.Snapshot.DeepCopy(), nil
+}
+
 func (e *etcdRestore) etcdRestore(controlPlane *rkev1.RKEControlPlane, clusterPlan *plan.Plan) error {
+	snapshot, err := e.snapshot(controlPlane)
+	if err != nil {
+		return err
+	}
+
 	servers := collect(clusterPlan, isEtcd)
 
 	for _, server := range servers {
-		if controlPlane.Spec.ETCDSnapshotRestore.S3 != nil ||
+		if snapshot.S3 != nil ||
 			(server.Machine.Status.NodeRef != nil &&
-				server.Machine.Status.NodeRef.Name == controlPlane.Spec.ETCDSnapshotRestore.NodeName) {
-			restorePlan, err := e.restorePlan(controlPlane, controlPlane.Spec.ETCDSnapshotRestore)
+				server.Machine.Status.NodeRef.Name == snapshot.NodeName) {
+			restorePlan, err := e.restorePlan(controlPlane, snapshot)
 			if err != nil {
 				return err
 			}
@@ -189,6 +280,9 @@ func (e *etcdRestore) Restore(controlPlane *rkev1.RKEControlPlane, clusterPlan *
 
 	switch controlPlane.Status.ETCDSnapshotRestorePhase {
 	case rkev1.ETCDSnapshotPhaseStarted:
+		if _, err := e.snapshot(controlPlane); err != nil {
+			return err
+		}
 		return e.setState(controlPlane, controlPlane.Spec.ETCDSnapshotRestore, rkev1.ETCDSnapshotPhaseShutdown)
 	case rkev1.ETCDSnapshotPhaseShutdown:
 		if err := e.etcdShutdown(controlPlane, clusterPlan); err != nil {
@@ -0,0 +1,30 @@
+package planner
+
+import (
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppliedSpecRestored(t *testing.T) {
+	controlPlane := &rkev1.RKEControlPlane{}
+	controlPlane.Spec.KubernetesVersion = "v1.20.11+rke2r2"
+	controlPlane.Spec.AdditionalManifest = "new"
+
+	appliedSpec := &rkev1.AppliedSpec{
+		KubernetesVersion: "v1.20.11+rke2r2",
+		RKEConfig: &rkev1.RKEClusterSpecCommon{
+			AdditionalManifest: "old",
+		},
+	}
+
+	assert.True(t, appliedSpecRestored(controlPlane, appliedSpec, rkev1.ETCDSnapshotRestoreRKEConfigKubernetesVersion))
+	assert.False(t, appliedSpecRestored(controlPlane, appliedSpec, rkev1.ETCDSnapshotRestoreRKEConfigAll))
+
+	controlPlane.Spec.AdditionalManifest = "old"
+	assert.True(t, appliedSpecRestored(controlPlane, appliedSpec, rkev1.ETCDSnapshotRestoreRKEConfigAll))
+
+	controlPlane.Spec.KubernetesVersion = "v1.21.5+rke2r2"
+	assert.False(t, appliedSpecRestored(controlPlane, appliedSpec, rkev1.ETCDSnapshotRestoreRKEConfigKubernetesVersion))
+}
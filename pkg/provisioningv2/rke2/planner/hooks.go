@@ -0,0 +1,115 @@
+package planner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
+)
+
+// lifecycleHookInstructions returns the instructions of the hooks of point that match machine, in the order they
+// are defined.
+func lifecycleHookInstructions(controlPlane *rkev1.RKEControlPlane, machine *capi.Machine, point rkev1.LifecycleHookPoint, env ...string) ([]plan.Instruction, error) {
+	var result []plan.Instruction
+	for _, hook := range controlPlane.Spec.LifecycleHooks {
+		if hook.Point != point {
+			continue
+		}
+		if hook.MachineLabelSelector != nil {
+			sel, err := metav1.LabelSelectorAsSelector(hook.MachineLabelSelector)
+			if err != nil {
+				return nil, fmt.Errorf("invalid machineLabelSelector of lifecycle hook [%s]: %w", hook.Name, err)
+			}
+			if !sel.Matches(labels.Set(machine.Labels)) {
+				continue
+			}
+		}
+
+		image := hook.Image
+		if image == "" {
+			image = getInstallerImage(controlPlane)
+		}
+		result = append(result, plan.Instruction{
+			Name:       fmt.Sprintf("%s-hook-%s", point, hook.Name),
+			Image:      image,
+			Command:    hook.Command,
+			Args:       hook.Args,
+			Env:        append(append([]string{"LIFECYCLE_HOOK_POINT=" + string(point)}, hook.Env...), env...),
+			SaveOutput: true,
+		})
+	}
+	return result, nil
+}
+
+func (p *Planner) addPostInstallHookInstructions(nodePlan plan.NodePlan, controlPlane *rkev1.RKEControlPlane, machine *capi.Machine) (plan.NodePlan, error) {
+	instructions, err := lifecycleHookInstructions(controlPlane, machine, rkev1.LifecycleHookPostInstall)
+	if err != nil {
+		return nodePlan, err
+	}
+	nodePlan.Instructions = append(nodePlan.Instructions, instructions...)
+	return nodePlan, nil
+}
+
+// preDrainPlan is the plan a node applies before it is drained to receive desired. It only runs the pre-drain hooks,
+// which are told the hash of the plan that follows. ok is false if no pre-drain hook matches the machine.
+func (p *Planner) preDrainPlan(controlPlane *rkev1.RKEControlPlane, machine *capi.Machine, desired plan.NodePlan) (plan.NodePlan, bool, error) {
+	data, err := json.Marshal(desired)
+	if err != nil {
+		return plan.NodePlan{}, false, err
+	}
+	digest := sha256.Sum256(data)
+
+	instructions, err := lifecycleHookInstructions(controlPlane, machine, rkev1.LifecycleHookPreDrain,
+		"LIFECYCLE_HOOK_NEXT_PLAN="+hex.EncodeToString(digest[:]))
+	if err != nil || len(instructions) == 0 {
+		return plan.NodePlan{}, false, err
+	}
+
+	nodePlan, err := commonNodePlan(p.secretCache, controlPlane, plan.NodePlan{
+		Instructions: instructions,
+	})
+	return nodePlan, true, err
+}
+
+// runPreDrainHooks returns true once the pre-drain hooks of the machine ran successfully for desired, machines that
+// never applied a plan have nothing to protect and skip them.
+func (p *Planner) runPreDrainHooks(controlPlane *rkev1.RKEControlPlane, entry planEntry, desired plan.NodePlan) (bool, error) {
+	if entry.Plan == nil || entry.Plan.AppliedPlan == nil {
+		return true, nil
+	}
+
+	preDrainPlan, ok, err := p.preDrainPlan(controlPlane, entry.Machine, desired)
+	if err != nil || !ok {
+		return !ok, err
+	}
+
+	if equality.Semantic.DeepEqual(*entry.Plan.AppliedPlan, preDrainPlan) {
+		return true, nil
+	}
+
+	if !equality.Semantic.DeepEqual(entry.Plan.Plan, preDrainPlan) {
+		return false, p.store.UpdatePlan(entry.Machine, preDrainPlan)
+	}
+
+	return false, nil
+}
+
+// postRejoinPlan is the plan a node gets once it applied desired and was uncordoned. It is desired followed by the
+// post-rejoin hooks, so the node doesn't restart, and ok is false if no post-rejoin hook matches the machine.
+func postRejoinPlan(controlPlane *rkev1.RKEControlPlane, machine *capi.Machine, desired plan.NodePlan) (plan.NodePlan, bool, error) {
+	instructions, err := lifecycleHookInstructions(controlPlane, machine, rkev1.LifecycleHookPostRejoin)
+	if err != nil || len(instructions) == 0 {
+		return desired, false, err
+	}
+
+	result := desired
+	result.Instructions = append(append([]plan.Instruction{}, desired.Instructions...), instructions...)
+	return result, true, nil
+}
@@ -0,0 +1,64 @@
+package planner
+
+import (
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
+)
+
+func TestLifecycleHookInstructions(t *testing.T) {
+	controlPlane := &rkev1.RKEControlPlane{}
+	controlPlane.Spec.LifecycleHooks = []rkev1.LifecycleHook{
+		{
+			Name:    "quiesce",
+			Point:   rkev1.LifecycleHookPreDrain,
+			Image:   "example/storage-tools:v1",
+			Command: "quiesce.sh",
+			MachineLabelSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"storage": "true"},
+			},
+		},
+		{
+			Name:    "cis",
+			Point:   rkev1.LifecycleHookPostRejoin,
+			Command: "harden.sh",
+			Env:     []string{"PROFILE=cis-1.6"},
+		},
+	}
+	storage := &capi.Machine{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"storage": "true"}}}
+	other := &capi.Machine{}
+
+	instructions, err := lifecycleHookInstructions(controlPlane, storage, rkev1.LifecycleHookPreDrain, "LIFECYCLE_HOOK_NEXT_PLAN=abc")
+	require.NoError(t, err)
+	assert.Equal(t, []plan.Instruction{{
+		Name:       "pre-drain-hook-quiesce",
+		Image:      "example/storage-tools:v1",
+		Command:    "quiesce.sh",
+		Env:        []string{"LIFECYCLE_HOOK_POINT=pre-drain", "LIFECYCLE_HOOK_NEXT_PLAN=abc"},
+		SaveOutput: true,
+	}}, instructions)
+
+	instructions, err = lifecycleHookInstructions(controlPlane, other, rkev1.LifecycleHookPreDrain)
+	require.NoError(t, err)
+	assert.Empty(t, instructions)
+
+	desired := plan.NodePlan{Instructions: []plan.Instruction{{Command: "sh"}}}
+	rejoinPlan, ok, err := postRejoinPlan(controlPlane, other, desired)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Len(t, desired.Instructions, 1)
+	require.Len(t, rejoinPlan.Instructions, 2)
+	assert.Equal(t, "post-rejoin-hook-cis", rejoinPlan.Instructions[1].Name)
+	assert.Equal(t, []string{"LIFECYCLE_HOOK_POINT=post-rejoin", "PROFILE=cis-1.6"}, rejoinPlan.Instructions[1].Env)
+	assert.Equal(t, getInstallerImage(controlPlane), rejoinPlan.Instructions[1].Image)
+
+	controlPlane.Spec.LifecycleHooks = nil
+	_, ok, err = postRejoinPlan(controlPlane, other, desired)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,51 @@
+package planner
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rancher/wrangler/pkg/generic"
+)
+
+var (
+	reconcileDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: "planner",
+			Name:      "reconcile_duration_seconds",
+			Help:      "Duration of planner reconciles of RKE control planes by result (success, waiting, skip or error)",
+			Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		},
+		[]string{"result"},
+	)
+
+	reconcileErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: "planner",
+			Name:      "reconcile_errors_total",
+			Help:      "Total count of reconciles that failed with an error, by controller and kind of the reconciled object",
+		},
+		[]string{"controller", "kind"},
+	)
+)
+
+// Collectors returns the planner metrics for registration with Prometheus.
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{reconcileDuration, reconcileErrors}
+}
+
+func observeReconcile(start time.Time, err error) {
+	var errWaiting ErrWaiting
+	result := "success"
+	switch {
+	case err == nil:
+	case errors.As(err, &errWaiting):
+		result = "waiting"
+	case errors.Is(err, generic.ErrSkip):
+		result = "skip"
+	default:
+		result = "error"
+		reconcileErrors.WithLabelValues("planner", "RKEControlPlane").Inc()
+	}
+	reconcileDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+}
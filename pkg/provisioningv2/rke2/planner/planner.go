@@ -12,6 +12,8 @@
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/moby/locker"
 	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
@@ -112,7 +114,7 @@ func (e errIgnore) Error() string {
 type Planner struct {
 	ctx                           context.Context
 	store                         *PlanStore
-	rkeControlPlanes              rkecontrollers.RKEControlPlaneClient
+	rkeControlPlanes              rkecontrollers.RKEControlPlaneController
 	secretClient                  corecontrollers.SecretClient
 	secretCache                   corecontrollers.SecretCache
 	machines                      capicontrollers.MachineClient
@@ -123,12 +125,20 @@ type Planner struct {
 	locker                        locker.Locker
 	etcdRestore                   *etcdRestore
 	etcdCreate                    *etcdCreate
+	certificateRotation           *certificateRotation
+	encryptionKeyRotation         *encryptionKeyRotation
+	snapshotChecksums             *snapshotChecksums
 	etcdArgs                      s3Args
 }
 
+// addIndexerOnce guards the indexer registration, a planner is created by the controllers and by the API server.
+var addIndexerOnce sync.Once
+
 func New(ctx context.Context, clients *wrangler.Context) *Planner {
-	clients.Mgmt.ClusterRegistrationToken().Cache().AddIndexer(clusterRegToken, func(obj *v3.ClusterRegistrationToken) ([]string, error) {
-		return []string{obj.Spec.ClusterName}, nil
+	addIndexerOnce.Do(func() {
+		clients.Mgmt.ClusterRegistrationToken().Cache().AddIndexer(clusterRegToken, func(obj *v3.ClusterRegistrationToken) ([]string, error) {
+			return []string{obj.Spec.ClusterName}, nil
+		})
 	})
 	store := NewStore(clients.Core.Secret(),
 		clients.CAPI.Machine().Cache())
@@ -145,6 +155,9 @@ func New(ctx context.Context, clients *wrangler.Context) *Planner {
 		kubeconfig:                    kubeconfig.New(clients),
 		etcdRestore:                   newETCDRestore(clients, store),
 		etcdCreate:                    newETCDCreate(clients, store),
+		certificateRotation:           newCertificateRotation(clients, store),
+		encryptionKeyRotation:         newEncryptionKeyRotation(clients, store),
+		snapshotChecksums:             newSnapshotChecksums(clients, store),
 	}
 }
 
@@ -165,10 +178,14 @@ func (p *Planner) getCAPICluster(controlPlane *rkev1.RKEControlPlane) (*capi.Clu
 	return p.capiClusters.Get(controlPlane.Namespace, ref.Name)
 }
 
-func (p *Planner) Process(controlPlane *rkev1.RKEControlPlane) error {
+func (p *Planner) Process(controlPlane *rkev1.RKEControlPlane) (err error) {
 	p.locker.Lock(string(controlPlane.UID))
 	defer p.locker.Unlock(string(controlPlane.UID))
 
+	defer func(start time.Time) {
+		observeReconcile(start, err)
+	}(time.Now())
+
 	cluster, err := p.getCAPICluster(controlPlane)
 	if err != nil {
 		return err
@@ -197,11 +214,29 @@ func (p *Planner) Process(controlPlane *rkev1.RKEControlPlane) error {
 		return err
 	}
 
+	if err := p.certificateRotation.Rotate(controlPlane, plan); err != nil {
+		return err
+	}
+
+	if err := p.encryptionKeyRotation.Rotate(controlPlane, plan); err != nil {
+		return err
+	}
+
 	if _, err := p.electInitNode(controlPlane, plan); err != nil {
 		return err
 	}
 
-	err = p.reconcile(controlPlane, secret, plan, "bootstrap", true, isInitNode, none,
+	upgrade, err := newUpgrade(controlPlane, plan, time.Now())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if statusErr := p.setUpgradeStatus(controlPlane, upgrade, err == nil); statusErr != nil && err == nil {
+			err = statusErr
+		}
+	}()
+
+	err = p.reconcile(controlPlane, secret, plan, upgrade, "bootstrap", true, isInitNode, none,
 		controlPlane.Spec.UpgradeStrategy.ControlPlaneConcurrency, "",
 		controlPlane.Spec.UpgradeStrategy.ControlPlaneDrainOptions)
 	firstIgnoreError, err = ignoreErrors(firstIgnoreError, err)
@@ -218,7 +253,7 @@ func (p *Planner) Process(controlPlane *rkev1.RKEControlPlane) error {
 		return ErrWaiting("waiting for join url to be available on bootstrap node")
 	}
 
-	err = p.reconcile(controlPlane, secret, plan, "etcd", true, isEtcd, isInitNode,
+	err = p.reconcile(controlPlane, secret, plan, upgrade, "etcd", true, isEtcd, isInitNode,
 		controlPlane.Spec.UpgradeStrategy.ControlPlaneConcurrency, joinServer,
 		controlPlane.Spec.UpgradeStrategy.ControlPlaneDrainOptions)
 	firstIgnoreError, err = ignoreErrors(firstIgnoreError, err)
@@ -226,7 +261,7 @@ func (p *Planner) Process(controlPlane *rkev1.RKEControlPlane) error {
 		return err
 	}
 
-	err = p.reconcile(controlPlane, secret, plan, "control plane", true, isControlPlane, isInitNode,
+	err = p.reconcile(controlPlane, secret, plan, upgrade, "control plane", true, isControlPlane, isInitNode,
 		controlPlane.Spec.UpgradeStrategy.ControlPlaneConcurrency, joinServer,
 		controlPlane.Spec.UpgradeStrategy.ControlPlaneDrainOptions)
 	firstIgnoreError, err = ignoreErrors(firstIgnoreError, err)
@@ -239,7 +274,7 @@ func (p *Planner) Process(controlPlane *rkev1.RKEControlPlane) error {
 		return ErrWaiting("waiting for control plane to be available")
 	}
 
-	err = p.reconcile(controlPlane, secret, plan, "worker", false, isOnlyWorker, isInitNode,
+	err = p.reconcile(controlPlane, secret, plan, upgrade, "worker", false, isOnlyWorker, isInitNode,
 		controlPlane.Spec.UpgradeStrategy.WorkerConcurrency, joinServer,
 		controlPlane.Spec.UpgradeStrategy.WorkerDrainOptions)
 	firstIgnoreError, err = ignoreErrors(firstIgnoreError, err)
@@ -251,7 +286,7 @@ func (p *Planner) Process(controlPlane *rkev1.RKEControlPlane) error {
 		return ErrWaiting(firstIgnoreError.Error())
 	}
 
-	return nil
+	return p.snapshotChecksums.Read(controlPlane, plan)
 }
 
 func ignoreErrors(firstIgnoreError error, err error) (error, error) {
@@ -412,6 +447,7 @@ func detailMessage(machines []string, messages map[string]string) string {
 }
 
 func (p *Planner) reconcile(controlPlane *rkev1.RKEControlPlane, secret plan.Secret, clusterPlan *plan.Plan,
+	upgrade *upgrade,
 	tierName string,
 	required bool,
 	include, exclude roleFilter, maxUnavailable string, joinServer string, drainOptions rkev1.DrainOptions) error {
@@ -421,6 +457,9 @@ func (p *Planner) reconcile(controlPlane *rkev1.RKEControlPlane, secret plan.Sec
 		errMachines []string
 		draining    []string
 		uncordoned  []string
+		hooks       []string
+		held        []string
+		holdReason  string
 		messages    = map[string]string{}
 	)
 
@@ -446,18 +485,40 @@ func (p *Planner) reconcile(controlPlane *rkev1.RKEControlPlane, secret plan.Sec
 		}
 		messages[entry.Machine.Name] = strings.Join(summary.Message, ", ")
 
+		var current *plan.NodePlan
+		if entry.Plan != nil {
+			current = &entry.Plan.Plan
+		}
 		plan, err := p.desiredPlan(controlPlane, secret, entry, isInitNode(entry.Machine), joinServer)
 		if err != nil {
 			return err
 		}
+		plan = withSnapshotChecksums(current, withCertificateCheck(controlPlane, withoutSnapshotChecksums(current), plan))
+
+		// a node that ran its post-rejoin hooks is in sync with the desired plan followed by the hooks
+		rejoinPlan, hasRejoinHooks, err := postRejoinPlan(controlPlane, entry.Machine, plan)
+		if err != nil {
+			return err
+		}
+		rejoined := hasRejoinHooks && entry.Plan != nil && equality.Semantic.DeepEqual(*withoutSnapshotChecksums(current), rejoinPlan)
+		if rejoined {
+			upgrade.observe(entry, withSnapshotChecksums(current, rejoinPlan))
+		} else {
+			upgrade.observe(entry, plan)
+		}
 
 		if entry.Plan == nil {
 			outOfSync = append(outOfSync, entry.Machine.Name)
 			if err := p.store.UpdatePlan(entry.Machine, plan); err != nil {
 				return err
 			}
-		} else if !equality.Semantic.DeepEqual(entry.Plan.Plan, plan) {
+		} else if !rejoined && !equality.Semantic.DeepEqual(entry.Plan.Plan, plan) {
 			outOfSync = append(outOfSync, entry.Machine.Name)
+			if reason := upgrade.holdReason(entry); reason != "" {
+				held = append(held, entry.Machine.Name)
+				holdReason = reason
+				continue
+			}
 			// Conditions
 			// 1. If plan is not in sync then there is no harm in updating it to something else because
 			//    the node will have already been considered unavailable.
@@ -467,16 +528,23 @@ func (p *Planner) reconcile(controlPlane *rkev1.RKEControlPlane, secret plan.Sec
 				if entry.Plan.InSync {
 					unavailable++
 				}
-				if ok, err := p.drain(entry.Machine, clusterPlan, drainOptions); err != nil {
+				if ok, err := p.runPreDrainHooks(controlPlane, entry, plan); err != nil {
+					return err
+				} else if !ok {
+					hooks = append(hooks, entry.Machine.Name)
+				} else if ok, err := p.drain(entry.Machine, clusterPlan, drainOptions); err != nil {
 					return err
 				} else if ok {
 					if err := p.store.UpdatePlan(entry.Machine, plan); err != nil {
 						return err
 					}
+					upgrade.planUpdated(entry.Machine)
 				} else {
 					draining = append(draining, entry.Machine.Name)
 				}
 			}
+		} else if rejoined && !entry.Plan.InSync {
+			hooks = append(hooks, entry.Machine.Name)
 		} else if !entry.Plan.InSync {
 			outOfSync = append(outOfSync, entry.Machine.Name)
 		} else {
@@ -484,6 +552,11 @@ func (p *Planner) reconcile(controlPlane *rkev1.RKEControlPlane, secret plan.Sec
 				return err
 			} else if !ok {
 				uncordoned = append(uncordoned, entry.Machine.Name)
+			} else if hasRejoinHooks && !rejoined {
+				if err := p.store.UpdatePlan(entry.Machine, rejoinPlan); err != nil {
+					return err
+				}
+				hooks = append(hooks, entry.Machine.Name)
 			}
 		}
 	}
@@ -498,6 +571,11 @@ func (p *Planner) reconcile(controlPlane *rkev1.RKEControlPlane, secret plan.Sec
 		return errIgnore("failing " + tierName + " machine(s) " + strings.Join(errMachines, ",") + detailMessage(errMachines, messages))
 	}
 
+	held = atMostThree(held)
+	if len(held) > 0 {
+		return ErrWaiting(holdReason + ", holding " + tierName + " node(s) " + strings.Join(held, ","))
+	}
+
 	outOfSync = atMostThree(outOfSync)
 	if len(outOfSync) > 0 {
 		return ErrWaiting("provisioning " + tierName + " node(s) " + strings.Join(outOfSync, ",") + detailMessage(outOfSync, messages))
@@ -513,6 +591,11 @@ func (p *Planner) reconcile(controlPlane *rkev1.RKEControlPlane, secret plan.Sec
 		return ErrWaiting("uncordoning " + tierName + " node(s) " + strings.Join(uncordoned, ",") + detailMessage(uncordoned, messages))
 	}
 
+	hooks = atMostThree(hooks)
+	if len(hooks) > 0 {
+		return ErrWaiting("running lifecycle hooks on " + tierName + " node(s) " + strings.Join(hooks, ",") + detailMessage(hooks, messages))
+	}
+
 	nonReady = atMostThree(nonReady)
 	if len(nonReady) > 0 {
 		// we want these errors to get reported, but not block the process
@@ -916,6 +999,11 @@ func (p *Planner) desiredPlan(controlPlane *rkev1.RKEControlPlane, secret plan.S
 		return nodePlan, err
 	}
 
+	nodePlan, err = p.addPostInstallHookInstructions(nodePlan, controlPlane, entry.Machine)
+	if err != nil {
+		return nodePlan, err
+	}
+
 	if initNode && IsOnlyEtcd(entry.Machine) {
 		nodePlan, err = p.addInitNodeInstruction(nodePlan, controlPlane, entry.Machine)
 		if err != nil {
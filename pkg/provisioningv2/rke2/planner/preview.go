@@ -0,0 +1,252 @@
+package planner
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/wrangler/pkg/name"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
+)
+
+// installInstruction is how the unnamed instruction running the installer is reported in a preview.
+const installInstruction = "install"
+
+// PlanPreview is the result of running the planner against a control plane without pushing any plan.
+type PlanPreview struct {
+	Tiers []TierPlanPreview `json:"tiers,omitempty"`
+}
+
+// TierPlanPreview lists the nodes of one tier in the order the planner rolls them out. Tiers are rolled out one
+// after another, nodes of a tier in batches of at most Concurrency nodes.
+type TierPlanPreview struct {
+	Name        string            `json:"name,omitempty"`
+	Concurrency int               `json:"concurrency,omitempty"`
+	Nodes       []NodePlanPreview `json:"nodes,omitempty"`
+}
+
+// NodePlanPreview is the difference between the plan a node applied last and the plan it would get.
+type NodePlanPreview struct {
+	Machine             string   `json:"machine,omitempty"`
+	InSync              bool     `json:"inSync"`
+	Restart             bool     `json:"restart"`
+	Batch               int      `json:"batch,omitempty"`
+	FilesAdded          []string `json:"filesAdded,omitempty"`
+	FilesChanged        []string `json:"filesChanged,omitempty"`
+	FilesRemoved        []string `json:"filesRemoved,omitempty"`
+	InstructionsAdded   []string `json:"instructionsAdded,omitempty"`
+	InstructionsChanged []string `json:"instructionsChanged,omitempty"`
+	InstructionsRemoved []string `json:"instructionsRemoved,omitempty"`
+	ProbesChanged       []string `json:"probesChanged,omitempty"`
+}
+
+type previewTier struct {
+	name           string
+	include        roleFilter
+	exclude        roleFilter
+	maxUnavailable string
+	joinServer     string
+}
+
+// Preview computes the plan of every machine of controlPlane, which may carry a spec that isn't saved yet, and
+// compares it to the plan the machine applied last. Nothing is written, so secrets and init node elections that
+// Process would create are not part of the result.
+func (p *Planner) Preview(controlPlane *rkev1.RKEControlPlane) (*PlanPreview, error) {
+	cluster, err := p.getCAPICluster(controlPlane)
+	if err != nil {
+		return nil, err
+	}
+
+	clusterPlan, err := p.store.Load(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := p.loadRKEStateSecret(controlPlane)
+	if err != nil {
+		return nil, err
+	}
+
+	joinServer := initNodeJoinURL(clusterPlan)
+	tiers := []previewTier{
+		{"bootstrap", isInitNode, none, controlPlane.Spec.UpgradeStrategy.ControlPlaneConcurrency, ""},
+		{"etcd", isEtcd, isInitNode, controlPlane.Spec.UpgradeStrategy.ControlPlaneConcurrency, joinServer},
+		{"control plane", isControlPlane, isInitNode, controlPlane.Spec.UpgradeStrategy.ControlPlaneConcurrency, joinServer},
+		{"worker", isOnlyWorker, isInitNode, controlPlane.Spec.UpgradeStrategy.WorkerConcurrency, p.getControlPlaneJoinURL(clusterPlan)},
+	}
+
+	result := &PlanPreview{}
+	for _, tier := range tiers {
+		entries := collect(clusterPlan, tier.include)
+		concurrency, _, err := calculateConcurrency(tier.maxUnavailable, entries, tier.exclude)
+		if err != nil {
+			return nil, err
+		}
+
+		tierPreview := TierPlanPreview{
+			Name:        tier.name,
+			Concurrency: concurrency,
+		}
+		changed := 0
+		for _, entry := range entries {
+			if tier.exclude(entry.Machine) {
+				continue
+			}
+
+			desired, err := p.desiredPlan(controlPlane, secret, entry, isInitNode(entry.Machine), tier.joinServer)
+			if err != nil {
+				return nil, err
+			}
+
+			var applied *plan.NodePlan
+			if entry.Plan != nil {
+				applied = entry.Plan.AppliedPlan
+			}
+			desired = withSnapshotChecksums(applied, withCertificateCheck(controlPlane, withoutSnapshotChecksums(applied), desired))
+
+			// nodes that ran their post-rejoin hooks applied the desired plan followed by the hooks
+			rejoinPlan, ok, err := postRejoinPlan(controlPlane, entry.Machine, desired)
+			if err != nil {
+				return nil, err
+			}
+			if ok && applied != nil && reflect.DeepEqual(*withoutSnapshotChecksums(applied), rejoinPlan) {
+				desired = withSnapshotChecksums(applied, rejoinPlan)
+			}
+			nodePreview := diffNodePlan(entry.Machine, applied, desired)
+			if !nodePreview.InSync {
+				nodePreview.Batch = batch(changed, concurrency)
+				changed++
+			}
+			tierPreview.Nodes = append(tierPreview.Nodes, nodePreview)
+		}
+		result.Tiers = append(result.Tiers, tierPreview)
+	}
+
+	return result, nil
+}
+
+// batch returns the 1-based batch of the n-th changed node. A concurrency of 0 means no limit.
+func batch(n, concurrency int) int {
+	if concurrency <= 0 {
+		return 1
+	}
+	return n/concurrency + 1
+}
+
+// initNodeJoinURL returns the join url of the elected init node without electing one.
+func initNodeJoinURL(clusterPlan *plan.Plan) string {
+	for _, entry := range collect(clusterPlan, isEtcd) {
+		if isInitNode(entry.Machine) && entry.Machine.DeletionTimestamp == nil {
+			return entry.Machine.Annotations[JoinURLAnnotation]
+		}
+	}
+	return ""
+}
+
+// loadRKEStateSecret is the read only counterpart of ensureRKEStateSecret.
+func (p *Planner) loadRKEStateSecret(controlPlane *rkev1.RKEControlPlane) (plan.Secret, error) {
+	if controlPlane.Spec.UnmanagedConfig {
+		return plan.Secret{}, nil
+	}
+
+	secret, err := p.secretCache.Get(controlPlane.Namespace, name.SafeConcatName(controlPlane.Name, "rke", "state"))
+	if apierror.IsNotFound(err) {
+		return plan.Secret{}, nil
+	} else if err != nil {
+		return plan.Secret{}, err
+	}
+
+	return plan.Secret{
+		ServerToken: string(secret.Data["serverToken"]),
+		AgentToken:  string(secret.Data["agentToken"]),
+	}, nil
+}
+
+// diffNodePlan compares the desired plan of machine to the plan it applied last, applied is nil for machines that
+// never applied a plan. Only file paths and instruction names are reported because the content carries tokens.
+func diffNodePlan(machine *capi.Machine, applied *plan.NodePlan, desired plan.NodePlan) NodePlanPreview {
+	result := NodePlanPreview{
+		Machine: machine.Name,
+	}
+	if applied == nil {
+		applied = &plan.NodePlan{}
+	}
+
+	appliedFiles := map[string]plan.File{}
+	for _, file := range applied.Files {
+		appliedFiles[file.Path] = file
+	}
+	desiredFiles := map[string]bool{}
+	for _, file := range desired.Files {
+		desiredFiles[file.Path] = true
+		if old, ok := appliedFiles[file.Path]; !ok {
+			result.FilesAdded = append(result.FilesAdded, file.Path)
+		} else if old != file {
+			result.FilesChanged = append(result.FilesChanged, file.Path)
+		}
+	}
+	for _, file := range applied.Files {
+		if !desiredFiles[file.Path] {
+			result.FilesRemoved = append(result.FilesRemoved, file.Path)
+		}
+	}
+
+	appliedInstructions := instructionsByName(applied.Instructions)
+	desiredInstructions := instructionsByName(desired.Instructions)
+	for name, instruction := range desiredInstructions {
+		if old, ok := appliedInstructions[name]; !ok {
+			result.InstructionsAdded = append(result.InstructionsAdded, name)
+		} else if !reflect.DeepEqual(old, instruction) {
+			result.InstructionsChanged = append(result.InstructionsChanged, name)
+		} else {
+			continue
+		}
+		// the installer restarts the service when its image or restart stamp changes, the other instructions
+		// only collect information
+		if name == installInstruction {
+			result.Restart = true
+		}
+	}
+	for name := range appliedInstructions {
+		if _, ok := desiredInstructions[name]; !ok {
+			result.InstructionsRemoved = append(result.InstructionsRemoved, name)
+		}
+	}
+
+	for name, probe := range desired.Probes {
+		if old, ok := applied.Probes[name]; !ok || old != probe {
+			result.ProbesChanged = append(result.ProbesChanged, name)
+		}
+	}
+	for name := range applied.Probes {
+		if _, ok := desired.Probes[name]; !ok {
+			result.ProbesChanged = append(result.ProbesChanged, name)
+		}
+	}
+
+	for _, list := range [][]string{result.FilesAdded, result.FilesChanged, result.FilesRemoved, result.InstructionsAdded,
+		result.InstructionsChanged, result.InstructionsRemoved, result.ProbesChanged} {
+		sort.Strings(list)
+	}
+	result.InSync = len(result.FilesAdded)+len(result.FilesChanged)+len(result.FilesRemoved)+
+		len(result.InstructionsAdded)+len(result.InstructionsChanged)+len(result.InstructionsRemoved)+
+		len(result.ProbesChanged) == 0 && applied.Error == desired.Error
+	return result
+}
+
+func instructionsByName(instructions []plan.Instruction) map[string]plan.Instruction {
+	result := map[string]plan.Instruction{}
+	for i, instruction := range instructions {
+		name := instruction.Name
+		if name == "" && i == 0 {
+			name = installInstruction
+		} else if name == "" {
+			name = fmt.Sprintf("instruction-%d", i)
+		}
+		result[name] = instruction
+	}
+	return result
+}
@@ -0,0 +1,60 @@
+package planner
+
+import (
+	"testing"
+
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
+)
+
+func TestDiffNodePlan(t *testing.T) {
+	machine := &capi.Machine{ObjectMeta: metav1.ObjectMeta{Name: "m1"}}
+	applied := &plan.NodePlan{
+		Files: []plan.File{
+			{Path: "/etc/rancher/rke2/config.yaml", Content: "a"},
+			{Path: "/var/lib/rancher/rke2/server/manifests/old.yaml", Content: "b", Dynamic: true},
+		},
+		Instructions: []plan.Instruction{
+			{Image: "installer:v1.21.1", Env: []string{"RESTART_STAMP=1"}},
+		},
+	}
+
+	preview := diffNodePlan(machine, applied, *applied)
+	assert.True(t, preview.InSync)
+	assert.False(t, preview.Restart)
+
+	desired := plan.NodePlan{
+		Files: []plan.File{
+			{Path: "/etc/rancher/rke2/config.yaml", Content: "c"},
+			{Path: "/var/lib/rancher/rke2/server/manifests/new.yaml", Content: "d", Dynamic: true},
+		},
+		Instructions: []plan.Instruction{
+			{Image: "installer:v1.21.2", Env: []string{"RESTART_STAMP=2"}},
+			{Name: "capture-address"},
+		},
+	}
+	preview = diffNodePlan(machine, applied, desired)
+	assert.Equal(t, "m1", preview.Machine)
+	assert.False(t, preview.InSync)
+	assert.True(t, preview.Restart)
+	assert.Equal(t, []string{"/var/lib/rancher/rke2/server/manifests/new.yaml"}, preview.FilesAdded)
+	assert.Equal(t, []string{"/etc/rancher/rke2/config.yaml"}, preview.FilesChanged)
+	assert.Equal(t, []string{"/var/lib/rancher/rke2/server/manifests/old.yaml"}, preview.FilesRemoved)
+	assert.Equal(t, []string{"capture-address"}, preview.InstructionsAdded)
+	assert.Equal(t, []string{installInstruction}, preview.InstructionsChanged)
+
+	preview = diffNodePlan(machine, nil, desired)
+	assert.False(t, preview.InSync)
+	assert.True(t, preview.Restart)
+	assert.Len(t, preview.FilesAdded, 2)
+}
+
+func TestBatch(t *testing.T) {
+	assert.Equal(t, 1, batch(5, 0))
+	assert.Equal(t, 1, batch(0, 2))
+	assert.Equal(t, 1, batch(1, 2))
+	assert.Equal(t, 2, batch(2, 2))
+	assert.Equal(t, 3, batch(2, 1))
+}
@@ -64,6 +64,9 @@ func toRegistryConfig(secrets v1.SecretCache, runtime, namespace string, registr
 		}
 
 		if len(config.CABundle) > 0 {
+			if registryConfig.TLS == nil {
+				registryConfig.TLS = &tlsConfig{}
+			}
 			file := toFile(runtime, fmt.Sprintf("tls/registries/%s/ca.crt", registryName), config.CABundle)
 			registryConfig.TLS.CAFile = file.Path
 			files = append(files, file)
@@ -75,7 +78,7 @@ func toRegistryConfig(secrets v1.SecretCache, runtime, namespace string, registr
 				return nil, nil, err
 			}
 			if secret.Type != rkev1.AuthConfigSecretType {
-				return nil, nil, fmt.Errorf("secret [%s] must be of type [%s]", config.TLSSecretName, rkev1.AuthConfigSecretType)
+				return nil, nil, fmt.Errorf("secret [%s] must be of type [%s]", config.AuthConfigSecretName, rkev1.AuthConfigSecretType)
 			}
 			registryConfig.Auth = &authConfig{
 				Username:      string(secret.Data[rkev1.UsernameAuthConfigSecretKey]),
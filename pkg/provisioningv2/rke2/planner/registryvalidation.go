@@ -0,0 +1,326 @@
+package planner
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/registryauth"
+	v1 "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const registryPingTimeout = 10 * time.Second
+
+// RegistryValidation is the result of checking the registries config of a cluster from the rancher server. Nodes may
+// reach registries rancher can't, a failed ping is worth a look but doesn't mean the nodes will fail too.
+type RegistryValidation struct {
+	Valid   bool                       `json:"valid"`
+	Results []RegistryValidationResult `json:"results,omitempty"`
+}
+
+type RegistryValidationResult struct {
+	Registry string `json:"registry,omitempty"`
+	Endpoint string `json:"endpoint,omitempty"`
+	// Check is one of tlsSecret, caBundle, authSecret, endpoint, ping or auth
+	Check   string `json:"check,omitempty"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+}
+
+type resolvedRegistry struct {
+	tls  *tls.Config
+	auth *rkev1.AuthConfig
+}
+
+func (r *RegistryValidation) add(result RegistryValidationResult) {
+	if !result.Passed {
+		r.Valid = false
+	}
+	r.Results = append(r.Results, result)
+}
+
+// ValidateRegistries resolves the secrets referenced by registry, checks the certificates parse and pings the v2
+// API of every endpoint, answering the authentication challenge with the configured credentials.
+func ValidateRegistries(ctx context.Context, secrets v1.SecretCache, namespace string, registry *rkev1.Registry) *RegistryValidation {
+	validation := &RegistryValidation{
+		Valid: true,
+	}
+	if registry == nil {
+		return validation
+	}
+
+	var configNames, mirrorNames []string
+	for name := range registry.Configs {
+		configNames = append(configNames, name)
+	}
+	for name := range registry.Mirrors {
+		mirrorNames = append(mirrorNames, name)
+	}
+	sort.Strings(configNames)
+	sort.Strings(mirrorNames)
+
+	resolved := map[string]*resolvedRegistry{}
+	for _, name := range configNames {
+		resolved[name] = resolveRegistry(validation, secrets, namespace, name, registry.Configs[name])
+	}
+
+	pinged := map[string]bool{}
+	for _, name := range mirrorNames {
+		for _, endpoint := range registry.Mirrors[name].Endpoints {
+			if u, err := url.Parse(endpoint); err == nil {
+				pinged[u.Host] = true
+			}
+			checkEndpoint(ctx, validation, name, endpoint, resolved)
+		}
+	}
+
+	// registries that aren't the endpoint of a mirror are pulled from directly
+	for _, name := range configNames {
+		if !pinged[name] {
+			checkEndpoint(ctx, validation, name, "https://"+name, resolved)
+		}
+	}
+
+	return validation
+}
+
+func resolveRegistry(validation *RegistryValidation, secrets v1.SecretCache, namespace, name string, config rkev1.RegistryConfig) *resolvedRegistry {
+	result := &resolvedRegistry{
+		tls: &tls.Config{
+			InsecureSkipVerify: config.InsecureSkipVerify,
+		},
+	}
+	fail := func(check, format string, args ...interface{}) {
+		validation.add(RegistryValidationResult{
+			Registry: name,
+			Check:    check,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+	pass := func(check, message string) {
+		validation.add(RegistryValidationResult{
+			Registry: name,
+			Check:    check,
+			Passed:   true,
+			Message:  message,
+		})
+	}
+
+	if config.TLSSecretName != "" {
+		secret, err := secrets.Get(namespace, config.TLSSecretName)
+		if err != nil {
+			fail("tlsSecret", "failed to lookup secret [%s]: %v", config.TLSSecretName, err)
+		} else if secret.Type != corev1.SecretTypeTLS {
+			fail("tlsSecret", "secret [%s] must be of type [%s]", config.TLSSecretName, corev1.SecretTypeTLS)
+		} else if pair, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey]); err != nil {
+			fail("tlsSecret", "secret [%s] has no valid certificate and key pair: %v", config.TLSSecretName, err)
+		} else {
+			result.tls.Certificates = []tls.Certificate{pair}
+			pass("tlsSecret", fmt.Sprintf("client certificate of secret [%s] is valid", config.TLSSecretName))
+		}
+	}
+
+	if len(config.CABundle) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(config.CABundle) {
+			fail("caBundle", "no PEM encoded certificate could be parsed from the CA bundle")
+		} else {
+			result.tls.RootCAs = pool
+			pass("caBundle", "CA bundle is valid")
+		}
+	}
+
+	if config.AuthConfigSecretName != "" {
+		secret, err := secrets.Get(namespace, config.AuthConfigSecretName)
+		if err != nil {
+			fail("authSecret", "failed to lookup secret [%s]: %v", config.AuthConfigSecretName, err)
+			return result
+		}
+		if secret.Type != rkev1.AuthConfigSecretType {
+			fail("authSecret", "secret [%s] must be of type [%s]", config.AuthConfigSecretName, rkev1.AuthConfigSecretType)
+			return result
+		}
+		auth := &rkev1.AuthConfig{
+			Username:      string(secret.Data[rkev1.UsernameAuthConfigSecretKey]),
+			Password:      string(secret.Data[rkev1.PasswordAuthConfigSecretKey]),
+			Auth:          string(secret.Data[rkev1.AuthAuthConfigSecretKey]),
+			IdentityToken: string(secret.Data[rkev1.IdentityTokenAuthConfigSecretKey]),
+		}
+		if auth.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+			parts := strings.SplitN(string(decoded), ":", 2)
+			if err != nil || len(parts) != 2 {
+				fail("authSecret", "key [%s] of secret [%s] must be the base64 encoding of username:password",
+					rkev1.AuthAuthConfigSecretKey, config.AuthConfigSecretName)
+				return result
+			}
+			auth.Username, auth.Password = parts[0], parts[1]
+		}
+		if auth.Username == "" && auth.IdentityToken == "" {
+			fail("authSecret", "secret [%s] has no credentials", config.AuthConfigSecretName)
+			return result
+		}
+		result.auth = auth
+		pass("authSecret", fmt.Sprintf("credentials of secret [%s] resolved", config.AuthConfigSecretName))
+	}
+
+	return result
+}
+
+func checkEndpoint(ctx context.Context, validation *RegistryValidation, name, endpoint string, resolved map[string]*resolvedRegistry) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		validation.add(RegistryValidationResult{
+			Registry: name,
+			Endpoint: endpoint,
+			Check:    "endpoint",
+			Message:  "endpoint must be a URL with an http or https scheme and a host",
+		})
+		return
+	}
+
+	// containerd picks the config of an endpoint by its host
+	registry := resolved[u.Host]
+	if registry == nil {
+		registry = &resolvedRegistry{}
+	}
+
+	client := &http.Client{
+		Timeout: registryPingTimeout,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: registry.tls,
+		},
+	}
+
+	insecure := registry.tls != nil && registry.tls.InsecureSkipVerify
+	for _, result := range pingRegistry(ctx, client, u, registry.auth, insecure) {
+		result.Registry = name
+		result.Endpoint = endpoint
+		validation.add(result)
+	}
+}
+
+// pingRegistry calls the v2 API of the registry at base. If the registry asks for authentication, the challenge is
+// answered with auth, or anonymously if auth is nil. insecure is whether TLS verification is skipped for the registry.
+func pingRegistry(ctx context.Context, client *http.Client, base *url.URL, auth *rkev1.AuthConfig, insecure bool) []RegistryValidationResult {
+	v2 := *base
+	v2.Path = strings.TrimSuffix(v2.Path, "/") + "/v2/"
+
+	resp, err := get(ctx, client, v2.String(), nil)
+	if err != nil {
+		return []RegistryValidationResult{{Check: "ping", Message: err.Error()}}
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return []RegistryValidationResult{{Check: "ping", Passed: true, Message: "registry API is reachable and requires no authentication"}}
+	case http.StatusUnauthorized:
+	default:
+		return []RegistryValidationResult{{Check: "ping", Message: fmt.Sprintf("unexpected status %d from %s, the endpoint is not a v2 registry API", resp.StatusCode, v2.String())}}
+	}
+
+	results := []RegistryValidationResult{{Check: "ping", Passed: true, Message: "registry API is reachable"}}
+	scheme, params := registryauth.ParseChallenge(resp.Header.Get("WWW-Authenticate"))
+	switch scheme {
+	case "basic":
+		if auth == nil || auth.Username == "" {
+			return append(results, RegistryValidationResult{Check: "auth", Message: "registry requires basic authentication and no username and password are configured"})
+		}
+		resp, err := get(ctx, client, v2.String(), func(req *http.Request) {
+			req.SetBasicAuth(auth.Username, auth.Password)
+		})
+		if err != nil {
+			return append(results, RegistryValidationResult{Check: "auth", Message: err.Error()})
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return append(results, RegistryValidationResult{Check: "auth", Message: fmt.Sprintf("credentials were rejected with status %d", resp.StatusCode)})
+		}
+		return append(results, RegistryValidationResult{Check: "auth", Passed: true, Message: "authenticated with basic authentication"})
+	case "bearer":
+		return append(results, requestToken(ctx, client, base, insecure, params, auth))
+	default:
+		return append(results, RegistryValidationResult{Check: "auth", Message: fmt.Sprintf("unsupported authentication challenge [%s]", resp.Header.Get("WWW-Authenticate"))})
+	}
+}
+
+// requestToken asks the realm of a bearer challenge for a token. The realm is chosen by the registry, so the configured
+// credentials are only sent to a realm that passes registryauth.CheckRealm.
+func requestToken(ctx context.Context, client *http.Client, base *url.URL, insecure bool, params map[string]string, auth *rkev1.AuthConfig) RegistryValidationResult {
+	realm, err := registryauth.ParseRealm(params["realm"])
+	if err != nil {
+		return RegistryValidationResult{Check: "auth", Message: err.Error()}
+	}
+	if auth != nil {
+		if err := registryauth.CheckRealm(base, realm, insecure); err != nil {
+			return RegistryValidationResult{Check: "auth", Message: fmt.Sprintf("credentials were not verified, %v", err)}
+		}
+	}
+
+	query := realm.Query()
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+
+	var (
+		resp *http.Response
+	)
+	if auth != nil && auth.Username == "" && auth.IdentityToken != "" {
+		form := url.Values{
+			"grant_type":    []string{"refresh_token"},
+			"refresh_token": []string{auth.IdentityToken},
+			"service":       []string{params["service"]},
+			"client_id":     []string{"rancher"},
+		}
+		req, reqErr := http.NewRequestWithContext(ctx, http.MethodPost, realm.String(), strings.NewReader(form.Encode()))
+		if reqErr != nil {
+			return RegistryValidationResult{Check: "auth", Message: reqErr.Error()}
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp, err = client.Do(req)
+	} else {
+		realm.RawQuery = query.Encode()
+		resp, err = get(ctx, client, realm.String(), func(req *http.Request) {
+			if auth != nil && auth.Username != "" {
+				req.SetBasicAuth(auth.Username, auth.Password)
+			}
+		})
+	}
+	if err != nil {
+		return RegistryValidationResult{Check: "auth", Message: err.Error()}
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return RegistryValidationResult{Check: "auth", Message: fmt.Sprintf("token request to %s failed with status %d", realm.Host, resp.StatusCode)}
+	}
+	if auth == nil {
+		return RegistryValidationResult{Check: "auth", Passed: true, Message: "anonymous access is allowed"}
+	}
+	return RegistryValidationResult{Check: "auth", Passed: true, Message: "authenticated with a bearer token"}
+}
+
+func get(ctx context.Context, client *http.Client, url string, modify func(*http.Request)) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if modify != nil {
+		modify(req)
+	}
+	return client.Do(req)
+}
@@ -0,0 +1,160 @@
+package planner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPingRegistryBasic(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if user, pass, ok := req.BasicAuth(); ok && user == "admin" && pass == "secret" {
+			return
+		}
+		rw.Header().Set("WWW-Authenticate", `Basic realm="Registry"`)
+		rw.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	results := pingRegistry(context.Background(), server.Client(), u, &rkev1.AuthConfig{Username: "admin", Password: "secret"}, false)
+	require.Len(t, results, 2)
+	assert.True(t, results[0].Passed)
+	assert.True(t, results[1].Passed)
+
+	results = pingRegistry(context.Background(), server.Client(), u, &rkev1.AuthConfig{Username: "admin", Password: "wrong"}, false)
+	require.Len(t, results, 2)
+	assert.False(t, results[1].Passed)
+
+	results = pingRegistry(context.Background(), server.Client(), u, nil, false)
+	require.Len(t, results, 2)
+	assert.False(t, results[1].Passed)
+}
+
+func TestPingRegistryBearer(t *testing.T) {
+	var tokenURL string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/token":
+			user, pass, ok := req.BasicAuth()
+			if req.URL.Query().Get("service") != "test" || (ok && (user != "admin" || pass != "secret")) {
+				rw.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(rw, `{"token":"abc"}`)
+		case "/mirror/v2/":
+			rw.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s",service="test"`, tokenURL))
+			rw.WriteHeader(http.StatusUnauthorized)
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	tokenURL = server.URL + "/token"
+
+	u, err := url.Parse(server.URL + "/mirror")
+	require.NoError(t, err)
+
+	results := pingRegistry(context.Background(), server.Client(), u, &rkev1.AuthConfig{Username: "admin", Password: "secret"}, false)
+	require.Len(t, results, 2)
+	assert.True(t, results[1].Passed)
+	assert.Equal(t, "authenticated with a bearer token", results[1].Message)
+
+	results = pingRegistry(context.Background(), server.Client(), u, nil, false)
+	require.Len(t, results, 2)
+	assert.Equal(t, "anonymous access is allowed", results[1].Message)
+
+	results = pingRegistry(context.Background(), server.Client(), u, &rkev1.AuthConfig{Username: "admin", Password: "wrong"}, false)
+	require.Len(t, results, 2)
+	assert.False(t, results[1].Passed)
+
+	u, err = url.Parse(server.URL + "/other")
+	require.NoError(t, err)
+	results = pingRegistry(context.Background(), server.Client(), u, nil, false)
+	require.Len(t, results, 1)
+	assert.False(t, results[0].Passed)
+}
+
+func TestPingRegistryForeignRealm(t *testing.T) {
+	var realmRequests int
+	realm := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		realmRequests++
+		if _, _, ok := req.BasicAuth(); ok {
+			t.Error("credentials were sent to a realm on another host")
+		}
+		fmt.Fprint(rw, `{"token":"abc"}`)
+	}))
+	defer realm.Close()
+	registry := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test"`, realm.URL))
+		rw.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer registry.Close()
+	u, err := url.Parse(registry.URL)
+	require.NoError(t, err)
+
+	results := pingRegistry(context.Background(), registry.Client(), u, &rkev1.AuthConfig{Username: "admin", Password: "secret"}, false)
+	require.Len(t, results, 2)
+	assert.False(t, results[1].Passed)
+	assert.Equal(t, 0, realmRequests)
+
+	results = pingRegistry(context.Background(), registry.Client(), u, &rkev1.AuthConfig{IdentityToken: "token"}, false)
+	require.Len(t, results, 2)
+	assert.False(t, results[1].Passed)
+	assert.Equal(t, 0, realmRequests)
+
+	results = pingRegistry(context.Background(), registry.Client(), u, nil, false)
+	require.Len(t, results, 2)
+	assert.True(t, results[1].Passed)
+	assert.Equal(t, 1, realmRequests)
+}
+
+func TestValidateRegistries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	validation := ValidateRegistries(context.Background(), nil, "fleet-default", &rkev1.Registry{
+		Mirrors: map[string]rkev1.Mirror{
+			"docker.io": {
+				Endpoints: []string{server.URL, "registry.example.com"},
+			},
+		},
+		Configs: map[string]rkev1.RegistryConfig{
+			host: {
+				CABundle: []byte("not a certificate"),
+			},
+		},
+	})
+
+	assert.False(t, validation.Valid)
+	assert.Equal(t, []RegistryValidationResult{
+		{
+			Registry: host,
+			Check:    "caBundle",
+			Message:  "no PEM encoded certificate could be parsed from the CA bundle",
+		},
+		{
+			Registry: "docker.io",
+			Endpoint: server.URL,
+			Check:    "ping",
+			Passed:   true,
+			Message:  "registry API is reachable and requires no authentication",
+		},
+		{
+			Registry: "docker.io",
+			Endpoint: "registry.example.com",
+			Check:    "endpoint",
+			Message:  "endpoint must be a URL with an http or https scheme and a host",
+		},
+	}, validation.Results)
+}
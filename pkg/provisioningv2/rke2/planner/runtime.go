@@ -20,6 +20,10 @@ func GetRuntimeServerUnit(kubernetesVersion string) string {
 	return RuntimeRKE2 + "-server"
 }
 
+func GetRuntimeAgentUnit(kubernetesVersion string) string {
+	return GetRuntime(kubernetesVersion) + "-agent"
+}
+
 func GetRuntimeEnv(kubernetesVersion string) string {
 	return strings.ToUpper(GetRuntime(kubernetesVersion))
 }
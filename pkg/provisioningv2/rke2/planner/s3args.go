@@ -21,7 +21,7 @@ func (s *s3Args) ToArgs(s3 *rkev1.ETCDSnapshotS3, controlPlane *rkev1.RKEControl
 	}
 
 	var (
-		s3Cred s3Credential
+		s3Cred S3Credential
 	)
 
 	args = append(args,
@@ -33,7 +33,7 @@ func (s *s3Args) ToArgs(s3 *rkev1.ETCDSnapshotS3, controlPlane *rkev1.RKEControl
 		credName = controlPlane.Spec.ETCD.S3.CloudCredentialName
 	}
 
-	s3Cred, err = getS3Credential(s.secretCache, controlPlane.Namespace, credName, s3.Region)
+	s3Cred, err = GetS3Credential(s.secretCache, controlPlane.Namespace, credName, s3.Region)
 	if err != nil {
 		return
 	}
@@ -72,13 +72,14 @@ func (s *s3Args) ToArgs(s3 *rkev1.ETCDSnapshotS3, controlPlane *rkev1.RKEControl
 	return
 }
 
-type s3Credential struct {
+type S3Credential struct {
 	AccessKey string
 	SecretKey string
 	Region    string
 }
 
-func getS3Credential(secretCache corecontrollers.SecretCache, namespace, name, region string) (result s3Credential, _ error) {
+// GetS3Credential reads the cloud credential name in namespace. region overrides the default region of the credential.
+func GetS3Credential(secretCache corecontrollers.SecretCache, namespace, name, region string) (result S3Credential, _ error) {
 	if name == "" {
 		result.Region = region
 		return result, nil
@@ -95,7 +96,7 @@ func getS3Credential(secretCache corecontrollers.SecretCache, namespace, name, r
 	if region == "" {
 		region = string(secret.Data["defaultRegion"])
 	}
-	return s3Credential{
+	return S3Credential{
 		AccessKey: string(secret.Data["accessKey"]),
 		SecretKey: string(secret.Data["secretKey"]),
 		Region:    region,
@@ -0,0 +1,198 @@
+package planner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	provisioningcontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
+	rkecontroller "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/wrangler"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// The runtime takes the scheduled snapshots of a cluster on its own, so once a cluster is rolled out the planner reads
+// the checksums of the local snapshots of an etcd node as soon as the node lists a snapshot without one, and again
+// every snapshot verify interval. The checksums are read by adding the snapshot checksums instruction to the plan of
+// the node, running the plan again doesn't restart the runtime as long as its configuration is unchanged.
+
+type snapshotChecksums struct {
+	controlPlanes rkecontroller.RKEControlPlaneController
+	clusterCache  provisioningcontrollers.ClusterCache
+	store         *PlanStore
+}
+
+func newSnapshotChecksums(clients *wrangler.Context, store *PlanStore) *snapshotChecksums {
+	return &snapshotChecksums{
+		controlPlanes: clients.RKE.RKEControlPlane(),
+		clusterCache:  clients.Provisioning.Cluster().Cache(),
+		store:         store,
+	}
+}
+
+// SnapshotVerifyInterval is how often the snapshots of a cluster are verified.
+func SnapshotVerifyInterval() time.Duration {
+	interval := settings.ETCDSnapshotVerifyInterval.GetInt()
+	if interval <= 0 {
+		interval = 86400
+	}
+	return time.Duration(interval) * time.Second
+}
+
+// snapshotChecksumsInstructionFor returns the instruction saving the sha256 of every local snapshot of a node, env
+// only changes the instruction so that the node runs it again.
+func snapshotChecksumsInstructionFor(controlPlane *rkev1.RKEControlPlane, env ...string) plan.Instruction {
+	return plan.Instruction{
+		Name:       snapshotChecksumsInstruction,
+		Image:      getInstallerImage(controlPlane),
+		Command:    "sh",
+		Env:        env,
+		SaveOutput: true,
+		Args: []string{
+			"-c",
+			fmt.Sprintf("sha256sum /var/lib/rancher/%s/server/db/snapshots/* 2>/dev/null || true",
+				GetRuntime(controlPlane.Spec.KubernetesVersion)),
+		},
+	}
+}
+
+// withoutSnapshotChecksums returns the plan without the snapshot checksums instruction the planner adds to the plan of
+// etcd nodes.
+func withoutSnapshotChecksums(nodePlan *plan.NodePlan) *plan.NodePlan {
+	if nodePlan == nil || lastSnapshotChecksums(*nodePlan) == nil {
+		return nodePlan
+	}
+	result := *nodePlan
+	result.Instructions = nodePlan.Instructions[:len(nodePlan.Instructions)-1]
+	return &result
+}
+
+// withSnapshotChecksums keeps the snapshot checksums instruction added to the current plan of a node that is
+// otherwise in sync with the desired plan, reading the checksums must not cause the node to be planned again.
+func withSnapshotChecksums(current *plan.NodePlan, desired plan.NodePlan) plan.NodePlan {
+	base := withoutSnapshotChecksums(current)
+	if base != current && equality.Semantic.DeepEqual(*base, desired) {
+		return *current
+	}
+	return desired
+}
+
+func lastSnapshotChecksums(nodePlan plan.NodePlan) *plan.Instruction {
+	if len(nodePlan.Instructions) == 0 {
+		return nil
+	}
+	last := nodePlan.Instructions[len(nodePlan.Instructions)-1]
+	if last.Name != snapshotChecksumsInstruction {
+		return nil
+	}
+	return &last
+}
+
+// uncheckedSnapshots returns the sorted names of the local snapshots of nodeName that have no checksum.
+func uncheckedSnapshots(snapshots []rkev1.ETCDSnapshot, checksums []rkev1.ETCDSnapshotChecksum, nodeName string) (local int, unchecked []string) {
+	checked := map[string]bool{}
+	for _, checksum := range checksums {
+		if checksum.NodeName == nodeName {
+			checked[checksum.Name] = true
+		}
+	}
+	for _, snapshot := range snapshots {
+		if snapshot.S3 != nil || snapshot.NodeName != nodeName {
+			continue
+		}
+		local++
+		if !checked[snapshot.Name] {
+			unchecked = append(unchecked, snapshot.Name)
+		}
+	}
+	sort.Strings(unchecked)
+	return local, unchecked
+}
+
+func hasEnv(instruction plan.Instruction, env string) bool {
+	for _, e := range instruction.Env {
+		if e == env {
+			return true
+		}
+	}
+	return false
+}
+
+func nodeChecksums(checksums []rkev1.ETCDSnapshotChecksum, nodeName string) (result []rkev1.ETCDSnapshotChecksum) {
+	for _, checksum := range checksums {
+		if checksum.NodeName == nodeName {
+			result = append(result, checksum)
+		}
+	}
+	return result
+}
+
+// Read saves the checksums the etcd nodes read since the last call and asks the nodes with unchecked snapshots, or
+// whose checksums are older than the verify interval, to read them again.
+func (s *snapshotChecksums) Read(controlPlane *rkev1.RKEControlPlane, clusterPlan *plan.Plan) error {
+	cluster, err := s.clusterCache.Get(controlPlane.Namespace, controlPlane.Spec.ClusterName)
+	if apierror.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var (
+		interval  = SnapshotVerifyInterval()
+		now       = time.Now()
+		period    = now.Truncate(interval)
+		checksums = controlPlane.Status.ETCDSnapshotChecksums
+		changed   bool
+	)
+
+	for _, entry := range collect(clusterPlan, isEtcd) {
+		if entry.Machine.Status.NodeRef == nil || entry.Plan == nil || entry.Plan.AppliedPlan == nil || !entry.Plan.InSync {
+			continue
+		}
+		nodeName := entry.Machine.Status.NodeRef.Name
+
+		last := lastSnapshotChecksums(entry.Plan.Plan)
+		if last != nil {
+			read := parseSnapshotChecksums(nodeName, entry.Plan.Output[snapshotChecksumsInstruction])
+			if !equality.Semantic.DeepEqual(read, nodeChecksums(checksums, nodeName)) {
+				checksums = mergeSnapshotChecksums(checksums, nodeName, read)
+				changed = true
+			}
+		}
+
+		local, unchecked := uncheckedSnapshots(cluster.Status.ETCDSnapshots, checksums, nodeName)
+		if local == 0 {
+			continue
+		}
+		snapshotsEnv := "SNAPSHOTS=" + strings.Join(unchecked, ",")
+		periodEnv := "VERIFY_PERIOD=" + period.UTC().Format(time.RFC3339)
+		// a node that already read its snapshots in this period is not asked again for the ones it doesn't have
+		if last != nil && hasEnv(*last, periodEnv) && (len(unchecked) == 0 || hasEnv(*last, snapshotsEnv)) {
+			continue
+		}
+
+		nodePlan := *withoutSnapshotChecksums(&entry.Plan.Plan)
+		nodePlan.Instructions = append(append([]plan.Instruction{}, nodePlan.Instructions...),
+			snapshotChecksumsInstructionFor(controlPlane, snapshotsEnv, periodEnv))
+		if err := s.store.UpdatePlan(entry.Machine, nodePlan); err != nil {
+			return err
+		}
+	}
+
+	s.controlPlanes.EnqueueAfter(controlPlane.Namespace, controlPlane.Name, period.Add(interval).Sub(now))
+
+	if !changed {
+		return nil
+	}
+	controlPlane = controlPlane.DeepCopy()
+	controlPlane.Status.ETCDSnapshotChecksums = checksums
+	if _, err := s.controlPlanes.UpdateStatus(controlPlane); err != nil {
+		return err
+	}
+	return ErrWaiting("refreshing etcd snapshot checksums")
+}
@@ -0,0 +1,57 @@
+package planner
+
+import (
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithSnapshotChecksums(t *testing.T) {
+	controlPlane := &rkev1.RKEControlPlane{
+		Spec: rkev1.RKEControlPlaneSpec{
+			KubernetesVersion: "v1.21.4+rke2r2",
+		},
+	}
+	desired := plan.NodePlan{
+		Instructions: []plan.Instruction{{Name: "install"}},
+	}
+	checksummed := desired
+	checksummed.Instructions = append([]plan.Instruction{{Name: "install"}},
+		snapshotChecksumsInstructionFor(controlPlane, "SNAPSHOTS=snap-1"))
+
+	assert.Equal(t, desired, withSnapshotChecksums(nil, desired))
+	assert.Equal(t, desired, withSnapshotChecksums(&desired, desired))
+	assert.Equal(t, &desired, withoutSnapshotChecksums(&checksummed))
+
+	// reading the checksums doesn't take a node out of sync
+	assert.Equal(t, checksummed, withSnapshotChecksums(&checksummed, desired))
+
+	changed := plan.NodePlan{
+		Instructions: []plan.Instruction{{Name: "upgrade"}},
+	}
+	assert.Equal(t, changed, withSnapshotChecksums(&checksummed, changed))
+}
+
+func TestUncheckedSnapshots(t *testing.T) {
+	snapshots := []rkev1.ETCDSnapshot{
+		{Name: "snap-3", NodeName: "node1"},
+		{Name: "snap-1", NodeName: "node1"},
+		{Name: "snap-2", NodeName: "node1"},
+		{Name: "snap-1", NodeName: "node2"},
+		{Name: "snap-1-s3", NodeName: "node1", S3: &rkev1.ETCDSnapshotS3{}},
+	}
+	checksums := []rkev1.ETCDSnapshotChecksum{
+		{Name: "snap-1", NodeName: "node1", Checksum: "aaa"},
+		{Name: "snap-2", NodeName: "node2", Checksum: "bbb"},
+	}
+
+	local, unchecked := uncheckedSnapshots(snapshots, checksums, "node1")
+	assert.Equal(t, 3, local)
+	assert.Equal(t, []string{"snap-2", "snap-3"}, unchecked)
+
+	local, unchecked = uncheckedSnapshots(snapshots, checksums, "node3")
+	assert.Equal(t, 0, local)
+	assert.Empty(t, unchecked)
+}
@@ -251,3 +251,48 @@ func assignAndCheckPlan(store *PlanStore, msg string, server planEntry, newPlan
 	}
 	return nil
 }
+
+// rollOutPlan assigns nodePlan to the machines matching include that already applied a plan, at most maxUnavailable
+// at a time. Machines that haven't applied a plan yet are still being provisioned and are skipped. It returns
+// ErrWaiting with msg until every machine applied nodePlan.
+func rollOutPlan(store *PlanStore, clusterPlan *plan.Plan, include roleFilter, nodePlan plan.NodePlan, maxUnavailable string, msg string) error {
+	var (
+		entries    []planEntry
+		waiting    []planEntry
+		inProgress []string
+	)
+
+	for _, entry := range collect(clusterPlan, include) {
+		if entry.Plan != nil && entry.Plan.AppliedPlan != nil {
+			entries = append(entries, entry)
+		}
+	}
+
+	concurrency, _, err := calculateConcurrency(maxUnavailable, entries, none)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !equality.Semantic.DeepEqual(entry.Plan.Plan, nodePlan) {
+			waiting = append(waiting, entry)
+		} else if !entry.Plan.InSync {
+			inProgress = append(inProgress, entry.Machine.Name)
+		}
+	}
+
+	for _, entry := range waiting {
+		if concurrency != 0 && len(inProgress) >= concurrency {
+			break
+		}
+		if err := store.UpdatePlan(entry.Machine, nodePlan); err != nil {
+			return err
+		}
+		inProgress = append(inProgress, entry.Machine.Name)
+	}
+
+	if len(inProgress) > 0 {
+		return ErrWaiting(msg + " node(s) " + strings.Join(atMostThree(inProgress), ","))
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+package planner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/robfig/cron"
+	"k8s.io/apimachinery/pkg/api/equality"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
+)
+
+// upgrade decides during a single pass of Process which machines that already applied a plan may receive a new
+// one. Machines that never applied a plan are being provisioned and are never held.
+type upgrade struct {
+	strategy rkev1.ClusterUpgradeStrategy
+	status   rkev1.UpgradeStatus
+	now      time.Time
+
+	// hold is the reason no machine may receive a new plan, empty if they may
+	hold    string
+	phase   rkev1.UpgradePhase
+	requeue time.Duration
+
+	// canary is set while only the canary workers may receive a new plan
+	canary       bool
+	canaries     map[string]bool
+	canaryInSync map[string]bool
+
+	pending             int
+	otherWorkersPending int
+	workersSeen         bool
+}
+
+func newUpgrade(controlPlane *rkev1.RKEControlPlane, clusterPlan *plan.Plan, now time.Time) (*upgrade, error) {
+	u := &upgrade{
+		strategy:     controlPlane.Spec.UpgradeStrategy,
+		now:          now,
+		canaries:     map[string]bool{},
+		canaryInSync: map[string]bool{},
+	}
+	if controlPlane.Status.Upgrade != nil {
+		controlPlane.Status.Upgrade.DeepCopyInto(&u.status)
+	}
+	u.status.NextWindow = ""
+
+	var canaries []string
+	for _, name := range u.status.CanaryMachines {
+		if _, ok := clusterPlan.Machines[name]; ok {
+			u.canaries[name] = true
+			canaries = append(canaries, name)
+		}
+	}
+	u.status.CanaryMachines = canaries
+
+	if u.strategy.Paused {
+		u.setHold(rkev1.UpgradePhasePaused, "upgrade is paused")
+		return u, nil
+	}
+
+	if u.status.Phase == rkev1.UpgradePhaseHalted {
+		u.setHold(rkev1.UpgradePhaseHalted, u.status.Message)
+		return u, nil
+	}
+
+	if u.active() && u.strategy.MaxUnhealthyMachines > 0 {
+		if unhealthy := unhealthyMachines(clusterPlan); len(unhealthy) > u.strategy.MaxUnhealthyMachines {
+			u.setHold(rkev1.UpgradePhaseHalted, fmt.Sprintf("upgrade halted, %d machine(s) have failing probes: %s, pause and resume the upgrade to continue",
+				len(unhealthy), strings.Join(atMostThree(unhealthy), ",")))
+			return u, nil
+		}
+	}
+
+	open, next, err := maintenanceWindowOpen(u.strategy.MaintenanceWindows, now)
+	if err != nil {
+		return nil, err
+	}
+	if !open {
+		u.status.NextWindow = next.UTC().Format(time.RFC3339)
+		u.requeue = next.Sub(now)
+		u.setHold(rkev1.UpgradePhaseWaitingForWindow, "waiting for maintenance window opening at "+u.status.NextWindow)
+		return u, nil
+	}
+
+	u.canary = u.strategy.Canary != nil && u.strategy.Canary.Workers > 0 && !u.status.CanaryComplete
+	if u.canary {
+		u.phase = rkev1.UpgradePhaseCanary
+	} else {
+		u.phase = rkev1.UpgradePhaseRolling
+	}
+	return u, nil
+}
+
+func (u *upgrade) setHold(phase rkev1.UpgradePhase, message string) {
+	u.hold = message
+	u.phase = phase
+}
+
+func (u *upgrade) active() bool {
+	return u.status.Phase != "" && u.status.Phase != rkev1.UpgradePhaseComplete
+}
+
+// observe records the state of a machine compared to its desired plan, it is called for every machine reconciled.
+func (u *upgrade) observe(entry planEntry, desired plan.NodePlan) {
+	if entry.Plan == nil || entry.Plan.AppliedPlan == nil {
+		return
+	}
+
+	changed := !equality.Semantic.DeepEqual(entry.Plan.Plan, desired)
+	if changed || !equality.Semantic.DeepEqual(entry.Plan.Plan, *entry.Plan.AppliedPlan) {
+		u.pending++
+	}
+
+	if !isOnlyWorker(entry.Machine) {
+		return
+	}
+	u.workersSeen = true
+	if u.canaries[entry.Machine.Name] {
+		u.canaryInSync[entry.Machine.Name] = !changed && entry.Plan.InSync
+	} else if changed {
+		u.otherWorkersPending++
+	}
+}
+
+// holdReason returns why the machine may not receive its new plan, or an empty string if it may.
+func (u *upgrade) holdReason(entry planEntry) string {
+	if entry.Plan == nil || entry.Plan.AppliedPlan == nil {
+		return ""
+	}
+	if u.hold != "" {
+		return u.hold
+	}
+	if u.canary && isOnlyWorker(entry.Machine) && !u.canaries[entry.Machine.Name] &&
+		len(u.canaries) >= u.strategy.Canary.Workers {
+		return "waiting for canary workers " + strings.Join(u.status.CanaryMachines, ",")
+	}
+	return ""
+}
+
+// planUpdated records that the machine received its new plan, the first workers to receive one become the canaries.
+func (u *upgrade) planUpdated(machine *capi.Machine) {
+	if !u.canary || !isOnlyWorker(machine) || u.canaries[machine.Name] {
+		return
+	}
+	u.canaries[machine.Name] = true
+	u.canaryInSync[machine.Name] = false
+	u.otherWorkersPending--
+	u.status.CanaryMachines = append(u.status.CanaryMachines, machine.Name)
+	sort.Strings(u.status.CanaryMachines)
+}
+
+// result returns the upgrade status after the pass, complete is true if Process found every machine in sync.
+func (u *upgrade) result(original *rkev1.UpgradeStatus, complete bool) *rkev1.UpgradeStatus {
+	if u.pending == 0 {
+		if !complete || original == nil || original.Phase == rkev1.UpgradePhaseComplete {
+			return original
+		}
+		return &rkev1.UpgradeStatus{
+			Rollout: u.status.Rollout,
+			Phase:   rkev1.UpgradePhaseComplete,
+		}
+	}
+
+	status := u.status.DeepCopy()
+	if !u.active() {
+		status.Rollout++
+	}
+	status.PendingMachines = u.pending
+	status.Phase = u.phase
+	status.Message = u.hold
+	if u.hold == "" && u.canary {
+		u.canaryResult(status)
+	}
+	return status
+}
+
+func (u *upgrade) canaryResult(status *rkev1.UpgradeStatus) {
+	canary := u.strategy.Canary
+	switch {
+	case !u.workersSeen || len(u.canaries) == 0:
+		status.Message = "waiting to upgrade canary workers"
+		status.CanaryHealthySince = ""
+		return
+	case len(u.canaries) < canary.Workers && u.otherWorkersPending > 0:
+		status.Message = "upgrading canary workers"
+		status.CanaryHealthySince = ""
+		return
+	}
+
+	for name := range u.canaries {
+		if !u.canaryInSync[name] {
+			status.Message = "waiting for canary workers to be upgraded and healthy"
+			status.CanaryHealthySince = ""
+			return
+		}
+	}
+
+	since, err := time.Parse(time.RFC3339, status.CanaryHealthySince)
+	if err != nil {
+		since = u.now
+		status.CanaryHealthySince = since.UTC().Format(time.RFC3339)
+	}
+	if soakEnd := since.Add(time.Duration(canary.SoakSeconds) * time.Second); soakEnd.After(u.now) {
+		status.Message = "canary workers healthy, soaking until " + soakEnd.UTC().Format(time.RFC3339)
+		u.requeue = soakEnd.Sub(u.now)
+		return
+	}
+
+	if canary.RequireApproval && canary.ApprovedRollout != status.Rollout {
+		status.Phase = rkev1.UpgradePhaseWaitingForApproval
+		status.Message = fmt.Sprintf("canary workers healthy, set approvedRollout to %d to upgrade the other workers", status.Rollout)
+		return
+	}
+
+	status.Phase = rkev1.UpgradePhaseRolling
+	status.Message = ""
+	status.CanaryComplete = true
+	// the other workers are released on the next pass
+	u.requeue = time.Second
+}
+
+func unhealthyMachines(clusterPlan *plan.Plan) (result []string) {
+	for name, node := range clusterPlan.Nodes {
+		if _, ok := clusterPlan.Machines[name]; ok && node != nil && node.AppliedPlan != nil && !node.Healthy {
+			result = append(result, name)
+		}
+	}
+	sort.Strings(result)
+	return
+}
+
+// maintenanceWindowOpen returns whether one of the windows is open at now and, if none is, when the next one opens.
+// No windows means always open.
+func maintenanceWindowOpen(windows []rkev1.MaintenanceWindow, now time.Time) (bool, time.Time, error) {
+	var next time.Time
+	if len(windows) == 0 {
+		return true, next, nil
+	}
+
+	for _, window := range windows {
+		schedule, err := cron.ParseStandard(window.Schedule)
+		if err != nil {
+			return false, next, fmt.Errorf("invalid maintenance window schedule [%s]: %w", window.Schedule, err)
+		}
+		duration, err := time.ParseDuration(window.Duration)
+		if err != nil || duration <= 0 {
+			return false, next, fmt.Errorf("invalid maintenance window duration [%s]", window.Duration)
+		}
+		location := time.UTC
+		if window.TimeZone != "" {
+			location, err = time.LoadLocation(window.TimeZone)
+			if err != nil {
+				return false, next, fmt.Errorf("invalid maintenance window time zone [%s]: %w", window.TimeZone, err)
+			}
+		}
+
+		localNow := now.In(location)
+		// the first start after now - duration is the latest window that could still be open
+		if start := schedule.Next(localNow.Add(-duration)); !start.After(localNow) {
+			return true, time.Time{}, nil
+		}
+		if start := schedule.Next(localNow); next.IsZero() || start.Before(next) {
+			next = start
+		}
+	}
+
+	return false, next, nil
+}
+
+func (p *Planner) setUpgradeStatus(controlPlane *rkev1.RKEControlPlane, upgrade *upgrade, complete bool) error {
+	status := upgrade.result(controlPlane.Status.Upgrade, complete)
+	if upgrade.requeue > 0 {
+		p.rkeControlPlanes.EnqueueAfter(controlPlane.Namespace, controlPlane.Name, upgrade.requeue)
+	}
+	if equality.Semantic.DeepEqual(status, controlPlane.Status.Upgrade) {
+		return nil
+	}
+
+	controlPlane = controlPlane.DeepCopy()
+	controlPlane.Status.Upgrade = status
+	_, err := p.rkeControlPlanes.UpdateStatus(controlPlane)
+	return err
+}
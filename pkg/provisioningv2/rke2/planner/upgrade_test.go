@@ -0,0 +1,163 @@
+package planner
+
+import (
+	"testing"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha4"
+)
+
+func TestMaintenanceWindowOpen(t *testing.T) {
+	windows := []rkev1.MaintenanceWindow{
+		{Schedule: "0 2 * * *", Duration: "2h"},
+	}
+
+	open, _, err := maintenanceWindowOpen(nil, time.Now())
+	require.NoError(t, err)
+	assert.True(t, open)
+
+	open, _, err = maintenanceWindowOpen(windows, time.Date(2021, 7, 1, 3, 30, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.True(t, open)
+
+	open, next, err := maintenanceWindowOpen(windows, time.Date(2021, 7, 1, 4, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.False(t, open)
+	assert.True(t, next.Equal(time.Date(2021, 7, 2, 2, 0, 0, 0, time.UTC)))
+
+	windows[0].TimeZone = "America/New_York"
+	open, _, err = maintenanceWindowOpen(windows, time.Date(2021, 7, 1, 7, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.True(t, open)
+
+	_, _, err = maintenanceWindowOpen([]rkev1.MaintenanceWindow{{Schedule: "0 2 * * *"}}, time.Now())
+	assert.Error(t, err)
+}
+
+func testWorker(name string, applied, current string, healthy bool) (*capi.Machine, *plan.Node) {
+	machine := &capi.Machine{ObjectMeta: metav1.ObjectMeta{
+		Name:   name,
+		Labels: map[string]string{WorkerRoleLabel: "true"},
+	}}
+	node := &plan.Node{
+		Plan:        plan.NodePlan{Files: []plan.File{{Path: current}}},
+		AppliedPlan: &plan.NodePlan{Files: []plan.File{{Path: applied}}},
+		Healthy:     healthy,
+	}
+	node.InSync = healthy && applied == current
+	return machine, node
+}
+
+func testUpgradePass(t *testing.T, controlPlane *rkev1.RKEControlPlane, clusterPlan *plan.Plan, now time.Time) (*upgrade, []string) {
+	u, err := newUpgrade(controlPlane, clusterPlan, now)
+	require.NoError(t, err)
+
+	var updated []string
+	desired := plan.NodePlan{Files: []plan.File{{Path: "new"}}}
+	for _, entry := range collect(clusterPlan, isOnlyWorker) {
+		u.observe(entry, desired)
+		if !equalPlan(entry.Plan.Plan, desired) && u.holdReason(entry) == "" {
+			updated = append(updated, entry.Machine.Name)
+			u.planUpdated(entry.Machine)
+		}
+	}
+	controlPlane.Status.Upgrade = u.result(controlPlane.Status.Upgrade, len(updated) == 0 && u.pending == 0)
+	return u, updated
+}
+
+func equalPlan(a, b plan.NodePlan) bool {
+	return len(a.Files) == len(b.Files) && a.Files[0] == b.Files[0]
+}
+
+func TestCanaryUpgrade(t *testing.T) {
+	now := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+	controlPlane := &rkev1.RKEControlPlane{}
+	controlPlane.Spec.UpgradeStrategy.Canary = &rkev1.CanaryStrategy{
+		Workers:         1,
+		SoakSeconds:     60,
+		RequireApproval: true,
+	}
+	clusterPlan := &plan.Plan{
+		Machines: map[string]*capi.Machine{},
+		Nodes:    map[string]*plan.Node{},
+	}
+	for _, name := range []string{"w1", "w2", "w3"} {
+		clusterPlan.Machines[name], clusterPlan.Nodes[name] = testWorker(name, "old", "old", true)
+	}
+
+	// only the first worker becomes the canary
+	_, updated := testUpgradePass(t, controlPlane, clusterPlan, now)
+	assert.Equal(t, []string{"w1"}, updated)
+	assert.Equal(t, int64(1), controlPlane.Status.Upgrade.Rollout)
+	assert.Equal(t, rkev1.UpgradePhaseCanary, controlPlane.Status.Upgrade.Phase)
+	assert.Equal(t, []string{"w1"}, controlPlane.Status.Upgrade.CanaryMachines)
+
+	// the canary applied its plan and is healthy, the soak starts
+	clusterPlan.Machines["w1"], clusterPlan.Nodes["w1"] = testWorker("w1", "new", "new", true)
+	u, updated := testUpgradePass(t, controlPlane, clusterPlan, now)
+	assert.Empty(t, updated)
+	assert.Equal(t, rkev1.UpgradePhaseCanary, controlPlane.Status.Upgrade.Phase)
+	assert.Equal(t, time.Minute, u.requeue)
+
+	// the soak is over but the rollout is not approved
+	_, updated = testUpgradePass(t, controlPlane, clusterPlan, now.Add(time.Minute))
+	assert.Empty(t, updated)
+	assert.Equal(t, rkev1.UpgradePhaseWaitingForApproval, controlPlane.Status.Upgrade.Phase)
+
+	controlPlane.Spec.UpgradeStrategy.Canary.ApprovedRollout = 1
+	_, updated = testUpgradePass(t, controlPlane, clusterPlan, now.Add(time.Minute))
+	assert.Empty(t, updated)
+	assert.Equal(t, rkev1.UpgradePhaseRolling, controlPlane.Status.Upgrade.Phase)
+	assert.True(t, controlPlane.Status.Upgrade.CanaryComplete)
+
+	_, updated = testUpgradePass(t, controlPlane, clusterPlan, now.Add(time.Minute))
+	assert.Equal(t, []string{"w2", "w3"}, updated)
+	assert.Equal(t, rkev1.UpgradePhaseRolling, controlPlane.Status.Upgrade.Phase)
+
+	for _, name := range []string{"w2", "w3"} {
+		clusterPlan.Machines[name], clusterPlan.Nodes[name] = testWorker(name, "new", "new", true)
+	}
+	_, updated = testUpgradePass(t, controlPlane, clusterPlan, now.Add(time.Minute))
+	assert.Empty(t, updated)
+	assert.Equal(t, &rkev1.UpgradeStatus{Rollout: 1, Phase: rkev1.UpgradePhaseComplete}, controlPlane.Status.Upgrade)
+}
+
+func TestUpgradeHold(t *testing.T) {
+	controlPlane := &rkev1.RKEControlPlane{}
+	clusterPlan := &plan.Plan{
+		Machines: map[string]*capi.Machine{},
+		Nodes:    map[string]*plan.Node{},
+	}
+	clusterPlan.Machines["w1"], clusterPlan.Nodes["w1"] = testWorker("w1", "old", "old", true)
+	clusterPlan.Machines["w2"], clusterPlan.Nodes["w2"] = testWorker("w2", "old", "old", false)
+	clusterPlan.Machines["w3"], clusterPlan.Nodes["w3"] = testWorker("w3", "old", "old", false)
+
+	controlPlane.Spec.UpgradeStrategy.Paused = true
+	_, updated := testUpgradePass(t, controlPlane, clusterPlan, time.Now())
+	assert.Empty(t, updated)
+	assert.Equal(t, rkev1.UpgradePhasePaused, controlPlane.Status.Upgrade.Phase)
+
+	controlPlane.Spec.UpgradeStrategy.Paused = false
+	controlPlane.Spec.UpgradeStrategy.MaxUnhealthyMachines = 1
+	_, updated = testUpgradePass(t, controlPlane, clusterPlan, time.Now())
+	assert.Empty(t, updated)
+	assert.Equal(t, rkev1.UpgradePhaseHalted, controlPlane.Status.Upgrade.Phase)
+
+	// a halt sticks until the upgrade is paused and resumed
+	clusterPlan.Machines["w2"], clusterPlan.Nodes["w2"] = testWorker("w2", "old", "old", true)
+	_, updated = testUpgradePass(t, controlPlane, clusterPlan, time.Now())
+	assert.Empty(t, updated)
+	assert.Equal(t, rkev1.UpgradePhaseHalted, controlPlane.Status.Upgrade.Phase)
+
+	controlPlane.Spec.UpgradeStrategy.Paused = true
+	testUpgradePass(t, controlPlane, clusterPlan, time.Now())
+	controlPlane.Spec.UpgradeStrategy.Paused = false
+	_, updated = testUpgradePass(t, controlPlane, clusterPlan, time.Now())
+	assert.Equal(t, []string{"w1", "w2", "w3"}, updated)
+	assert.Equal(t, rkev1.UpgradePhaseRolling, controlPlane.Status.Upgrade.Phase)
+}
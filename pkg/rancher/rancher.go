@@ -25,6 +25,7 @@
 	dashboarddata "github.com/rancher/rancher/pkg/data/dashboard"
 	"github.com/rancher/rancher/pkg/features"
 	mgmntv3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/metrics"
 	"github.com/rancher/rancher/pkg/multiclustermanager"
 	"github.com/rancher/rancher/pkg/namespace"
 	"github.com/rancher/rancher/pkg/tls"
@@ -49,22 +50,25 @@
 const encryptionConfigUpdate = "provisioner.cattle.io/encrypt-migrated"
 
 type Options struct {
-	ACMEDomains       cli.StringSlice
-	AddLocal          string
-	Embedded          bool
-	BindHost          string
-	HTTPListenPort    int
-	HTTPSListenPort   int
-	K8sMode           string
-	Debug             bool
-	Trace             bool
-	NoCACerts         bool
-	AuditLogPath      string
-	AuditLogMaxage    int
-	AuditLogMaxsize   int
-	AuditLogMaxbackup int
-	AuditLevel        int
-	Features          string
+	ACMEDomains              cli.StringSlice
+	AddLocal                 string
+	Embedded                 bool
+	BindHost                 string
+	HTTPListenPort           int
+	HTTPSListenPort          int
+	K8sMode                  string
+	Debug                    bool
+	Trace                    bool
+	NoCACerts                bool
+	AuditLogPath             string
+	AuditLogMaxage           int
+	AuditLogMaxsize          int
+	AuditLogMaxbackup        int
+	AuditLevel               int
+	AuditPolicyFile          string
+	AuditLogHashChain        bool
+	AuditLogHashChainKeyFile string
+	Features                 string
 }
 
 type Rancher struct {
@@ -171,7 +175,16 @@ func New(ctx context.Context, clientConfg clientcmd.ClientConfig, opts *Options)
 		return nil, err
 	}
 
-	auditLogWriter := audit.NewLogWriter(opts.AuditLogPath, opts.AuditLevel, opts.AuditLogMaxage, opts.AuditLogMaxbackup, opts.AuditLogMaxsize)
+	auditPolicy, err := audit.LoadPolicy(opts.AuditPolicyFile)
+	if err != nil {
+		return nil, err
+	}
+	auditLogWriter := audit.NewLogWriter(opts.AuditLogPath, opts.AuditLevel, opts.AuditLogMaxage, opts.AuditLogMaxbackup, opts.AuditLogMaxsize, auditPolicy)
+	if opts.AuditLogHashChain {
+		if err := auditLogWriter.EnableHashChain(opts.AuditLogHashChainKeyFile); err != nil {
+			return nil, err
+		}
+	}
 	auditFilter, err := audit.NewAuditLogMiddleware(auditLogWriter)
 	if err != nil {
 		return nil, err
@@ -182,6 +195,7 @@ func New(ctx context.Context, clientConfg clientcmd.ClientConfig, opts *Options)
 		Auth: authServer.Authenticator.Chain(
 			auditFilter),
 		Handler: responsewriter.Chain{
+			metrics.APIMiddleware,
 			auth.SetXAPICattleAuthHeader,
 			responsewriter.ContentTypeOptions,
 			websocket.NewWebsocketHandler,
@@ -0,0 +1,45 @@
+// Package registryauth answers the authentication challenges of container registries.
+package registryauth
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var challengeParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// ParseChallenge returns the lower cased scheme and the parameters of a WWW-Authenticate header
+func ParseChallenge(header string) (string, map[string]string) {
+	params := map[string]string{}
+	parts := strings.SplitN(strings.TrimSpace(header), " ", 2)
+	if len(parts) == 2 {
+		for _, match := range challengeParam.FindAllStringSubmatch(parts[1], -1) {
+			params[strings.ToLower(match[1])] = match[2]
+		}
+	}
+	return strings.ToLower(parts[0]), params
+}
+
+// ParseRealm parses the token realm of a bearer challenge, which must be an http or https URL.
+func ParseRealm(realm string) (*url.URL, error) {
+	u, err := url.Parse(realm)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid token realm [%s] in authentication challenge", realm)
+	}
+	return u, nil
+}
+
+// CheckRealm returns an error if the credentials of the registry at registry must not be sent to realm. The realm is
+// named by the registry, so credentials only go to the registry host itself, and only over https unless the registry
+// is reached insecurely anyway.
+func CheckRealm(registry, realm *url.URL, insecure bool) error {
+	if !strings.EqualFold(realm.Host, registry.Host) {
+		return fmt.Errorf("token realm %s is not served by the registry host %s", realm.Host, registry.Host)
+	}
+	if realm.Scheme != "https" && registry.Scheme != "http" && !insecure {
+		return fmt.Errorf("token realm %s of registry %s does not use https", realm.Redacted(), registry.Host)
+	}
+	return nil
+}
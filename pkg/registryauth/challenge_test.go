@@ -0,0 +1,67 @@
+package registryauth
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := ParseChallenge(`Bearer realm="https://auth.example.com/token",service="registry",scope="repository:charts/app:pull"`)
+	assert.Equal(t, "bearer", scheme)
+	assert.Equal(t, map[string]string{
+		"realm":   "https://auth.example.com/token",
+		"service": "registry",
+		"scope":   "repository:charts/app:pull",
+	}, params)
+
+	scheme, params = ParseChallenge(`Basic realm="Registry"`)
+	assert.Equal(t, "basic", scheme)
+	assert.Equal(t, "Registry", params["realm"])
+}
+
+func TestParseRealm(t *testing.T) {
+	_, err := ParseRealm("https://registry.example.com/token")
+	assert.NoError(t, err)
+
+	for _, realm := range []string{"", "/token", "ftp://registry.example.com/token", "registry.example.com/token"} {
+		_, err := ParseRealm(realm)
+		assert.Error(t, err, realm)
+	}
+}
+
+func TestCheckRealm(t *testing.T) {
+	tests := []struct {
+		name     string
+		registry string
+		realm    string
+		insecure bool
+		valid    bool
+	}{
+		{"same host", "https://registry.example.com", "https://registry.example.com/token", false, true},
+		{"other host", "https://registry.example.com", "https://auth.example.com/token", false, false},
+		{"other port", "https://registry.example.com", "https://registry.example.com:8443/token", false, false},
+		{"other host insecure", "https://registry.example.com", "https://auth.example.com/token", true, false},
+		{"http realm", "https://registry.example.com", "http://registry.example.com/token", false, false},
+		{"http realm insecure", "https://registry.example.com", "http://registry.example.com/token", true, true},
+		{"http registry", "http://registry.example.com", "http://registry.example.com/token", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, err := url.Parse(tt.registry)
+			require.NoError(t, err)
+			realm, err := url.Parse(tt.realm)
+			require.NoError(t, err)
+
+			err = CheckRealm(registry, realm, tt.insecure)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
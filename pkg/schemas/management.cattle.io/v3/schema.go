@@ -478,6 +478,9 @@ func authnTypes(schemas *types.Schemas) *types.Schemas {
 					Output: "user",
 				},
 				"refreshauthprovideraccess": {},
+				"unlock": {
+					Output: "user",
+				},
 			}
 			schema.CollectionActions = map[string]types.Action{
 				"changepassword": {
@@ -513,6 +516,24 @@ func authnTypes(schemas *types.Schemas) *types.Schemas {
 		}).
 		MustImport(&Version, v3.GithubConfigTestOutput{}).
 		MustImport(&Version, v3.GithubConfigApplyInput{}).
+		//Gitlab Config
+		MustImportAndCustomize(&Version, v3.GitlabConfig{}, func(schema *types.Schema) {
+			schema.BaseType = "authConfig"
+			schema.ResourceActions = map[string]types.Action{
+				"disable": {},
+				"configureTest": {
+					Input:  "gitlabConfig",
+					Output: "gitlabConfigTestOutput",
+				},
+				"testAndApply": {
+					Input: "gitlabConfigApplyInput",
+				},
+			}
+			schema.CollectionMethods = []string{}
+			schema.ResourceMethods = []string{http.MethodGet, http.MethodPut}
+		}).
+		MustImport(&Version, v3.GitlabConfigTestOutput{}).
+		MustImport(&Version, v3.GitlabConfigApplyInput{}).
 		//AzureAD Config
 		MustImportAndCustomize(&Version, v3.AzureADConfig{}, func(schema *types.Schema) {
 			schema.BaseType = "authConfig"
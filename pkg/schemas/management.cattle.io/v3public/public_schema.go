@@ -38,14 +38,20 @@ func authProvidersTypes(schemas *types.Schemas) *types.Schemas {
 			schema.BaseType = "authProvider"
 			schema.ResourceActions = map[string]types.Action{
 				"login": {
-					Input:  "basicLogin",
+					Input:  "localLogin",
 					Output: "token",
 				},
+				"enrollMfa": {
+					Input:  "localLogin",
+					Output: "mfaEnrollOutput",
+				},
 			}
 			schema.CollectionMethods = []string{}
 			schema.ResourceMethods = []string{http.MethodGet}
 		}).
 		MustImport(&PublicVersion, v3.BasicLogin{}).
+		MustImport(&PublicVersion, v3.LocalLogin{}).
+		MustImport(&PublicVersion, v3.MFAEnrollOutput{}).
 		// Github provider
 		MustImportAndCustomize(&PublicVersion, v3.GithubProvider{}, func(schema *types.Schema) {
 			schema.BaseType = "authProvider"
@@ -59,6 +65,19 @@ func authProvidersTypes(schemas *types.Schemas) *types.Schemas {
 			schema.ResourceMethods = []string{http.MethodGet}
 		}).
 		MustImport(&PublicVersion, v3.GithubLogin{}).
+		// Gitlab provider
+		MustImportAndCustomize(&PublicVersion, v3.GitlabProvider{}, func(schema *types.Schema) {
+			schema.BaseType = "authProvider"
+			schema.ResourceActions = map[string]types.Action{
+				"login": {
+					Input:  "gitlabLogin",
+					Output: "token",
+				},
+			}
+			schema.CollectionMethods = []string{}
+			schema.ResourceMethods = []string{http.MethodGet}
+		}).
+		MustImport(&PublicVersion, v3.GitlabLogin{}).
 		// Google OAuth provider
 		MustImportAndCustomize(&PublicVersion, v3.GoogleOAuthProvider{}, func(schema *types.Schema) {
 			schema.BaseType = "authProvider"
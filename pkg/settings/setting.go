@@ -22,8 +22,22 @@
 	AgentImage                        = NewSetting("agent-image", "rancher/rancher-agent:master-head")
 	AgentRolloutTimeout               = NewSetting("agent-rollout-timeout", "300s")
 	AgentRolloutWait                  = NewSetting("agent-rollout-wait", "true")
+	AuditLogSinks                     = NewSetting("audit-log-sinks", "file") // comma separated list of file, stdout, webhook and syslog
+	AuditLogSinkBufferSize            = NewSetting("audit-log-sink-buffer-size", "10000")
+	AuditLogSyslogAddress             = NewSetting("audit-log-syslog-address", "") // tcp://host:port or tls://host:port
+	AuditLogSyslogCACerts             = NewSetting("audit-log-syslog-cacerts", "")
+	AuditLogWebhookBatchSize          = NewSetting("audit-log-webhook-batch-size", "100")
+	AuditLogWebhookCACerts            = NewSetting("audit-log-webhook-cacerts", "")
+	AuditLogWebhookFlushIntervalMS    = NewSetting("audit-log-webhook-flush-interval-ms", "5000")
+	AuditLogWebhookURL                = NewSetting("audit-log-webhook-url", "")
 	AuthImage                         = NewSetting("auth-image", v32.ToolsSystemImages.AuthSystemImages.KubeAPIAuth)
-	AuthTokenMaxTTLMinutes            = NewSetting("auth-token-max-ttl-minutes", "0") // never expire
+	AuthLocalLockoutDurationMinutes   = NewSetting("auth-local-lockout-duration-minutes", "15")
+	AuthLocalLockoutIPThreshold       = NewSetting("auth-local-lockout-ip-threshold", "20")           // failed logins per source IP, 0 disables
+	AuthLocalLockoutTrustForwardedFor = NewSetting("auth-local-lockout-trust-forwarded-for", "false") // only enable behind a proxy that appends to X-Forwarded-For
+	AuthLocalLockoutTrustedProxies    = NewSetting("auth-local-lockout-trusted-proxies", "")          // comma separated IPs or CIDRs of further proxies in X-Forwarded-For
+	AuthLocalLockoutUserThreshold     = NewSetting("auth-local-lockout-user-threshold", "10")         // failed logins per user, 0 disables
+	AuthLocalMFARequired              = NewSetting("auth-local-mfa-required", "none")                 // none, admins or all local users must use TOTP
+	AuthTokenMaxTTLMinutes            = NewSetting("auth-token-max-ttl-minutes", "0")                 // never expire
 	AuthorizationCacheTTLSeconds      = NewSetting("authorization-cache-ttl-seconds", "10")
 	AuthorizationDenyCacheTTLSeconds  = NewSetting("authorization-deny-cache-ttl-seconds", "10")
 	AzureGroupCacheSize               = NewSetting("azure-group-cache-size", "10000")
@@ -56,10 +70,16 @@
 	KDMBranch                         = NewSetting("kdm-branch", "dev-v2.6")
 	MachineVersion                    = NewSetting("machine-version", "dev")
 	Namespace                         = NewSetting("namespace", os.Getenv("CATTLE_NAMESPACE"))
+	PasswordDenyList                  = NewSetting("password-deny-list", "") // comma separated, in addition to the built-in common passwords
+	PasswordHistoryCount              = NewSetting("password-history-count", "0")
+	PasswordMaxAgeDays                = NewSetting("password-max-age-days", "0")          // never expire
+	PasswordMinCharacterClasses       = NewSetting("password-min-character-classes", "0") // of lowercase, uppercase, digits and symbols
+	PasswordMinLength                 = NewSetting("password-min-length", "1")
 	PeerServices                      = NewSetting("peer-service", os.Getenv("CATTLE_PEER_SERVICE"))
 	RDNSServerBaseURL                 = NewSetting("rdns-base-url", "https://api.lb.rancher.cloud/v1")
 	RkeVersion                        = NewSetting("rke-version", "")
 	RkeMetadataConfig                 = NewSetting("rke-metadata-config", getMetadataConfig())
+	SCIMProvider                      = NewSetting("scim-provider", "") // auth provider of the users provisioned through SCIM, empty disables SCIM
 	ServerImage                       = NewSetting("server-image", "rancher/rancher")
 	ServerURL                         = NewSetting("server-url", "")
 	ServerVersion                     = NewSetting("server-version", "dev")
@@ -112,6 +132,8 @@
 	GKEUpstreamRefresh                = NewSetting("gke-refresh", "300")
 	HideLocalCluster                  = NewSetting("hide-local-cluster", "false")
 	MachineProvisionImage             = NewSetting("machine-provision-image", "rancher/machine:v0.15.0-rancher60")
+	ClusterAutoscalerImage            = NewSetting("cluster-autoscaler-image", "rancher/mirrored-cluster-autoscaler:v1.21.0")
+	ETCDSnapshotVerifyInterval        = NewSetting("etcd-snapshot-verify-interval", "86400") // seconds between two checks that an etcd snapshot still exists and matches its checksum
 
 	FleetMinVersion          = NewSetting("fleet-min-version", "")
 	RancherWebhookMinVersion = NewSetting("rancher-webhook-min-version", "")
@@ -29,6 +29,7 @@ func (a *Authorizers) Authorize(req *http.Request) (clientKey string, authed boo
 			}
 			continue
 		}
+		recordSession(req, key)
 		return key, authed, err
 	}
 
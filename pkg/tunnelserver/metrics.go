@@ -0,0 +1,69 @@
+package tunnelserver
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	tunnelSessions = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: "tunnel",
+			Name:      "sessions",
+			Help:      "Number of active agent tunnel sessions per cluster",
+		},
+		[]string{"cluster"},
+	)
+)
+
+type sessionKey struct{}
+
+// session records the cluster a tunnel request was authorized for, so the session can be accounted for when it ends.
+type session struct {
+	sync.Mutex
+	cluster string
+}
+
+// Collectors returns the tunnel metrics for registration with Prometheus.
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{tunnelSessions}
+}
+
+// SessionMetrics wraps the tunnel server handler to track active sessions per cluster. The handler blocks for the
+// lifetime of a session, the cluster is filled in by Authorizers.Authorize once the agent is authorized.
+func SessionMetrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		s := &session{}
+		next.ServeHTTP(rw, req.WithContext(context.WithValue(req.Context(), sessionKey{}, s)))
+
+		s.Lock()
+		defer s.Unlock()
+		if s.cluster != "" {
+			tunnelSessions.WithLabelValues(s.cluster).Dec()
+		}
+	})
+}
+
+func recordSession(req *http.Request, clientKey string) {
+	s, ok := req.Context().Value(sessionKey{}).(*session)
+	if !ok {
+		return
+	}
+
+	// node agents connect as <cluster>:<node>
+	cluster := clientKey
+	if i := strings.Index(cluster, ":"); i >= 0 {
+		cluster = cluster[:i]
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	if s.cluster == "" {
+		s.cluster = cluster
+		tunnelSessions.WithLabelValues(cluster).Inc()
+	}
+}